@@ -11,10 +11,16 @@ import (
 	"syscall"
 	"time"
 
+	internalPaths "zeropoint-agent/internal"
 	"zeropoint-agent/internal/api"
 	"zeropoint-agent/internal/boot"
+	"zeropoint-agent/internal/config"
 	"zeropoint-agent/internal/envoy"
 	"zeropoint-agent/internal/mdns"
+	"zeropoint-agent/internal/metrics"
+	"zeropoint-agent/internal/modules"
+	"zeropoint-agent/internal/system"
+	"zeropoint-agent/internal/terraform"
 	"zeropoint-agent/internal/xds"
 
 	"github.com/moby/moby/client"
@@ -33,6 +39,11 @@ import (
 
 // @BasePath /
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description "Bearer <token>". Read tokens may call GET endpoints; everything else needs an admin token.
+
 var (
 	// Version is set at build time via ldflags
 	version = "0.0.0-dev"
@@ -50,6 +61,7 @@ func main() {
 
 	// Customize version output to only print version string
 	rootCmd.SetVersionTemplate("{{.Version}}\n")
+	rootCmd.Flags().String("config", config.DefaultPath, "Path to the agent configuration file")
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -65,14 +77,35 @@ func run(cmd *cobra.Command, args []string) {
 
 	logger.Info("zeropoint-agent starting")
 
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	// An explicitly given config file must exist; the default one is optional
+	configPath, _ := cmd.Flags().GetString("config")
+	cfg, err := config.Load(configPath, cmd.Flags().Changed("config"))
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+	internalPaths.Configure(cfg.StorageRoot, cfg.CertsDir)
+	system.SetGPUVendorOverride(cfg.GPUVendor)
+	terraform.SetLockTimeout(time.Duration(cfg.ModuleLockTimeout))
+	modules.SetSourceAllowlist(cfg.ModuleSourceAllowlist)
+	xds.SetAccessLog(cfg.Envoy.AccessLog, cfg.Envoy.AccessLogFormat)
+	logger.Info("configuration loaded", "path", configPath, "port", cfg.Port, "storage_root", cfg.StorageRoot)
+
+	// The client configures the transport for the Docker host; it is wrapped afterwards so
+	// failed API calls are counted in the metrics. Every request goes to the same host, so
+	// keep more idle connections to it than the default two.
+	dockerTransport := http.DefaultTransport.(*http.Transport).Clone()
+	dockerTransport.MaxIdleConnsPerHost = 10
+	dockerTransport.IdleConnTimeout = 30 * time.Second
+	dockerHTTP := &http.Client{Transport: dockerTransport, CheckRedirect: client.CheckRedirect}
+	dockerClient, err := client.NewClientWithOpts(client.WithHTTPClient(dockerHTTP), client.WithHost(client.DefaultDockerHost), client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		log.Fatalf("failed to create docker client: %v", err)
 	}
 	defer dockerClient.Close()
+	dockerHTTP.Transport = metrics.InstrumentDocker(dockerHTTP.Transport)
 
 	// Start Envoy proxy
-	envoyMgr := envoy.NewManager(dockerClient, logger)
+	envoyMgr := envoy.NewManager(dockerClient, cfg.Envoy, logger)
 	if err := envoyMgr.EnsureRunning(context.Background()); err != nil {
 		log.Fatalf("failed to start envoy: %v", err)
 	}
@@ -83,8 +116,8 @@ func run(cmd *cobra.Command, args []string) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	logger.Info("starting xDS server on port 18000")
-	if err := xdsServer.Start(ctx, 18000); err != nil {
+	logger.Info("starting xDS server", "port", cfg.Envoy.XDSPort)
+	if err := xdsServer.Start(ctx, cfg.Envoy.XDSPort); err != nil {
 		log.Fatalf("failed to start xDS server: %v", err)
 	}
 	logger.Info("xDS server started successfully")
@@ -103,41 +136,37 @@ func run(cmd *cobra.Command, args []string) {
 		log.Fatalf("failed to set initial snapshot: %v", err)
 	}
 
-	// Get port from environment variable, default to 2370
-	portStr := os.Getenv("ZEROPOINT_AGENT_PORT")
-	if portStr == "" {
-		portStr = "2370"
-	}
-
-	portNum, err := strconv.Atoi(portStr)
-	if err != nil {
-		log.Fatalf("invalid port number: %v", err)
-	}
+	// Keep Envoy running; after a restart, re-push the snapshot so listeners come back
+	envoyMgr.StartMonitor(ctx, xdsServer.Resync)
+	envoyMgr.StartLogTail(ctx)
 
 	// Register mDNS service (before router so it's available for exposures)
-	mdnsService := mdns.NewService(logger)
-	if err := mdnsService.Register(context.Background(), portNum); err != nil {
+	mdnsService := mdns.NewService(cfg.MDNS, logger)
+	if err := mdnsService.Register(context.Background(), cfg.Port); err != nil {
 		logger.Warn("failed to register mDNS service", "error", err)
 	}
 	defer mdnsService.Shutdown()
 
 	// Initialize boot monitor
-	bootMonitor := boot.NewBootMonitor(logger)
+	bootMonitor := boot.NewBootMonitor(cfg.MarkerDir, cfg.Retention.BootLogLimit, logger)
 
 	// Start boot monitoring from FIFO
 	go func() {
-		if err := bootMonitor.StreamBootLog("/tmp/zeropoint-log"); err != nil {
+		if err := bootMonitor.StreamBootLog(cfg.BootLog); err != nil {
 			logger.Error("boot log monitoring failed", "error", err)
 		}
 	}()
 
-	router, err := api.NewRouter(dockerClient, xdsServer, mdnsService, bootMonitor, logger)
+	router, worker, err := api.NewRouter(cfg, dockerClient, envoyMgr, xdsServer, mdnsService, bootMonitor, logger)
 	if err != nil {
 		log.Fatalf("failed to create router: %v", err)
 	}
 
+	worker.Start(ctx)
+	logger.Info("job worker started")
+
 	srv := &http.Server{
-		Addr:    ":" + portStr,
+		Addr:    ":" + strconv.Itoa(cfg.Port),
 		Handler: router,
 	}
 
@@ -154,11 +183,27 @@ func run(cmd *cobra.Command, args []string) {
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
 
+	// Stop picking up jobs right away and drain the worker alongside the HTTP server,
+	// before cancelling the root context, so the running job gets a chance to finish.
+	// A job still running after the grace period is marked interrupted and recovered on
+	// the next start.
+	drainTimeout := time.Duration(cfg.JobDrainTimeout)
+
+	logger.Info("draining job worker", "timeout", drainTimeout)
+	drained := make(chan bool, 1)
+	go func() { drained <- worker.Drain(drainTimeout) }()
+
 	logger.Info("shutting down server")
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("server shutdown failed: %v", err)
+		logger.Error("server shutdown failed", "error", err)
+	} else {
+		logger.Info("server stopped")
+	}
+
+	if !<-drained {
+		logger.Warn("job worker did not drain in time, running job interrupted")
 	}
-	logger.Info("server stopped")
+	cancel()
 }
@@ -0,0 +1,401 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/audit"
+
+	"github.com/gorilla/mux"
+)
+
+// API token scopes. Admin includes everything read allows.
+const (
+	ScopeRead  = "read"
+	ScopeAdmin = "admin"
+)
+
+const (
+	apiTokensFileName = "api_tokens.json"
+	apiTokenPrefix    = "zpt_"
+)
+
+// APIToken is a stored API token. Only the SHA-256 of the secret is kept.
+type APIToken struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Scope     string    `json:"scope"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APITokenInfo describes a stored API token without its hash
+type APITokenInfo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Scope     string    `json:"scope"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenStore keeps the API tokens, hashed at rest, plus the admin token from the bootstrap file
+type TokenStore struct {
+	mu            sync.RWMutex
+	tokens        map[string]APIToken // keyed by ID
+	bootstrapHash string
+	storagePath   string
+	logger        *slog.Logger
+}
+
+// NewTokenStore loads the API tokens from the storage root and reads the bootstrap token
+// from bootstrapPath, creating it with a fresh admin token if it doesn't exist
+func NewTokenStore(bootstrapPath string, logger *slog.Logger) (*TokenStore, error) {
+	storageRoot := internalPaths.GetStorageRoot()
+	if err := os.MkdirAll(storageRoot, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	store := &TokenStore{
+		tokens:      make(map[string]APIToken),
+		storagePath: filepath.Join(storageRoot, apiTokensFileName),
+		logger:      logger,
+	}
+
+	data, err := os.ReadFile(store.storagePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read API tokens: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &store.tokens); err != nil {
+			return nil, fmt.Errorf("failed to parse API tokens: %w", err)
+		}
+	}
+
+	if err := store.loadBootstrapToken(bootstrapPath); err != nil {
+		logger.Warn("bootstrap API token unavailable, only stored tokens are accepted", "error", err)
+	}
+
+	return store, nil
+}
+
+// loadBootstrapToken reads the admin token from path, generating one on first start. Without
+// it the API only accepts stored tokens; it never falls back to being open.
+func (s *TokenStore) loadBootstrapToken(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	secret := strings.TrimSpace(string(data))
+	if os.IsNotExist(err) {
+		if secret, err = generateAPIToken(); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(secret+"\n"), 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		s.logger.Info("generated bootstrap API token", "path", path)
+	}
+	if secret == "" {
+		return fmt.Errorf("%s is empty", path)
+	}
+
+	s.bootstrapHash = hashAPIToken(secret)
+	return nil
+}
+
+// Create stores a new token and returns its secret, which is never shown again
+func (s *TokenStore) Create(name, scope string) (APITokenInfo, string, error) {
+	if scope != ScopeRead && scope != ScopeAdmin {
+		return APITokenInfo{}, "", fmt.Errorf("scope must be %q or %q", ScopeRead, ScopeAdmin)
+	}
+
+	secret, err := generateAPIToken()
+	if err != nil {
+		return APITokenInfo{}, "", err
+	}
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return APITokenInfo{}, "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+
+	token := APIToken{
+		ID:        hex.EncodeToString(idBytes),
+		Name:      name,
+		Scope:     scope,
+		Hash:      hashAPIToken(secret),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[token.ID] = token
+	if err := s.save(); err != nil {
+		delete(s.tokens, token.ID)
+		return APITokenInfo{}, "", err
+	}
+
+	s.logger.Info("created API token", "id", token.ID, "name", name, "scope", scope)
+	return token.info(), secret, nil
+}
+
+// List returns the stored tokens, oldest first
+func (s *TokenStore) List() []APITokenInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]APITokenInfo, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		infos = append(infos, token.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	return infos
+}
+
+// Delete revokes a stored token
+func (s *TokenStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[id]
+	if !ok {
+		return fmt.Errorf("token not found: %s", id)
+	}
+	delete(s.tokens, id)
+	if err := s.save(); err != nil {
+		s.tokens[id] = token
+		return err
+	}
+
+	s.logger.Info("deleted API token", "id", id)
+	return nil
+}
+
+// Authenticate returns the token matching a presented secret
+func (s *TokenStore) Authenticate(secret string) (APITokenInfo, bool) {
+	hash := hashAPIToken(secret)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.bootstrapHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(s.bootstrapHash)) == 1 {
+		return APITokenInfo{ID: "bootstrap", Name: "bootstrap", Scope: ScopeAdmin}, true
+	}
+	for _, token := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(token.Hash)) == 1 {
+			return token.info(), true
+		}
+	}
+	return APITokenInfo{}, false
+}
+
+// save persists the tokens (caller must hold the lock)
+func (s *TokenStore) save() error {
+	data, err := json.MarshalIndent(s.tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Atomic write: write to temp file, then rename
+	tmpPath := s.storagePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write API tokens: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		return fmt.Errorf("failed to restrict API tokens file: %w", err)
+	}
+	return os.Rename(tmpPath, s.storagePath)
+}
+
+// info hides the hash of a token
+func (t APIToken) info() APITokenInfo {
+	return APITokenInfo{
+		ID:        t.ID,
+		Name:      t.Name,
+		Scope:     t.Scope,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+func generateAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+	return apiTokenPrefix + hex.EncodeToString(b), nil
+}
+
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// requiredScope returns the scope a request needs, or "" for endpoints open to everyone.
+// Reads need the read scope; anything that changes state, token and git credential
+// management, the audit log and the full state export need admin.
+func requiredScope(r *http.Request) string {
+	path := r.URL.Path
+	switch {
+	case path == "/healthz" || path == "/readyz" || path == "/api/health" || path == "/api/healthz":
+		return ""
+	case !strings.HasPrefix(path, "/api/"):
+		return "" // Web UI static files and Prometheus metrics
+	case strings.HasPrefix(path, "/api/auth/") || strings.HasPrefix(path, "/api/modules/credentials") || path == "/api/audit" || path == "/api/system/export":
+		return ScopeAdmin
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return ScopeRead
+	default:
+		return ScopeAdmin
+	}
+}
+
+// authMiddleware requires a bearer token with the scope each route needs
+func authMiddleware(tokens *TokenStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		required := requiredScope(r)
+		if required == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(secret) == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="zeropoint"`)
+			writeAuthError(w, http.StatusUnauthorized, "unauthorized", "A bearer token is required.")
+			return
+		}
+		token, ok := tokens.Authenticate(strings.TrimSpace(secret))
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="zeropoint", error="invalid_token"`)
+			writeAuthError(w, http.StatusUnauthorized, "unauthorized", "The bearer token is invalid or revoked.")
+			return
+		}
+		audit.SetPrincipal(r, token.Name+" ("+token.ID+")")
+		if required == ScopeAdmin && token.Scope != ScopeAdmin {
+			writeAuthError(w, http.StatusForbidden, "forbidden", "This endpoint requires a token with the admin scope.")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeAuthError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   code,
+		"message": message,
+	})
+}
+
+// AuthHandlers manages API tokens
+type AuthHandlers struct {
+	store  *TokenStore
+	logger *slog.Logger
+}
+
+// NewAuthHandlers creates a new auth handlers instance
+func NewAuthHandlers(store *TokenStore, logger *slog.Logger) *AuthHandlers {
+	return &AuthHandlers{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// CreateTokenRequest creates an API token
+type CreateTokenRequest struct {
+	Name  string `json:"name" example:"monitoring"`
+	Scope string `json:"scope" example:"read"` // "read" or "admin"
+}
+
+// CreateTokenResponse returns a new API token
+type CreateTokenResponse struct {
+	APITokenInfo
+	Token string `json:"token"` // Only returned once; store it now
+}
+
+// CreateToken handles POST /auth/tokens
+// @ID createAuthToken
+// @Summary Create an API token
+// @Description Creates a bearer token with the read or admin scope. Read tokens can only call GET endpoints. The token is returned once; only its hash is stored. Requires the admin scope.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body CreateTokenRequest true "Token"
+// @Success 201 {object} CreateTokenResponse
+// @Failure 400 {string} string "Invalid request"
+// @Failure 401 {object} map[string]string "Missing or invalid token"
+// @Failure 403 {object} map[string]string "Token lacks the admin scope"
+// @Router /auth/tokens [post]
+func (h *AuthHandlers) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON in request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	info, secret, err := h.store.Create(req.Name, req.Scope)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateTokenResponse{APITokenInfo: info, Token: secret})
+}
+
+// ListTokens handles GET /auth/tokens
+// @ID listAuthTokens
+// @Summary List API tokens
+// @Description Lists the stored API tokens without their secrets. The bootstrap token is not listed. Requires the admin scope.
+// @Tags auth
+// @Produce json
+// @Success 200 {array} APITokenInfo
+// @Failure 401 {object} map[string]string "Missing or invalid token"
+// @Failure 403 {object} map[string]string "Token lacks the admin scope"
+// @Router /auth/tokens [get]
+func (h *AuthHandlers) ListTokens(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.store.List())
+}
+
+// DeleteToken handles DELETE /auth/tokens/{id}
+// @ID deleteAuthToken
+// @Summary Revoke an API token
+// @Description Requires the admin scope.
+// @Tags auth
+// @Param id path string true "Token ID"
+// @Success 204
+// @Failure 401 {object} map[string]string "Missing or invalid token"
+// @Failure 403 {object} map[string]string "Token lacks the admin scope"
+// @Failure 404 {string} string "Token not found"
+// @Router /auth/tokens/{id} [delete]
+func (h *AuthHandlers) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.Delete(mux.Vars(r)["id"]); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
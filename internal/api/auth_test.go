@@ -0,0 +1,71 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	internalPaths "zeropoint-agent/internal"
+)
+
+func TestFreshInstallRequiresToken(t *testing.T) {
+	internalPaths.Configure(t.TempDir(), t.TempDir())
+	t.Cleanup(func() { internalPaths.Configure(".", "/etc/zeropoint/certs") })
+
+	tokenFile := filepath.Join(t.TempDir(), "zeropoint", "api-token")
+	tokens, err := NewTokenStore(tokenFile, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(tokenFile)
+	if err != nil {
+		t.Fatalf("no bootstrap token written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("bootstrap token mode = %v, want 0600", info.Mode().Perm())
+	}
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrap := strings.TrimSpace(string(data))
+
+	handler := authMiddleware(tokens, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	request := func(method, path, token string) int {
+		r := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Nobody can mint the first token, or reach anything else, without the bootstrap token
+	if code := request(http.MethodPost, "/api/auth/tokens", ""); code != http.StatusUnauthorized {
+		t.Errorf("creating a token without auth returned %d, want 401", code)
+	}
+	if code := request(http.MethodGet, "/api/modules", ""); code != http.StatusUnauthorized {
+		t.Errorf("listing modules without auth returned %d, want 401", code)
+	}
+	if code := request(http.MethodPost, "/api/auth/tokens", bootstrap); code != http.StatusNoContent {
+		t.Errorf("creating a token with the bootstrap token returned %d", code)
+	}
+
+	// A restart reads the same token instead of replacing it
+	again, err := NewTokenStore(tokenFile, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := again.Authenticate(bootstrap); !ok {
+		t.Error("bootstrap token changed across restarts")
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -25,16 +26,32 @@ func NewBootHandlers(monitor *boot.BootMonitor) *BootHandlers {
 }
 
 // HandleBootStatus serves GET /api/boot/status
-// Returns an ordered array of service marker lists in the order observed.
+// Returns the current boot status snapshot.
 //
 // @ID getBootStatus
+// @Summary Get boot status
+// @Description Returns whether boot is complete or failed, the current phase, per-phase and per-service states, failed services and the most recent log entries
+// @Tags boot
+// @Produce json
+// @Success 200 {object} boot.BootStatus
+// @Router /api/boot/status [get]
+func (h *BootHandlers) HandleBootStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.monitor.GetStatus())
+}
+
+// HandleBootServices serves GET /api/boot/services
+// Returns an ordered array of service marker lists in the order observed.
+//
+// @ID listBootServices
 // @Summary Get boot service markers
 // @Description Returns an ordered array of services each with an array of MarkerEntry seen so far for that service
 // @Tags boot
 // @Produce json
 // @Success 200 {array} boot.ServiceMarkers "Ordered list of services with markers"
-// @Router /api/boot/status [get]
-func (h *BootHandlers) HandleBootStatus(w http.ResponseWriter, r *http.Request) {
+// @Router /api/boot/services [get]
+func (h *BootHandlers) HandleBootServices(w http.ResponseWriter, r *http.Request) {
 	// Return ordered slice: [{service, markers}, ...]
 	markers := h.monitor.GetServiceStatuses()
 	w.Header().Set("Content-Type", "application/json")
@@ -42,7 +59,7 @@ func (h *BootHandlers) HandleBootStatus(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(markers)
 }
 
-// HandleBootService serves GET /api/boot/status/{service}
+// HandleBootService serves GET /api/boot/services/{service}, also at /api/boot/status/{service}
 // Returns marker history for a single service as an array of MarkerEntry
 // @ID getBootService
 // @Summary Get service marker history
@@ -51,7 +68,7 @@ func (h *BootHandlers) HandleBootStatus(w http.ResponseWriter, r *http.Request)
 // @Produce json
 // @Param service path string true "Service name"
 // @Success 200 {array} boot.MarkerEntry
-// @Router /api/boot/status/{service} [get]
+// @Router /api/boot/services/{service} [get]
 func (h *BootHandlers) HandleBootService(w http.ResponseWriter, r *http.Request) {
 	// Extract service from URL path using mux vars if present
 	service := ""
@@ -60,8 +77,9 @@ func (h *BootHandlers) HandleBootService(w http.ResponseWriter, r *http.Request)
 	}
 	if service == "" {
 		// Fallback: parse path directly
-		// path: /api/boot/status/{service}
-		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/boot/status/"), "/")
+		// path: /api/boot/services/{service} or /api/boot/status/{service}
+		path := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/boot/services/"), "/api/boot/status/")
+		parts := strings.Split(path, "/")
 		if len(parts) > 0 {
 			service = parts[0]
 		}
@@ -131,7 +149,7 @@ func (h *BootHandlers) HandleBootMarker(w http.ResponseWriter, r *http.Request)
 //
 // @ID getBootLogs
 // @Summary Get boot logs
-// @Description Returns boot process logs with optional filtering by service or level
+// @Description Returns boot process logs with optional filtering by service and level
 // @Tags boot
 // @Produce json
 // @Param service query string false "Filter by service name"
@@ -158,14 +176,21 @@ func (h *BootHandlers) HandleBootLogs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Get logs by service
+	// Get logs by service and level; both filters apply when given
 	var logs []boot.LogEntry
-	if service != "" {
-		logs = h.monitor.GetLogsByService(service)
-	} else if level != "" {
+	if service == "" && level != "" {
 		logs = h.monitor.GetLogsByLevel(level)
 	} else {
-		logs = h.monitor.GetLogsByService("")
+		logs = h.monitor.GetLogsByService(service)
+		if level != "" {
+			filtered := make([]boot.LogEntry, 0, len(logs))
+			for _, entry := range logs {
+				if entry.Level == level {
+					filtered = append(filtered, entry)
+				}
+			}
+			logs = filtered
+		}
 	}
 
 	// Apply offset and limit
@@ -196,15 +221,6 @@ func (h *BootHandlers) HandleBootLogs(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// HandleBootStream serves WS /api/boot/stream
-// Streams boot status updates in real-time
-//
-// @ID streamBootUpdates
-// @Summary Stream boot updates
-// @Description WebSocket endpoint that streams real-time boot status updates and log entries
-// @Tags boot
-// @Success 101 "Switching Protocols"
-// @Router /api/boot/stream [get]
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -214,14 +230,82 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// HandleBootStream serves GET /api/boot/stream
+// Streams boot status updates in real-time, over WebSocket when the client asks for an
+// upgrade and as Server-Sent Events otherwise
+//
+// @ID streamBootUpdates
+// @Summary Stream boot updates
+// @Description Streams the current boot status followed by real-time updates. Each message is a StatusUpdate whose type is "status_update" (data is the BootStatus) or "log_entry" (data is a LogEntry). Without a WebSocket upgrade, messages are sent as Server-Sent Events named after their type.
+// @Tags boot
+// @Produce text/event-stream
+// @Success 200 {object} boot.StatusUpdate "Server-Sent Events stream"
+// @Success 101 "Switching Protocols"
+// @Router /api/boot/stream [get]
 func (h *BootHandlers) HandleBootStream(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		h.streamWebSocket(w, r)
+		return
+	}
+	h.streamSSE(w, r)
+}
+
+// streamSSE sends boot updates as Server-Sent Events until the client disconnects
+func (h *BootHandlers) streamSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before taking the snapshot so no update falls in between
+	sub := h.monitor.Subscribe()
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	send := func(update boot.StatusUpdate) bool {
+		data, err := json.Marshal(update)
+		if err != nil {
+			return true
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", update.Type, data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	if !send(boot.StatusUpdate{Type: "status_update", Data: h.monitor.GetStatus()}) {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case update, ok := <-sub.C:
+			if !ok || !send(update) {
+				return
+			}
+		}
+	}
+}
+
+// streamWebSocket sends boot updates over a WebSocket until the client disconnects
+func (h *BootHandlers) streamWebSocket(w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		http.Error(w, "failed to upgrade connection", http.StatusBadRequest)
+		// Upgrade has already replied to the client
 		return
 	}
 	defer conn.Close()
 
+	sub := h.monitor.Subscribe()
+	defer sub.Close()
+
 	// Send current status immediately as a status_update
 	status := h.monitor.GetStatus()
 	initialUpdate := boot.StatusUpdate{
@@ -232,16 +316,29 @@ func (h *BootHandlers) HandleBootStream(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Subscribe to updates
-	updates := h.monitor.Subscribe()
-	defer func() {
-		// TODO: unsubscribe from monitor
+	// The client never sends anything; reading only notices when it goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
 	}()
 
 	// Stream updates to client
-	for update := range updates {
-		if err := conn.WriteJSON(update); err != nil {
+	for {
+		select {
+		case <-closed:
 			return
+		case update, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(update); err != nil {
+				return
+			}
 		}
 	}
 }
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"zeropoint-agent/internal/modules"
+	"zeropoint-agent/internal/queue"
 
 	"github.com/gorilla/mux"
 )
@@ -19,17 +21,19 @@ type BundleHandlers struct {
 	exposureHandlers *ExposureHandlers
 	linkHandlers     *LinkHandlers
 	uninstaller      *modules.Uninstaller
+	jobs             *queue.Manager
 	logger           *slog.Logger
 }
 
 // NewBundleHandlers creates a new bundle handlers instance
-func NewBundleHandlers(bundleStore *BundleStore, exposureStore *ExposureStore, exposureHandlers *ExposureHandlers, linkHandlers *LinkHandlers, uninstaller *modules.Uninstaller, logger *slog.Logger) *BundleHandlers {
+func NewBundleHandlers(bundleStore *BundleStore, exposureStore *ExposureStore, exposureHandlers *ExposureHandlers, linkHandlers *LinkHandlers, uninstaller *modules.Uninstaller, jobs *queue.Manager, logger *slog.Logger) *BundleHandlers {
 	return &BundleHandlers{
 		bundleStore:      bundleStore,
 		exposureStore:    exposureStore,
 		exposureHandlers: exposureHandlers,
 		linkHandlers:     linkHandlers,
 		uninstaller:      uninstaller,
+		jobs:             jobs,
 		logger:           logger,
 	}
 }
@@ -52,11 +56,44 @@ type BundleResponse struct {
 	Links map[string][]BundleLink `json:"links,omitempty"`
 	// Map of exposure IDs to exposure details
 	Exposures map[string]BundleExposure `json:"exposures,omitempty"`
-	// Bundle status: "queued", "running", "completed", "failed", "partially_completed"
+	// Bundle status: "queued", "running", "completed", "failed", "rolling_back", "rolled_back"
 	// required: true
 	Status string `json:"status"`
 	// Unix timestamp when bundle was installed
 	InstalledAt int64 `json:"installed_at,omitempty"`
+	// Every component with whether uninstalling this bundle would remove it
+	Components []BundleComponentOwnership `json:"components"`
+}
+
+// swagger:model BundleComponentOwnership
+type BundleComponentOwnership struct {
+	// Component kind: "module", "link" or "exposure"
+	Kind string `json:"kind"`
+	// Component ID
+	ID string `json:"id"`
+	// Whether another installed bundle also uses this component, so uninstalling this bundle retains it
+	Shared bool `json:"shared"`
+	// IDs of the other bundles using this component
+	SharedWith []string `json:"shared_with,omitempty"`
+}
+
+// componentOwnership lists a bundle's components with the other bundles sharing them
+func componentOwnership(record *BundleRecord, shares bundleShares) []BundleComponentOwnership {
+	components := make([]BundleComponentOwnership, 0, len(record.Components.Modules)+len(record.Components.Links)+len(record.Components.Exposures))
+	add := func(kind string, statuses []BundleComponentStatus, shared map[string][]string) {
+		for _, comp := range statuses {
+			components = append(components, BundleComponentOwnership{
+				Kind:       kind,
+				ID:         comp.ID,
+				Shared:     len(shared[comp.ID]) > 0,
+				SharedWith: shared[comp.ID],
+			})
+		}
+	}
+	add("module", record.Components.Modules, shares.Modules)
+	add("link", record.Components.Links, shares.Links)
+	add("exposure", record.Components.Exposures, shares.Exposures)
+	return components
 }
 
 // swagger:model BundleLink
@@ -112,6 +149,8 @@ func (h *BundleHandlers) ListBundles(w http.ResponseWriter, r *http.Request) {
 		for _, comp := range record.Components.Exposures {
 			bundle.Exposures[comp.ID] = BundleExposure{}
 		}
+		shares, _ := h.bundleStore.ComponentShares(record.ID)
+		bundle.Components = componentOwnership(record, shares)
 
 		bundles = append(bundles, bundle)
 	}
@@ -123,7 +162,7 @@ func (h *BundleHandlers) ListBundles(w http.ResponseWriter, r *http.Request) {
 // GetBundle handles GET /api/bundles/{bundle-id} - gets a specific installed bundle
 // @ID getBundle
 // @Summary Get bundle details
-// @Description Get details of a specific installed bundle
+// @Description Get details of a specific installed bundle, including which components are shared with other bundles and would be retained on uninstall
 // @Tags bundles
 // @Produce json
 // @Param bundle-id path string true "Bundle ID"
@@ -167,6 +206,8 @@ func (h *BundleHandlers) GetBundle(w http.ResponseWriter, r *http.Request) {
 	for _, comp := range record.Components.Exposures {
 		bundle.Exposures[comp.ID] = BundleExposure{}
 	}
+	shares, _ := h.bundleStore.ComponentShares(record.ID)
+	bundle.Components = componentOwnership(record, shares)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(bundle)
@@ -175,7 +216,7 @@ func (h *BundleHandlers) GetBundle(w http.ResponseWriter, r *http.Request) {
 // DeleteBundle handles DELETE /api/bundles/{bundle-id} - uninstalls all bundle components immediately with streaming updates
 // @ID deleteBundle
 // @Summary Delete a bundle (immediate uninstall with streaming updates)
-// @Description Uninstall all bundle components immediately and remove the bundle. Streams SSE updates for each component.
+// @Description Uninstall all bundle components immediately and remove the bundle. Components another installed bundle still uses are retained. Streams SSE updates for each component.
 // @Tags bundles
 // @Produce text/event-stream
 // @Param bundle-id path string true "Bundle ID"
@@ -212,8 +253,22 @@ func (h *BundleHandlers) DeleteBundle(w http.ResponseWriter, r *http.Request) {
 
 	ctx := context.Background()
 
+	// Components another bundle still uses are left in place
+	shares, _ := h.bundleStore.ComponentShares(bundleID)
+	retain := func(kind, id string, shared map[string][]string) bool {
+		if len(shared[id]) == 0 {
+			return false
+		}
+		fmt.Fprintf(w, "data: {\"component\":\"%s\",\"type\":\"%s\",\"status\":\"retained\",\"message\":\"still used by bundle %s\"}\n\n", id, kind, strings.Join(shared[id], ", "))
+		flusher.Flush()
+		return true
+	}
+
 	// Uninstall exposures first (they have no dependencies)
 	for _, expComp := range record.Components.Exposures {
+		if retain("exposure", expComp.ID, shares.Exposures) {
+			continue
+		}
 		if err := h.exposureHandlers.DeleteExposure(ctx, expComp.ID); err != nil {
 			h.logger.Error("failed to delete exposure", "exposure_id", expComp.ID, "error", err)
 			fmt.Fprintf(w, "data: {\"component\":\"%s\",\"type\":\"exposure\",\"status\":\"failed\",\"error\":\"%s\"}\n\n", expComp.ID, err.Error())
@@ -225,6 +280,9 @@ func (h *BundleHandlers) DeleteBundle(w http.ResponseWriter, r *http.Request) {
 
 	// Delete links second (modules still exist)
 	for _, linkComp := range record.Components.Links {
+		if retain("link", linkComp.ID, shares.Links) {
+			continue
+		}
 		if err := h.linkHandlers.DeleteLink(ctx, linkComp.ID); err != nil {
 			h.logger.Error("failed to delete link", "link_id", linkComp.ID, "error", err)
 			fmt.Fprintf(w, "data: {\"component\":\"%s\",\"type\":\"link\",\"status\":\"failed\",\"error\":\"%s\"}\n\n", linkComp.ID, err.Error())
@@ -236,6 +294,9 @@ func (h *BundleHandlers) DeleteBundle(w http.ResponseWriter, r *http.Request) {
 
 	// Uninstall modules last (they're the foundation)
 	for _, modComp := range record.Components.Modules {
+		if retain("module", modComp.ID, shares.Modules) {
+			continue
+		}
 		// Create a no-op progress callback
 		noOpCallback := func(update modules.ProgressUpdate) {}
 		if err := h.uninstaller.Uninstall(modules.UninstallRequest{ModuleID: modComp.ID}, noOpCallback); err != nil {
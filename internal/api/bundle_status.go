@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"zeropoint-agent/internal/queue"
+
+	"github.com/gorilla/mux"
+)
+
+// BundleComponentProgress is the live state of one bundle component
+type BundleComponentProgress struct {
+	Kind   string `json:"kind"` // "module", "link" or "exposure"
+	ID     string `json:"id"`
+	Status string `json:"status"` // "queued", "running", "completed", "failed", "cancelled", "deleted" or "unknown"
+	JobID  string `json:"job_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BundleStatusResponse is returned by GET /bundles/{bundle-id}/status
+type BundleStatusResponse struct {
+	ID         string                    `json:"id"`
+	Name       string                    `json:"name"`
+	Status     string                    `json:"status"`
+	JobID      string                    `json:"job_id,omitempty"`
+	Percent    int                       `json:"percent"` // Share of components whose job finished, 0-100
+	Components []BundleComponentProgress `json:"components"`
+}
+
+// GetBundleStatus handles GET /api/bundles/{bundle-id}/status
+// @ID getBundleStatus
+// @Summary Get bundle installation progress
+// @Description Combines the bundle record with the live state of each component's job. A component still recorded as queued takes the status of its job, so progress stays accurate if the agent restarted before the bundle job recorded it.
+// @Tags bundles
+// @Produce json
+// @Param bundle-id path string true "Bundle ID"
+// @Success 200 {object} BundleStatusResponse "Bundle progress"
+// @Failure 404 {string} string "Bundle not found"
+// @Router /bundles/{bundle-id}/status [get]
+func (h *BundleHandlers) GetBundleStatus(w http.ResponseWriter, r *http.Request) {
+	bundleID := mux.Vars(r)["bundle-id"]
+
+	recordIface, err := h.bundleStore.GetBundle(bundleID)
+	if err != nil {
+		http.Error(w, "bundle not found", http.StatusNotFound)
+		return
+	}
+	record, ok := recordIface.(*BundleRecord)
+	if !ok {
+		http.Error(w, "invalid bundle record", http.StatusInternalServerError)
+		return
+	}
+
+	response := BundleStatusResponse{
+		ID:     record.ID,
+		Name:   record.Name,
+		Status: record.Status,
+		JobID:  record.JobID,
+	}
+
+	// Component jobs are the dependencies of the bundle_install job
+	componentJobs := make(map[string]*queue.JobResponse)
+	if record.JobID != "" && h.jobs != nil {
+		if bundleJob, err := h.jobs.Get(record.JobID); err == nil {
+			for _, depJobID := range bundleJob.DependsOn {
+				depJob, err := h.jobs.Get(depJobID)
+				if err != nil {
+					continue
+				}
+				for _, key := range componentJobKeys(depJob.Command) {
+					componentJobs[key] = depJob
+				}
+			}
+
+			// The record stays "running" if the agent stopped before the bundle job finished it
+			if record.Status == "running" && (bundleJob.Status == queue.StatusFailed || bundleJob.Status == queue.StatusCancelled) {
+				response.Status = "failed"
+			}
+		}
+	}
+
+	finished := 0
+	add := func(kind string, components []BundleComponentStatus) {
+		for _, comp := range components {
+			progress := BundleComponentProgress{Kind: kind, ID: comp.ID, Status: comp.Status, Error: comp.Error}
+			if job, ok := componentJobs[kind+"/"+comp.ID]; ok {
+				progress.JobID = job.ID
+				if comp.Status == "queued" {
+					progress.Status = string(job.Status)
+					progress.Error = job.Error
+				}
+			} else if comp.Status == "queued" && response.Status != "running" {
+				// The job is gone and the bundle no longer waits for it
+				progress.Status = "unknown"
+			}
+			if progress.Status != "queued" && progress.Status != "running" {
+				finished++
+			}
+			response.Components = append(response.Components, progress)
+		}
+	}
+	add("module", record.Components.Modules)
+	add("link", record.Components.Links)
+	add("exposure", record.Components.Exposures)
+
+	if len(response.Components) == 0 {
+		response.Components = []BundleComponentProgress{}
+		if response.Status != "running" {
+			response.Percent = 100
+		}
+	} else {
+		response.Percent = finished * 100 / len(response.Components)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// componentJobKeys identifies the bundle components a job installs, as "<kind>/<id>"
+func componentJobKeys(cmd queue.Command) []string {
+	var kind, arg string
+	switch cmd.Type {
+	case queue.CmdInstallModule:
+		kind, arg = "module", "module_id"
+	case queue.CmdCreateLink:
+		kind, arg = "link", "link_id"
+	case queue.CmdCreateExposure:
+		kind, arg = "exposure", "exposure_id"
+	case queue.CmdCreateExposures:
+		var keys []string
+		for _, id := range queue.BatchExposureIDs(cmd) {
+			keys = append(keys, "exposure/"+id)
+		}
+		return keys
+	default:
+		return nil
+	}
+	id, _ := cmd.Args[arg].(string)
+	if id == "" {
+		return nil
+	}
+	return []string{kind + "/" + id}
+}
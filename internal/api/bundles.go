@@ -6,10 +6,13 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
 	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/catalog"
+	"zeropoint-agent/internal/queue"
 )
 
 const bundlesFileName = "bundles.json"
@@ -17,7 +20,7 @@ const bundlesFileName = "bundles.json"
 // BundleComponentStatus represents the status of a bundle component
 type BundleComponentStatus struct {
 	ID     string `json:"id"`
-	Status string `json:"status"` // "completed", "failed", "pending", "running"
+	Status string `json:"status"` // "queued", "completed", "failed", "cancelled" or "deleted"
 	Error  string `json:"error,omitempty"`
 }
 
@@ -32,11 +35,15 @@ type BundleComponents struct {
 type BundleRecord struct {
 	ID          string           `json:"id"`
 	Name        string           `json:"name"`
-	Status      string           `json:"status"` // "running", "completed", "failed", "partially_completed"
+	Status      string           `json:"status"` // "running", "completed", "failed", "rolling_back" or "rolled_back"
 	InstalledAt time.Time        `json:"installed_at"`
 	CompletedAt *time.Time       `json:"completed_at,omitempty"`
 	Components  BundleComponents `json:"components"`
 	JobID       string           `json:"job_id,omitempty"` // Reference to the bundle_install job
+
+	// Catalog definition the components were last installed or upgraded from; absent for
+	// bundles installed before definitions were recorded
+	Definition *catalog.CatalogBundle `json:"definition,omitempty"`
 }
 
 // BundleStore manages installed bundles with persistent storage
@@ -225,8 +232,23 @@ func (s *BundleStore) UpdateExposureComponentStatus(bundleID, exposureID, status
 	return fmt.Errorf("exposure component not found: %s", exposureID)
 }
 
-// CompleteBundleInstallation marks the bundle as completed or failed
-func (s *BundleStore) CompleteBundleInstallation(bundleID string, success bool) error {
+// SetBundleStatus updates the status of a bundle that is still in progress, e.g. "rolling_back"
+func (s *BundleStore) SetBundleStatus(bundleID, status string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	bundle, ok := s.bundles[bundleID]
+	if !ok {
+		return fmt.Errorf("bundle not found: %s", bundleID)
+	}
+
+	bundle.Status = status
+	return s.save()
+}
+
+// CompleteBundleInstallation records the terminal status of a bundle installation:
+// "completed", "failed" or "rolled_back"
+func (s *BundleStore) CompleteBundleInstallation(bundleID, status string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
@@ -237,45 +259,82 @@ func (s *BundleStore) CompleteBundleInstallation(bundleID string, success bool)
 
 	now := time.Now()
 	bundle.CompletedAt = &now
+	bundle.Status = status
 
-	if success {
-		bundle.Status = "completed"
-	} else {
-		// Check if any components failed
-		hasFailed := false
-		for _, comp := range bundle.Components.Modules {
-			if comp.Status == "failed" {
-				hasFailed = true
-				break
-			}
-		}
-		if !hasFailed {
-			for _, comp := range bundle.Components.Links {
-				if comp.Status == "failed" {
-					hasFailed = true
-					break
-				}
-			}
-		}
-		if !hasFailed {
-			for _, comp := range bundle.Components.Exposures {
-				if comp.Status == "failed" {
-					hasFailed = true
-					break
-				}
-			}
-		}
+	return s.save()
+}
 
-		if hasFailed {
-			bundle.Status = "partially_completed"
-		} else {
-			bundle.Status = "completed"
-		}
+// SetDefinition records the catalog definition a bundle is installed from
+func (s *BundleStore) SetDefinition(bundleID string, definition catalog.CatalogBundle) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	bundle, ok := s.bundles[bundleID]
+	if !ok {
+		return fmt.Errorf("bundle not found: %s", bundleID)
+	}
+
+	bundle.Definition = &definition
+	return s.save()
+}
+
+// UpgradeBundle replaces a bundle's definition and components after a successful upgrade.
+// Touched components are marked completed; untouched ones keep their status.
+func (s *BundleStore) UpgradeBundle(bundleID string, definition catalog.CatalogBundle, touched queue.BundleDelta) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	bundle, ok := s.bundles[bundleID]
+	if !ok {
+		return fmt.Errorf("bundle not found: %s", bundleID)
+	}
+
+	linkIDs := make([]string, 0, len(definition.Links))
+	for linkID := range definition.Links {
+		linkIDs = append(linkIDs, linkID)
+	}
+	exposureIDs := make([]string, 0, len(definition.Exposures))
+	for exposureID := range definition.Exposures {
+		exposureIDs = append(exposureIDs, exposureID)
+	}
+	sort.Strings(linkIDs)
+	sort.Strings(exposureIDs)
+
+	bundle.Components = BundleComponents{
+		Modules:   upgradedComponents(bundle.Components.Modules, definition.Modules, touched.Modules),
+		Links:     upgradedComponents(bundle.Components.Links, linkIDs, touched.Links),
+		Exposures: upgradedComponents(bundle.Components.Exposures, exposureIDs, touched.Exposures),
 	}
+	bundle.Definition = &definition
+	bundle.Status = "completed"
+	now := time.Now()
+	bundle.CompletedAt = &now
 
 	return s.save()
 }
 
+// upgradedComponents lists ids as components, keeping the previous status of untouched ones
+func upgradedComponents(previous []BundleComponentStatus, ids, touched []string) []BundleComponentStatus {
+	byID := make(map[string]BundleComponentStatus, len(previous))
+	for _, component := range previous {
+		byID[component.ID] = component
+	}
+	touchedIDs := make(map[string]bool, len(touched))
+	for _, id := range touched {
+		touchedIDs[id] = true
+	}
+
+	components := make([]BundleComponentStatus, 0, len(ids))
+	for _, id := range ids {
+		component, ok := byID[id]
+		if !ok || touchedIDs[id] {
+			component = BundleComponentStatus{ID: id, Status: "completed"}
+		}
+		components = append(components, component)
+	}
+	return components
+}
+
 // GetBundle retrieves a bundle by ID
 func (s *BundleStore) GetBundle(bundleID string) (interface{}, error) {
 	s.mutex.RLock()
@@ -289,6 +348,94 @@ func (s *BundleStore) GetBundle(bundleID string) (interface{}, error) {
 	return bundle, nil
 }
 
+// GetBundleComponents returns a bundle's catalog name, recorded definition and component IDs
+func (s *BundleStore) GetBundleComponents(bundleID string) (*queue.InstalledBundle, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	bundle, ok := s.bundles[bundleID]
+	if !ok {
+		return nil, fmt.Errorf("bundle not found: %s", bundleID)
+	}
+
+	shares := s.sharesLocked(bundle)
+	installed := &queue.InstalledBundle{
+		Name:            bundle.Name,
+		Definition:      bundle.Definition,
+		Modules:         componentIDs(bundle.Components.Modules),
+		Links:           componentIDs(bundle.Components.Links),
+		Exposures:       componentIDs(bundle.Components.Exposures),
+		SharedModules:   shares.Modules,
+		SharedLinks:     shares.Links,
+		SharedExposures: shares.Exposures,
+	}
+	return installed, nil
+}
+
+// bundleShares maps each component of a bundle that other bundles also hold to those
+// bundles' IDs
+type bundleShares struct {
+	Modules   map[string][]string
+	Links     map[string][]string
+	Exposures map[string][]string
+}
+
+// ComponentShares returns the components of a bundle that other installed bundles also hold
+func (s *BundleStore) ComponentShares(bundleID string) (bundleShares, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	bundle, ok := s.bundles[bundleID]
+	if !ok {
+		return bundleShares{}, fmt.Errorf("bundle not found: %s", bundleID)
+	}
+	return s.sharesLocked(bundle), nil
+}
+
+// sharesLocked finds the other bundles holding each of a bundle's components (caller must
+// hold the lock)
+func (s *BundleStore) sharesLocked(bundle *BundleRecord) bundleShares {
+	holders := func(pick func(*BundleRecord) []BundleComponentStatus) map[string][]string {
+		shared := make(map[string][]string)
+		for _, component := range pick(bundle) {
+			for otherID, other := range s.bundles {
+				if otherID == bundle.ID || other.Status == "rolled_back" {
+					continue
+				}
+				for _, otherComponent := range pick(other) {
+					if otherComponent.ID == component.ID && holdsComponent(otherComponent.Status) {
+						shared[component.ID] = append(shared[component.ID], otherID)
+						break
+					}
+				}
+			}
+			sort.Strings(shared[component.ID])
+		}
+		return shared
+	}
+
+	return bundleShares{
+		Modules:   holders(func(b *BundleRecord) []BundleComponentStatus { return b.Components.Modules }),
+		Links:     holders(func(b *BundleRecord) []BundleComponentStatus { return b.Components.Links }),
+		Exposures: holders(func(b *BundleRecord) []BundleComponentStatus { return b.Components.Exposures }),
+	}
+}
+
+// holdsComponent reports whether a bundle still relies on a component with the given status.
+// Components that were deleted, or never created, don't keep a shared component alive.
+func holdsComponent(status string) bool {
+	return status != "deleted" && status != "failed" && status != "cancelled"
+}
+
+// componentIDs returns the IDs of a list of bundle components, in order
+func componentIDs(components []BundleComponentStatus) []string {
+	ids := make([]string, 0, len(components))
+	for _, component := range components {
+		ids = append(ids, component.ID)
+	}
+	return ids
+}
+
 // ListBundles returns all installed bundles
 func (s *BundleStore) ListBundles() []*BundleRecord {
 	s.mutex.RLock()
@@ -343,3 +490,5 @@ func (s *BundleStore) load() error {
 
 	return json.Unmarshal(data, &s.bundles)
 }
+
+var _ queue.BundleStoreHandler = (*BundleStore)(nil)
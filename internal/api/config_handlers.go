@@ -0,0 +1,31 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"zeropoint-agent/internal/config"
+)
+
+// ConfigHandlers serves the agent's effective configuration
+type ConfigHandlers struct {
+	cfg *config.Config
+}
+
+// NewConfigHandlers creates a new config handlers instance
+func NewConfigHandlers(cfg *config.Config) *ConfigHandlers {
+	return &ConfigHandlers{cfg: cfg}
+}
+
+// GetConfig handles GET /config
+// @ID getConfig
+// @Summary Get the effective agent configuration
+// @Description Returns the configuration the agent runs with: the built-in defaults, overlaid by the config file and then by environment variables. Credentials embedded in URLs are redacted.
+// @Tags system
+// @Produce json
+// @Success 200 {object} config.Config
+// @Router /config [get]
+func (h *ConfigHandlers) GetConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.cfg.Redacted())
+}
@@ -88,12 +88,71 @@ func (g *DependencyGraph) TopologicalSort() ([]string, error) {
 
 	// Check for cycles
 	if len(result) != len(g.nodes) {
-		return nil, fmt.Errorf("circular dependency detected")
+		return nil, fmt.Errorf("circular dependency detected: %s", strings.Join(g.FindCycle(), " → "))
 	}
 
 	return result, nil
 }
 
+// FindCycle returns one dependency cycle as a path that starts and ends with the same app
+// (a → b → a, each depending on the next), or nil if the graph is acyclic
+func (g *DependencyGraph) FindCycle() []string {
+	// Walk "depends on" edges; edges are stored in the reverse direction
+	dependsOn := make(map[string][]string)
+	for to, froms := range g.edges {
+		for _, from := range froms {
+			dependsOn[from] = append(dependsOn[from], to)
+		}
+	}
+
+	apps := make([]string, 0, len(g.nodes))
+	for app := range g.nodes {
+		apps = append(apps, app)
+		sort.Strings(dependsOn[app])
+	}
+	sort.Strings(apps)
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(app string) []string
+	visit = func(app string) []string {
+		state[app] = inProgress
+		path = append(path, app)
+		for _, dep := range dependsOn[app] {
+			switch state[dep] {
+			case inProgress:
+				for i, p := range path {
+					if p == dep {
+						return append(append([]string{}, path[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[app] = done
+		return nil
+	}
+
+	for _, app := range apps {
+		if state[app] == unvisited {
+			if cycle := visit(app); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
 // AnalyzeDependencies builds a dependency graph from app configurations
 func AnalyzeDependencies(apps map[string]map[string]interface{}) (*DependencyGraph, error) {
 	graph := NewDependencyGraph()
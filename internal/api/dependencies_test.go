@@ -0,0 +1,58 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTopologicalSortReportsCycle(t *testing.T) {
+	// a depends on b, b on c, c on a; d depends on a but is not part of the cycle
+	apps := map[string]map[string]interface{}{
+		"a": {"url": "${b.url}"},
+		"b": {"host": map[string]interface{}{"from_module": "c", "output": "host"}},
+		"c": {"token": "${a.token}"},
+		"d": {"url": "${a.url}"},
+	}
+
+	graph, err := AnalyzeDependencies(apps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cycle := graph.FindCycle(); !reflect.DeepEqual(cycle, []string{"a", "b", "c", "a"}) {
+		t.Errorf("FindCycle() = %v, want [a b c a]", cycle)
+	}
+
+	_, err = graph.TopologicalSort()
+	if err == nil {
+		t.Fatal("TopologicalSort accepted a cyclic graph")
+	}
+	if !strings.Contains(err.Error(), "a → b → c → a") {
+		t.Errorf("error %q does not name the cycle a → b → c → a", err)
+	}
+}
+
+func TestTopologicalSortAcyclic(t *testing.T) {
+	apps := map[string]map[string]interface{}{
+		"app":   {"db_url": "${db.url}", "cache": "${cache.host}"},
+		"db":    {},
+		"cache": {"plain": "value"},
+	}
+
+	graph, err := AnalyzeDependencies(apps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycle := graph.FindCycle(); cycle != nil {
+		t.Errorf("FindCycle() = %v on an acyclic graph", cycle)
+	}
+
+	order, err := graph.TopologicalSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, []string{"cache", "db", "app"}) {
+		t.Errorf("TopologicalSort() = %v, want [cache db app]", order)
+	}
+}
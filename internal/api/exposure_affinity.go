@@ -0,0 +1,77 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"zeropoint-agent/internal/xds"
+)
+
+// ExposureAffinity pins each client of an HTTP exposure to one upstream, either through a
+// cookie Envoy sets on the first response or through a header the client already sends.
+// Affinity only matters once an exposure maps to more than one backend; with a single
+// container every request reaches it regardless.
+type ExposureAffinity struct {
+	Cookie    string `json:"cookie,omitempty"`     // Cookie name, e.g. "zp_session"
+	CookieTTL string `json:"cookie_ttl,omitempty"` // Go duration, e.g. "1h"; session cookie if omitted
+	Header    string `json:"header,omitempty"`     // Request header to hash, e.g. "x-session-id"
+}
+
+// validate checks that exactly one affinity source is set
+func (a *ExposureAffinity) validate() error {
+	if (a.Cookie == "") == (a.Header == "") {
+		return fmt.Errorf("affinity requires exactly one of cookie or header")
+	}
+	if a.CookieTTL != "" && a.Cookie == "" {
+		return fmt.Errorf("affinity cookie_ttl requires cookie")
+	}
+	if _, err := a.cookieTTL(); err != nil {
+		return err
+	}
+	if a.Cookie != "" && !isToken(a.Cookie) {
+		return fmt.Errorf("invalid affinity cookie name %q", a.Cookie)
+	}
+	if a.Header != "" && !isToken(a.Header) {
+		return fmt.Errorf("invalid affinity header name %q", a.Header)
+	}
+	return nil
+}
+
+// cookieTTL parses the cookie lifetime; zero means a session cookie
+func (a *ExposureAffinity) cookieTTL() (time.Duration, error) {
+	if a.CookieTTL == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(a.CookieTTL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid affinity cookie_ttl %q: %w", a.CookieTTL, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("affinity cookie_ttl must be positive")
+	}
+	return d, nil
+}
+
+// toXDS converts the affinity for the snapshot
+func (a *ExposureAffinity) toXDS() (*xds.SessionAffinity, error) {
+	ttl, err := a.cookieTTL()
+	if err != nil {
+		return nil, err
+	}
+	return &xds.SessionAffinity{
+		Cookie:    a.Cookie,
+		CookieTTL: ttl,
+		Header:    strings.ToLower(a.Header), // Envoy stores header names lowercased
+	}, nil
+}
+
+// isToken reports whether name is an HTTP token, as cookie and header names must be
+func isToken(name string) bool {
+	for _, c := range name {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune("()<>@,;:\\\"/[]?={}", c) {
+			return false
+		}
+	}
+	return true
+}
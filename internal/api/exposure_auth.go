@@ -0,0 +1,55 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"zeropoint-agent/internal/audit"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	// Password hashes of exposure users stay out of the audit log
+	audit.RegisterRedactedFields("auth")
+}
+
+// ExposureAuth puts an HTTP exposure behind basic auth. Credentials are checked against
+// htpasswd bcrypt entries, as printed by `htpasswd -nbB <user> <password>`.
+type ExposureAuth struct {
+	Users []string `json:"users"` // "<user>:$2y$<cost>$<salt and hash>"
+}
+
+// validate checks every htpasswd entry, so a malformed one never reaches Envoy
+func (a *ExposureAuth) validate() error {
+	if len(a.Users) == 0 {
+		return fmt.Errorf("auth requires at least one user")
+	}
+
+	seen := make(map[string]bool, len(a.Users))
+	for _, entry := range a.Users {
+		user, hash, ok := strings.Cut(entry, ":")
+		if !ok || user == "" {
+			return fmt.Errorf("invalid auth user %q: expected <user>:<bcrypt hash>", entry)
+		}
+		if seen[user] {
+			return fmt.Errorf("duplicate auth user %q", user)
+		}
+		seen[user] = true
+
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("auth user %q: hash is not a bcrypt hash, generate one with htpasswd -B: %v", user, err)
+		}
+	}
+	return nil
+}
+
+// usernames returns the users allowed in, leaving out their hashes
+func (a *ExposureAuth) usernames() []string {
+	names := make([]string, 0, len(a.Users))
+	for _, entry := range a.Users {
+		user, _, _ := strings.Cut(entry, ":")
+		names = append(names, user)
+	}
+	return names
+}
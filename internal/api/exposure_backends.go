@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+// backendLabelPrefix marks a backends entry as a Docker label selector, e.g.
+// "label:com.example.replica-of=web" or "label:com.example.web"
+const backendLabelPrefix = "label:"
+
+// backendResolveTimeout bounds the Docker lookups of label selectors on each snapshot push
+const backendResolveTimeout = 5 * time.Second
+
+// validateBackends checks the syntax of an exposure's backends entries
+func validateBackends(backends []string) error {
+	seen := make(map[string]bool, len(backends))
+	for _, backend := range backends {
+		if backend == "" {
+			return fmt.Errorf("backends entries must not be empty")
+		}
+		if selector, ok := strings.CutPrefix(backend, backendLabelPrefix); ok {
+			if key, _, _ := strings.Cut(selector, "="); key == "" {
+				return fmt.Errorf("invalid backend selector %q: expected label:<key> or label:<key>=<value>", backend)
+			}
+		}
+		if seen[backend] {
+			return fmt.Errorf("duplicate backend %q", backend)
+		}
+		seen[backend] = true
+	}
+	return nil
+}
+
+// resolveBackends returns the container names behind an exposure: the named containers and
+// every container matching a label selector, in a stable order. Only the module's own
+// containers, named {module}-*, are included, so an exposure cannot reach into other
+// modules or unrelated containers on the host. Without backends, that is the module's main
+// container. With all set, stopped containers match selectors too.
+func (s *ExposureStore) resolveBackends(ctx context.Context, moduleID string, backends []string, all bool) ([]string, error) {
+	if len(backends) == 0 {
+		return []string{moduleID + "-main"}, nil
+	}
+
+	owned, err := moduleOwnership(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, backend := range backends {
+		selector, ok := strings.CutPrefix(backend, backendLabelPrefix)
+		if !ok {
+			if !owned(backend) {
+				s.logger.Warn("ignoring backend of another module", "module_id", moduleID, "backend", backend)
+				continue
+			}
+			if !seen[backend] {
+				seen[backend] = true
+				names = append(names, backend)
+			}
+			continue
+		}
+
+		result, err := s.dockerClient.ContainerList(ctx, client.ContainerListOptions{
+			All:     all,
+			Filters: make(client.Filters).Add("label", selector),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve backend selector %q: %w", backend, err)
+		}
+		for _, c := range result.Items {
+			if len(c.Names) == 0 {
+				continue
+			}
+			name := strings.TrimPrefix(c.Names[0], "/")
+			if owned(name) && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// moduleOwnership returns a check for whether a container name belongs to moduleID rather
+// than to another installed module whose ID extends it
+func moduleOwnership(moduleID string) (func(name string) bool, error) {
+	moduleIDs, err := installedModuleIDs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installed modules: %w", err)
+	}
+	moduleIDs = append(moduleIDs, moduleID)
+	return func(name string) bool {
+		return owningModule([]string{name}, moduleIDs) == moduleID
+	}, nil
+}
+
+// namedBackends returns the container names among backends, leaving out label selectors
+func namedBackends(backends []string) []string {
+	var names []string
+	for _, backend := range backends {
+		if !strings.HasPrefix(backend, backendLabelPrefix) {
+			names = append(names, backend)
+		}
+	}
+	return names
+}
+
+// verifyBackends checks that every named backend is a container of the module that exists,
+// and that each label selector matches at least one of the module's containers
+func (s *ExposureStore) verifyBackends(ctx context.Context, moduleID string, backends []string) error {
+	owned, err := moduleOwnership(moduleID)
+	if err != nil {
+		return err
+	}
+
+	for _, backend := range backends {
+		if selector, ok := strings.CutPrefix(backend, backendLabelPrefix); ok {
+			result, err := s.dockerClient.ContainerList(ctx, client.ContainerListOptions{
+				All:     true,
+				Filters: make(client.Filters).Add("label", selector),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to resolve backend selector %q: %w", backend, err)
+			}
+			matched := false
+			for _, c := range result.Items {
+				if len(c.Names) > 0 && owned(strings.TrimPrefix(c.Names[0], "/")) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return fmt.Errorf("backend selector %q matches no containers of module %s", backend, moduleID)
+			}
+			continue
+		}
+
+		if !owned(backend) {
+			return fmt.Errorf("backend container %s does not belong to module %s; backends must be named %s-*", backend, moduleID, moduleID)
+		}
+
+		if _, err := s.dockerClient.ContainerInspect(ctx, backend, client.ContainerInspectOptions{}); err != nil {
+			return fmt.Errorf("backend container %s not found: %w", backend, err)
+		}
+	}
+	return nil
+}
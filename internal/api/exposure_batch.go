@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// exposureBatch records what a batch changed in the store, so it can be undone
+type exposureBatch struct {
+	created []*Exposure
+	aliases map[string][]string // previous aliases of existing exposures, keyed by ID
+}
+
+// CreateExposuresBatch creates several exposures as one change. Every spec is validated
+// against the store and the rest of the batch before anything is kept, the store is saved
+// once and a single xDS snapshot carries all of them, so a bundle never routes some of its
+// hostnames while others still 404. Specs are keyed by exposure ID; an ID that already
+// exists is kept, with changed aliases applied as in CreateExposure. If a spec is invalid,
+// or the save or the snapshot push fails, the store is left as it was.
+func (s *ExposureStore) CreateExposuresBatch(ctx context.Context, specs map[string]ExposureSpec) ([]*Exposure, error) {
+	checked := make(map[string]ExposureSpec, len(specs))
+	for id, spec := range specs {
+		checked[id] = s.withDeclaredPorts(spec)
+	}
+
+	exposures, batch, err := s.createExposuresBatch(ctx, checked)
+	if err != nil {
+		return nil, err
+	}
+
+	// Push right away instead of after the debounce, so a failed push can still be undone
+	err = s.snapshots.sync(ctx, false)
+	if err != nil {
+		err = fmt.Errorf("failed to update xDS snapshot: %w", err)
+	} else {
+		err = s.confirmPush(ctx)
+	}
+	if err != nil {
+		s.rollbackBatch(batch)
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, exposure := range batch.created {
+		if exposure.Protocol == "http" {
+			s.registerMDNS(exposureNames(exposure)...)
+		}
+	}
+	for id, previous := range batch.aliases {
+		if exposure, ok := s.exposures[id]; ok {
+			s.announceAliases(previous, exposure.Aliases)
+		}
+	}
+
+	s.logger.Info("created exposure batch", "exposures", len(exposures), "created", len(batch.created))
+	return exposures, nil
+}
+
+// createExposuresBatch validates and stores a batch, in ID order so host ports are
+// allocated deterministically, and saves it without marking the snapshot dirty
+func (s *ExposureStore) createExposuresBatch(ctx context.Context, specs map[string]ExposureSpec) ([]*Exposure, exposureBatch, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ids := make([]string, 0, len(specs))
+	for id := range specs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	batch := exposureBatch{aliases: make(map[string][]string)}
+	exposures := make([]*Exposure, 0, len(ids))
+	for _, id := range ids {
+		exposure, err := s.addToBatch(ctx, &batch, id, specs[id])
+		if err != nil {
+			s.revertBatch(batch)
+			return nil, exposureBatch{}, fmt.Errorf("exposure %s: %w", id, err)
+		}
+		exposures = append(exposures, exposure)
+	}
+
+	if err := s.save(); err != nil {
+		s.revertBatch(batch)
+		return nil, exposureBatch{}, fmt.Errorf("failed to save exposures: %w", err)
+	}
+
+	return exposures, batch, nil
+}
+
+// addToBatch applies one spec of a batch to the map, so later specs are checked against
+// it (caller must hold the lock)
+func (s *ExposureStore) addToBatch(ctx context.Context, batch *exposureBatch, id string, spec ExposureSpec) (*Exposure, error) {
+	aliases, err := specAliases(&spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing, exists := s.exposures[id]; exists {
+		if spec.Protocol == "http" && !sameNames(existing.Aliases, aliases) {
+			candidate := *existing
+			candidate.Aliases = aliases
+			if err := s.checkConflicts(&candidate); err != nil {
+				return nil, err
+			}
+			batch.aliases[id] = existing.Aliases
+			existing.Aliases = aliases
+		}
+		return existing, nil
+	}
+
+	exposure, err := s.newExposure(ctx, id, spec, aliases, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.exposures[id] = exposure
+	batch.created = append(batch.created, exposure)
+	return exposure, nil
+}
+
+// revertBatch removes the exposures a batch created and restores changed aliases (caller
+// must hold the lock)
+func (s *ExposureStore) revertBatch(batch exposureBatch) {
+	for _, exposure := range batch.created {
+		if s.exposures[exposure.ID] == exposure {
+			delete(s.exposures, exposure.ID)
+		}
+	}
+	for id, previous := range batch.aliases {
+		if exposure, ok := s.exposures[id]; ok {
+			exposure.Aliases = previous
+		}
+	}
+}
+
+// rollbackBatch undoes a saved batch whose snapshot could not be pushed, saves the store
+// again and pushes the restored configuration
+func (s *ExposureStore) rollbackBatch(batch exposureBatch) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.revertBatch(batch)
+	if err := s.save(); err != nil {
+		s.logger.Error("failed to save exposures after rolling back batch", "error", err)
+	}
+	s.snapshots.markDirty()
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"zeropoint-agent/internal/validator"
+)
+
+// HostnameConflictResponse is the body of a 409 for a hostname another exposure answers to
+type HostnameConflictResponse struct {
+	Error      string `json:"error"`
+	ExposureID string `json:"exposure_id"` // The exposure holding the hostname, e.g. to reuse it instead
+}
+
+// writeHostnameConflict answers a hostname conflict with a 409 naming the exposure that
+// holds the name, and reports whether err was one
+func writeHostnameConflict(w http.ResponseWriter, err error) bool {
+	var conflict *HostnameConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(HostnameConflictResponse{Error: err.Error(), ExposureID: conflict.ExposureID})
+	return true
+}
+
+// CheckHostnameResponse reports whether a hostname can be used for a new HTTP exposure
+type CheckHostnameResponse struct {
+	Hostname   string `json:"hostname"` // Normalized (lowercased) form
+	Valid      bool   `json:"valid"`
+	Error      string `json:"error,omitempty"` // Why the hostname is invalid
+	Available  bool   `json:"available"`
+	ExposureID string `json:"exposure_id,omitempty"` // The exposure already answering to the hostname or hostname.local
+}
+
+// CheckHostname handles GET /exposures/check
+// @ID checkExposureHostname
+// @Summary Check a hostname for a new exposure
+// @Description Validates a hostname as an RFC 1123 name and reports whether an HTTP exposure already answers to it. Names are compared case-insensitively and with or without .local, since every hostname is also routed as hostname.local. Another exposure can still share a taken hostname by using a different path prefix.
+// @Tags exposures
+// @Param hostname query string true "Hostname to check"
+// @Success 200 {object} CheckHostnameResponse
+// @Failure 400 {string} string "hostname is required"
+// @Router /exposures/check [get]
+func (h *ExposureHandlers) CheckHostname(w http.ResponseWriter, r *http.Request) {
+	hostname := validator.NormalizeHostname(r.URL.Query().Get("hostname"))
+	if hostname == "" {
+		http.Error(w, "hostname is required", http.StatusBadRequest)
+		return
+	}
+
+	resp := CheckHostnameResponse{Hostname: hostname, Valid: true}
+	if err := validator.ValidateHostname(hostname); err != nil {
+		resp.Valid = false
+		resp.Error = err.Error()
+	} else {
+		resp.ExposureID = h.store.HostnameOwner(hostname)
+		resp.Available = resp.ExposureID == ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"zeropoint-agent/internal/xds"
+)
+
+// listenerCheckDelay gives Envoy time to apply a snapshot before its listeners are checked
+const listenerCheckDelay = 3 * time.Second
+
+// listenerState tracks exposures whose Envoy listener failed to come up
+type listenerState struct {
+	mu         sync.RWMutex
+	generation uint64            // Incremented per snapshot push; stale checks are discarded
+	degraded   map[string]string // exposure ID -> reason
+}
+
+// adminListeners is the subset of Envoy's /listeners?format=json response we need
+type adminListeners struct {
+	ListenerStatuses []struct {
+		Name string `json:"name"`
+	} `json:"listener_statuses"`
+}
+
+// listenerName returns the Envoy listener that serves an exposure
+func listenerName(exp *xds.Exposure) string {
+	switch exp.Protocol {
+	case "tcp":
+		return fmt.Sprintf("tcp_listener_%s", exp.ID)
+	case "udp":
+		return fmt.Sprintf("udp_listener_%s", exp.ID)
+	}
+	return "http_listener"
+}
+
+// scheduleListenerCheck verifies in the background that Envoy bound every listener in
+// the snapshot just pushed. Envoy drops listeners it cannot bind (e.g. a port already in
+// use) without telling the control plane, so this is the only way to notice.
+func (s *ExposureStore) scheduleListenerCheck(exposures []*xds.Exposure) {
+	if s.envoyAdminAddr == "" {
+		return
+	}
+
+	expected := make(map[string][]string) // exposure ID -> listeners it needs
+	for _, exp := range exposures {
+		expected[exp.ID] = append(expected[exp.ID], listenerName(exp))
+		if exp.TLS != nil {
+			expected[exp.ID] = append(expected[exp.ID], "https_listener")
+		}
+	}
+
+	s.listeners.mu.Lock()
+	s.listeners.generation++
+	generation := s.listeners.generation
+	s.listeners.mu.Unlock()
+
+	go func() {
+		time.Sleep(listenerCheckDelay)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		active, err := fetchActiveListeners(ctx, s.envoyAdminAddr)
+		if err != nil {
+			// Envoy being unreachable is reported by the Envoy monitor; don't guess here
+			s.logger.Debug("skipping listener check", "error", err)
+			return
+		}
+
+		degraded := make(map[string]string)
+		for id, names := range expected {
+			for _, name := range names {
+				if !active[name] {
+					degraded[id] = fmt.Sprintf("envoy listener %s is not active (port may be in use)", name)
+					break
+				}
+			}
+		}
+
+		s.listeners.mu.Lock()
+		defer s.listeners.mu.Unlock()
+		if generation != s.listeners.generation {
+			return // A newer snapshot was pushed while we waited
+		}
+		s.listeners.degraded = degraded
+
+		for id, reason := range degraded {
+			s.logger.Warn("exposure degraded", "exposure_id", id, "reason", reason)
+		}
+	}()
+}
+
+// listenerProblem returns why an exposure's listener is not active, or "" if it is fine
+func (s *ExposureStore) listenerProblem(exposureID string) string {
+	s.listeners.mu.RLock()
+	defer s.listeners.mu.RUnlock()
+	return s.listeners.degraded[exposureID]
+}
+
+// fetchActiveListeners returns the names of listeners Envoy currently has active
+func fetchActiveListeners(ctx context.Context, adminAddr string) (map[string]bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s/listeners?format=json", adminAddr), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("envoy admin returned %s", resp.Status)
+	}
+
+	var listeners adminListeners
+	if err := json.NewDecoder(resp.Body).Decode(&listeners); err != nil {
+		return nil, fmt.Errorf("failed to decode listeners: %w", err)
+	}
+
+	active := make(map[string]bool, len(listeners.ListenerStatuses))
+	for _, l := range listeners.ListenerStatuses {
+		active[l.Name] = true
+	}
+	return active, nil
+}
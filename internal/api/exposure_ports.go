@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"zeropoint-agent/internal/config"
+	"zeropoint-agent/internal/modules"
+)
+
+// ModulePortSource provides the containers a module declares through its {container}_ports
+// terraform outputs. ok is false when they are unknown, e.g. the outputs cannot be read.
+type ModulePortSource interface {
+	DeclaredContainers(moduleID string) (containers map[string]modules.Container, ok bool)
+}
+
+// SetModulePorts sets where exposures look up the ports a module declares and how an
+// undeclared container port is treated: config.PortCheckStrict rejects it,
+// config.PortCheckWarn only logs it and config.PortCheckOff skips the lookup. Without a
+// source, container ports are not checked.
+func (s *ExposureStore) SetModulePorts(source ModulePortSource, mode string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.modulePorts = source
+	s.portCheck = mode
+}
+
+// declaredPorts holds the containers a module declares, looked up for a spec before the
+// store lock is taken, since reading terraform outputs on a cache miss is slow
+type declaredPorts struct {
+	containers map[string]modules.Container
+	known      bool
+}
+
+// withDeclaredPorts returns the spec with the containers its module declares attached for
+// verifyDeclaredPort. The lookup may run terraform, so the caller must not hold the lock.
+func (s *ExposureStore) withDeclaredPorts(spec ExposureSpec) ExposureSpec {
+	s.mutex.RLock()
+	source, mode := s.modulePorts, s.portCheck
+	s.mutex.RUnlock()
+
+	if source == nil || mode == config.PortCheckOff || spec.AllowUndeclaredPort {
+		return spec
+	}
+	containers, ok := source.DeclaredContainers(spec.ModuleID)
+	spec.declared = &declaredPorts{containers: containers, known: ok}
+	return spec
+}
+
+// verifyDeclaredPort rejects a container port the module does not declare, since a route
+// to it would only produce upstream connect errors. Without backends the main container's
+// ports are checked; with backends, those of every container the module declares, as
+// replicas and label selectors do not map to a single output. Modules whose outputs are
+// unknown or declare no ports are not checked, nor are specs withDeclaredPorts did not
+// look up. In warn mode the mismatch is only logged (caller must hold the lock).
+func (s *ExposureStore) verifyDeclaredPort(spec ExposureSpec) error {
+	if s.modulePorts == nil || s.portCheck == config.PortCheckOff || spec.AllowUndeclaredPort || spec.declared == nil {
+		return nil
+	}
+	containers := spec.declared.containers
+	if !spec.declared.known {
+		s.logger.Debug("module ports unknown, not checking container port", "module_id", spec.ModuleID)
+		return nil
+	}
+
+	transport := "tcp"
+	if spec.Protocol == "udp" {
+		transport = "udp"
+	}
+
+	var declared []string
+	for name, container := range containers {
+		if len(spec.Backends) == 0 && name != "main" {
+			continue
+		}
+		for portName, port := range container.Ports {
+			if uint32(port.Port) == spec.ContainerPort && port.Transport == transport {
+				return nil
+			}
+			declared = append(declared, fmt.Sprintf("%d/%s (%s.%s)", port.Port, port.Transport, name, portName))
+		}
+	}
+	if len(declared) == 0 {
+		return nil
+	}
+
+	sort.Strings(declared)
+	if s.portCheck == config.PortCheckWarn {
+		s.logger.Warn("exposure container port not declared by module", "module_id", spec.ModuleID, "container_port", spec.ContainerPort, "transport", transport, "declared", declared)
+		return nil
+	}
+	return fmt.Errorf("container_port %d/%s is not declared by module %s, which declares %s; set allow_undeclared_port to expose it anyway",
+		spec.ContainerPort, transport, spec.ModuleID, strings.Join(declared, ", "))
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+)
+
+// ReconcileResponse is returned by POST /exposures/reconcile
+type ReconcileResponse struct {
+	SnapshotVersion string   `json:"snapshot_version"`  // Version of the snapshot pushed from the reloaded exposures
+	Exposures       int      `json:"exposures"`         // Exposures loaded from disk
+	Added           []string `json:"added,omitempty"`   // Exposures on disk that were not in memory
+	Removed         []string `json:"removed,omitempty"` // Exposures in memory that were no longer on disk
+}
+
+// Reconcile reloads the exposures from disk, reconnects their containers to the Envoy
+// network and pushes a fresh snapshot under a new version, whether or not anything changed.
+// It is the recovery path when Envoy's view has drifted from the stored exposures.
+func (s *ExposureStore) Reconcile(ctx context.Context) (*ReconcileResponse, error) {
+	response, err := s.reload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ForceSync(ctx); err != nil {
+		return nil, fmt.Errorf("failed to push xDS snapshot: %w", err)
+	}
+	response.SnapshotVersion = s.xdsServer.Status().SnapshotVersion
+
+	if s.mdnsService != nil {
+		if err := s.mdnsService.ReregisterAllExposures(s.getExposureInfos()); err != nil {
+			s.logger.Warn("failed to re-register mDNS exposures", "error", err)
+		}
+	}
+
+	s.logger.Info("reconciled exposures", "exposures", response.Exposures, "added", len(response.Added), "removed", len(response.Removed), "version", response.SnapshotVersion)
+	return response, nil
+}
+
+// reload replaces the in-memory exposures with those on disk and reconnects their
+// containers. A missing file is an error here: wiping every exposure because the file
+// vanished would make an outage worse.
+func (s *ExposureStore) reload(ctx context.Context) (*ReconcileResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := os.Stat(s.storagePath); err != nil {
+		return nil, fmt.Errorf("failed to read exposures: %w", err)
+	}
+
+	previous := s.exposures
+	if err := s.load(); err != nil {
+		return nil, fmt.Errorf("failed to load exposures: %w", err)
+	}
+
+	response := &ReconcileResponse{Exposures: len(s.exposures)}
+	for id := range s.exposures {
+		if _, ok := previous[id]; !ok {
+			response.Added = append(response.Added, id)
+		}
+	}
+	for id := range previous {
+		if _, ok := s.exposures[id]; !ok {
+			response.Removed = append(response.Removed, id)
+		}
+	}
+	sort.Strings(response.Added)
+	sort.Strings(response.Removed)
+
+	if err := s.reconcileNetworks(ctx); err != nil {
+		s.logger.Warn("failed to reconcile networks", "error", err)
+	}
+	return response, nil
+}
+
+// ReconcileExposures handles POST /exposures/reconcile
+// @ID reconcileExposures
+// @Summary Rebuild routing from the stored exposures
+// @Description Break-glass recovery for when Envoy's routing has drifted from the stored exposures. Reloads exposures from disk, reconnects their containers to the Envoy network, pushes a fresh xDS snapshot under a new version and re-announces mDNS names. Unlike /envoy/resync, which re-pushes what the agent holds in memory, this re-reads the exposures file.
+// @Tags exposures
+// @Produce json
+// @Success 200 {object} ReconcileResponse
+// @Failure 500 {string} string "Exposures could not be loaded or the snapshot could not be pushed"
+// @Router /exposures/reconcile [post]
+func (h *ExposureHandlers) ReconcileExposures(w http.ResponseWriter, r *http.Request) {
+	response, err := h.store.Reconcile(r.Context())
+	if err != nil {
+		h.logger.Error("failed to reconcile exposures", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// retryOnTokens are the Envoy retry conditions accepted in retry_on
+var retryOnTokens = map[string]bool{
+	"5xx":                    true,
+	"gateway-error":          true,
+	"reset":                  true,
+	"reset-before-request":   true,
+	"connect-failure":        true,
+	"envoy-ratelimited":      true,
+	"retriable-4xx":          true,
+	"refused-stream":         true,
+	"retriable-status-codes": true,
+	"retriable-headers":      true,
+	// gRPC status conditions
+	"cancelled":          true,
+	"deadline-exceeded":  true,
+	"internal":           true,
+	"resource-exhausted": true,
+	"unavailable":        true,
+}
+
+// parseRequestTimeout parses an exposure's request_timeout. An empty value means no
+// timeout, which keeps long-running and streaming requests working.
+func parseRequestTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid request_timeout %q: %w", timeout, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("request_timeout must be positive")
+	}
+	return d, nil
+}
+
+// validateRetryPolicy checks the request timeout and retry settings of an HTTP exposure
+func validateRetryPolicy(timeout string, numRetries uint32, retryOn []string) error {
+	if _, err := parseRequestTimeout(timeout); err != nil {
+		return err
+	}
+	if numRetries > 0 && len(retryOn) == 0 {
+		return fmt.Errorf("num_retries requires retry_on")
+	}
+	var unknown []string
+	for _, token := range retryOn {
+		if !retryOnTokens[token] {
+			unknown = append(unknown, token)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown retry_on condition(s): %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package api
+
+import "testing"
+
+func TestValidateRetryPolicy(t *testing.T) {
+	cases := []struct {
+		name       string
+		timeout    string
+		numRetries uint32
+		retryOn    []string
+		valid      bool
+	}{
+		{"defaults", "", 0, nil, true},
+		{"known conditions", "30s", 3, []string{"5xx", "reset", "connect-failure"}, true},
+		{"unknown condition", "", 2, []string{"5xx", "sometimes"}, false},
+		{"retries without conditions", "", 2, nil, false},
+		{"invalid timeout", "soon", 0, nil, false},
+		{"negative timeout", "-5s", 0, nil, false},
+	}
+	for _, tc := range cases {
+		err := validateRetryPolicy(tc.timeout, tc.numRetries, tc.retryOn)
+		if (err == nil) != tc.valid {
+			t.Errorf("%s: error %v, want valid %v", tc.name, err, tc.valid)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ExposureStatsResponse is returned by GET /exposures/{exposure_id}/stats
+type ExposureStatsResponse struct {
+	ExposureID        string            `json:"exposure_id"`
+	Cluster           string            `json:"cluster"`
+	Requests          uint64            `json:"requests"`           // upstream_rq_total (http only)
+	Responses2xx      uint64            `json:"responses_2xx"`      // upstream_rq_2xx
+	Responses4xx      uint64            `json:"responses_4xx"`      // upstream_rq_4xx
+	Responses5xx      uint64            `json:"responses_5xx"`      // upstream_rq_5xx
+	Connections       uint64            `json:"connections"`        // upstream_cx_total
+	ActiveConnections uint64            `json:"active_connections"` // upstream_cx_active
+	ConnectFailures   uint64            `json:"connect_failures"`   // upstream_cx_connect_fail
+	ConnectTimeouts   uint64            `json:"connect_timeouts"`   // upstream_cx_connect_timeout
+	Counters          map[string]uint64 `json:"counters"`           // All cluster stats, without the cluster prefix
+}
+
+// adminStats is the subset of Envoy's /stats?format=json response we need
+type adminStats struct {
+	Stats []struct {
+		Name  string       `json:"name"`
+		Value *json.Number `json:"value"` // Absent for histograms
+	} `json:"stats"`
+}
+
+// GetExposureStats handles GET /exposures/{exposure_id}/stats
+// @ID getExposureStats
+// @Summary Get exposure traffic stats
+// @Description Returns Envoy's upstream counters for the exposure's cluster: requests, response classes, connections and connect failures
+// @Tags exposures
+// @Produce json
+// @Param exposure_id path string true "Exposure ID"
+// @Success 200 {object} ExposureStatsResponse
+// @Failure 404 {string} string "Exposure not found"
+// @Failure 502 {string} string "Envoy admin interface unreachable"
+// @Router /exposures/{exposure_id}/stats [get]
+func (h *ExposureHandlers) GetExposureStats(w http.ResponseWriter, r *http.Request) {
+	exposureID := mux.Vars(r)["exposure_id"]
+
+	if _, err := h.store.GetExposure(exposureID); err != nil {
+		http.Error(w, "exposure not found", http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	clusterName := fmt.Sprintf("cluster_%s", exposureID)
+	counters, err := fetchClusterStats(ctx, h.store.envoyAdminAddr, clusterName)
+	if err != nil {
+		h.logger.Warn("failed to query envoy stats", "exposure_id", exposureID, "error", err)
+		http.Error(w, fmt.Sprintf("envoy admin unavailable: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	resp := ExposureStatsResponse{
+		ExposureID:        exposureID,
+		Cluster:           clusterName,
+		Requests:          counters["upstream_rq_total"],
+		Responses2xx:      counters["upstream_rq_2xx"],
+		Responses4xx:      counters["upstream_rq_4xx"],
+		Responses5xx:      counters["upstream_rq_5xx"],
+		Connections:       counters["upstream_cx_total"],
+		ActiveConnections: counters["upstream_cx_active"],
+		ConnectFailures:   counters["upstream_cx_connect_fail"],
+		ConnectTimeouts:   counters["upstream_cx_connect_timeout"],
+		Counters:          counters,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// fetchClusterStats returns the counters and gauges Envoy reports for a cluster,
+// keyed by stat name with the "cluster.<name>." prefix removed
+func fetchClusterStats(ctx context.Context, adminAddr, clusterName string) (map[string]uint64, error) {
+	if adminAddr == "" {
+		return nil, fmt.Errorf("envoy admin address not configured")
+	}
+
+	prefix := "cluster." + clusterName + "."
+	query := url.Values{
+		"format": {"json"},
+		"filter": {"^" + regexp.QuoteMeta(prefix)},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s/stats?%s", adminAddr, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("envoy admin returned %s", resp.Status)
+	}
+
+	var stats adminStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("failed to decode stats: %w", err)
+	}
+
+	counters := make(map[string]uint64)
+	for _, stat := range stats.Stats {
+		if stat.Value == nil || !strings.HasPrefix(stat.Name, prefix) {
+			continue
+		}
+		value, err := stat.Value.Int64()
+		if err != nil || value < 0 {
+			continue
+		}
+		counters[strings.TrimPrefix(stat.Name, prefix)] = uint64(value)
+	}
+
+	return counters, nil
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"zeropoint-agent/internal/xds"
+)
+
+// snapshotDebounce is how long exposure changes must settle before a snapshot is pushed,
+// so a bundle creating several exposures results in a single push
+const snapshotDebounce = 200 * time.Millisecond
+
+// snapshotCoordinator rebuilds and pushes xDS snapshots outside the exposure store lock.
+// Mutations only mark the store dirty; a background worker coalesces them.
+type snapshotCoordinator struct {
+	store *ExposureStore
+	dirty chan struct{} // buffered; a pending signal means a push is owed
+
+	mu       sync.Mutex // serializes builds and pushes
+	lastHash string     // resource hash of the last pushed snapshot
+}
+
+func newSnapshotCoordinator(store *ExposureStore) *snapshotCoordinator {
+	return &snapshotCoordinator{
+		store: store,
+		dirty: make(chan struct{}, 1),
+	}
+}
+
+// markDirty requests a push without blocking. Safe to call with the store lock held.
+func (c *snapshotCoordinator) markDirty() {
+	select {
+	case c.dirty <- struct{}{}:
+	default:
+	}
+}
+
+// run pushes a snapshot after each burst of changes until ctx is cancelled
+func (c *snapshotCoordinator) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.dirty:
+		}
+
+		// Wait for the burst to settle, restarting the wait on every new change
+		timer := time.NewTimer(snapshotDebounce)
+	settle:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-c.dirty:
+				timer.Reset(snapshotDebounce)
+			case <-timer.C:
+				break settle
+			}
+		}
+
+		if err := c.sync(ctx, false); err != nil {
+			c.store.logger.Error("failed to update xDS snapshot", "error", err)
+		}
+	}
+}
+
+// sync builds a snapshot from the current exposures and pushes it under a new version.
+// Unless force is set, the push is skipped when the resources match the last push.
+func (c *snapshotCoordinator) sync(ctx context.Context, force bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store.mutex.RLock()
+	stored := make([]*Exposure, 0, len(c.store.exposures))
+	for _, exp := range c.store.exposures {
+		copied := *exp
+		stored = append(stored, &copied)
+	}
+	c.store.mutex.RUnlock()
+
+	// Resolving backends asks Docker, so it must not hold up writers to the store
+	exposures := c.store.xdsExposures(stored)
+
+	// Versions are only consumed by snapshots that are actually pushed
+	candidate, err := xds.BuildSnapshotFromExposures("", exposures)
+	if err != nil {
+		return err
+	}
+	hash, err := xds.ResourceHash(candidate)
+	if err != nil {
+		return err
+	}
+	if !force && hash == c.lastHash {
+		c.store.logger.Debug("xDS configuration unchanged, skipping push")
+		return nil
+	}
+
+	snapshot, err := xds.BuildSnapshotFromExposures(c.store.xdsServer.NextVersion(), exposures)
+	if err != nil {
+		return err
+	}
+	if err := c.store.xdsServer.UpdateSnapshot(ctx, snapshot); err != nil {
+		return err
+	}
+
+	c.lastHash = hash
+	c.store.scheduleListenerCheck(exposures)
+	return nil
+}
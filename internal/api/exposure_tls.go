@@ -0,0 +1,75 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/xds"
+)
+
+// ExposureTLS configures TLS termination for an HTTP exposure. Either CertName or
+// both CertFile and KeyFile must be set.
+type ExposureTLS struct {
+	CertFile      string `json:"cert_file,omitempty"`      // PEM certificate chain on the agent host
+	KeyFile       string `json:"key_file,omitempty"`       // PEM private key on the agent host
+	CertName      string `json:"cert_name,omitempty"`      // Stored certificate: <certs dir>/<name>.crt and <name>.key
+	RedirectHTTPS bool   `json:"redirect_https,omitempty"` // Redirect plaintext HTTP requests to HTTPS
+}
+
+// certPaths resolves the certificate and key file paths
+func (t *ExposureTLS) certPaths() (string, string, error) {
+	if t.CertName != "" {
+		if t.CertFile != "" || t.KeyFile != "" {
+			return "", "", fmt.Errorf("tls: cert_name cannot be combined with cert_file/key_file")
+		}
+		if strings.ContainsAny(t.CertName, `/\`) || t.CertName == "." || t.CertName == ".." {
+			return "", "", fmt.Errorf("tls: invalid cert_name %q", t.CertName)
+		}
+		certsDir := internalPaths.GetCertsDir()
+		return filepath.Join(certsDir, t.CertName+".crt"), filepath.Join(certsDir, t.CertName+".key"), nil
+	}
+
+	if t.CertFile == "" || t.KeyFile == "" {
+		return "", "", fmt.Errorf("tls: cert_name or both cert_file and key_file are required")
+	}
+	return t.CertFile, t.KeyFile, nil
+}
+
+// loadTLSCertificate reads and validates the certificate and key. It fails if either
+// file cannot be parsed or the key does not match the certificate, so a bad
+// certificate is never pushed to Envoy.
+func loadTLSCertificate(t *ExposureTLS) (*xds.TLSCertificate, error) {
+	certPath, keyPath, err := t.certPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("tls: failed to read certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("tls: failed to read private key: %w", err)
+	}
+
+	// X509KeyPair parses both and verifies the key matches the leaf certificate
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("tls: invalid certificate or key: %w", err)
+	}
+
+	if _, err := x509.ParseCertificate(pair.Certificate[0]); err != nil {
+		return nil, fmt.Errorf("tls: invalid certificate: %w", err)
+	}
+
+	return &xds.TLSCertificate{
+		CertPEM: certPEM,
+		KeyPEM:  keyPEM,
+	}, nil
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// UpdateExposure replaces an exposure's configuration while keeping its ID. The spec is
+// validated like a new exposure's, against every other stored exposure, and the change is
+// pushed as a single xDS snapshot, so routing moves from the old configuration to the new
+// one without a gap. A TCP or UDP exposure keeps its host port unless the spec asks for a
+// different one or the protocol changes; the old port is released and the new one claimed
+// under the same lock, so no other exposure can take either in between. If the save or
+// the snapshot push fails, the previous configuration is restored.
+func (s *ExposureStore) UpdateExposure(ctx context.Context, id string, spec ExposureSpec) (*Exposure, error) {
+	previous, updated, err := s.updateExposure(ctx, id, s.withDeclaredPorts(spec))
+	if err != nil {
+		return nil, err
+	}
+
+	// Push right away instead of after the debounce, so a failed push can still be undone
+	err = s.snapshots.sync(ctx, false)
+	if err != nil {
+		err = fmt.Errorf("failed to update xDS snapshot: %w", err)
+	} else {
+		err = s.confirmPush(ctx)
+	}
+	if err != nil {
+		s.restoreExposure(previous, updated)
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	s.unregisterUnusedNames(previous)
+	if updated.Protocol == "http" {
+		s.registerMDNS(exposureNames(updated)...)
+	}
+
+	s.logger.Info("updated exposure", "exposure_id", id, "protocol", updated.Protocol, "hostname", updated.Hostname, "container_port", updated.ContainerPort, "host_port", updated.HostPort)
+	return updated, nil
+}
+
+// updateExposure validates the spec, swaps the new exposure in and saves the store without
+// marking the snapshot dirty. It returns the replaced exposure alongside the new one.
+func (s *ExposureStore) updateExposure(ctx context.Context, id string, spec ExposureSpec) (*Exposure, *Exposure, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous, ok := s.exposures[id]
+	if !ok {
+		return nil, nil, errExposureNotFound
+	}
+
+	aliases, err := specAliases(&spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	updated, err := s.newExposure(ctx, id, spec, aliases, previous)
+	if err != nil {
+		return nil, nil, err
+	}
+	updated.CreatedAt = previous.CreatedAt
+
+	s.exposures[id] = updated
+	if err := s.save(); err != nil {
+		s.exposures[id] = previous
+		return nil, nil, fmt.Errorf("failed to save exposures: %w", err)
+	}
+	return previous, updated, nil
+}
+
+// restoreExposure puts back the configuration an update replaced, unless the exposure
+// changed again since, saves the store and pushes the restored configuration
+func (s *ExposureStore) restoreExposure(previous, updated *Exposure) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.exposures[previous.ID] != updated {
+		return
+	}
+	s.exposures[previous.ID] = previous
+	if err := s.save(); err != nil {
+		s.logger.Error("failed to save exposures after restoring exposure", "exposure_id", previous.ID, "error", err)
+	}
+	s.snapshots.markDirty()
+}
+
+// UpdateExposureHTTP handles PUT /exposures/{exposure_id}
+// @ID updateExposure
+// @Summary Update an exposure in place
+// @Description Replaces the configuration of an existing exposure, keeping its ID, instead of deleting and recreating it. The body is the same as for creating it and replaces the whole configuration; it is validated like a new exposure against every other exposure. Routing switches over with a single snapshot push. A tcp or udp exposure keeps its host port unless host_port asks for another one or the protocol changes. If Envoy rejects the new configuration, the previous one is restored.
+// @Tags exposures
+// @Param exposure_id path string true "Exposure ID"
+// @Param body body CreateExposureRequest true "Exposure configuration"
+// @Success 200 {object} ExposureResponse
+// @Failure 400 {string} string "Bad request, invalid hostname or alias, or container_port not declared by the module"
+// @Failure 404 {string} string "Exposure not found"
+// @Failure 409 {object} HostnameConflictResponse "Hostname or alias already used by another exposure (also as name.local), or requested host port reserved or in use (plain text)"
+// @Failure 500 {string} string "Envoy rejected the resulting configuration"
+// @Router /exposures/{exposure_id} [put]
+func (h *ExposureHandlers) UpdateExposureHTTP(w http.ResponseWriter, r *http.Request) {
+	exposureID := mux.Vars(r)["exposure_id"]
+
+	var req CreateExposureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ModuleID == "" {
+		http.Error(w, "module_id is required in request body", http.StatusBadRequest)
+		return
+	}
+	if req.Protocol == "" {
+		http.Error(w, "protocol is required in request body", http.StatusBadRequest)
+		return
+	}
+	if req.ContainerPort == 0 {
+		http.Error(w, "container_port is required in request body", http.StatusBadRequest)
+		return
+	}
+
+	exposure, err := h.store.UpdateExposure(r.Context(), exposureID, ExposureSpec{
+		ModuleID:       req.ModuleID,
+		Protocol:       req.Protocol,
+		Hostname:       req.Hostname,
+		Aliases:        req.Aliases,
+		PathPrefix:     req.PathPrefix,
+		PrefixRewrite:  req.PrefixRewrite,
+		ContainerPort:  req.ContainerPort,
+		HostPort:       req.HostPort,
+		Backends:       req.Backends,
+		Tags:           req.Tags,
+		TLS:            req.TLS,
+		Options:        req.Options,
+		RequestTimeout: req.RequestTimeout,
+		NumRetries:     req.NumRetries,
+		RetryOn:        req.RetryOn,
+		Auth:           req.Auth,
+		Affinity:       req.Affinity,
+
+		AllowUndeclaredPort: req.AllowUndeclaredPort,
+	})
+	if err != nil {
+		h.logger.Error("failed to update exposure", "exposure_id", exposureID, "error", err)
+		if writeHostnameConflict(w, err) {
+			return
+		}
+		status := exposureErrorStatus(err, http.StatusBadRequest)
+		if errors.Is(err, errExposureNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toExposureResponse(exposure, h.store))
+}
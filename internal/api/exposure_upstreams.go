@@ -0,0 +1,290 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moby/moby/api/types/events"
+	"github.com/moby/moby/client"
+)
+
+const (
+	upstreamWatchMinBackoff = time.Second
+	upstreamWatchMaxBackoff = 30 * time.Second
+)
+
+// Upstream health of an exposure, from the state of the containers behind it
+const (
+	UpstreamUp      = "up"      // every backend container is running
+	UpstreamPartial = "partial" // some backend containers are running
+	UpstreamDown    = "down"    // no backend container is running
+	UpstreamUnknown = "unknown" // the Docker event stream is not connected yet
+)
+
+// watchedContainer is the last known state of a container
+type watchedContainer struct {
+	state  string // Docker state, e.g. "running" or "exited"
+	labels map[string]string
+}
+
+// upstreamWatch tracks container states from the Docker event stream so exposure health
+// can be reported without inspecting containers on every request
+type upstreamWatch struct {
+	mu         sync.RWMutex
+	synced     bool // containers reflects a full listing plus the events since
+	containers map[string]watchedContainer
+
+	// Answer HTTP requests for exposures whose upstream is down with a 503 from Envoy
+	// instead of proxying them into connect errors
+	directResponse bool
+}
+
+// StartUpstreamWatch follows container start, stop, die and destroy events until ctx is
+// cancelled, keeping each exposure's upstream health current. The stream is reopened with
+// backoff when it fails, e.g. while the Docker daemon restarts, and containers are listed
+// again on every reconnect so changes missed in between are picked up. With
+// directResponse set, HTTP exposures whose upstream is down are answered by Envoy with a
+// 503 until a backend container runs again.
+func (s *ExposureStore) StartUpstreamWatch(ctx context.Context, directResponse bool) {
+	s.upstreams.mu.Lock()
+	s.upstreams.directResponse = directResponse
+	s.upstreams.mu.Unlock()
+
+	go s.runUpstreamWatch(ctx)
+}
+
+func (s *ExposureStore) runUpstreamWatch(ctx context.Context) {
+	backoff := upstreamWatchMinBackoff
+	for {
+		connected, err := s.watchContainerEvents(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = upstreamWatchMinBackoff
+		}
+
+		s.upstreams.mu.Lock()
+		s.upstreams.synced = false
+		s.upstreams.mu.Unlock()
+
+		s.logger.Warn("docker event stream lost, reconnecting", "error", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, upstreamWatchMaxBackoff)
+	}
+}
+
+// watchContainerEvents lists all containers, then applies container events until the
+// stream fails. connected reports whether the listing succeeded.
+func (s *ExposureStore) watchContainerEvents(ctx context.Context) (connected bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before listing so no event falls between the two
+	stream := s.dockerClient.Events(ctx, client.EventsListOptions{
+		Filters: make(client.Filters).
+			Add("type", string(events.ContainerEventType)).
+			Add("event", string(events.ActionStart), string(events.ActionStop), string(events.ActionDie), string(events.ActionDestroy)),
+	})
+
+	result, err := s.dockerClient.ContainerList(ctx, client.ContainerListOptions{All: true})
+	if err != nil {
+		return false, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	containers := make(map[string]watchedContainer, len(result.Items))
+	for _, c := range result.Items {
+		if len(c.Names) == 0 {
+			continue
+		}
+		containers[strings.TrimPrefix(c.Names[0], "/")] = watchedContainer{state: string(c.State), labels: c.Labels}
+	}
+
+	s.upstreams.mu.Lock()
+	s.upstreams.containers = containers
+	s.upstreams.synced = true
+	s.upstreams.mu.Unlock()
+	s.logger.Info("watching container events", "containers", len(containers))
+	s.upstreamsChanged()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case err := <-stream.Err:
+			return true, err
+		case msg := <-stream.Messages:
+			s.applyContainerEvent(msg)
+		}
+	}
+}
+
+// applyContainerEvent records a container's new state and, if the container is behind an
+// exposure, lets the snapshot follow
+func (s *ExposureStore) applyContainerEvent(msg events.Message) {
+	name := msg.Actor.Attributes["name"]
+	if name == "" {
+		return
+	}
+
+	labels := containerLabels(msg.Actor.Attributes)
+
+	s.upstreams.mu.Lock()
+	switch msg.Action {
+	case events.ActionStart:
+		s.upstreams.containers[name] = watchedContainer{state: "running", labels: labels}
+	case events.ActionStop, events.ActionDie:
+		s.upstreams.containers[name] = watchedContainer{state: "exited", labels: labels}
+	case events.ActionDestroy:
+		delete(s.upstreams.containers, name)
+	}
+	s.upstreams.mu.Unlock()
+
+	s.mutex.RLock()
+	var affected []string
+	for _, exp := range s.exposures {
+		if s.backsExposure(exp, name, labels) {
+			affected = append(affected, exp.ID)
+		}
+	}
+	s.mutex.RUnlock()
+
+	if len(affected) > 0 {
+		sort.Strings(affected)
+		s.logger.Info("exposure upstream changed", "container", name, "event", msg.Action, "exposures", affected)
+		s.upstreamsChanged()
+	}
+}
+
+// upstreamsChanged pushes a snapshot when routes follow upstream health. Unchanged
+// resources are not pushed again, so this is cheap when nothing flipped.
+func (s *ExposureStore) upstreamsChanged() {
+	s.upstreams.mu.RLock()
+	directResponse := s.upstreams.directResponse
+	s.upstreams.mu.RUnlock()
+
+	if directResponse && s.snapshots != nil {
+		s.snapshots.markDirty()
+	}
+}
+
+// eventAttributes are the attributes Docker adds to container events besides the labels
+var eventAttributes = map[string]bool{"name": true, "image": true, "exitCode": true, "signal": true, "execDuration": true}
+
+// containerLabels returns the labels among a container event's attributes
+func containerLabels(attributes map[string]string) map[string]string {
+	labels := make(map[string]string, len(attributes))
+	for key, value := range attributes {
+		if !eventAttributes[key] {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// backsExposure reports whether a container is one of the exposure's backends
+func (s *ExposureStore) backsExposure(exp *Exposure, name string, labels map[string]string) bool {
+	if len(exp.Backends) == 0 {
+		return name == exp.ModuleID+"-main"
+	}
+	for _, backend := range exp.Backends {
+		if selector, ok := strings.CutPrefix(backend, backendLabelPrefix); ok {
+			if matchesLabelSelector(labels, selector) {
+				return true
+			}
+		} else if backend == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesLabelSelector applies a "key" or "key=value" selector the way Docker's label filter does
+func matchesLabelSelector(labels map[string]string, selector string) bool {
+	key, value, hasValue := strings.Cut(selector, "=")
+	actual, ok := labels[key]
+	return ok && (!hasValue || actual == value)
+}
+
+// upstreamHealth returns the exposure's upstream health and, unless it is up, why
+func (s *ExposureStore) upstreamHealth(exp *Exposure) (string, string) {
+	s.upstreams.mu.RLock()
+	defer s.upstreams.mu.RUnlock()
+
+	if !s.upstreams.synced {
+		return UpstreamUnknown, ""
+	}
+
+	var names []string
+	for name, c := range s.upstreams.containers {
+		if s.backsExposure(exp, name, c.labels) {
+			names = append(names, name)
+		}
+	}
+	// Named backends and the main container count even when they do not exist
+	if len(exp.Backends) == 0 {
+		names = mergeNames(names, []string{exp.ModuleID + "-main"})
+	} else {
+		names = mergeNames(names, namedBackends(exp.Backends))
+	}
+	if len(names) == 0 {
+		return UpstreamDown, "no container matches the backends"
+	}
+
+	var down []string
+	for _, name := range names {
+		c, ok := s.upstreams.containers[name]
+		switch {
+		case !ok:
+			down = append(down, fmt.Sprintf("%s does not exist", name))
+		case c.state != "running":
+			down = append(down, fmt.Sprintf("%s is %s", name, c.state))
+		}
+	}
+	switch {
+	case len(down) == 0:
+		return UpstreamUp, ""
+	case len(down) < len(names):
+		return UpstreamPartial, strings.Join(down, "; ")
+	default:
+		return UpstreamDown, strings.Join(down, "; ")
+	}
+}
+
+// upstreamDownResponse reports whether Envoy should answer the exposure itself because its
+// upstream is down
+func (s *ExposureStore) upstreamDownResponse(exp *Exposure) bool {
+	s.upstreams.mu.RLock()
+	directResponse := s.upstreams.directResponse
+	s.upstreams.mu.RUnlock()
+
+	if !directResponse || exp.Protocol != "http" {
+		return false
+	}
+	health, _ := s.upstreamHealth(exp)
+	return health == UpstreamDown
+}
+
+// mergeNames adds the names in extra that are not in names yet, returning them sorted
+func mergeNames(names, extra []string) []string {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		seen[name] = true
+	}
+	for _, name := range extra {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -5,37 +5,81 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/config"
 	"zeropoint-agent/internal/mdns"
 	"zeropoint-agent/internal/network"
+	"zeropoint-agent/internal/validator"
 	"zeropoint-agent/internal/xds"
 
 	"github.com/moby/moby/client"
 )
 
-const (
-	exposuresFileName = "exposures.json"
-	minTCPPort        = 10000
-	maxTCPPort        = 60000
-)
+const exposuresFileName = "exposures.json"
+
+// errHostPortInUse is wrapped by errors for a requested TCP or UDP host port that another
+// exposure, the agent or another process on the host already holds
+var errHostPortInUse = errors.New("host port unavailable")
+
+// errExposureNotFound is returned for operations on an exposure ID that is not stored
+var errExposureNotFound = errors.New("exposure not found")
+
+// HostnameConflictError is returned for an HTTP exposure whose hostname or alias another
+// exposure already answers to, either exactly or through the name.local form every
+// hostname is also routed as
+type HostnameConflictError struct {
+	Name       string
+	PathPrefix string // Set when the hostname is shared and its path prefix collides
+	ExposureID string // The exposure holding the name
+}
+
+func (e *HostnameConflictError) Error() string {
+	if e.PathPrefix != "" {
+		return fmt.Sprintf("hostname %s with path prefix %q is already used by exposure %s", e.Name, e.PathPrefix, e.ExposureID)
+	}
+	return fmt.Sprintf("name %s is already used by exposure %s", e.Name, e.ExposureID)
+}
 
 // Exposure represents a service exposure
 type Exposure struct {
-	ID            string    `json:"id"`
-	ModuleID      string    `json:"module_id"`      // References Module.ID
-	Protocol      string    `json:"protocol"`       // "http" or "tcp"
-	Hostname      string    `json:"hostname"`       // required for http, optional for tcp
-	ContainerPort uint32    `json:"container_port"` // port inside container
-	HostPort      uint32    `json:"host_port"`      // auto-allocated for tcp, 0 for http
-	CreatedAt     time.Time `json:"created_at"`
-	Tags          []string  `json:"tags,omitempty"` // optional tags for categorization
+	ID             string            `json:"id"`
+	ModuleID       string            `json:"module_id"`                // References Module.ID
+	Protocol       string            `json:"protocol"`                 // "http", "tcp" or "udp"
+	Hostname       string            `json:"hostname"`                 // required for http, optional for tcp
+	Aliases        []string          `json:"aliases,omitempty"`        // http only; additional hostnames for the same routes
+	PathPrefix     string            `json:"path_prefix,omitempty"`    // http only; route only this path prefix on the hostname
+	PrefixRewrite  string            `json:"prefix_rewrite,omitempty"` // http only; replaces path_prefix before forwarding (e.g. "/")
+	ContainerPort  uint32            `json:"container_port"`           // port inside container
+	HostPort       uint32            `json:"host_port"`                // auto-allocated for tcp/udp, 0 for http
+	Backends       []string          `json:"backends,omitempty"`       // replica container names or "label:" selectors; the module's main container if empty
+	CreatedAt      time.Time         `json:"created_at"`
+	Tags           []string          `json:"tags,omitempty"`            // optional tags for categorization
+	TLS            *ExposureTLS      `json:"tls,omitempty"`             // optional TLS termination, http only
+	Options        *HTTPOptions      `json:"options,omitempty"`         // optional upstream protocol options, http only
+	RequestTimeout string            `json:"request_timeout,omitempty"` // http only; Go duration, e.g. "30s"; empty means no timeout
+	NumRetries     uint32            `json:"num_retries,omitempty"`     // http only; requires retry_on
+	RetryOn        []string          `json:"retry_on,omitempty"`        // http only; Envoy retry conditions, e.g. "5xx", "reset"
+	Auth           *ExposureAuth     `json:"auth,omitempty"`            // http only; require basic auth credentials
+	Affinity       *ExposureAffinity `json:"affinity,omitempty"`        // http only; pin clients to one upstream
+
+	AllowUndeclaredPort bool `json:"allow_undeclared_port,omitempty"` // container_port was not checked against the module's declared ports
+}
+
+// HTTPOptions describes what an HTTP exposure's upstream speaks beyond plain HTTP/1.1
+type HTTPOptions struct {
+	WebSocket bool `json:"websocket,omitempty"` // upgrades are allowed on every route; recorded for clients
+	GRPC      bool `json:"grpc,omitempty"`      // upstream is gRPC, proxied over HTTP/2
 }
 
 // MDNSService interface for mDNS operations
@@ -55,10 +99,27 @@ type ExposureStore struct {
 	storagePath    string
 	logger         *slog.Logger
 	mdnsService    MDNSService
+
+	portMin       uint32
+	portMax       uint32
+	reservedPorts map[uint32]string // TCP ports the agent and Envoy listen on, and what holds them
+
+	modulePorts ModulePortSource // Ports modules declare, to check container ports against
+	portCheck   string           // config.PortCheck* mode for undeclared container ports
+
+	envoyAdminAddr string
+	listeners      listenerState
+	ackTimeout     time.Duration // How long mutations wait for Envoy to ACK; 0 disables
+
+	snapshots *snapshotCoordinator
+
+	upstreams upstreamWatch // Container states behind the exposures, from Docker events
 }
 
-// NewExposureStore creates a new exposure store
-func NewExposureStore(dockerClient *client.Client, xdsServer *xds.Server, mdnsService MDNSService, logger *slog.Logger) (*ExposureStore, error) {
+// NewExposureStore creates a new exposure store. envoyAdminAddr is used to verify that
+// Envoy actually bound each exposure's listener after a snapshot push; host ports and the
+// snapshot ACK timeout come from cfg.
+func NewExposureStore(dockerClient *client.Client, xdsServer *xds.Server, mdnsService MDNSService, envoyAdminAddr string, cfg *config.Config, logger *slog.Logger) (*ExposureStore, error) {
 	storageRoot := internalPaths.GetStorageRoot()
 
 	// Ensure storage directory exists
@@ -76,6 +137,11 @@ func NewExposureStore(dockerClient *client.Client, xdsServer *xds.Server, mdnsSe
 		storagePath:    storagePath,
 		logger:         logger,
 		mdnsService:    mdnsService,
+		envoyAdminAddr: envoyAdminAddr,
+		listeners:      listenerState{degraded: make(map[string]string)},
+		ackTimeout:     time.Duration(cfg.Envoy.AckTimeout),
+		portMin:        uint32(cfg.ExposurePortMin),
+		portMax:        uint32(cfg.ExposurePortMax),
 	}
 
 	// Load existing exposures from disk
@@ -88,10 +154,13 @@ func NewExposureStore(dockerClient *client.Client, xdsServer *xds.Server, mdnsSe
 		logger.Warn("failed to reconcile networks", "error", err)
 	}
 
-	// Rebuild xDS snapshot from loaded exposures
-	if err := store.updateSnapshot(context.Background()); err != nil {
+	// Rebuild xDS snapshot from loaded exposures, then let later changes go through the
+	// debounced coordinator
+	store.snapshots = newSnapshotCoordinator(store)
+	if err := store.ForceSync(context.Background()); err != nil {
 		return nil, fmt.Errorf("failed to build initial snapshot: %w", err)
 	}
+	go store.snapshots.run(context.Background())
 
 	// Re-register all exposures via mDNS
 	if mdnsService != nil {
@@ -104,54 +173,265 @@ func NewExposureStore(dockerClient *client.Client, xdsServer *xds.Server, mdnsSe
 	return store, nil
 }
 
-// CreateExposure creates or returns existing exposure with user-provided ID (idempotent)
-func (s *ExposureStore) CreateExposure(ctx context.Context, exposureID, moduleID, protocol, hostname string, containerPort uint32, tags []string) (*Exposure, bool, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+// ExposureSpec is the requested configuration for an exposure
+type ExposureSpec struct {
+	ModuleID      string
+	Protocol      string // "http", "tcp" or "udp"
+	Hostname      string
+	Aliases       []string // http only
+	PathPrefix    string   // http only
+	PrefixRewrite string   // http only
+	ContainerPort uint32
+	HostPort      uint32   // tcp/udp only; 0 allocates one from the configured range
+	Backends      []string // container names or "label:" selectors; the module's main container if empty
+	Tags          []string
+	TLS           *ExposureTLS // http only
+	Options       *HTTPOptions // http only
+	// Request timeout and retry policy, http only
+	RequestTimeout string
+	NumRetries     uint32
+	RetryOn        []string
+	Auth           *ExposureAuth     // http only
+	Affinity       *ExposureAffinity // http only
+
+	AllowUndeclaredPort bool // Skip checking ContainerPort against the module's {container}_ports outputs
+
+	declared *declaredPorts // Set by withDeclaredPorts before the store lock is taken
+}
 
-	// Validate protocol
-	if protocol != "http" && protocol != "tcp" {
-		return nil, false, fmt.Errorf("protocol must be 'http' or 'tcp'")
+// CreateExposure creates or returns existing exposure with user-provided ID (idempotent).
+// Re-creating an existing exposure with a different alias set updates its aliases in place.
+// Unless the ACK wait is disabled, it returns once Envoy accepted the new configuration, and
+// fails with an *xds.RejectedError if Envoy rejected it.
+func (s *ExposureStore) CreateExposure(ctx context.Context, exposureID string, spec ExposureSpec) (*Exposure, bool, error) {
+	exposure, created, err := s.createExposure(ctx, exposureID, s.withDeclaredPorts(spec))
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.confirmPush(ctx); err != nil {
+		return nil, false, err
 	}
+	return exposure, created, nil
+}
 
-	// Validate hostname for http
-	if protocol == "http" && hostname == "" {
-		return nil, false, fmt.Errorf("hostname required for http exposures")
+func (s *ExposureStore) createExposure(ctx context.Context, exposureID string, spec ExposureSpec) (*Exposure, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	aliases, err := specAliases(&spec)
+	if err != nil {
+		return nil, false, err
 	}
 
 	// Check if exposure already exists by ID
 	if existing, exists := s.exposures[exposureID]; exists {
+		if spec.Protocol == "http" && !sameNames(existing.Aliases, aliases) {
+			if err := s.updateAliases(ctx, existing, aliases); err != nil {
+				return nil, false, err
+			}
+		}
 		return existing, false, nil
 	}
 
-	// Verify container exists
-	if err := s.verifyContainer(ctx, moduleID); err != nil {
+	exposure, err := s.newExposure(ctx, exposureID, spec, aliases, nil)
+	if err != nil {
 		return nil, false, err
 	}
 
+	// Store exposure
+	s.exposures[exposure.ID] = exposure
+
+	// Save to disk
+	if err := s.save(); err != nil {
+		delete(s.exposures, exposure.ID)
+		return nil, false, fmt.Errorf("failed to save exposures: %w", err)
+	}
+
+	// Push the new configuration once the burst of changes settles
+	s.snapshots.markDirty()
+
+	// Register mDNS for HTTP exposures with hostname
+	if exposure.Protocol == "http" {
+		s.registerMDNS(exposureNames(exposure)...)
+	}
+
+	return exposure, true, nil
+}
+
+// specAliases validates the protocol and names of a spec, lowercases its hostname in
+// place and returns its normalized aliases
+func specAliases(spec *ExposureSpec) ([]string, error) {
+	protocol := spec.Protocol
+
+	// Validate protocol
+	if protocol != "http" && protocol != "tcp" && protocol != "udp" {
+		return nil, fmt.Errorf("protocol must be 'http', 'tcp' or 'udp'")
+	}
+
+	// Validate hostname for http
+	spec.Hostname = validator.NormalizeHostname(spec.Hostname)
+	if protocol == "http" && spec.Hostname == "" {
+		return nil, fmt.Errorf("hostname required for http exposures")
+	}
+	if spec.Hostname != "" {
+		if err := validator.ValidateHostname(spec.Hostname); err != nil {
+			return nil, err
+		}
+	}
+
+	if protocol != "http" && len(spec.Aliases) > 0 {
+		return nil, fmt.Errorf("aliases are only supported for http exposures")
+	}
+	return normalizeAliases(spec.Hostname, spec.Aliases)
+}
+
+// newExposure validates the rest of the spec and builds a new exposure: it checks the
+// containers, conflicts with stored exposures, allocates a host port and connects the
+// backends to zeropoint-network. previous is the stored exposure being replaced, if any;
+// its host port is kept when the protocol stays and the spec asks for that port or none.
+// The exposure is not stored (caller must hold the lock).
+func (s *ExposureStore) newExposure(ctx context.Context, exposureID string, spec ExposureSpec, aliases []string, previous *Exposure) (*Exposure, error) {
+	protocol := spec.Protocol
+
+	if protocol == "http" && spec.HostPort != 0 {
+		return nil, fmt.Errorf("host_port is only supported for tcp and udp exposures")
+	}
+
+	// Validate path routing
+	if protocol != "http" && (spec.PathPrefix != "" || spec.PrefixRewrite != "") {
+		return nil, fmt.Errorf("path_prefix and prefix_rewrite are only supported for http exposures")
+	}
+	pathPrefix, err := normalizePathPrefix(spec.PathPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if spec.PrefixRewrite != "" {
+		if pathPrefix == "" {
+			return nil, fmt.Errorf("prefix_rewrite requires path_prefix")
+		}
+		if !strings.HasPrefix(spec.PrefixRewrite, "/") {
+			return nil, fmt.Errorf("prefix_rewrite must start with '/'")
+		}
+	}
+
+	// Validate certificates up front so a bad cert never reaches Envoy
+	if spec.TLS != nil {
+		if protocol != "http" {
+			return nil, fmt.Errorf("tls is only supported for http exposures")
+		}
+		if _, err := loadTLSCertificate(spec.TLS); err != nil {
+			return nil, err
+		}
+	}
+
+	if spec.Options != nil && protocol != "http" {
+		return nil, fmt.Errorf("options are only supported for http exposures")
+	}
+
+	if spec.Auth != nil {
+		if protocol != "http" {
+			return nil, fmt.Errorf("auth is only supported for http exposures")
+		}
+		if err := spec.Auth.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if spec.Affinity != nil {
+		if protocol != "http" {
+			return nil, fmt.Errorf("affinity is only supported for http exposures")
+		}
+		if err := spec.Affinity.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if protocol != "http" && (spec.RequestTimeout != "" || spec.NumRetries > 0 || len(spec.RetryOn) > 0) {
+		return nil, fmt.Errorf("request_timeout, num_retries and retry_on are only supported for http exposures")
+	}
+	if err := validateRetryPolicy(spec.RequestTimeout, spec.NumRetries, spec.RetryOn); err != nil {
+		return nil, err
+	}
+
+	if err := validateBackends(spec.Backends); err != nil {
+		return nil, err
+	}
+
+	// Verify the containers exist
+	if err := s.verifyContainer(ctx, spec.ModuleID, spec.Backends); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyDeclaredPort(spec); err != nil {
+		return nil, err
+	}
+
+	// UDP has no handshake, so a wrong port would fail silently; check it against the image
+	if protocol == "udp" {
+		if err := s.verifyUDPPort(ctx, spec.ModuleID, spec.ContainerPort); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create new exposure
 	exposure := &Exposure{
-		ID:            exposureID, // Use provided ID instead of generating
-		ModuleID:      moduleID,
-		Protocol:      protocol,
-		Hostname:      hostname,
-		ContainerPort: containerPort,
-		Tags:          tags,
-		CreatedAt:     time.Now(),
-	}
-
-	// Allocate host port for TCP
-	if protocol == "tcp" {
-		hostPort, err := s.allocatePort()
-		if err != nil {
-			return nil, false, err
+		ID:             exposureID, // Use provided ID instead of generating
+		ModuleID:       spec.ModuleID,
+		Protocol:       protocol,
+		Hostname:       spec.Hostname,
+		Aliases:        aliases,
+		PathPrefix:     pathPrefix,
+		PrefixRewrite:  spec.PrefixRewrite,
+		ContainerPort:  spec.ContainerPort,
+		HostPort:       spec.HostPort,
+		Backends:       spec.Backends,
+		Tags:           spec.Tags,
+		TLS:            spec.TLS,
+		Options:        spec.Options,
+		RequestTimeout: spec.RequestTimeout,
+		NumRetries:     spec.NumRetries,
+		RetryOn:        spec.RetryOn,
+		Auth:           spec.Auth,
+		Affinity:       spec.Affinity,
+		CreatedAt:      time.Now(),
+
+		AllowUndeclaredPort: spec.AllowUndeclaredPort,
+	}
+
+	// Two exposures cannot claim the same hostname and path, the same name, or the same host port
+	if err := s.checkConflicts(exposure); err != nil {
+		return nil, err
+	}
+
+	// Allocate or verify the host port for TCP and UDP. The previous exposure still holds
+	// its port, so allocatePort never hands it out while it is being replaced.
+	if protocol == "tcp" || protocol == "udp" {
+		switch {
+		case previous != nil && previous.Protocol == protocol && (spec.HostPort == 0 || spec.HostPort == previous.HostPort):
+			// Envoy is bound to it, so it cannot be probed
+			exposure.HostPort = previous.HostPort
+		case spec.HostPort == 0:
+			allocated, err := s.allocatePort(protocol)
+			if err != nil {
+				return nil, err
+			}
+			exposure.HostPort = allocated
+		default:
+			if err := s.checkHostPort(protocol, spec.HostPort); err != nil {
+				return nil, err
+			}
 		}
-		exposure.HostPort = hostPort
 	}
 
-	// Ensure container is on zeropoint-network
-	if err := s.ensureNetwork(ctx, moduleID); err != nil {
-		return nil, false, err
+	// Ensure the containers are on zeropoint-network
+	backends, err := s.resolveBackends(ctx, spec.ModuleID, spec.Backends, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, backend := range backends {
+		if err := s.ensureContainerNetwork(ctx, backend); err != nil {
+			return nil, err
+		}
 	}
 
 	// Ensure Envoy is also connected to zeropoint-network (critical for xDS to work)
@@ -160,30 +440,48 @@ func (s *ExposureStore) CreateExposure(ctx context.Context, exposureID, moduleID
 		// Don't fail the exposure creation, but log the issue
 	}
 
-	// Store exposure
-	s.exposures[exposure.ID] = exposure
+	return exposure, nil
+}
 
-	// Save to disk
-	if err := s.save(); err != nil {
-		delete(s.exposures, exposure.ID)
-		return nil, false, fmt.Errorf("failed to save exposures: %w", err)
+// updateAliases replaces an existing exposure's aliases (caller must hold the lock)
+func (s *ExposureStore) updateAliases(ctx context.Context, exposure *Exposure, aliases []string) error {
+	candidate := *exposure
+	candidate.Aliases = aliases
+	if err := s.checkConflicts(&candidate); err != nil {
+		return err
 	}
 
-	// Update xDS snapshot
-	if err := s.updateSnapshot(ctx); err != nil {
-		s.logger.Error("failed to update xDS snapshot", "error", err)
-		// Don't fail the request, just log
+	previous := exposure.Aliases
+	exposure.Aliases = aliases
+	if err := s.save(); err != nil {
+		exposure.Aliases = previous
+		return fmt.Errorf("failed to save exposures: %w", err)
 	}
 
-	// Register mDNS for HTTP exposures with hostname
-	if protocol == "http" && hostname != "" && s.mdnsService != nil {
-		if err := s.mdnsService.RegisterExposure(hostname, 80); err != nil {
-			s.logger.Warn("failed to register mDNS for exposure", "hostname", hostname, "error", err)
-			// Don't fail the request, just log
+	// Push the new configuration once the burst of changes settles
+	s.snapshots.markDirty()
+
+	s.announceAliases(previous, aliases)
+
+	s.logger.Info("updated exposure aliases", "exposure_id", exposure.ID, "aliases", aliases)
+	return nil
+}
+
+// announceAliases drops mDNS records for aliases that were removed and no other exposure
+// uses, and announces the new ones (caller must hold the lock)
+func (s *ExposureStore) announceAliases(previous, aliases []string) {
+	current := make(map[string]bool)
+	for _, name := range aliases {
+		current[name] = true
+	}
+	var dropped []string
+	for _, name := range previous {
+		if !current[name] && !s.nameInUse(name, "") {
+			dropped = append(dropped, name)
 		}
 	}
-
-	return exposure, true, nil
+	s.unregisterMDNS(dropped...)
+	s.registerMDNS(aliases...)
 }
 
 // GetExposure retrieves an exposure by ID
@@ -193,7 +491,7 @@ func (s *ExposureStore) GetExposure(id string) (*Exposure, error) {
 
 	exposure, ok := s.exposures[id]
 	if !ok {
-		return nil, fmt.Errorf("exposure not found")
+		return nil, errExposureNotFound
 	}
 	return exposure, nil
 }
@@ -207,102 +505,160 @@ func (s *ExposureStore) ListExposures() []*Exposure {
 	for _, exp := range s.exposures {
 		exposures = append(exposures, exp)
 	}
+	sortExposures(exposures)
 	return exposures
 }
 
-// DeleteExposure removes an exposure
+// CountByProtocol returns the number of exposures per protocol
+func (s *ExposureStore) CountByProtocol() map[string]int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	counts := make(map[string]int)
+	for _, exp := range s.exposures {
+		counts[exp.Protocol]++
+	}
+	return counts
+}
+
+// DeleteExposure removes an exposure and, like CreateExposure, waits for Envoy to accept
+// the resulting configuration
 func (s *ExposureStore) DeleteExposure(ctx context.Context, id string) error {
+	if err := s.deleteExposure(id); err != nil {
+		return err
+	}
+	return s.confirmPush(ctx)
+}
+
+func (s *ExposureStore) deleteExposure(id string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	exposure, ok := s.exposures[id]
 	if !ok {
-		return fmt.Errorf("exposure not found")
-	}
-
-	// Unregister mDNS if it's an HTTP exposure with hostname
-	if exposure.Protocol == "http" && exposure.Hostname != "" && s.mdnsService != nil {
-		if err := s.mdnsService.UnregisterExposure(exposure.Hostname); err != nil {
-			s.logger.Warn("failed to unregister mDNS for exposure", "hostname", exposure.Hostname, "error", err)
-		}
+		return errExposureNotFound
 	}
 
 	delete(s.exposures, id)
 
+	// Unregister mDNS for names no other exposure still uses
+	s.unregisterUnusedNames(exposure)
+
 	// Save to disk
 	if err := s.save(); err != nil {
 		return fmt.Errorf("failed to save exposures: %w", err)
 	}
 
-	// Update xDS snapshot
-	if err := s.updateSnapshot(ctx); err != nil {
-		s.logger.Error("failed to update xDS snapshot", "error", err)
-	}
+	// Push the new configuration once the burst of changes settles
+	s.snapshots.markDirty()
 
 	return nil
 }
 
-// GetExposureByModuleID returns an exposure by module ID
-func (s *ExposureStore) GetExposureByModuleID(moduleID string) *Exposure {
+// ListExposuresByModuleID returns every exposure for a module, sorted by ID
+func (s *ExposureStore) ListExposuresByModuleID(moduleID string) []*Exposure {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
+	var exposures []*Exposure
 	for _, exp := range s.exposures {
 		if exp.ModuleID == moduleID {
-			return exp
+			exposures = append(exposures, exp)
 		}
 	}
-	return nil
+	sortExposures(exposures)
+	return exposures
 }
 
-// DeleteExposureByModuleID removes an exposure by module ID
-func (s *ExposureStore) DeleteExposureByModuleID(ctx context.Context, moduleID string) error {
+// DeleteExposuresByModuleID removes every exposure for a module and returns how many were removed
+func (s *ExposureStore) DeleteExposuresByModuleID(ctx context.Context, moduleID string) (int, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	// Find exposure with matching module_id
-	var exposureID string
-	var exposure *Exposure
+	var removed []*Exposure
 	for id, exp := range s.exposures {
 		if exp.ModuleID == moduleID {
-			exposureID = id
-			exposure = exp
-			break
+			removed = append(removed, exp)
+			delete(s.exposures, id)
 		}
 	}
 
-	if exposureID == "" {
-		return fmt.Errorf("exposure not found for module_id: %s", moduleID)
+	if len(removed) == 0 {
+		return 0, nil
 	}
 
-	// Unregister mDNS if it's an HTTP exposure with hostname
-	if exposure.Protocol == "http" && exposure.Hostname != "" && s.mdnsService != nil {
-		if err := s.mdnsService.UnregisterExposure(exposure.Hostname); err != nil {
-			s.logger.Warn("failed to unregister mDNS for exposure", "hostname", exposure.Hostname, "error", err)
-		}
-	}
-
-	delete(s.exposures, exposureID)
+	// Unregister mDNS for names no remaining exposure uses. The removed exposures
+	// are already out of the map, so only survivors are considered.
+	s.unregisterUnusedNames(removed...)
 
 	// Save to disk
 	if err := s.save(); err != nil {
-		return fmt.Errorf("failed to save exposures: %w", err)
+		return 0, fmt.Errorf("failed to save exposures: %w", err)
+	}
+
+	// Push the new configuration once the burst of changes settles
+	s.snapshots.markDirty()
+
+	return len(removed), nil
+}
+
+// checkConflicts rejects an exposure that would claim a route or port another exposure
+// already holds (caller must hold the lock)
+func (s *ExposureStore) checkConflicts(exposure *Exposure) error {
+	for _, exp := range s.exposures {
+		if err := exposureConflict(exposure, exp); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// Update xDS snapshot
-	if err := s.updateSnapshot(ctx); err != nil {
-		s.logger.Error("failed to update xDS snapshot", "error", err)
+// exposureConflict returns why exposure cannot coexist with exp, or nil if it can. Both
+// must have normalized names.
+func exposureConflict(exposure, exp *Exposure) error {
+	if exp.ID == exposure.ID || exp.Protocol != exposure.Protocol {
+		return nil
 	}
 
+	switch exposure.Protocol {
+	case "http":
+		if exp.Hostname == exposure.Hostname && exp.PathPrefix == exposure.PathPrefix {
+			return &HostnameConflictError{Name: exposure.Hostname, PathPrefix: displayPathPrefix(exposure.PathPrefix), ExposureID: exp.ID}
+		}
+		// Exposures sharing a hostname share a virtual host; otherwise every
+		// name must be unique or Envoy would see the same domain twice
+		if exp.Hostname == exposure.Hostname {
+			return nil
+		}
+		for _, name := range exposureNames(exposure) {
+			for _, other := range exposureNames(exp) {
+				if sameName(name, other) {
+					return &HostnameConflictError{Name: name, ExposureID: exp.ID}
+				}
+			}
+		}
+	case "tcp", "udp":
+		if exposure.HostPort != 0 && exp.HostPort == exposure.HostPort {
+			return fmt.Errorf("%w: %d is already used by exposure %s", errHostPortInUse, exposure.HostPort, exp.ID)
+		}
+	}
 	return nil
 }
 
-// findExposure checks if an exposure already exists
-func (s *ExposureStore) findExposure(moduleID, protocol, hostname string, containerPort uint32) *Exposure {
+// sortExposures orders exposures by ID for stable API output
+func sortExposures(exposures []*Exposure) {
+	sort.Slice(exposures, func(i, j int) bool {
+		return exposures[i].ID < exposures[j].ID
+	})
+}
+
+// findExposure checks if an exposure already exists, comparing the full set of names
+func (s *ExposureStore) findExposure(moduleID, protocol, hostname string, aliases []string, containerPort uint32) *Exposure {
 	for _, exp := range s.exposures {
 		if exp.ModuleID == moduleID &&
 			exp.Protocol == protocol &&
 			exp.Hostname == hostname &&
+			sameNames(exp.Aliases, aliases) &&
 			exp.ContainerPort == containerPort {
 			return exp
 		}
@@ -310,26 +666,243 @@ func (s *ExposureStore) findExposure(moduleID, protocol, hostname string, contai
 	return nil
 }
 
-// allocatePort finds the next available TCP port
-func (s *ExposureStore) allocatePort() (uint32, error) {
+// exposureNames returns the hostname and aliases an HTTP exposure answers to
+func exposureNames(exp *Exposure) []string {
+	if exp.Protocol != "http" || exp.Hostname == "" {
+		return nil
+	}
+	return append([]string{exp.Hostname}, exp.Aliases...)
+}
+
+// nameInUse reports whether an HTTP exposure other than excludeID answers to name,
+// ignoring any .local suffix (caller must hold the lock)
+func (s *ExposureStore) nameInUse(name, excludeID string) bool {
+	return s.nameOwner(name, excludeID) != ""
+}
+
+// nameOwner returns the ID of an HTTP exposure other than excludeID that answers to name,
+// ignoring any .local suffix, or "" if there is none (caller must hold the lock)
+func (s *ExposureStore) nameOwner(name, excludeID string) string {
+	var owners []string
+	for _, exp := range s.exposures {
+		if exp.ID == excludeID {
+			continue
+		}
+		for _, other := range exposureNames(exp) {
+			if sameName(other, name) {
+				owners = append(owners, exp.ID)
+				break
+			}
+		}
+	}
+	if len(owners) == 0 {
+		return ""
+	}
+	sort.Strings(owners)
+	return owners[0]
+}
+
+// HostnameOwner returns the ID of the HTTP exposure answering to name, with or without
+// .local, or "" if the name is free
+func (s *ExposureStore) HostnameOwner(name string) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.nameOwner(name, "")
+}
+
+// registerMDNS announces hostnames via mDNS. Failures are logged, not returned.
+func (s *ExposureStore) registerMDNS(names ...string) {
+	if s.mdnsService == nil {
+		return
+	}
+	for _, name := range names {
+		if err := s.mdnsService.RegisterExposure(name, 80); err != nil {
+			s.logger.Warn("failed to register mDNS for exposure", "hostname", name, "error", err)
+		}
+	}
+}
+
+// unregisterMDNS withdraws mDNS records. Failures are logged, not returned.
+func (s *ExposureStore) unregisterMDNS(names ...string) {
+	if s.mdnsService == nil {
+		return
+	}
+	for _, name := range names {
+		if err := s.mdnsService.UnregisterExposure(name); err != nil {
+			s.logger.Warn("failed to unregister mDNS for exposure", "hostname", name, "error", err)
+		}
+	}
+}
+
+// unregisterUnusedNames withdraws mDNS records for names of removed exposures that no
+// remaining exposure answers to (caller must hold the lock and have removed them already)
+func (s *ExposureStore) unregisterUnusedNames(removed ...*Exposure) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, exp := range removed {
+		for _, name := range exposureNames(exp) {
+			if !seen[name] && !s.nameInUse(name, "") {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	s.unregisterMDNS(names...)
+}
+
+// normalizeAliases validates and lowercases aliases, dropping duplicates and the hostname
+// itself
+func normalizeAliases(hostname string, aliases []string) ([]string, error) {
+	var result []string
+	for _, alias := range aliases {
+		alias = validator.NormalizeHostname(alias)
+		if alias == "" {
+			return nil, fmt.Errorf("aliases cannot be empty")
+		}
+		if err := validator.ValidateHostname(alias); err != nil {
+			return nil, fmt.Errorf("invalid alias: %w", err)
+		}
+		if sameName(alias, hostname) {
+			continue
+		}
+		duplicate := false
+		for _, existing := range result {
+			if sameName(existing, alias) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			result = append(result, alias)
+		}
+	}
+	return result, nil
+}
+
+// sameName compares hostnames case-insensitively, treating name and name.local as
+// equal since both are routed to the same virtual host
+func sameName(a, b string) bool {
+	a = strings.TrimSuffix(validator.NormalizeHostname(a), ".local")
+	b = strings.TrimSuffix(validator.NormalizeHostname(b), ".local")
+	return a == b
+}
+
+// sameNames reports whether two alias sets contain the same names in any order
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if sameName(x, y) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizePathPrefix validates a path prefix and strips any trailing slash.
+// An empty prefix or "/" means the exposure claims the whole hostname.
+func normalizePathPrefix(prefix string) (string, error) {
+	if prefix == "" || prefix == "/" {
+		return "", nil
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		return "", fmt.Errorf("path_prefix must start with '/'")
+	}
+	if strings.ContainsAny(prefix, "?#* ") {
+		return "", fmt.Errorf("path_prefix contains invalid characters")
+	}
+	return strings.TrimRight(prefix, "/"), nil
+}
+
+// displayPathPrefix renders the root prefix as "/" for messages
+func displayPathPrefix(prefix string) string {
+	if prefix == "" {
+		return "/"
+	}
+	return prefix
+}
+
+// allocatePort finds the next port in the configured range that no exposure of the
+// same protocol holds and that nothing else on the host is bound to
+func (s *ExposureStore) allocatePort(protocol string) (uint32, error) {
 	usedPorts := make(map[uint32]bool)
 	for _, exp := range s.exposures {
-		if exp.Protocol == "tcp" {
+		if exp.Protocol == protocol {
 			usedPorts[exp.HostPort] = true
 		}
 	}
 
-	for port := uint32(minTCPPort); port < maxTCPPort; port++ {
-		if !usedPorts[port] {
-			return port, nil
+	for port := s.portMin; port <= s.portMax; port++ {
+		if usedPorts[port] {
+			continue
+		}
+		if _, reserved := s.reservedPorts[port]; reserved && protocol == "tcp" {
+			continue
 		}
+		if err := probeHostPort(protocol, port); err != nil {
+			s.logger.Debug("skipping host port in use", "port", port, "error", err)
+			continue
+		}
+		return port, nil
 	}
 
-	return 0, fmt.Errorf("no available ports in range %d-%d", minTCPPort, maxTCPPort)
+	return 0, fmt.Errorf("no available ports in range %d-%d", s.portMin, s.portMax)
+}
+
+// ReservePorts sets the TCP ports exposures may not request because the agent or Envoy
+// listens on them; holders describes each port in error messages
+func (s *ExposureStore) ReservePorts(holders map[uint32]string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reservedPorts = holders
 }
 
-// verifyContainer checks if a container exists for the given app ID
-func (s *ExposureStore) verifyContainer(ctx context.Context, appID string) error {
+// checkHostPort verifies that a requested host port is free: not reserved, and bindable.
+// Ports other exposures hold were already rejected by checkConflicts.
+func (s *ExposureStore) checkHostPort(protocol string, port uint32) error {
+	if holder, reserved := s.reservedPorts[port]; reserved && protocol == "tcp" {
+		return fmt.Errorf("%w: %d is reserved for %s", errHostPortInUse, port, holder)
+	}
+	if err := probeHostPort(protocol, port); err != nil {
+		return fmt.Errorf("%w: %d: %v", errHostPortInUse, port, err)
+	}
+	return nil
+}
+
+// probeHostPort checks that a TCP or UDP port can be bound on all interfaces
+func probeHostPort(protocol string, port uint32) error {
+	addr := fmt.Sprintf("0.0.0.0:%d", port)
+
+	if protocol == "udp" {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return lis.Close()
+}
+
+// verifyContainer checks that the exposure's containers exist: each of the backends, which
+// must belong to the app, or the app's main container when there are none
+func (s *ExposureStore) verifyContainer(ctx context.Context, appID string, backends []string) error {
+	if len(backends) > 0 {
+		return s.verifyBackends(ctx, appID, backends)
+	}
+
 	// Container name is app ID + "-main"
 	containerName := appID + "-main"
 	_, err := s.dockerClient.ContainerInspect(ctx, containerName, client.ContainerInspectOptions{})
@@ -339,6 +912,29 @@ func (s *ExposureStore) verifyContainer(ctx context.Context, appID string) error
 	return nil
 }
 
+// verifyUDPPort rejects a UDP container port the image does not expose. Images that
+// declare no exposed ports at all are accepted, since there is nothing to check against.
+func (s *ExposureStore) verifyUDPPort(ctx context.Context, moduleID string, containerPort uint32) error {
+	if containerPort == 0 || containerPort > 65535 {
+		return fmt.Errorf("invalid container port %d", containerPort)
+	}
+
+	info, err := s.dockerClient.ContainerInspect(ctx, moduleID+"-main", client.ContainerInspectOptions{})
+	if err != nil {
+		return fmt.Errorf("container not found for module %s: %w", moduleID, err)
+	}
+	if info.Container.Config == nil || len(info.Container.Config.ExposedPorts) == 0 {
+		return nil
+	}
+
+	for port := range info.Container.Config.ExposedPorts {
+		if port.Proto() == "udp" && uint32(port.Num()) == containerPort {
+			return nil
+		}
+	}
+	return fmt.Errorf("container for module %s does not expose %d/udp", moduleID, containerPort)
+}
+
 // getContainerStatus checks if a container exists and is running
 func (s *ExposureStore) getContainerStatus(moduleID string) string {
 	containerName := moduleID + "-main"
@@ -354,9 +950,13 @@ func (s *ExposureStore) getContainerStatus(moduleID string) string {
 
 // ensureNetwork connects container to zeropoint-network
 func (s *ExposureStore) ensureNetwork(ctx context.Context, appID string) error {
-	networkName := "zeropoint-network"
 	// Container name is app ID + "-main"
-	containerName := appID + "-main"
+	return s.ensureContainerNetwork(ctx, appID+"-main")
+}
+
+// ensureContainerNetwork connects a container by name to zeropoint-network
+func (s *ExposureStore) ensureContainerNetwork(ctx context.Context, containerName string) error {
+	networkName := "zeropoint-network"
 
 	// Create network if it doesn't exist
 	networkList, err := s.dockerClient.NetworkList(ctx, client.NetworkListOptions{})
@@ -447,35 +1047,128 @@ func (s *ExposureStore) EnsureModuleOnNetwork(ctx context.Context, moduleID, net
 // reconcileNetworks ensures all containers are connected to zeropoint-network
 func (s *ExposureStore) reconcileNetworks(ctx context.Context) error {
 	for _, exp := range s.exposures {
-		if err := s.ensureNetwork(ctx, exp.ModuleID); err != nil {
-			s.logger.Warn("failed to reconnect container to network", "module_id", exp.ModuleID, "error", err)
+		backends, err := s.resolveBackends(ctx, exp.ModuleID, exp.Backends, true)
+		if err != nil {
+			s.logger.Warn("failed to resolve exposure backends", "exposure_id", exp.ID, "error", err)
+			continue
+		}
+		for _, backend := range backends {
+			if err := s.ensureContainerNetwork(ctx, backend); err != nil {
+				s.logger.Warn("failed to reconnect container to network", "module_id", exp.ModuleID, "container", backend, "error", err)
+			}
 		}
 	}
 	return nil
 }
 
-// updateSnapshot rebuilds and pushes xDS snapshot
-func (s *ExposureStore) updateSnapshot(ctx context.Context) error {
-	exposures := make([]*xds.Exposure, 0, len(s.exposures))
-	for _, exp := range s.exposures {
+// xdsExposures converts copies of the stored exposures for the snapshot builder. It looks
+// up backends through Docker, so the caller must not hold the lock.
+func (s *ExposureStore) xdsExposures(stored []*Exposure) []*xds.Exposure {
+	exposures := make([]*xds.Exposure, 0, len(stored))
+	for _, exp := range stored {
 		// xDS needs container name, which is moduleID + "-main"
 		xdsExp := &xds.Exposure{
 			ID:            exp.ID,
 			ModuleName:    exp.ModuleID + "-main", // Convert module ID to container name
 			Protocol:      exp.Protocol,
 			Hostname:      exp.Hostname,
+			Aliases:       exp.Aliases,
+			PathPrefix:    exp.PathPrefix,
+			PrefixRewrite: exp.PrefixRewrite,
 			ContainerPort: exp.ContainerPort,
 			HostPort:      exp.HostPort,
 		}
+		// Label selectors are resolved on every push, so the cluster follows replicas that
+		// were running at the time; a failed lookup keeps the named backends only
+		if len(exp.Backends) > 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), backendResolveTimeout)
+			backends, err := s.resolveBackends(ctx, exp.ModuleID, exp.Backends, false)
+			cancel()
+			if err != nil {
+				s.logger.Warn("failed to resolve exposure backends", "exposure_id", exp.ID, "error", err)
+				backends = namedBackends(exp.Backends)
+			}
+			if len(backends) == 0 {
+				// Keep a host in the cluster; Envoy reports it unhealthy until a replica runs
+				backends = []string{xdsExp.ModuleName}
+			}
+			xdsExp.Backends = backends
+		}
+		// Validated at create time; a bad value read back from disk just leaves no timeout
+		if timeout, err := parseRequestTimeout(exp.RequestTimeout); err == nil {
+			xdsExp.RequestTimeout = timeout
+		} else {
+			s.logger.Warn("ignoring invalid request timeout", "exposure_id", exp.ID, "error", err)
+		}
+		xdsExp.NumRetries = exp.NumRetries
+		xdsExp.RetryOn = exp.RetryOn
+		xdsExp.UpstreamDown = s.upstreamDownResponse(exp)
+		if exp.Auth != nil {
+			xdsExp.BasicAuthUsers = exp.Auth.Users
+		}
+		if exp.Affinity != nil {
+			if affinity, err := exp.Affinity.toXDS(); err == nil {
+				xdsExp.Affinity = affinity
+			} else {
+				s.logger.Warn("ignoring invalid session affinity", "exposure_id", exp.ID, "error", err)
+			}
+		}
+		if exp.Options != nil {
+			xdsExp.WebSocket = exp.Options.WebSocket
+			xdsExp.GRPC = exp.Options.GRPC
+		}
+
+		// Certificates are re-read on every push so rotated files are picked up. If one
+		// has become invalid, keep serving the exposure over plain HTTP only.
+		if exp.TLS != nil {
+			cert, err := loadTLSCertificate(exp.TLS)
+			if err != nil {
+				s.logger.Error("skipping TLS for exposure", "exposure_id", exp.ID, "error", err)
+			} else {
+				xdsExp.TLS = cert
+				xdsExp.RedirectHTTPS = exp.TLS.RedirectHTTPS
+			}
+		}
+
 		exposures = append(exposures, xdsExp)
 	}
+	return exposures
+}
 
-	snapshot, err := xds.BuildSnapshotFromExposures(s.xdsServer.NextVersion(), exposures)
-	if err != nil {
-		return err
+// confirmPush pushes pending changes without waiting for the debounce and blocks until Envoy
+// ACKs them. A rejected snapshot is returned as an error; a timeout is only logged, since
+// Envoy may simply be restarting and will pick the snapshot up when it reconnects. ctx
+// running out while waiting is returned as its error.
+func (s *ExposureStore) confirmPush(ctx context.Context) error {
+	if s.ackTimeout <= 0 {
+		return nil
 	}
 
-	return s.xdsServer.UpdateSnapshot(ctx, snapshot)
+	if err := s.snapshots.sync(ctx, false); err != nil {
+		return fmt.Errorf("failed to update xDS snapshot: %w", err)
+	}
+	version := s.xdsServer.Status().SnapshotVersion
+
+	waitCtx, cancel := context.WithTimeout(ctx, s.ackTimeout)
+	defer cancel()
+	err := s.xdsServer.WaitForAck(waitCtx, version)
+	if errors.Is(err, xds.ErrAckTimeout) {
+		select {
+		case <-ctx.Done():
+			// The caller's own deadline ran out, not just the wait for the ACK
+			return ctx.Err()
+		default:
+		}
+		s.logger.Warn("envoy did not acknowledge snapshot in time", "version", version, "timeout", s.ackTimeout)
+		return nil
+	}
+	return err
+}
+
+// ForceSync rebuilds and pushes the xDS snapshot immediately, even if the configuration
+// has not changed since the last push
+func (s *ExposureStore) ForceSync(ctx context.Context) error {
+	return s.snapshots.sync(ctx, true)
 }
 
 // save writes exposures to disk
@@ -494,7 +1187,7 @@ func (s *ExposureStore) save() error {
 	return os.Rename(tmpPath, s.storagePath)
 }
 
-// load reads exposures from disk
+// load replaces the exposures with those on disk. On error the current set is kept.
 func (s *ExposureStore) load() error {
 	data, err := os.ReadFile(s.storagePath)
 	if err != nil {
@@ -504,7 +1197,12 @@ func (s *ExposureStore) load() error {
 		return err
 	}
 
-	return json.Unmarshal(data, &s.exposures)
+	exposures := make(map[string]*Exposure)
+	if err := json.Unmarshal(data, &exposures); err != nil {
+		return err
+	}
+	s.exposures = exposures
+	return nil
 }
 
 // generateID creates a random exposure ID
@@ -525,6 +1223,12 @@ func (s *ExposureStore) getExposureInfos() []mdns.ExposureInfo {
 			Protocol: exp.Protocol,
 			Hostname: exp.Hostname,
 		})
+		for _, alias := range exp.Aliases {
+			infos = append(infos, mdns.ExposureInfo{
+				Protocol: exp.Protocol,
+				Hostname: alias,
+			})
+		}
 	}
 	return infos
 }
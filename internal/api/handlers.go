@@ -3,19 +3,25 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
 	internalPaths "zeropoint-agent/internal"
 	"zeropoint-agent/internal/modules"
 	"zeropoint-agent/internal/network"
+	"zeropoint-agent/internal/queue"
 	"zeropoint-agent/internal/system"
 	"zeropoint-agent/internal/terraform"
+	"zeropoint-agent/internal/xds"
 
 	"github.com/gorilla/mux"
 	"github.com/moby/moby/client"
@@ -33,24 +39,53 @@ type (
 
 // CreateExposureRequest represents the request body for creating an exposure
 type CreateExposureRequest struct {
-	ModuleID      string   `json:"module_id"`
-	Protocol      string   `json:"protocol"`
-	Hostname      string   `json:"hostname,omitempty"`
-	ContainerPort uint32   `json:"container_port"`
-	Tags          []string `json:"tags,omitempty"`
+	ModuleID       string            `json:"module_id"`
+	Protocol       string            `json:"protocol"`
+	Hostname       string            `json:"hostname,omitempty"`
+	Aliases        []string          `json:"aliases,omitempty"`        // Additional hostnames served by the same routes (http only)
+	PathPrefix     string            `json:"path_prefix,omitempty"`    // Route only this path on the hostname (http only)
+	PrefixRewrite  string            `json:"prefix_rewrite,omitempty"` // Replace path_prefix before forwarding, e.g. "/"
+	ContainerPort  uint32            `json:"container_port"`
+	HostPort       uint32            `json:"host_port,omitempty"` // Requested host port (tcp/udp only); allocated if omitted
+	Backends       []string          `json:"backends,omitempty"`  // Replica containers of the module ({module}-*) by name or "label:<key>=<value>" selector; defaults to the module's main container
+	Tags           []string          `json:"tags,omitempty"`
+	TLS            *ExposureTLS      `json:"tls,omitempty"`             // Terminate TLS on port 443 (http only)
+	Options        *HTTPOptions      `json:"options,omitempty"`         // Upstream protocol options such as websocket or grpc (http only)
+	RequestTimeout string            `json:"request_timeout,omitempty"` // Go duration, e.g. "30s"; no timeout if omitted (http only)
+	NumRetries     uint32            `json:"num_retries,omitempty"`     // Retries per request; requires retry_on (http only)
+	RetryOn        []string          `json:"retry_on,omitempty"`        // Envoy retry conditions, e.g. "5xx", "reset", "connect-failure" (http only)
+	Auth           *ExposureAuth     `json:"auth,omitempty"`            // Require basic auth; htpasswd bcrypt entries (http only)
+	Affinity       *ExposureAffinity `json:"affinity,omitempty"`        // Pin clients to one upstream by cookie or header; needs more than one backend to matter (http only)
+
+	// Expose a container_port the module does not declare in its {container}_ports outputs
+	AllowUndeclaredPort bool `json:"allow_undeclared_port,omitempty"`
 }
 
 // ExposureResponse represents the response for an exposure
 type ExposureResponse struct {
-	ID            string   `json:"id"`
-	ModuleID      string   `json:"module_id"`
-	Protocol      string   `json:"protocol"`
-	Hostname      string   `json:"hostname,omitempty"`
-	ContainerPort uint32   `json:"container_port"`
-	HostPort      uint32   `json:"host_port,omitempty"`
-	Status        string   `json:"status"` // "available" or "unavailable"
-	CreatedAt     string   `json:"created_at"`
-	Tags          []string `json:"tags,omitempty"`
+	ID             string            `json:"id"`
+	ModuleID       string            `json:"module_id"`
+	Protocol       string            `json:"protocol"`
+	Hostname       string            `json:"hostname,omitempty"`
+	Aliases        []string          `json:"aliases,omitempty"`
+	PathPrefix     string            `json:"path_prefix,omitempty"`
+	PrefixRewrite  string            `json:"prefix_rewrite,omitempty"`
+	ContainerPort  uint32            `json:"container_port"`
+	HostPort       uint32            `json:"host_port,omitempty"`
+	Backends       []string          `json:"backends,omitempty"`
+	Status         string            `json:"status"`                    // "available", "unavailable" or "degraded"
+	StatusReason   string            `json:"status_reason,omitempty"`   // Why the exposure is degraded
+	Upstream       string            `json:"upstream"`                  // Backend containers running: "up", "partial", "down", or "unknown" until Docker events are followed
+	UpstreamReason string            `json:"upstream_reason,omitempty"` // Which backend containers are not running
+	CreatedAt      string            `json:"created_at"`
+	Tags           []string          `json:"tags,omitempty"`
+	TLS            *ExposureTLS      `json:"tls,omitempty"`
+	Options        *HTTPOptions      `json:"options,omitempty"`
+	RequestTimeout string            `json:"request_timeout,omitempty"`
+	NumRetries     uint32            `json:"num_retries,omitempty"`
+	RetryOn        []string          `json:"retry_on,omitempty"`
+	AuthUsers      []string          `json:"auth_users,omitempty"` // Users allowed through basic auth; hashes are not returned
+	Affinity       *ExposureAffinity `json:"affinity,omitempty"`
 }
 
 // ListExposuresResponse represents the response for listing exposures
@@ -75,13 +110,15 @@ func NewExposureHandlers(store *ExposureStore, logger *slog.Logger) *ExposureHan
 // CreateExposureHTTP handles POST /exposures/{exposure_id}
 // @ID createExposure
 // @Summary Create an exposure for an application
-// @Description Exposes an application externally via Envoy reverse proxy
+// @Description Exposes an application externally via Envoy reverse proxy. Hostnames and aliases must be RFC 1123 names and are stored lowercased. HTTP exposures can share a hostname by using distinct path prefixes, and exposures with tls are also served on port 443 using SNI. The container_port must be one the module declares in its {container}_ports outputs (the main container's, unless backends are given) unless allow_undeclared_port is set or the agent's exposure_port_check is warn or off.
 // @Tags exposures
 // @Param exposure_id path string true "Exposure ID"
 // @Param body body CreateExposureRequest true "Exposure configuration"
 // @Success 201 {object} ExposureResponse
 // @Success 200 {object} ExposureResponse "Exposure already exists"
-// @Failure 400 {string} string "Bad request"
+// @Failure 400 {string} string "Bad request, invalid hostname or alias, or container_port not declared by the module"
+// @Failure 409 {object} HostnameConflictResponse "Hostname or alias already used by another exposure (also as name.local), or requested host port reserved or in use (plain text)"
+// @Failure 500 {string} string "Envoy rejected the resulting configuration"
 // @Router /exposures/{exposure_id} [post]
 func (h *ExposureHandlers) CreateExposureHTTP(w http.ResponseWriter, r *http.Request) {
 	// Get exposure_id from URL path
@@ -113,10 +150,33 @@ func (h *ExposureHandlers) CreateExposureHTTP(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	exposure, created, err := h.store.CreateExposure(r.Context(), exposureID, req.ModuleID, req.Protocol, req.Hostname, req.ContainerPort, req.Tags)
+	exposure, created, err := h.store.CreateExposure(r.Context(), exposureID, ExposureSpec{
+		ModuleID:       req.ModuleID,
+		Protocol:       req.Protocol,
+		Hostname:       req.Hostname,
+		Aliases:        req.Aliases,
+		PathPrefix:     req.PathPrefix,
+		PrefixRewrite:  req.PrefixRewrite,
+		ContainerPort:  req.ContainerPort,
+		HostPort:       req.HostPort,
+		Backends:       req.Backends,
+		Tags:           req.Tags,
+		TLS:            req.TLS,
+		Options:        req.Options,
+		RequestTimeout: req.RequestTimeout,
+		NumRetries:     req.NumRetries,
+		RetryOn:        req.RetryOn,
+		Auth:           req.Auth,
+		Affinity:       req.Affinity,
+
+		AllowUndeclaredPort: req.AllowUndeclaredPort,
+	})
 	if err != nil {
 		h.logger.Error("failed to create exposure", "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		if writeHostnameConflict(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), exposureErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 
@@ -134,19 +194,38 @@ func (h *ExposureHandlers) CreateExposureHTTP(w http.ResponseWriter, r *http.Req
 // ListExposures handles GET /exposures
 // @ID listExposures
 // @Summary List all exposures
-// @Description Returns all active exposures
+// @Description Returns all active exposures, optionally filtered by module, protocol or hostname. upstream reports whether the containers behind each exposure are running, as tracked from Docker container events.
 // @Tags exposures
+// @Param module_id query string false "Only exposures for this module"
+// @Param protocol query string false "Only exposures with this protocol (http, tcp, udp)"
+// @Param hostname query string false "Only exposures with this hostname"
 // @Success 200 {object} ListExposuresResponse
 // @Router /exposures [get]
 func (h *ExposureHandlers) ListExposures(w http.ResponseWriter, r *http.Request) {
-	exposures := h.store.ListExposures()
+	query := r.URL.Query()
+	moduleID := query.Get("module_id")
+	protocol := query.Get("protocol")
+	hostname := query.Get("hostname")
+
+	var exposures []*Exposure
+	if moduleID != "" {
+		exposures = h.store.ListExposuresByModuleID(moduleID)
+	} else {
+		exposures = h.store.ListExposures()
+	}
 
 	resp := ListExposuresResponse{
-		Exposures: make([]ExposureResponse, len(exposures)),
+		Exposures: make([]ExposureResponse, 0, len(exposures)),
 	}
 
-	for i, exp := range exposures {
-		resp.Exposures[i] = toExposureResponse(exp, h.store)
+	for _, exp := range exposures {
+		if protocol != "" && exp.Protocol != protocol {
+			continue
+		}
+		if hostname != "" && exp.Hostname != hostname {
+			continue
+		}
+		resp.Exposures = append(resp.Exposures, toExposureResponse(exp, h.store))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -179,8 +258,77 @@ func (h *ExposureHandlers) GetExposure(w http.ResponseWriter, r *http.Request) {
 }
 
 // CreateExposure creates an exposure (for job queue)
-func (h *ExposureHandlers) CreateExposure(ctx context.Context, exposureID, moduleID, protocol, hostname string, containerPort uint32, tags []string) error {
-	_, _, err := h.store.CreateExposure(ctx, exposureID, moduleID, protocol, hostname, containerPort, tags)
+func (h *ExposureHandlers) CreateExposure(ctx context.Context, exposureID, moduleID, protocol, hostname string, containerPort uint32, tags []string, opts queue.ExposureOptions) error {
+	var tlsConfig *ExposureTLS
+	if opts.TLSCertName != "" || opts.TLSCertFile != "" || opts.TLSKeyFile != "" {
+		tlsConfig = &ExposureTLS{
+			CertFile:      opts.TLSCertFile,
+			KeyFile:       opts.TLSKeyFile,
+			CertName:      opts.TLSCertName,
+			RedirectHTTPS: opts.RedirectHTTPS,
+		}
+	}
+
+	var httpOptions *HTTPOptions
+	if opts.WebSocket || opts.GRPC {
+		httpOptions = &HTTPOptions{WebSocket: opts.WebSocket, GRPC: opts.GRPC}
+	}
+
+	var auth *ExposureAuth
+	if len(opts.AuthUsers) > 0 {
+		auth = &ExposureAuth{Users: opts.AuthUsers}
+	}
+
+	var affinity *ExposureAffinity
+	if opts.AffinityCookie != "" || opts.AffinityHeader != "" {
+		affinity = &ExposureAffinity{
+			Cookie:    opts.AffinityCookie,
+			CookieTTL: opts.AffinityCookieTTL,
+			Header:    opts.AffinityHeader,
+		}
+	}
+
+	_, _, err := h.store.CreateExposure(ctx, exposureID, ExposureSpec{
+		ModuleID:       moduleID,
+		Protocol:       protocol,
+		Hostname:       hostname,
+		Aliases:        opts.Aliases,
+		PathPrefix:     opts.PathPrefix,
+		PrefixRewrite:  opts.PrefixRewrite,
+		ContainerPort:  containerPort,
+		HostPort:       opts.HostPort,
+		Backends:       opts.Backends,
+		Tags:           tags,
+		TLS:            tlsConfig,
+		Options:        httpOptions,
+		RequestTimeout: opts.RequestTimeout,
+		NumRetries:     opts.NumRetries,
+		RetryOn:        opts.RetryOn,
+		Auth:           auth,
+		Affinity:       affinity,
+
+		AllowUndeclaredPort: opts.AllowUndeclaredPort,
+	})
+	return err
+}
+
+// CreateExposures creates several exposures with a single routing update (for job queue)
+func (h *ExposureHandlers) CreateExposures(ctx context.Context, exposures []queue.BatchExposure) error {
+	specs := make(map[string]ExposureSpec, len(exposures))
+	for _, exposure := range exposures {
+		if _, dup := specs[exposure.ExposureID]; dup {
+			return fmt.Errorf("duplicate exposure %s", exposure.ExposureID)
+		}
+		specs[exposure.ExposureID] = ExposureSpec{
+			ModuleID:      exposure.ModuleID,
+			Protocol:      exposure.Protocol,
+			Hostname:      exposure.Hostname,
+			ContainerPort: exposure.ContainerPort,
+
+			AllowUndeclaredPort: exposure.AllowUndeclaredPort,
+		}
+	}
+	_, err := h.store.CreateExposuresBatch(ctx, specs)
 	return err
 }
 
@@ -189,6 +337,11 @@ func (h *ExposureHandlers) DeleteExposure(ctx context.Context, exposureID string
 	return h.store.DeleteExposure(ctx, exposureID)
 }
 
+// DeleteExposuresByModuleID removes all exposures for a module (for job queue)
+func (h *ExposureHandlers) DeleteExposuresByModuleID(ctx context.Context, moduleID string) (int, error) {
+	return h.store.DeleteExposuresByModuleID(ctx, moduleID)
+}
+
 // DeleteExposureHTTP handles DELETE /exposures/{exposure_id}
 // @ID deleteExposure
 // @Summary Delete an exposure
@@ -197,6 +350,7 @@ func (h *ExposureHandlers) DeleteExposure(ctx context.Context, exposureID string
 // @Param exposure_id path string true "Exposure ID"
 // @Success 204 "No content"
 // @Failure 404 {string} string "Exposure not found"
+// @Failure 500 {string} string "Envoy rejected the resulting configuration"
 // @Router /exposures/{exposure_id} [delete]
 func (h *ExposureHandlers) DeleteExposureHTTP(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -204,33 +358,68 @@ func (h *ExposureHandlers) DeleteExposureHTTP(w http.ResponseWriter, r *http.Req
 
 	if err := h.store.DeleteExposure(r.Context(), exposureID); err != nil {
 		h.logger.Error("failed to delete exposure", "error", err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), exposureErrorStatus(err, http.StatusNotFound))
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// exposureErrorStatus maps configuration Envoy rejected to a server error and a taken host
+// port to a conflict; anything else gets the handler's usual status
+func exposureErrorStatus(err error, fallback int) int {
+	var rejected *xds.RejectedError
+	if errors.As(err, &rejected) {
+		return http.StatusInternalServerError
+	}
+	if errors.Is(err, errHostPortInUse) {
+		return http.StatusConflict
+	}
+	return fallback
+}
+
 // toExposureResponse converts an Exposure to ExposureResponse
 func toExposureResponse(exp *Exposure, store *ExposureStore) ExposureResponse {
 	resp := ExposureResponse{
-		ID:            exp.ID,
-		ModuleID:      exp.ModuleID,
-		Protocol:      exp.Protocol,
-		ContainerPort: exp.ContainerPort,
-		Status:        store.getContainerStatus(exp.ModuleID),
-		CreatedAt:     exp.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		Tags:          exp.Tags,
+		ID:             exp.ID,
+		ModuleID:       exp.ModuleID,
+		Protocol:       exp.Protocol,
+		ContainerPort:  exp.ContainerPort,
+		Backends:       exp.Backends,
+		Status:         store.getContainerStatus(exp.ModuleID),
+		CreatedAt:      exp.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Tags:           exp.Tags,
+		PathPrefix:     exp.PathPrefix,
+		PrefixRewrite:  exp.PrefixRewrite,
+		TLS:            exp.TLS,
+		Options:        exp.Options,
+		RequestTimeout: exp.RequestTimeout,
+		NumRetries:     exp.NumRetries,
+		RetryOn:        exp.RetryOn,
+		Affinity:       exp.Affinity,
+	}
+
+	resp.Upstream, resp.UpstreamReason = store.upstreamHealth(exp)
+
+	// The container may be up while Envoy failed to bind the exposure's port
+	if reason := store.listenerProblem(exp.ID); reason != "" && resp.Status == "available" {
+		resp.Status = "degraded"
+		resp.StatusReason = reason
 	}
 
 	if exp.Hostname != "" {
 		resp.Hostname = exp.Hostname
+		resp.Aliases = exp.Aliases
 	}
 
 	if exp.HostPort != 0 {
 		resp.HostPort = exp.HostPort
 	}
 
+	if exp.Auth != nil {
+		resp.AuthUsers = exp.Auth.usernames()
+	}
+
 	return resp
 }
 
@@ -267,6 +456,7 @@ type LinkRequest struct {
 type CreateLinkRequest struct {
 	Modules map[string]map[string]interface{} `json:"modules"`
 	Tags    []string                          `json:"tags,omitempty"`
+	DryRun  bool                              `json:"dry_run,omitempty"` // Validate and report order without applying
 }
 
 // LinksResponse represents the response from listing links
@@ -282,10 +472,13 @@ type AppReference struct {
 
 // LinkResponse represents the response from linking modules
 type LinkResponse struct {
-	Success      bool              `json:"success"`
-	Message      string            `json:"message,omitempty"`
-	AppliedOrder []string          `json:"applied_order,omitempty"`
-	Errors       map[string]string `json:"errors,omitempty"`
+	Success      bool                         `json:"success"`
+	DryRun       bool                         `json:"dry_run,omitempty"` // True if nothing was applied
+	Message      string                       `json:"message,omitempty"`
+	AppliedOrder []string                     `json:"applied_order,omitempty"`
+	References   map[string]map[string]string `json:"references,omitempty"` // Dry run only: module -> input -> "module.output"
+	Errors       map[string]string            `json:"errors,omitempty"`
+	Problems     []ReferenceProblem           `json:"problems,omitempty"` // Invalid bindings found before anything was applied
 }
 
 // ModulesResponse encapsulates a list of modules
@@ -298,7 +491,9 @@ func (h *LinkHandlers) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/links", h.ListLinks).Methods("GET")
 	router.HandleFunc("/links/{id}", h.GetLink).Methods("GET")
 	router.HandleFunc("/links/{id}", h.CreateOrUpdateLink).Methods("POST")
+	router.HandleFunc("/links/{id}", h.PatchLink).Methods("PATCH")
 	router.HandleFunc("/links/{id}", h.DeleteLinkHTTP).Methods("DELETE")
+	router.HandleFunc("/links/{id}/refresh", h.RefreshLink).Methods("POST")
 }
 
 // ListLinks handles GET /links
@@ -321,11 +516,11 @@ func (h *LinkHandlers) ListLinks(w http.ResponseWriter, r *http.Request) {
 // GetLink handles GET /links/{id}
 // @ID getLink
 // @Summary Get link details
-// @Description Returns details for a specific link
+// @Description Returns details for a specific link, including whether each reference's producer output changed since it was applied
 // @Tags links
 // @Param id path string true "Link ID"
 // @Produce json
-// @Success 200 {object} Link
+// @Success 200 {object} LinkDetailResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /links/{id} [get]
 func (h *LinkHandlers) GetLink(w http.ResponseWriter, r *http.Request) {
@@ -338,21 +533,27 @@ func (h *LinkHandlers) GetLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	response := LinkDetailResponse{
+		Link:            link,
+		ReferenceStatus: h.referenceStatus(link),
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(link)
+	json.NewEncoder(w).Encode(response)
 }
 
 // CreateOrUpdateLink handles POST /links/{id}
 // @ID createOrUpdateLink
 // @Summary Create or update a link
-// @Description Create or update a link between multiple modules
+// @Description Create or update a link between multiple modules. Inputs of modules that ship a zeropoint.inputs.json schema are checked against it first: undeclared inputs and values of the wrong type are rejected. With dry_run, validates modules, references and inputs and returns the apply order without running terraform.
 // @Tags links
 // @Param id path string true "Link ID"
 // @Accept json
 // @Produce json
+// @Param dry_run query bool false "Report order and references without applying"
 // @Param request body CreateLinkRequest true "Link configuration"
 // @Success 200 {object} LinkResponse
-// @Failure 400 {object} ErrorResponse
+// @Failure 400 {object} LinkResponse "Invalid references, or inputs the module's input schema rejects, listed in problems"
 // @Failure 500 {object} ErrorResponse
 // @Router /links/{id} [post]
 func (h *LinkHandlers) CreateOrUpdateLink(w http.ResponseWriter, r *http.Request) {
@@ -366,29 +567,109 @@ func (h *LinkHandlers) CreateOrUpdateLink(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if r.URL.Query().Get("dry_run") == "true" {
+		req.DryRun = true
+	}
+
+	if req.DryRun {
+		h.logger.Info("Dry run for link", "link_id", linkID, "modules", getAppNames(req.Modules))
+		response := h.dryRunLink(req.Modules)
+
+		w.Header().Set("Content-Type", "application/json")
+		if response.Success {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	h.logger.Info("Creating/updating link", "link_id", linkID, "modules", getAppNames(req.Modules))
 
 	// Use the existing linking logic
 	response := h.linkApps(linkID, req.Modules, req.Tags)
 
 	w.Header().Set("Content-Type", "application/json")
-	if response.Success {
-		w.WriteHeader(http.StatusOK)
-	} else {
-		w.WriteHeader(http.StatusInternalServerError)
-	}
+	w.WriteHeader(linkResponseStatus(response))
 	json.NewEncoder(w).Encode(response)
 }
 
+// dryRunLink runs the validation and ordering steps of linkApps and checks that every
+// reference resolves, without backing up state or applying terraform
+func (h *LinkHandlers) dryRunLink(modules map[string]map[string]interface{}) LinkResponse {
+	if problems := h.linkProblems(modules); len(problems) > 0 {
+		return LinkResponse{
+			Success:  false,
+			DryRun:   true,
+			Message:  "Dry run: link has invalid references or inputs",
+			Problems: problems,
+		}
+	}
+
+	graph, err := AnalyzeDependencies(modules)
+	if err != nil {
+		return LinkResponse{
+			Success: false,
+			DryRun:  true,
+			Message: fmt.Sprintf("Dependency analysis failed: %v", err),
+		}
+	}
+
+	order, err := graph.TopologicalSort()
+	if err != nil {
+		return LinkResponse{
+			Success: false,
+			DryRun:  true,
+			Message: fmt.Sprintf("Dependency resolution failed: %v", err),
+		}
+	}
+
+	// Only modules in this request are applied; referenced-only modules just provide outputs
+	var applyOrder []string
+	errors := make(map[string]string)
+	for _, moduleName := range order {
+		config, exists := modules[moduleName]
+		if !exists {
+			continue
+		}
+		applyOrder = append(applyOrder, moduleName)
+
+		if _, err := h.resolveAppReferences(config); err != nil {
+			errors[moduleName] = err.Error()
+		}
+	}
+
+	references, _, _ := collectLinkReferences(modules)
+
+	response := LinkResponse{
+		Success:      len(errors) == 0,
+		DryRun:       true,
+		Message:      "Dry run: no changes applied",
+		AppliedOrder: applyOrder,
+		References:   references,
+	}
+	if len(errors) > 0 {
+		response.Message = "Dry run: some references could not be resolved"
+		response.Errors = errors
+	}
+	return response
+}
+
 // CreateLink creates a link between multiple modules (for job queue)
 func (h *LinkHandlers) CreateLink(ctx context.Context, linkID string, modules map[string]map[string]interface{}, tags []string) error {
-	response := h.linkApps(linkID, modules, tags)
+	response := h.applyLink(ctx, linkID, modules, nil, tags)
 	if !response.Success {
-		return fmt.Errorf(response.Message)
+		return linkResponseError(response)
 	}
 	return nil
 }
 
+// LinksReferencingModule returns the links in which other modules consume a module's outputs (for job queue)
+func (h *LinkHandlers) LinksReferencingModule(moduleID string) []string {
+	return h.linkStore.LinksReferencingModule(moduleID)
+}
+
 // DeleteLink removes a link and cleans up associated resources
 func (h *LinkHandlers) DeleteLink(ctx context.Context, id string) error {
 	return h.linkStore.DeleteLink(ctx, id)
@@ -423,13 +704,21 @@ func (h *LinkHandlers) DeleteLinkHTTP(w http.ResponseWriter, r *http.Request) {
 
 // linkApps contains the core linking logic (refactored from LinkApps)
 func (h *LinkHandlers) linkApps(linkID string, modules map[string]map[string]interface{}, tags []string) LinkResponse {
+	return h.applyLink(context.Background(), linkID, modules, nil, tags)
+}
+
+// applyLink validates and orders the link's modules, applies the configuration of those in
+// only (every module when only is nil) and stores the link with all of its modules.
+// Cancelling ctx kills a running terraform apply; the state backup is then restored.
+func (h *LinkHandlers) applyLink(ctx context.Context, linkID string, modules map[string]map[string]interface{}, only map[string]bool, tags []string) LinkResponse {
 
-	// Step 1: Validate all modules exist
-	if err := h.validateAppsExist(modules); err != nil {
-		h.logger.Error("Module validation failed", "error", err)
+	// Step 1: Validate modules, references and inputs before touching any state
+	if problems := h.linkProblems(modules); len(problems) > 0 {
+		h.logger.Error("Link validation failed", "link_id", linkID, "problems", len(problems))
 		return LinkResponse{
-			Success: false,
-			Message: err.Error(),
+			Success:  false,
+			Message:  "Link has invalid references or inputs",
+			Problems: problems,
 		}
 	}
 
@@ -468,16 +757,21 @@ func (h *LinkHandlers) linkApps(linkID string, modules map[string]map[string]int
 	// Step 4: Apply configurations in dependency order
 	errors := make(map[string]string)
 	appliedModules := []string{}
+	resolvedValues := make(map[string]map[string]interface{})
 
 	for _, moduleName := range order {
 		config, exists := modules[moduleName]
 		if !exists {
 			continue // Module not in this link request
 		}
+		if only != nil && !only[moduleName] {
+			continue // Unchanged by this update
+		}
 
 		h.logger.Info("Applying configuration", "module", moduleName, "config", config)
 
-		if err := h.applyModuleConfiguration(moduleName, config); err != nil {
+		resolved, err := h.applyModuleConfiguration(ctx, moduleName, config)
+		if err != nil {
 			errors[moduleName] = err.Error()
 			h.logger.Error("Failed to apply configuration", "module", moduleName, "error", err)
 
@@ -497,6 +791,7 @@ func (h *LinkHandlers) linkApps(linkID string, modules map[string]map[string]int
 		}
 
 		appliedModules = append(appliedModules, moduleName)
+		resolvedValues[moduleName] = resolved
 
 		// Create shared networks for any modules this module references
 		if err := h.createSharedNetworksForReferences(moduleName, config); err != nil {
@@ -511,11 +806,38 @@ func (h *LinkHandlers) linkApps(linkID string, modules map[string]map[string]int
 	}
 
 	// Step 5: Collect references and networks, then store the successful link
+	references, sharedNetworks, moduleNetworks := collectLinkReferences(modules)
+
+	if _, err := h.linkStore.CreateOrUpdateLink(context.Background(), linkID, modules, references, sharedNetworks, moduleNetworks, order, tags); err != nil {
+		h.logger.Warn("Failed to store link", "error", err)
+		// Don't fail the operation for storage failures
+	} else if err := h.linkStore.RecordResolvedValues(linkID, resolvedValues); err != nil {
+		h.logger.Warn("Failed to store resolved reference values", "link_id", linkID, "error", err)
+	}
+
+	return LinkResponse{
+		Success:      true,
+		Message:      "All modules linked successfully",
+		AppliedOrder: appliedModules,
+	}
+}
+
+// collectLinkReferences parses references from module configurations and returns them
+// as module -> input -> "module.output", along with the shared network names they require
+// and, per module, the shared networks its container joins
+func collectLinkReferences(modules map[string]map[string]interface{}) (map[string]map[string]string, []string, map[string][]string) {
 	references := make(map[string]map[string]string)
 	var sharedNetworks []string
 
-	// Parse references from module configurations and collect network names
 	networkNames := make(map[string]bool)
+	memberships := make(map[string]map[string]bool)
+	join := func(moduleName, networkName string) {
+		if memberships[moduleName] == nil {
+			memberships[moduleName] = make(map[string]bool)
+		}
+		memberships[moduleName][networkName] = true
+	}
+
 	for moduleName, config := range modules {
 		appRefs := make(map[string]string)
 		for inputName, value := range config {
@@ -529,6 +851,10 @@ func (h *LinkHandlers) linkApps(linkID string, modules map[string]map[string]int
 				}
 				networkName := fmt.Sprintf("zeropoint-link-%s-%s", linkModules[0], linkModules[1])
 				networkNames[networkName] = true
+
+				// Both ends of the reference are connected to the network
+				join(moduleName, networkName)
+				join(ref.FromModule, networkName)
 			}
 		}
 		if len(appRefs) > 0 {
@@ -540,17 +866,17 @@ func (h *LinkHandlers) linkApps(linkID string, modules map[string]map[string]int
 	for networkName := range networkNames {
 		sharedNetworks = append(sharedNetworks, networkName)
 	}
+	sort.Strings(sharedNetworks)
 
-	if _, err := h.linkStore.CreateOrUpdateLink(context.Background(), linkID, modules, references, sharedNetworks, order, tags); err != nil {
-		h.logger.Warn("Failed to store link", "error", err)
-		// Don't fail the operation for storage failures
+	moduleNetworks := make(map[string][]string)
+	for moduleName, networks := range memberships {
+		for networkName := range networks {
+			moduleNetworks[moduleName] = append(moduleNetworks[moduleName], networkName)
+		}
+		sort.Strings(moduleNetworks[moduleName])
 	}
 
-	return LinkResponse{
-		Success:      true,
-		Message:      "All modules linked successfully",
-		AppliedOrder: appliedModules,
-	}
+	return references, sharedNetworks, moduleNetworks
 }
 
 // Helper function to extract app names from request
@@ -562,44 +888,21 @@ func getAppNames(apps map[string]map[string]interface{}) []string {
 	return names
 }
 
-// validateAppsExist checks that all referenced apps exist on disk
-func (h *LinkHandlers) validateAppsExist(apps map[string]map[string]interface{}) error {
-	for appName := range apps {
-		appDir := filepath.Join(h.appsDir, appName)
-		if _, err := os.Stat(appDir); os.IsNotExist(err) {
-			return fmt.Errorf("app %s does not exist", appName)
-		}
-	}
-
-	// Also validate referenced modules in module references
-	for moduleName, config := range apps {
-		for inputName, value := range config {
-			if ref, isRef := parseAppReference(value); isRef {
-				refModuleDir := filepath.Join(h.appsDir, ref.FromModule)
-				if _, err := os.Stat(refModuleDir); os.IsNotExist(err) {
-					return fmt.Errorf("module %s references non-existent module %s in input %s", moduleName, ref.FromModule, inputName)
-				}
-			}
-		}
-	}
-
-	return nil
-}
-
-// applyModuleConfiguration applies configuration to a single module
-func (h *LinkHandlers) applyModuleConfiguration(moduleName string, config map[string]interface{}) error {
+// applyModuleConfiguration applies configuration to a single module and returns the values
+// its references resolved to
+func (h *LinkHandlers) applyModuleConfiguration(ctx context.Context, moduleName string, config map[string]interface{}) (map[string]interface{}, error) {
 	h.logger.Info("Applying configuration to module", "module", moduleName)
 
 	// Resolve app references to actual values
 	resolvedConfig, err := h.resolveAppReferences(config)
 	if err != nil {
-		return fmt.Errorf("failed to resolve references: %w", err)
+		return nil, fmt.Errorf("failed to resolve references: %w", err)
 	}
 
 	// Inject system variables (same as installer does)
 	variables, err := h.prepareSystemVariables(moduleName)
 	if err != nil {
-		return fmt.Errorf("failed to prepare system variables: %w", err)
+		return nil, fmt.Errorf("failed to prepare system variables: %w", err)
 	}
 
 	// Add user-provided variables (resolved)
@@ -629,17 +932,34 @@ func (h *LinkHandlers) applyModuleConfiguration(moduleName string, config map[st
 
 	// Apply configuration using Terraform
 	appDir := filepath.Join(h.appsDir, moduleName)
+	unlock, err := terraform.LockModule(ctx, appDir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := modules.CheckIntegrity(ctx, appDir); err != nil {
+		return nil, err
+	}
+
 	executor, err := terraform.NewExecutor(appDir)
 	if err != nil {
-		return fmt.Errorf("failed to create terraform executor: %w", err)
+		return nil, fmt.Errorf("failed to create terraform executor: %w", err)
 	}
 
-	if err := executor.Apply(variables); err != nil {
-		return fmt.Errorf("terraform apply failed: %w", err)
+	if err := executor.WithContext(ctx).Apply(variables); err != nil {
+		return nil, fmt.Errorf("terraform apply failed: %w", err)
+	}
+
+	referenceValues := make(map[string]interface{})
+	for key, value := range config {
+		if _, isRef := parseAppReference(value); isRef {
+			referenceValues[key] = resolvedConfig[key]
+		}
 	}
 
 	h.logger.Info("Configuration applied successfully", "module", moduleName)
-	return nil
+	return referenceValues, nil
 }
 
 // resolveAppReferences resolves module references to actual output values
@@ -702,12 +1022,9 @@ func (h *LinkHandlers) prepareSystemVariables(moduleName string) (map[string]str
 		"zp_gpu_vendor":   gpuVendor,
 	}
 
-	// Create app storage directory if needed
-	storageRoot := os.Getenv("MODULE_STORAGE_ROOT")
-	if storageRoot == "" {
-		storageRoot = "./data" // default fallback
-	}
-	appStoragePath := filepath.Join(storageRoot, "modules", moduleName)
+	// Use the same storage directory as the installer and uninstaller; re-applying with a
+	// different path would remount the module's volumes somewhere empty
+	appStoragePath := filepath.Join(internalPaths.GetDataDir(), moduleName)
 	if err := os.MkdirAll(appStoragePath, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create app storage directory: %w", err)
 	}
@@ -721,6 +1038,23 @@ func (h *LinkHandlers) prepareSystemVariables(moduleName string) (map[string]str
 	// Pass app storage root to terraform (must be absolute for Docker)
 	variables["zp_module_storage"] = absAppStoragePath
 
+	// Keep the limits the module was installed with; dropping them here would lift them
+	appDir := filepath.Join(h.appsDir, moduleName)
+	resources, err := modules.LoadResources(appDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load resource limits: %w", err)
+	}
+	limits, warnings, err := modules.ResourceVariables(appDir, resources)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		h.logger.Warn("resource limit not enforced", "module", moduleName, "warning", warning)
+	}
+	for name, value := range limits {
+		variables[name] = value
+	}
+
 	h.logger.Info("Prepared system variables", "module", moduleName, "variables", variables)
 	return variables, nil
 }
@@ -813,19 +1147,41 @@ type ModuleHandlers struct {
 	installer   *Installer
 	uninstaller *Uninstaller
 	docker      *client.Client
+	linkStore   *LinkStore
 	logger      *slog.Logger
+
+	linkHandlers *LinkHandlers // Detaches uninstalled modules from their links
+
+	// Cache of terraform-derived module details, invalidated on install/uninstall
+	cacheMu sync.RWMutex
+	cache   map[string]*moduleCacheEntry
+
+	logStreams chan struct{} // Slots for concurrent follow=true log streams
+
+	actionsMu   sync.Mutex
+	lastActions map[string]*modules.LifecycleAction // Most recent restart/stop/start per module
 }
 
 // NewModuleHandlers creates a new module handlers instance
-func NewModuleHandlers(installer *Installer, uninstaller *Uninstaller, docker *client.Client, logger *slog.Logger) *ModuleHandlers {
+func NewModuleHandlers(installer *Installer, uninstaller *Uninstaller, docker *client.Client, linkStore *LinkStore, logger *slog.Logger) *ModuleHandlers {
 	return &ModuleHandlers{
 		installer:   installer,
 		uninstaller: uninstaller,
 		docker:      docker,
+		linkStore:   linkStore,
 		logger:      logger,
+		cache:       make(map[string]*moduleCacheEntry),
+		logStreams:  make(chan struct{}, maxModuleLogStreams),
+		lastActions: make(map[string]*modules.LifecycleAction),
 	}
 }
 
+// SetLinkHandlers sets what removes an uninstalled module from its links. Without it,
+// DELETE /modules/{name} leaves links untouched.
+func (h *ModuleHandlers) SetLinkHandlers(linkHandlers *LinkHandlers) {
+	h.linkHandlers = linkHandlers
+}
+
 // InstallModule handles POST /modules/{name} with streaming progress updates
 // @ID installModule
 // @Summary Install a module
@@ -898,6 +1254,7 @@ func (h *ModuleHandlers) InstallModule(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Run installation with progress streaming
+	defer h.InvalidateModule(req.ModuleID)
 	if err := h.installer.Install(req, progressCallback); err != nil {
 		h.logger.Error("installation failed", "module_id", req.ModuleID, "error", err)
 		json.NewEncoder(w).Encode(ProgressUpdate{
@@ -913,12 +1270,15 @@ func (h *ModuleHandlers) InstallModule(w http.ResponseWriter, r *http.Request) {
 // UninstallModule handles DELETE /modules/{name} with streaming progress updates
 // @ID uninstallModule
 // @Summary Uninstall a module
-// @Description Uninstalls a module by name with streaming progress updates
+// @Description Uninstalls a module by name with streaming progress updates. Refused while other modules consume its outputs through a link, unless force is set. Once uninstalled, the module is removed from its links; links left with a single module are deleted along with their shared networks. The module's storage directory is kept for a reinstall unless purge_data is set.
 // @Tags modules
 // @Produce application/x-ndjson,text/event-stream
 // @Param name path string true "Module name"
+// @Param force query bool false "Uninstall even if links still reference the module, tearing those links down"
+// @Param purge_data query bool false "Also delete the module's storage directory"
 // @Success 200 {string} string "Uninstallation progress stream"
 // @Failure 400 {string} string "Bad request"
+// @Failure 409 {string} string "Module is referenced by links"
 // @Failure 500 {string} string "Internal server error"
 // @Router /modules/{name} [delete]
 func (h *ModuleHandlers) UninstallModule(w http.ResponseWriter, r *http.Request) {
@@ -935,8 +1295,16 @@ func (h *ModuleHandlers) UninstallModule(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if r.URL.Query().Get("force") != "true" {
+		if linkIDs := h.linkStore.LinksReferencingModule(moduleName); len(linkIDs) > 0 {
+			http.Error(w, fmt.Sprintf("module %s is referenced by links %s; uninstall with force=true to proceed", moduleName, strings.Join(linkIDs, ", ")), http.StatusConflict)
+			return
+		}
+	}
+
 	req := UninstallRequest{
-		ModuleID: moduleName,
+		ModuleID:  moduleName,
+		PurgeData: r.URL.Query().Get("purge_data") == "true",
 	}
 
 	// Setup streaming response
@@ -950,13 +1318,20 @@ func (h *ModuleHandlers) UninstallModule(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Stream progress updates
+	// Stream progress updates. "complete" is held back until the module is detached from
+	// its links, since clients may stop reading as soon as they see it.
+	var complete *ProgressUpdate
 	progressCallback := func(update ProgressUpdate) {
+		if update.Status == "complete" {
+			complete = &update
+			return
+		}
 		json.NewEncoder(w).Encode(update)
 		flusher.Flush()
 	}
 
 	// Run uninstallation with progress streaming
+	defer h.InvalidateModule(req.ModuleID)
 	if err := h.uninstaller.Uninstall(req, progressCallback); err != nil {
 		h.logger.Error("uninstallation failed", "module_id", req.ModuleID, "error", err)
 		json.NewEncoder(w).Encode(ProgressUpdate{
@@ -967,19 +1342,45 @@ func (h *ModuleHandlers) UninstallModule(w http.ResponseWriter, r *http.Request)
 		flusher.Flush()
 		return
 	}
+
+	// As with uninstall_module jobs, the links the module was part of must not keep
+	// pointing at it. A client going away must not cancel terraform halfway through the
+	// links.
+	if h.linkHandlers != nil {
+		detached, err := h.linkHandlers.DetachModule(context.WithoutCancel(r.Context()), req.ModuleID)
+		update := ProgressUpdate{
+			Status: "links",
+			Message: fmt.Sprintf("Removed module from %d links, deleted %d links and %d shared networks",
+				len(detached.UpdatedLinks)+len(detached.DeletedLinks), len(detached.DeletedLinks), len(detached.RemovedNetworks)),
+		}
+		if err != nil {
+			h.logger.Warn("failed to detach uninstalled module from links", "module_id", req.ModuleID, "error", err)
+			update.Error = err.Error()
+		}
+		json.NewEncoder(w).Encode(update)
+		flusher.Flush()
+	}
+
+	if complete != nil {
+		json.NewEncoder(w).Encode(complete)
+		flusher.Flush()
+	}
 }
 
 // ListModules handles GET /modules
 // @ID listModules
 // @Summary List installed modules
-// @Description Returns installed modules metadata
+// @Description Returns installed modules with source, installed commit, container status and terraform output names
 // @Tags modules
 // @Produce json
+// @Param refresh query bool false "Bypass the module cache and re-read terraform outputs"
 // @Success 200 {object} ModulesResponse
 // @Router /modules [get]
 func (h *ModuleHandlers) ListModules(w http.ResponseWriter, r *http.Request) {
+	refresh := r.URL.Query().Get("refresh") == "true"
+
 	// Discover modules from filesystem
-	list, err := h.discoverModules(r.Context())
+	list, err := h.discoverModules(r.Context(), refresh)
 	if err != nil {
 		http.Error(w, "failed to discover modules", http.StatusInternalServerError)
 		return
@@ -989,8 +1390,38 @@ func (h *ModuleHandlers) ListModules(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// GetModule handles GET /modules/{name}
+// @ID getModule
+// @Summary Get an installed module
+// @Description Returns a single installed module with source, installed commit, container status and terraform output names
+// @Tags modules
+// @Produce json
+// @Param name path string true "Module ID"
+// @Param refresh query bool false "Bypass the module cache and re-read terraform outputs"
+// @Success 200 {object} Module
+// @Failure 404 {string} string "Module not found"
+// @Router /modules/{name} [get]
+func (h *ModuleHandlers) GetModule(w http.ResponseWriter, r *http.Request) {
+	moduleID := mux.Vars(r)["name"]
+	refresh := r.URL.Query().Get("refresh") == "true"
+
+	module, err := h.loadModule(r.Context(), moduleID, refresh)
+	if err != nil {
+		h.logger.Error("failed to load module", "module_id", moduleID, "error", err)
+		http.Error(w, "failed to load module", http.StatusInternalServerError)
+		return
+	}
+	if module == nil {
+		http.Error(w, fmt.Sprintf("module '%s' not found", moduleID), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(module)
+}
+
 // discoverModules scans the modules/ directory for installed modules
-func (h *ModuleHandlers) discoverModules(ctx context.Context) ([]Module, error) {
+func (h *ModuleHandlers) discoverModules(ctx context.Context, refresh bool) ([]Module, error) {
 	modulesDir := internalPaths.GetModulesDir()
 	var result []Module
 
@@ -1002,47 +1433,33 @@ func (h *ModuleHandlers) discoverModules(ctx context.Context) ([]Module, error)
 		return nil, err
 	}
 
+	seen := make(map[string]bool)
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
 		}
 
-		moduleID := entry.Name()
-		modulePath := filepath.Join(modulesDir, moduleID)
-
-		// Check if main.tf exists
-		mainTfPath := filepath.Join(modulePath, "main.tf")
-		if _, err := os.Stat(mainTfPath); err != nil {
-			continue // Not a valid module
-		}
-
-		module := Module{
-			ID:         moduleID,
-			ModulePath: modulePath,
-			State:      modules.StateUnknown,
+		module, err := h.loadModule(ctx, entry.Name(), refresh)
+		if err != nil {
+			h.logger.Warn("failed to load module", "module_id", entry.Name(), "error", err)
+			continue
 		}
-
-		// Load metadata (including tags) from .zeropoint.json
-		if metadata, err := modules.LoadMetadata(modulePath); err != nil {
-			h.logger.Warn("failed to load metadata", "module_id", moduleID, "error", err)
-		} else if metadata != nil {
-			module.Tags = metadata.Tags
+		if module == nil {
+			continue // Not a valid module
 		}
 
-		// Query Docker for runtime status
-		if err := module.GetContainerStatus(ctx, h.docker); err != nil {
-			h.logger.Warn("failed to get container status", "module_id", moduleID, "error", err)
-		}
+		seen[module.ID] = true
+		result = append(result, *module)
+	}
 
-		// Load containers with ports and mounts from Terraform outputs
-		if containers, err := modules.LoadContainers(modulePath, moduleID); err != nil {
-			h.logger.Warn("failed to load containers", "module_id", moduleID, "error", err)
-		} else {
-			module.Containers = containers
+	// Drop cache entries for modules that no longer exist on disk
+	h.cacheMu.Lock()
+	for moduleID := range h.cache {
+		if !seen[moduleID] {
+			delete(h.cache, moduleID)
 		}
-
-		result = append(result, module)
 	}
+	h.cacheMu.Unlock()
 
 	return result, nil
 }
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"zeropoint-agent/internal/queue"
+)
+
+// DetachModule removes an uninstalled module from its links (for job queue). Shared networks
+// it joined are useless without it: the remaining members are disconnected and the networks
+// removed. Failures are collected so one stuck network does not stop the rest.
+func (h *LinkHandlers) DetachModule(ctx context.Context, moduleID string) (queue.LinkDetachResult, error) {
+	var result queue.LinkDetachResult
+
+	updated, deleted, networkPeers, err := h.linkStore.RemoveModule(moduleID)
+	if err != nil {
+		return result, err
+	}
+	result.UpdatedLinks = updated
+	result.DeletedLinks = deleted
+
+	networkNames := make([]string, 0, len(networkPeers))
+	for networkName := range networkPeers {
+		networkNames = append(networkNames, networkName)
+	}
+	sort.Strings(networkNames)
+
+	var errs []error
+	for _, networkName := range networkNames {
+		for _, moduleName := range networkPeers[networkName] {
+			if err := h.networkManager.DisconnectContainerFromNetwork(ctx, moduleName+"-main", networkName); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		removed, err := h.networkManager.RemoveNetwork(ctx, networkName)
+		if err != nil {
+			h.logger.Warn("Failed to remove link network", "network", networkName, "error", err)
+			errs = append(errs, err)
+			continue
+		}
+		if removed {
+			result.RemovedNetworks = append(result.RemovedNetworks, networkName)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
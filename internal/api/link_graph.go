@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// LinkGraphEdge is a single reference between two linked modules
+type LinkGraphEdge struct {
+	FromModule string `json:"from_module"` // Module providing the output
+	Output     string `json:"output"`      // Output name on from_module
+	ToModule   string `json:"to_module"`   // Module consuming the output
+	Input      string `json:"input"`       // Input variable on to_module
+}
+
+// LinkGraphResponse is returned by GET /links/{id}/graph
+type LinkGraphResponse struct {
+	LinkID       string          `json:"link_id"`
+	Nodes        []string        `json:"nodes"`
+	Edges        []LinkGraphEdge `json:"edges"`
+	AppliedOrder []string        `json:"applied_order"`
+}
+
+// graphvizContentType is the media type for DOT output
+const graphvizContentType = "text/vnd.graphviz"
+
+// GetLinkGraph handles GET /links/{id}/graph
+// @ID getLinkGraph
+// @Summary Get link dependency graph
+// @Description Returns the modules and references of a link as a dependency graph, plus the order modules are applied in. Send Accept: text/vnd.graphviz for DOT output.
+// @Tags links
+// @Param id path string true "Link ID"
+// @Produce json
+// @Produce text/vnd.graphviz
+// @Success 200 {object} LinkGraphResponse
+// @Failure 404 {string} string "Link not found"
+// @Failure 500 {string} string "Dependency analysis failed"
+// @Router /links/{id}/graph [get]
+func (h *LinkHandlers) GetLinkGraph(w http.ResponseWriter, r *http.Request) {
+	linkID := mux.Vars(r)["id"]
+
+	link, err := h.linkStore.GetLink(linkID)
+	if err != nil {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+
+	graph, err := AnalyzeDependencies(link.Modules)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("dependency analysis failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	order, err := graph.TopologicalSort()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("dependency resolution failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := LinkGraphResponse{
+		LinkID:       linkID,
+		Nodes:        make([]string, 0, len(graph.nodes)),
+		Edges:        linkGraphEdges(link.Modules),
+		AppliedOrder: order,
+	}
+	for node := range graph.nodes {
+		resp.Nodes = append(resp.Nodes, node)
+	}
+	sort.Strings(resp.Nodes)
+
+	if strings.Contains(r.Header.Get("Accept"), graphvizContentType) {
+		w.Header().Set("Content-Type", graphvizContentType)
+		fmt.Fprint(w, resp.DOT())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// linkGraphEdges lists every reference in a link's module configurations, sorted for stable output
+func linkGraphEdges(modules map[string]map[string]interface{}) []LinkGraphEdge {
+	edges := []LinkGraphEdge{}
+	for moduleName, config := range modules {
+		for inputName, value := range config {
+			if ref, isRef := parseAppReference(value); isRef {
+				edges = append(edges, LinkGraphEdge{
+					FromModule: ref.FromModule,
+					Output:     ref.Output,
+					ToModule:   moduleName,
+					Input:      inputName,
+				})
+			}
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].FromModule != edges[j].FromModule {
+			return edges[i].FromModule < edges[j].FromModule
+		}
+		if edges[i].ToModule != edges[j].ToModule {
+			return edges[i].ToModule < edges[j].ToModule
+		}
+		return edges[i].Input < edges[j].Input
+	})
+
+	return edges
+}
+
+// DOT renders the graph in Graphviz format, with edges pointing from provider to consumer
+func (g LinkGraphResponse) DOT() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "digraph %q {\n", g.LinkID)
+	b.WriteString("  rankdir=LR;\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  %q;\n", node)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", edge.FromModule, edge.ToModule, edge.Output+" -> "+edge.Input)
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
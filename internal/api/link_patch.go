@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// PatchLinkRequest changes part of a link without re-applying every module
+type PatchLinkRequest struct {
+	Modules map[string]map[string]interface{} `json:"modules,omitempty"` // Add modules, or merge bindings into existing ones; a null binding removes it
+	Remove  []string                          `json:"remove,omitempty"`  // Modules to detach from the link
+}
+
+// PatchLink handles PATCH /links/{id}
+// @ID patchLink
+// @Summary Partially update a link
+// @Description Adds modules, updates individual bindings or detaches modules. Only changed modules and modules that depend on them are re-applied. Detached modules are disconnected from shared networks the link no longer needs.
+// @Tags links
+// @Param id path string true "Link ID"
+// @Accept json
+// @Produce json
+// @Param request body PatchLinkRequest true "Link changes"
+// @Success 200 {object} LinkResponse
+// @Failure 400 {string} string "Invalid change"
+// @Failure 404 {string} string "Link not found"
+// @Failure 500 {object} LinkResponse
+// @Router /links/{id} [patch]
+func (h *LinkHandlers) PatchLink(w http.ResponseWriter, r *http.Request) {
+	linkID := mux.Vars(r)["id"]
+
+	var req PatchLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON in request body", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.linkStore.GetLink(linkID); err != nil {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+
+	// A client disconnecting must not abort terraform halfway through the update
+	response, err := h.patchLink(context.WithoutCancel(r.Context()), linkID, req.Modules, req.Remove)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(linkResponseStatus(response))
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateLink partially updates a link (for job queue)
+func (h *LinkHandlers) UpdateLink(ctx context.Context, linkID string, modules map[string]map[string]interface{}, remove []string) error {
+	response, err := h.patchLink(ctx, linkID, modules, remove)
+	if err != nil {
+		return err
+	}
+	if !response.Success {
+		return linkResponseError(response)
+	}
+	return nil
+}
+
+// patchLink merges the changes into the stored link, re-applies the affected modules and
+// disconnects modules from networks they no longer need. Invalid changes return an error;
+// apply failures are reported in the response.
+func (h *LinkHandlers) patchLink(ctx context.Context, linkID string, changes map[string]map[string]interface{}, remove []string) (LinkResponse, error) {
+	link, err := h.linkStore.GetLink(linkID)
+	if err != nil {
+		return LinkResponse{}, err
+	}
+	if len(changes) == 0 && len(remove) == 0 {
+		return LinkResponse{}, fmt.Errorf("modules or remove is required")
+	}
+
+	modules, err := mergeLinkModules(link.Modules, changes, remove)
+	if err != nil {
+		return LinkResponse{}, err
+	}
+	if len(modules) == 0 {
+		return LinkResponse{}, fmt.Errorf("update would remove every module; delete the link instead")
+	}
+
+	// Outputs of changed or detached modules may differ, so everything that depends on
+	// them is re-applied to re-resolve its references
+	changed := make(map[string]bool)
+	for moduleName := range changes {
+		changed[moduleName] = true
+	}
+	for _, moduleName := range remove {
+		changed[moduleName] = true
+	}
+	affected := linkDependents(modules, changed)
+	for moduleName := range changes {
+		affected[moduleName] = true
+	}
+
+	h.logger.Info("Updating link", "link_id", linkID, "changed", getAppNames(changes), "removed", remove)
+
+	previousNetworks := link.ModuleNetworks
+	if previousNetworks == nil {
+		_, _, previousNetworks = collectLinkReferences(link.Modules)
+	}
+
+	response := h.applyLink(ctx, linkID, modules, affected, link.Tags)
+	if !response.Success {
+		return response, nil
+	}
+
+	h.disconnectUnusedNetworks(ctx, linkID, previousNetworks, modules)
+
+	response.Message = "Link updated successfully"
+	return response, nil
+}
+
+// mergeLinkModules returns a copy of modules with changes merged in and removed modules dropped
+func mergeLinkModules(modules, changes map[string]map[string]interface{}, remove []string) (map[string]map[string]interface{}, error) {
+	merged := make(map[string]map[string]interface{}, len(modules))
+	for moduleName, config := range modules {
+		copied := make(map[string]interface{}, len(config))
+		for key, value := range config {
+			copied[key] = value
+		}
+		merged[moduleName] = copied
+	}
+
+	for _, moduleName := range remove {
+		if _, ok := merged[moduleName]; !ok {
+			return nil, fmt.Errorf("module %s is not part of the link", moduleName)
+		}
+		if _, ok := changes[moduleName]; ok {
+			return nil, fmt.Errorf("module %s cannot be both updated and removed", moduleName)
+		}
+		delete(merged, moduleName)
+	}
+
+	for moduleName, config := range changes {
+		target, ok := merged[moduleName]
+		if !ok {
+			target = make(map[string]interface{})
+			merged[moduleName] = target
+		}
+		for key, value := range config {
+			if value == nil {
+				delete(target, key)
+				continue
+			}
+			target[key] = value
+		}
+	}
+
+	return merged, nil
+}
+
+// linkDependents returns the modules that reference any changed module, directly or through
+// other modules in the link
+func linkDependents(modules map[string]map[string]interface{}, changed map[string]bool) map[string]bool {
+	dependents := make(map[string]bool)
+	for grew := true; grew; {
+		grew = false
+		for moduleName, config := range modules {
+			if dependents[moduleName] {
+				continue
+			}
+			for _, value := range config {
+				ref, isRef := parseAppReference(value)
+				if isRef && (changed[ref.FromModule] || dependents[ref.FromModule]) {
+					dependents[moduleName] = true
+					grew = true
+					break
+				}
+			}
+		}
+	}
+	return dependents
+}
+
+// disconnectUnusedNetworks removes containers from link networks they joined for the
+// previous configuration but no longer need. Network names only depend on the two modules,
+// so memberships still required by another link are kept.
+func (h *LinkHandlers) disconnectUnusedNetworks(ctx context.Context, linkID string, previous map[string][]string, modules map[string]map[string]interface{}) {
+	_, _, current := collectLinkReferences(modules)
+
+	stillNeeded := make(map[string]bool) // module + "/" + network
+	addNeeded := func(moduleNetworks map[string][]string) {
+		for moduleName, networks := range moduleNetworks {
+			for _, networkName := range networks {
+				stillNeeded[moduleName+"/"+networkName] = true
+			}
+		}
+	}
+	addNeeded(current)
+	for _, other := range h.linkStore.ListLinks() {
+		if other.ID == linkID {
+			continue
+		}
+		otherNetworks := other.ModuleNetworks
+		if otherNetworks == nil {
+			_, _, otherNetworks = collectLinkReferences(other.Modules)
+		}
+		addNeeded(otherNetworks)
+	}
+
+	for moduleName, networks := range previous {
+		for _, networkName := range networks {
+			if stillNeeded[moduleName+"/"+networkName] {
+				continue
+			}
+			h.logger.Info("Disconnecting module from link network", "module", moduleName, "network", networkName)
+			if err := h.networkManager.DisconnectContainerFromNetwork(ctx, moduleName+"-main", networkName); err != nil {
+				h.logger.Warn("Failed to disconnect module from link network", "module", moduleName, "network", networkName, "error", err)
+			}
+		}
+	}
+}
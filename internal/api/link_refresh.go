@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+)
+
+// LinkDetailResponse is a link together with the current state of its references
+type LinkDetailResponse struct {
+	*Link
+	ReferenceStatus map[string]map[string]ReferenceStatus `json:"reference_status,omitempty"` // module -> input -> status
+}
+
+// ReferenceStatus reports whether a reference's producer output still matches the applied value
+type ReferenceStatus struct {
+	Reference string `json:"reference"`       // "module.output"
+	Stale     bool   `json:"stale"`           // Output changed since it was applied (or the applied value is unknown)
+	Error     string `json:"error,omitempty"` // Output could not be read
+}
+
+// RefreshLink handles POST /links/{id}/refresh
+// @ID refreshLink
+// @Summary Re-resolve a link's references
+// @Description Re-reads the producer outputs of every reference on the link and re-applies the modules whose inputs changed, along with modules that depend on them
+// @Tags links
+// @Param id path string true "Link ID"
+// @Produce json
+// @Success 200 {object} LinkResponse
+// @Failure 404 {string} string "Link not found"
+// @Failure 409 {string} string "A referenced output cannot be read"
+// @Failure 500 {object} LinkResponse
+// @Router /links/{id}/refresh [post]
+func (h *LinkHandlers) RefreshLink(w http.ResponseWriter, r *http.Request) {
+	linkID := mux.Vars(r)["id"]
+
+	link, err := h.linkStore.GetLink(linkID)
+	if err != nil {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+
+	response, err := h.refreshLink(link)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(linkResponseStatus(response))
+	json.NewEncoder(w).Encode(response)
+}
+
+// refreshLink re-applies the stale modules of a link and everything that depends on them.
+// Unreadable producer outputs return an error since re-applying would fail anyway.
+func (h *LinkHandlers) refreshLink(link *Link) (LinkResponse, error) {
+	stale := make(map[string]bool)
+	for moduleName, inputs := range h.referenceStatus(link) {
+		for inputName, status := range inputs {
+			if status.Error != "" {
+				return LinkResponse{}, fmt.Errorf("cannot resolve %s for %s.%s: %s", status.Reference, moduleName, inputName, status.Error)
+			}
+			if status.Stale {
+				stale[moduleName] = true
+			}
+		}
+	}
+
+	if len(stale) == 0 {
+		return LinkResponse{
+			Success: true,
+			Message: "References are up to date",
+		}, nil
+	}
+
+	affected := linkDependents(link.Modules, stale)
+	for moduleName := range stale {
+		affected[moduleName] = true
+	}
+
+	staleModules := make([]string, 0, len(stale))
+	for moduleName := range stale {
+		staleModules = append(staleModules, moduleName)
+	}
+	sort.Strings(staleModules)
+	h.logger.Info("Refreshing link", "link_id", link.ID, "stale", staleModules)
+
+	response := h.applyLink(context.Background(), link.ID, link.Modules, affected, link.Tags)
+	if response.Success {
+		response.Message = "Link refreshed successfully"
+	}
+	return response, nil
+}
+
+// referenceStatus compares the current producer outputs of a link's references with the
+// values last applied to the consuming modules
+func (h *LinkHandlers) referenceStatus(link *Link) map[string]map[string]ReferenceStatus {
+	outputs := make(map[string]map[string]interface{}) // Read each producer's outputs once
+	outputErrors := make(map[string]error)
+
+	statuses := make(map[string]map[string]ReferenceStatus)
+	for moduleName, config := range link.Modules {
+		for inputName, value := range config {
+			ref, isRef := parseAppReference(value)
+			if !isRef {
+				continue
+			}
+
+			status := ReferenceStatus{Reference: fmt.Sprintf("%s.%s", ref.FromModule, ref.Output)}
+
+			if _, read := outputs[ref.FromModule]; !read && outputErrors[ref.FromModule] == nil {
+				producerOutputs, err := h.getAppOutputs(ref.FromModule)
+				if err != nil {
+					outputErrors[ref.FromModule] = err
+				} else {
+					outputs[ref.FromModule] = producerOutputs
+				}
+			}
+
+			if err := outputErrors[ref.FromModule]; err != nil {
+				status.Error = err.Error()
+			} else if current, ok := outputs[ref.FromModule][ref.Output]; !ok {
+				status.Error = fmt.Sprintf("output %s not found in app %s", ref.Output, ref.FromModule)
+			} else {
+				applied, recorded := link.ResolvedValues[moduleName][inputName]
+				status.Stale = !recorded || !sameResolvedValue(applied, current)
+			}
+
+			if statuses[moduleName] == nil {
+				statuses[moduleName] = make(map[string]ReferenceStatus)
+			}
+			statuses[moduleName][inputName] = status
+		}
+	}
+	return statuses
+}
+
+// sameResolvedValue compares an applied value with a current output. Applied values have been
+// through a JSON round trip, so both are compared in their JSON form.
+func sameResolvedValue(applied, current interface{}) bool {
+	appliedJSON, err := json.Marshal(applied)
+	if err != nil {
+		return false
+	}
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return false
+	}
+	return string(appliedJSON) == string(currentJSON)
+}
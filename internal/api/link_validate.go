@@ -0,0 +1,228 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"zeropoint-agent/internal/modules"
+)
+
+// ReferenceProblem describes a binding in a link request that cannot be applied
+type ReferenceProblem struct {
+	Module    string `json:"module,omitempty"`    // Module whose input holds the binding
+	Input     string `json:"input,omitempty"`     // Input name
+	Reference string `json:"reference,omitempty"` // The binding as written, or the cycle path
+	Reason    string `json:"reason"`              // unknown module, unknown output, self-reference, malformed reference, cycle, or an input the module's schema rejects
+}
+
+// validateReferences checks every binding of a link before anything is backed up or applied:
+// modules must exist, references must be well formed, must not point at their own module and
+// must name an output the producer actually has, and the references must not form a cycle.
+func (h *LinkHandlers) validateReferences(modules map[string]map[string]interface{}) []ReferenceProblem {
+	var problems []ReferenceProblem
+
+	moduleExists := func(moduleName string) bool {
+		_, err := os.Stat(filepath.Join(h.appsDir, moduleName))
+		return err == nil
+	}
+
+	outputs := make(map[string]map[string]interface{}) // Read each producer's outputs once
+	outputErrors := make(map[string]error)
+	producerOutputs := func(moduleName string) (map[string]interface{}, error) {
+		if _, read := outputs[moduleName]; !read && outputErrors[moduleName] == nil {
+			values, err := h.getAppOutputs(moduleName)
+			if err != nil {
+				outputErrors[moduleName] = err
+			} else {
+				outputs[moduleName] = values
+			}
+		}
+		return outputs[moduleName], outputErrors[moduleName]
+	}
+
+	for moduleName, config := range modules {
+		if !moduleExists(moduleName) {
+			problems = append(problems, ReferenceProblem{
+				Module: moduleName,
+				Reason: fmt.Sprintf("unknown module %s", moduleName),
+			})
+		}
+
+		for inputName, value := range config {
+			written := describeBinding(value)
+			problem := ReferenceProblem{Module: moduleName, Input: inputName, Reference: written}
+
+			ref, isRef := parseAppReference(value)
+			if !isRef {
+				if looksLikeReference(value) {
+					problem.Reason = "malformed reference; expected ${module.output} or {\"from_module\": ..., \"output\": ...}"
+					problems = append(problems, problem)
+				}
+				continue
+			}
+
+			switch {
+			case ref.FromModule == "" || ref.Output == "":
+				problem.Reason = "malformed reference; module and output must both be set"
+			case ref.FromModule == moduleName:
+				problem.Reason = "self-reference; a module cannot consume its own outputs"
+			case !moduleExists(ref.FromModule):
+				problem.Reason = fmt.Sprintf("unknown module %s", ref.FromModule)
+			default:
+				values, err := producerOutputs(ref.FromModule)
+				if err != nil {
+					problem.Reason = fmt.Sprintf("outputs of %s cannot be read: %v", ref.FromModule, err)
+				} else if _, ok := values[ref.Output]; !ok {
+					problem.Reason = fmt.Sprintf("unknown output %s of module %s", ref.Output, ref.FromModule)
+				}
+			}
+			if problem.Reason != "" {
+				problems = append(problems, problem)
+			}
+		}
+	}
+
+	// Self-references are already reported and would also show up as one-module cycles
+	acyclic := make(map[string]map[string]interface{}, len(modules))
+	for moduleName, config := range modules {
+		filtered := make(map[string]interface{}, len(config))
+		for inputName, value := range config {
+			if ref, isRef := parseAppReference(value); isRef && ref.FromModule == moduleName {
+				continue
+			}
+			filtered[inputName] = value
+		}
+		acyclic[moduleName] = filtered
+	}
+	if graph, err := AnalyzeDependencies(acyclic); err == nil {
+		if cycle := graph.FindCycle(); cycle != nil {
+			problems = append(problems, ReferenceProblem{
+				Reference: strings.Join(cycle, " → "),
+				Reason:    "dependency cycle",
+			})
+		}
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].Module != problems[j].Module {
+			return problems[i].Module < problems[j].Module
+		}
+		return problems[i].Input < problems[j].Input
+	})
+	return problems
+}
+
+// validateInputs checks the inputs of every module that ships an input schema
+// (zeropoint.inputs.json): inputs the schema does not declare are reported, and literal
+// values must match the declared type and enum. References are only checked by name, as
+// their values are not known until they are resolved. Modules without a schema accept any
+// input, as before.
+func (h *LinkHandlers) validateInputs(moduleConfigs map[string]map[string]interface{}) []ReferenceProblem {
+	var problems []ReferenceProblem
+	for moduleName, config := range moduleConfigs {
+		schema, err := modules.LoadInputSchema(filepath.Join(h.appsDir, moduleName))
+		if err != nil {
+			problems = append(problems, ReferenceProblem{
+				Module: moduleName,
+				Reason: fmt.Sprintf("input schema cannot be read: %v", err),
+			})
+			continue
+		}
+		if schema == nil {
+			continue
+		}
+
+		for inputName, value := range config {
+			checked := value
+			if _, isRef := parseAppReference(value); isRef {
+				if _, declared := schema.Properties[inputName]; declared {
+					continue
+				}
+				checked = nil
+			}
+			if reason := schema.CheckInput(inputName, checked); reason != "" {
+				problems = append(problems, ReferenceProblem{
+					Module:    moduleName,
+					Input:     inputName,
+					Reference: describeBinding(value),
+					Reason:    reason,
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// linkProblems runs validateReferences and validateInputs, sorting the problems by module
+// and input
+func (h *LinkHandlers) linkProblems(moduleConfigs map[string]map[string]interface{}) []ReferenceProblem {
+	problems := append(h.validateReferences(moduleConfigs), h.validateInputs(moduleConfigs)...)
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Module != problems[j].Module {
+			return problems[i].Module < problems[j].Module
+		}
+		return problems[i].Input < problems[j].Input
+	})
+	return problems
+}
+
+// looksLikeReference reports whether a value was probably meant as a reference but did not parse
+func looksLikeReference(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return strings.HasPrefix(v, "${")
+	case map[string]interface{}:
+		_, hasFromModule := v["from_module"]
+		_, hasOutput := v["output"]
+		return hasFromModule || hasOutput
+	}
+	return false
+}
+
+// describeBinding renders a binding for error messages
+func describeBinding(value interface{}) string {
+	if ref, isRef := parseAppReference(value); isRef {
+		return fmt.Sprintf("%s.%s", ref.FromModule, ref.Output)
+	}
+	if str, ok := value.(string); ok {
+		return str
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// linkResponseStatus maps a link response to an HTTP status: invalid references are the
+// caller's fault, anything else that failed happened while applying
+func linkResponseStatus(response LinkResponse) int {
+	switch {
+	case response.Success:
+		return http.StatusOK
+	case len(response.Problems) > 0:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// linkResponseError turns a failed link response into an error for the job queue, listing
+// any invalid references so they show up in the job
+func linkResponseError(response LinkResponse) error {
+	if len(response.Problems) == 0 {
+		return errors.New(response.Message)
+	}
+	details := make([]string, 0, len(response.Problems))
+	for _, problem := range response.Problems {
+		switch {
+		case problem.Input != "":
+			details = append(details, fmt.Sprintf("%s.%s (%s): %s", problem.Module, problem.Input, problem.Reference, problem.Reason))
+		case problem.Module != "":
+			details = append(details, fmt.Sprintf("%s: %s", problem.Module, problem.Reason))
+		default:
+			details = append(details, fmt.Sprintf("%s: %s", problem.Reason, problem.Reference))
+		}
+	}
+	return fmt.Errorf("%s: %s", response.Message, strings.Join(details, "; "))
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -23,9 +24,11 @@ const (
 // Link represents a group of linked modules with their references
 type Link struct {
 	ID              string                            `json:"id"`
-	Modules         map[string]map[string]interface{} `json:"modules"`         // Module configurations with references
-	References      map[string]map[string]string      `json:"references"`      // Resolved references for each module
-	SharedNetworks  []string                          `json:"shared_networks"` // Networks created for this link
+	Modules         map[string]map[string]interface{} `json:"modules"`                   // Module configurations with references
+	References      map[string]map[string]string      `json:"references"`                // Resolved references for each module
+	SharedNetworks  []string                          `json:"shared_networks"`           // Networks created for this link
+	ModuleNetworks  map[string][]string               `json:"module_networks,omitempty"` // Shared networks each module's container joined for this link
+	ResolvedValues  map[string]map[string]interface{} `json:"resolved_values,omitempty"` // Reference values last applied: module -> input -> value
 	DependencyOrder []string                          `json:"dependency_order"`
 	Tags            []string                          `json:"tags,omitempty"` // optional tags for categorization
 	CreatedAt       time.Time                         `json:"created_at"`
@@ -68,7 +71,7 @@ func NewLinkStore(dockerClient *client.Client, logger *slog.Logger) (*LinkStore,
 }
 
 // CreateOrUpdateLink creates or updates a link
-func (s *LinkStore) CreateOrUpdateLink(ctx context.Context, linkID string, modules map[string]map[string]interface{}, references map[string]map[string]string, sharedNetworks []string, dependencyOrder []string, tags []string) (*Link, error) {
+func (s *LinkStore) CreateOrUpdateLink(ctx context.Context, linkID string, modules map[string]map[string]interface{}, references map[string]map[string]string, sharedNetworks []string, moduleNetworks map[string][]string, dependencyOrder []string, tags []string) (*Link, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
@@ -84,6 +87,7 @@ func (s *LinkStore) CreateOrUpdateLink(ctx context.Context, linkID string, modul
 		link.Modules = modules
 		link.References = references
 		link.SharedNetworks = sharedNetworks
+		link.ModuleNetworks = moduleNetworks
 		link.DependencyOrder = dependencyOrder
 		link.Tags = tags
 		link.UpdatedAt = now
@@ -94,6 +98,7 @@ func (s *LinkStore) CreateOrUpdateLink(ctx context.Context, linkID string, modul
 			Modules:         modules,
 			References:      references,
 			SharedNetworks:  sharedNetworks,
+			ModuleNetworks:  moduleNetworks,
 			DependencyOrder: dependencyOrder,
 			Tags:            tags,
 			CreatedAt:       now,
@@ -112,6 +117,154 @@ func (s *LinkStore) CreateOrUpdateLink(ctx context.Context, linkID string, modul
 	return link, nil
 }
 
+// RecordResolvedValues stores the reference values just applied to a link's modules. Modules
+// that were not re-applied keep their previous values; modules no longer in the link are dropped.
+func (s *LinkStore) RecordResolvedValues(linkID string, applied map[string]map[string]interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	link, ok := s.links[linkID]
+	if !ok {
+		return fmt.Errorf("link not found")
+	}
+
+	values := make(map[string]map[string]interface{})
+	for moduleName, inputs := range link.ResolvedValues {
+		if _, inLink := link.Modules[moduleName]; inLink {
+			values[moduleName] = inputs
+		}
+	}
+	for moduleName, inputs := range applied {
+		if len(inputs) == 0 {
+			delete(values, moduleName)
+			continue
+		}
+		values[moduleName] = inputs
+	}
+	link.ResolvedValues = values
+
+	return s.save()
+}
+
+// LinksReferencingModule returns the IDs of links in which other modules consume the module's outputs
+func (s *LinkStore) LinksReferencingModule(moduleID string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var linkIDs []string
+	for id, link := range s.links {
+		for moduleName, config := range link.Modules {
+			if moduleName == moduleID {
+				continue
+			}
+			if referencesModule(config, moduleID) {
+				linkIDs = append(linkIDs, id)
+				break
+			}
+		}
+	}
+	sort.Strings(linkIDs)
+	return linkIDs
+}
+
+// referencesModule reports whether a module configuration uses any output of moduleID
+func referencesModule(config map[string]interface{}, moduleID string) bool {
+	for _, value := range config {
+		if ref, isRef := parseAppReference(value); isRef && ref.FromModule == moduleID {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveModule drops an uninstalled module from every link it belongs to. Shared networks the
+// module joined are dropped from those links too, since their names include the module; links
+// left without modules are deleted. Returns the updated and deleted link IDs and, per dropped
+// network, the other modules that had joined it.
+func (s *LinkStore) RemoveModule(moduleID string) ([]string, []string, map[string][]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var updated, deleted []string
+	networkPeers := make(map[string][]string)
+
+	for id, link := range s.links {
+		_, isMember := link.Modules[moduleID]
+		dropped := link.ModuleNetworks[moduleID]
+		if !isMember && len(dropped) == 0 {
+			continue
+		}
+
+		droppedNetworks := make(map[string]bool, len(dropped))
+		for _, networkName := range dropped {
+			droppedNetworks[networkName] = true
+			if _, ok := networkPeers[networkName]; !ok {
+				networkPeers[networkName] = nil
+			}
+		}
+
+		delete(link.Modules, moduleID)
+		delete(link.References, moduleID)
+		delete(link.ResolvedValues, moduleID)
+		delete(link.ModuleNetworks, moduleID)
+		for moduleName, networks := range link.ModuleNetworks {
+			kept := networks[:0]
+			for _, networkName := range networks {
+				if droppedNetworks[networkName] {
+					networkPeers[networkName] = append(networkPeers[networkName], moduleName)
+					continue
+				}
+				kept = append(kept, networkName)
+			}
+			link.ModuleNetworks[moduleName] = kept
+		}
+		sharedNetworks := link.SharedNetworks[:0]
+		for _, networkName := range link.SharedNetworks {
+			if !droppedNetworks[networkName] {
+				sharedNetworks = append(sharedNetworks, networkName)
+			}
+		}
+		link.SharedNetworks = sharedNetworks
+		order := link.DependencyOrder[:0]
+		for _, moduleName := range link.DependencyOrder {
+			if moduleName != moduleID {
+				order = append(order, moduleName)
+			}
+		}
+		link.DependencyOrder = order
+		link.UpdatedAt = time.Now()
+
+		if len(link.Modules) == 0 {
+			delete(s.links, id)
+			deleted = append(deleted, id)
+		} else {
+			updated = append(updated, id)
+		}
+	}
+
+	if len(updated) == 0 && len(deleted) == 0 {
+		return nil, nil, nil, nil
+	}
+
+	if err := s.save(); err != nil {
+		// Links were changed in place; go back to what is on disk
+		s.links = make(map[string]*Link)
+		if loadErr := s.load(); loadErr != nil {
+			s.logger.Error("failed to reload links", "error", loadErr)
+		}
+		return nil, nil, nil, fmt.Errorf("failed to save links: %w", err)
+	}
+
+	sort.Strings(updated)
+	sort.Strings(deleted)
+	for networkName, peers := range networkPeers {
+		sort.Strings(peers)
+		networkPeers[networkName] = peers
+	}
+	s.logger.Info("Removed module from links", "module_id", moduleID, "updated", updated, "deleted", deleted)
+	return updated, deleted, networkPeers, nil
+}
+
 // GetLink retrieves a link by ID
 func (s *LinkStore) GetLink(id string) (*Link, error) {
 	s.mutex.RLock()
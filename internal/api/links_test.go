@@ -0,0 +1,126 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCollectLinkReferences(t *testing.T) {
+	// Both reference forms: "${module.output}" strings and from_module/output maps
+	modules := map[string]map[string]interface{}{
+		"app": {
+			"db_host":  "${db.host}",
+			"cache":    map[string]interface{}{"from_module": "cache", "output": "url"},
+			"app_name": "wiki",
+		},
+		"db":    {"password": "hunter2"},
+		"cache": {},
+	}
+
+	references, sharedNetworks, moduleNetworks := collectLinkReferences(modules)
+
+	wantReferences := map[string]map[string]string{
+		"app": {"db_host": "db.host", "cache": "cache.url"},
+	}
+	if !reflect.DeepEqual(references, wantReferences) {
+		t.Errorf("references = %v, want %v", references, wantReferences)
+	}
+
+	wantNetworks := []string{"zeropoint-link-app-cache", "zeropoint-link-app-db"}
+	if !reflect.DeepEqual(sharedNetworks, wantNetworks) {
+		t.Errorf("shared networks = %v, want %v", sharedNetworks, wantNetworks)
+	}
+
+	wantMemberships := map[string][]string{
+		"app":   {"zeropoint-link-app-cache", "zeropoint-link-app-db"},
+		"cache": {"zeropoint-link-app-cache"},
+		"db":    {"zeropoint-link-app-db"},
+	}
+	if !reflect.DeepEqual(moduleNetworks, wantMemberships) {
+		t.Errorf("module networks = %v, want %v", moduleNetworks, wantMemberships)
+	}
+}
+
+func TestPatchLinkModules(t *testing.T) {
+	modules := map[string]map[string]interface{}{
+		"db":     {"size": "small"},
+		"app":    {"db_host": "${db.host}"},
+		"worker": {"queue": "${app.queue}"},
+		"docs":   {"title": "Docs"},
+	}
+
+	merged, err := mergeLinkModules(modules, map[string]map[string]interface{}{
+		"db": {"size": "large"},
+	}, []string{"docs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged["db"]["size"] != "large" || modules["db"]["size"] != "small" {
+		t.Errorf("merge changed db to %v and the original to %v", merged["db"], modules["db"])
+	}
+	if _, ok := merged["docs"]; ok {
+		t.Error("removed module docs is still part of the link")
+	}
+
+	// Modules consuming db's outputs, directly or through app, are re-applied with it
+	dependents := linkDependents(merged, map[string]bool{"db": true})
+	if !reflect.DeepEqual(dependents, map[string]bool{"app": true, "worker": true}) {
+		t.Errorf("dependents = %v, want app and worker", dependents)
+	}
+
+	if _, err := mergeLinkModules(modules, map[string]map[string]interface{}{"docs": {}}, []string{"docs"}); err == nil {
+		t.Error("merge accepted a module that is both updated and removed")
+	}
+	if _, err := mergeLinkModules(modules, nil, []string{"missing"}); err == nil {
+		t.Error("merge accepted removing a module that is not in the link")
+	}
+}
+
+func TestStateManagerRollback(t *testing.T) {
+	appsDir := t.TempDir()
+	stateFile := func(app string) string {
+		return filepath.Join(appsDir, app, "terraform.tfstate")
+	}
+	for _, app := range []string{"db", "app"} {
+		if err := os.MkdirAll(filepath.Join(appsDir, app), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(stateFile(app), []byte(app+" before"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sm := NewStateManager(appsDir)
+	backup, err := sm.BackupStates([]string{"db", "app", "not-installed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// db was applied before app failed; the rollback puts db's state back
+	if err := os.WriteFile(stateFile("db"), []byte("db after"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.RestoreStates(backup); err != nil {
+		t.Fatal(err)
+	}
+	for _, app := range []string{"db", "app"} {
+		data, err := os.ReadFile(stateFile(app))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != app+" before" {
+			t.Errorf("%s state = %q after rollback, want %q", app, data, app+" before")
+		}
+	}
+
+	if err := sm.CleanupBackup(backup); err != nil {
+		t.Fatal(err)
+	}
+	for app, backupFile := range backup.backups {
+		if _, err := os.Stat(backupFile); !os.IsNotExist(err) {
+			t.Errorf("backup of %s still exists after cleanup", app)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"zeropoint-agent/internal/modules"
+
+	"github.com/gorilla/mux"
+)
+
+// CredentialHandlers manages the git credentials used to clone private module repositories
+type CredentialHandlers struct {
+	store  *modules.CredentialStore
+	logger *slog.Logger
+}
+
+// NewCredentialHandlers creates a new credential handlers instance
+func NewCredentialHandlers(store *modules.CredentialStore, logger *slog.Logger) *CredentialHandlers {
+	return &CredentialHandlers{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// SetCredentialRequest stores a token for a git host. The token is given directly or as
+// the environment variable or file it is read from when cloning.
+type SetCredentialRequest struct {
+	Host     string `json:"host" example:"github.com"` // Bare host name; install requests refer to the credential by it
+	Username string `json:"username,omitempty"`        // Defaults to "git"; GitHub and GitLab accept any username with a token
+	modules.CredentialSecret
+}
+
+// SetCredential handles POST /modules/credentials
+// @ID setModuleCredential
+// @Summary Store a git credential
+// @Description Stores a token used to clone module sources from a host. Replaces any credential stored for the host. Give exactly one of token, token_env or token_file: a token is kept in a file readable only by the agent and is never returned, while an environment variable or file is read each time a module is cloned. Install and upgrade requests can name the credential in credential_ref instead of embedding a token in the source.
+// @Tags modules
+// @Accept json
+// @Produce json
+// @Param request body SetCredentialRequest true "Credential"
+// @Success 200 {object} modules.CredentialInfo
+// @Failure 400 {string} string "Invalid credential"
+// @Router /modules/credentials [post]
+func (h *CredentialHandlers) SetCredential(w http.ResponseWriter, r *http.Request) {
+	var req SetCredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON in request body", http.StatusBadRequest)
+		return
+	}
+
+	info, err := h.store.Set(req.Host, req.Username, req.CredentialSecret)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// ListCredentials handles GET /modules/credentials
+// @ID listModuleCredentials
+// @Summary List git credentials
+// @Description Lists the hosts with stored credentials. Tokens are reduced to a hint.
+// @Tags modules
+// @Produce json
+// @Success 200 {array} modules.CredentialInfo
+// @Router /modules/credentials [get]
+func (h *CredentialHandlers) ListCredentials(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.store.List())
+}
+
+// DeleteCredential handles DELETE /modules/credentials/{host}
+// @ID deleteModuleCredential
+// @Summary Delete a git credential
+// @Tags modules
+// @Param host path string true "Host"
+// @Success 204
+// @Failure 404 {string} string "Credential not found"
+// @Router /modules/credentials/{host} [delete]
+func (h *CredentialHandlers) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	host := mux.Vars(r)["host"]
+
+	if _, ok := h.store.Get(host); !ok {
+		http.Error(w, "Credential not found", http.StatusNotFound)
+		return
+	}
+	if err := h.store.Delete(host); err != nil {
+		h.logger.Error("failed to delete git credential", "host", host, "error", err)
+		http.Error(w, "Failed to delete credential", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
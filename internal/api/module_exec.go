@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/terraform"
+
+	"github.com/moby/moby/api/pkg/stdcopy"
+	"github.com/moby/moby/client"
+)
+
+// ExecInModule runs argv in the module's {module}-{container} container through the
+// Docker exec API (for the job queue), copying the command's output to stdout and stderr
+// and returning its exit code. The module is locked for the duration so a terraform apply
+// cannot recreate the container underneath the command. Cancelling ctx stops waiting for
+// the command, but Docker offers no way to stop the command itself.
+func (h *ModuleHandlers) ExecInModule(ctx context.Context, moduleID, container string, argv, env []string, stdout, stderr io.Writer) (int, error) {
+	modulePath := filepath.Join(internalPaths.GetModulesDir(), moduleID)
+	if _, err := os.Stat(filepath.Join(modulePath, "main.tf")); err != nil {
+		return 0, fmt.Errorf("%w: '%s'", errModuleNotInstalled, moduleID)
+	}
+
+	unlock, err := terraform.LockModule(ctx, modulePath)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	name := moduleID + "-" + container
+	inspect, err := h.docker.ContainerInspect(ctx, name, client.ContainerInspectOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("container %s not found: %w", name, err)
+	}
+	if inspect.Container.State == nil || !inspect.Container.State.Running {
+		return 0, fmt.Errorf("container %s is not running", name)
+	}
+
+	created, err := h.docker.ExecCreate(ctx, name, client.ExecCreateOptions{
+		Cmd:          argv,
+		Env:          env,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec in %s: %w", name, err)
+	}
+
+	attached, err := h.docker.ExecAttach(ctx, created.ID, client.ExecAttachOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to start exec in %s: %w", name, err)
+	}
+	defer attached.Close()
+
+	// The hijacked connection does not follow ctx, so close it to stop copying on timeout
+	copied := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(stdout, stderr, attached.Reader)
+		copied <- err
+	}()
+	select {
+	case <-ctx.Done():
+		attached.Close()
+		return 0, ctx.Err()
+	case err := <-copied:
+		if err != nil {
+			return 0, fmt.Errorf("failed to read output of exec in %s: %w", name, err)
+		}
+	}
+
+	result, err := h.docker.ExecInspect(ctx, created.ID, client.ExecInspectOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect exec in %s: %w", name, err)
+	}
+	h.logger.Info("module exec finished", "module_id", moduleID, "container", name, "exit_code", result.ExitCode)
+	return result.ExitCode, nil
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	internalPaths "zeropoint-agent/internal"
+
+	"github.com/gorilla/mux"
+	"github.com/moby/moby/client"
+)
+
+// ContainerHealth describes the runtime health of a single module container
+type ContainerHealth struct {
+	Name          string `json:"name"`
+	ID            string `json:"id"`
+	State         string `json:"state"`                // created, running, paused, restarting, exited, dead
+	Health        string `json:"health,omitempty"`     // Docker healthcheck status, if the image defines one
+	RestartCount  int    `json:"restart_count"`        // Restarts performed by Docker's restart policy
+	UptimeSeconds int64  `json:"uptime_seconds"`       // Zero unless running
+	StartedAt     string `json:"started_at,omitempty"` // RFC3339 timestamp from Docker
+	ExitCode      int    `json:"exit_code,omitempty"`  // Last exit code when not running
+	OOMKilled     bool   `json:"oom_killed,omitempty"` // Whether the last exit was an OOM kill
+	Error         string `json:"error,omitempty"`      // Docker-reported error, if any
+}
+
+// ModuleHealthResponse is returned by GET /modules/{name}/health
+type ModuleHealthResponse struct {
+	ModuleID   string            `json:"module_id"`
+	Healthy    bool              `json:"healthy"` // True if every container is running and not unhealthy
+	Containers []ContainerHealth `json:"containers"`
+}
+
+// GetModuleHealth handles GET /modules/{name}/health
+// @ID getModuleHealth
+// @Summary Get module container health
+// @Description Inspects every container belonging to the module ({module}-*) and reports state, restart count and uptime
+// @Tags modules
+// @Produce json
+// @Param name path string true "Module ID"
+// @Success 200 {object} ModuleHealthResponse
+// @Failure 404 {string} string "Module not found"
+// @Failure 503 {string} string "Docker unavailable"
+// @Router /modules/{name}/health [get]
+func (h *ModuleHandlers) GetModuleHealth(w http.ResponseWriter, r *http.Request) {
+	moduleID := mux.Vars(r)["name"]
+
+	containers, err := h.moduleContainerHealth(r.Context(), moduleID)
+	if err != nil {
+		h.logger.Error("failed to inspect module containers", "module_id", moduleID, "error", err)
+		http.Error(w, fmt.Sprintf("failed to inspect containers: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	if len(containers) == 0 {
+		module, err := h.loadModule(r.Context(), moduleID, false)
+		if err != nil || module == nil {
+			http.Error(w, fmt.Sprintf("module '%s' not found", moduleID), http.StatusNotFound)
+			return
+		}
+	}
+
+	resp := ModuleHealthResponse{
+		ModuleID:   moduleID,
+		Healthy:    len(containers) > 0,
+		Containers: containers,
+	}
+	for _, c := range containers {
+		if c.State != "running" || c.Health == "unhealthy" {
+			resp.Healthy = false
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// moduleContainer is a Docker container belonging to a module
+type moduleContainer struct {
+	ID    string
+	Name  string
+	State string
+}
+
+// moduleContainers lists all containers named {module}-*, sorted by name
+func (h *ModuleHandlers) moduleContainers(ctx context.Context, moduleID string) ([]moduleContainer, error) {
+	list, err := h.docker.ContainerList(ctx, client.ContainerListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	// Modules whose IDs extend this one (e.g. "web" vs "web-ui") share the prefix,
+	// so their containers must be excluded explicitly
+	var otherPrefixes []string
+	if entries, err := os.ReadDir(internalPaths.GetModulesDir()); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && entry.Name() != moduleID && strings.HasPrefix(entry.Name(), moduleID+"-") {
+				otherPrefixes = append(otherPrefixes, entry.Name()+"-")
+			}
+		}
+	}
+
+	prefix := moduleID + "-"
+	result := []moduleContainer{}
+	for _, c := range list.Items {
+		for _, n := range c.Names {
+			n = strings.TrimPrefix(n, "/")
+			if strings.HasPrefix(n, prefix) && !hasAnyPrefix(n, otherPrefixes) {
+				result = append(result, moduleContainer{ID: c.ID, Name: n, State: string(c.State)})
+				break
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// moduleContainerHealth inspects all containers named {module}-*
+func (h *ModuleHandlers) moduleContainerHealth(ctx context.Context, moduleID string) ([]ContainerHealth, error) {
+	containers, err := h.moduleContainers(ctx, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []ContainerHealth{}
+	for _, c := range containers {
+		name := c.Name
+		health := ContainerHealth{
+			Name:  name,
+			ID:    c.ID[:12],
+			State: c.State,
+		}
+
+		inspect, err := h.docker.ContainerInspect(ctx, c.ID, client.ContainerInspectOptions{})
+		if err != nil {
+			h.logger.Warn("failed to inspect container", "container", name, "error", err)
+			result = append(result, health)
+			continue
+		}
+
+		health.RestartCount = inspect.Container.RestartCount
+		if state := inspect.Container.State; state != nil {
+			health.State = string(state.Status)
+			health.StartedAt = state.StartedAt
+			health.OOMKilled = state.OOMKilled
+			health.Error = state.Error
+			if state.Health != nil {
+				health.Health = string(state.Health.Status)
+			}
+			if state.Running {
+				if started, err := time.Parse(time.RFC3339Nano, state.StartedAt); err == nil {
+					health.UptimeSeconds = int64(time.Since(started).Seconds())
+				}
+			} else {
+				health.ExitCode = state.ExitCode
+			}
+		}
+
+		result = append(result, health)
+	}
+
+	return result, nil
+}
+
+// hasAnyPrefix reports whether s starts with any of the given prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/modules"
+
+	"github.com/gorilla/mux"
+)
+
+// ModuleVerifyResponse is returned by GET /modules/{name}/verify
+type ModuleVerifyResponse struct {
+	ModuleID string `json:"module_id"`
+	modules.VerifyResult
+}
+
+// VerifyModule handles GET /modules/{name}/verify
+// @ID verifyModule
+// @Summary Verify module files against the install lockfile
+// @Description Re-hashes the module's files and compares them with zeropoint.lock.json written at install, reporting changed, missing and extra files. Terraform state and working files are not checked. Modules installed from a local path have no lockfile and report locked=false.
+// @Tags modules
+// @Produce json
+// @Param name path string true "Module ID"
+// @Success 200 {object} ModuleVerifyResponse
+// @Failure 404 {string} string "Module not found"
+// @Failure 500 {string} string "Verification failed"
+// @Router /modules/{name}/verify [get]
+func (h *ModuleHandlers) VerifyModule(w http.ResponseWriter, r *http.Request) {
+	moduleID := mux.Vars(r)["name"]
+	modulePath := filepath.Join(internalPaths.GetModulesDir(), moduleID)
+
+	if _, err := os.Stat(filepath.Join(modulePath, "main.tf")); err != nil {
+		http.Error(w, fmt.Sprintf("module '%s' not found", moduleID), http.StatusNotFound)
+		return
+	}
+
+	result, err := modules.VerifyModule(modulePath)
+	if err != nil {
+		h.logger.Error("failed to verify module", "module_id", moduleID, "error", err)
+		http.Error(w, fmt.Sprintf("failed to verify module: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ModuleVerifyResponse{ModuleID: moduleID, VerifyResult: *result})
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+
+	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/modules"
+	"zeropoint-agent/internal/terraform"
+)
+
+// moduleCacheEntry holds the parts of a module that are expensive to compute
+// (terraform output) and only change when the module is installed, upgraded or removed.
+// Container state is always queried live from Docker.
+type moduleCacheEntry struct {
+	metadata   *modules.Metadata
+	containers map[string]modules.Container
+	outputs    []string
+}
+
+// InvalidateModule drops the cached details for a module so the next read re-runs terraform output
+func (h *ModuleHandlers) InvalidateModule(moduleID string) {
+	h.cacheMu.Lock()
+	defer h.cacheMu.Unlock()
+	delete(h.cache, moduleID)
+}
+
+// DeclaredContainers returns the containers and ports a module declares through its
+// terraform outputs, reading them into the cache if the module is not cached yet (for
+// exposure port checks)
+func (h *ModuleHandlers) DeclaredContainers(moduleID string) (map[string]modules.Container, bool) {
+	h.cacheMu.RLock()
+	entry, cached := h.cache[moduleID]
+	h.cacheMu.RUnlock()
+
+	if !cached {
+		modulePath := filepath.Join(internalPaths.GetModulesDir(), moduleID)
+		if _, err := os.Stat(filepath.Join(modulePath, "main.tf")); err != nil {
+			return nil, false
+		}
+		entry = h.buildCacheEntry(modulePath, moduleID)
+		h.cacheMu.Lock()
+		h.cache[moduleID] = entry
+		h.cacheMu.Unlock()
+	}
+	return entry.containers, entry.containers != nil
+}
+
+// loadModule returns the inventory entry for a single module, or nil if no module
+// with that ID is installed
+func (h *ModuleHandlers) loadModule(ctx context.Context, moduleID string, refresh bool) (*Module, error) {
+	modulePath := filepath.Join(internalPaths.GetModulesDir(), moduleID)
+
+	// Check if main.tf exists
+	if _, err := os.Stat(filepath.Join(modulePath, "main.tf")); err != nil {
+		if os.IsNotExist(err) {
+			h.InvalidateModule(moduleID)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	h.cacheMu.RLock()
+	entry, cached := h.cache[moduleID]
+	h.cacheMu.RUnlock()
+
+	if !cached || refresh {
+		entry = h.buildCacheEntry(modulePath, moduleID)
+		h.cacheMu.Lock()
+		h.cache[moduleID] = entry
+		h.cacheMu.Unlock()
+	}
+
+	module := &Module{
+		ID:         moduleID,
+		ModulePath: modulePath,
+		State:      modules.StateUnknown,
+		Containers: entry.containers,
+		Outputs:    entry.outputs,
+		LastAction: h.lastModuleAction(moduleID),
+	}
+
+	if entry.metadata != nil {
+		module.Tags = entry.metadata.Tags
+		module.Source = entry.metadata.Source
+		module.Ref = entry.metadata.Ref
+		installedAt := entry.metadata.ClonedAt
+		module.InstalledAt = &installedAt
+		module.Resources = entry.metadata.Resources
+	}
+
+	// Query Docker for runtime status
+	if err := module.GetContainerStatus(ctx, h.docker); err != nil {
+		h.logger.Warn("failed to get container status", "module_id", moduleID, "error", err)
+	}
+
+	return module, nil
+}
+
+// buildCacheEntry reads metadata and terraform outputs for a module. Failures are
+// logged and leave the corresponding fields empty, matching discovery behavior.
+func (h *ModuleHandlers) buildCacheEntry(modulePath, moduleID string) *moduleCacheEntry {
+	entry := &moduleCacheEntry{}
+
+	// Load metadata (including tags) from .zeropoint.json
+	if metadata, err := modules.LoadMetadata(modulePath); err != nil {
+		h.logger.Warn("failed to load metadata", "module_id", moduleID, "error", err)
+	} else {
+		entry.metadata = metadata
+	}
+
+	executor, err := terraform.NewExecutor(modulePath)
+	if err != nil {
+		h.logger.Warn("failed to create terraform executor", "module_id", moduleID, "error", err)
+		return entry
+	}
+
+	outputs, err := executor.Output()
+	if err != nil {
+		h.logger.Warn("failed to read terraform outputs", "module_id", moduleID, "error", err)
+		return entry
+	}
+
+	for name := range outputs {
+		entry.outputs = append(entry.outputs, name)
+	}
+	sort.Strings(entry.outputs)
+
+	// Load containers with ports and mounts from Terraform outputs
+	if containers, err := modules.ContainersFromOutputs(outputs, moduleID); err != nil {
+		h.logger.Warn("failed to load containers", "module_id", moduleID, "error", err)
+	} else {
+		entry.containers = containers
+	}
+
+	return entry
+}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/modules"
+	"zeropoint-agent/internal/queue"
+	"zeropoint-agent/internal/terraform"
+
+	"github.com/gorilla/mux"
+	"github.com/moby/moby/client"
+)
+
+const (
+	// defaultModuleActionTimeout bounds how long each container gets to stop gracefully
+	// and to reach running again
+	defaultModuleActionTimeout = 60 * time.Second
+	containerPollInterval      = 500 * time.Millisecond
+)
+
+var (
+	errModuleNotInstalled = errors.New("module not installed")
+	errNoContainers       = errors.New("module has no containers")
+)
+
+// RestartModule handles POST /modules/{name}/restart
+// @ID restartModule
+// @Summary Restart a module's containers
+// @Description Stops every container of the module ({module}-*), {module}-main first, then starts them again with {module}-main last, waiting for each to reach running. Terraform is not run. Use POST /jobs/enqueue_module_action to chain a restart with other jobs.
+// @Tags modules
+// @Produce json
+// @Param name path string true "Module ID"
+// @Param timeout query int false "Seconds each container gets to stop and to reach running (default 60)"
+// @Success 200 {object} queue.ModuleActionResult
+// @Failure 400 {string} string "Invalid timeout"
+// @Failure 404 {string} string "Module not found, or its containers are not created yet"
+// @Failure 500 {object} queue.ModuleActionResult "Some containers failed"
+// @Router /modules/{name}/restart [post]
+func (h *ModuleHandlers) RestartModule(w http.ResponseWriter, r *http.Request) {
+	h.handleModuleAction(w, r, queue.ModuleActionRestart)
+}
+
+// StopModule handles POST /modules/{name}/stop
+// @ID stopModule
+// @Summary Stop a module's containers
+// @Description Stops every container of the module ({module}-*), {module}-main first. Terraform is not run; the next apply or start brings them back.
+// @Tags modules
+// @Produce json
+// @Param name path string true "Module ID"
+// @Param timeout query int false "Seconds each container gets to stop before it is killed (default 60)"
+// @Success 200 {object} queue.ModuleActionResult
+// @Failure 400 {string} string "Invalid timeout"
+// @Failure 404 {string} string "Module not found, or its containers are not created yet"
+// @Failure 500 {object} queue.ModuleActionResult "Some containers failed"
+// @Router /modules/{name}/stop [post]
+func (h *ModuleHandlers) StopModule(w http.ResponseWriter, r *http.Request) {
+	h.handleModuleAction(w, r, queue.ModuleActionStop)
+}
+
+// StartModule handles POST /modules/{name}/start
+// @ID startModule
+// @Summary Start a module's containers
+// @Description Starts every container of the module ({module}-*), {module}-main last, waiting for each to reach running.
+// @Tags modules
+// @Produce json
+// @Param name path string true "Module ID"
+// @Param timeout query int false "Seconds each container gets to reach running (default 60)"
+// @Success 200 {object} queue.ModuleActionResult
+// @Failure 400 {string} string "Invalid timeout"
+// @Failure 404 {string} string "Module not found, or its containers are not created yet"
+// @Failure 500 {object} queue.ModuleActionResult "Some containers failed"
+// @Router /modules/{name}/start [post]
+func (h *ModuleHandlers) StartModule(w http.ResponseWriter, r *http.Request) {
+	h.handleModuleAction(w, r, queue.ModuleActionStart)
+}
+
+func (h *ModuleHandlers) handleModuleAction(w http.ResponseWriter, r *http.Request, action string) {
+	moduleID := mux.Vars(r)["name"]
+
+	var timeout time.Duration
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			http.Error(w, "timeout must be a positive number of seconds", http.StatusBadRequest)
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	result, err := h.RunModuleAction(r.Context(), moduleID, action, timeout, "")
+	if result == nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errModuleNotInstalled) || errors.Is(err, errNoContainers) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// RunModuleAction restarts, stops or starts all containers of a module (for job queue and
+// the lifecycle endpoints). Containers stop with {module}-main first, as the other
+// containers are usually the services it depends on, and start in the reverse order.
+// Starting waits for each container to reach running within timeout. The module is locked
+// for the duration so a terraform apply cannot recreate containers underneath it. When
+// some containers fail, the result is returned along with the error.
+func (h *ModuleHandlers) RunModuleAction(ctx context.Context, moduleID, action string, timeout time.Duration, jobID string) (*queue.ModuleActionResult, error) {
+	modulePath := filepath.Join(internalPaths.GetModulesDir(), moduleID)
+	if _, err := os.Stat(filepath.Join(modulePath, "main.tf")); err != nil {
+		return nil, fmt.Errorf("%w: '%s'", errModuleNotInstalled, moduleID)
+	}
+	if timeout <= 0 {
+		timeout = defaultModuleActionTimeout
+	}
+
+	unlock, err := terraform.LockModule(ctx, modulePath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	containers, err := h.moduleContainers(ctx, moduleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("%w: '%s' has no containers yet; they are created when the module is applied", errNoContainers, moduleID)
+	}
+
+	record := h.beginModuleAction(moduleID, action, jobID)
+	h.logger.Info("running module action", "module_id", moduleID, "action", action, "containers", len(containers))
+
+	// Dependencies first, {module}-main last
+	ordered := make([]moduleContainer, 0, len(containers))
+	var main []moduleContainer
+	for _, c := range containers {
+		if c.Name == moduleID+"-main" {
+			main = append(main, c)
+		} else {
+			ordered = append(ordered, c)
+		}
+	}
+	ordered = append(ordered, main...)
+
+	result := &queue.ModuleActionResult{ModuleID: moduleID, Action: action}
+	failed := make(map[string]error)
+
+	if action == queue.ModuleActionStop || action == queue.ModuleActionRestart {
+		stopSeconds := int(timeout.Seconds())
+		for i := len(ordered) - 1; i >= 0; i-- {
+			c := ordered[i]
+			if _, err := h.docker.ContainerStop(ctx, c.ID, client.ContainerStopOptions{Timeout: &stopSeconds}); err != nil {
+				failed[c.Name] = fmt.Errorf("stop failed: %w", err)
+			}
+		}
+	}
+
+	if action == queue.ModuleActionStart || action == queue.ModuleActionRestart {
+		for _, c := range ordered {
+			if failed[c.Name] != nil {
+				continue
+			}
+			if _, err := h.docker.ContainerStart(ctx, c.ID, client.ContainerStartOptions{}); err != nil {
+				failed[c.Name] = fmt.Errorf("start failed: %w", err)
+				continue
+			}
+			if err := h.waitRunning(ctx, c.ID, timeout); err != nil {
+				failed[c.Name] = err
+			}
+		}
+	}
+
+	for _, c := range ordered {
+		outcome := queue.ContainerActionResult{Name: c.Name, State: c.State}
+		if inspect, err := h.docker.ContainerInspect(ctx, c.ID, client.ContainerInspectOptions{}); err == nil && inspect.Container.State != nil {
+			outcome.State = string(inspect.Container.State.Status)
+		}
+		if err := failed[c.Name]; err != nil {
+			outcome.Error = err.Error()
+		}
+		result.Containers = append(result.Containers, outcome)
+	}
+
+	if len(failed) > 0 {
+		err = fmt.Errorf("%s of module %s failed for %d of %d containers", action, moduleID, len(failed), len(ordered))
+	}
+	h.endModuleAction(moduleID, record, err)
+	return result, err
+}
+
+// waitRunning polls a started container until it is running, has exited, or timeout passes
+func (h *ModuleHandlers) waitRunning(ctx context.Context, containerID string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(containerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		inspect, err := h.docker.ContainerInspect(ctx, containerID, client.ContainerInspectOptions{})
+		if err == nil && inspect.Container.State != nil {
+			state := inspect.Container.State
+			if state.Running && !state.Restarting {
+				return nil
+			}
+			if state.Status == "exited" || state.Status == "dead" {
+				return fmt.Errorf("container %s with exit code %d", state.Status, state.ExitCode)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("container did not reach running within %s", timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// beginModuleAction records a lifecycle action as running so it shows in the module's status
+func (h *ModuleHandlers) beginModuleAction(moduleID, action, jobID string) *modules.LifecycleAction {
+	record := &modules.LifecycleAction{
+		Action:    action,
+		Status:    "running",
+		JobID:     jobID,
+		StartedAt: time.Now().UTC(),
+	}
+
+	h.actionsMu.Lock()
+	defer h.actionsMu.Unlock()
+	h.lastActions[moduleID] = record
+	return record
+}
+
+// endModuleAction records the outcome of a lifecycle action
+func (h *ModuleHandlers) endModuleAction(moduleID string, record *modules.LifecycleAction, err error) {
+	h.actionsMu.Lock()
+	defer h.actionsMu.Unlock()
+
+	completedAt := time.Now().UTC()
+	record.CompletedAt = &completedAt
+	record.Status = "completed"
+	if err != nil {
+		record.Status = "failed"
+		record.Error = err.Error()
+	}
+	h.lastActions[moduleID] = record
+}
+
+// lastModuleAction returns a copy of the most recent lifecycle action of a module, if any
+func (h *ModuleHandlers) lastModuleAction(moduleID string) *modules.LifecycleAction {
+	h.actionsMu.Lock()
+	defer h.actionsMu.Unlock()
+
+	record, ok := h.lastActions[moduleID]
+	if !ok {
+		return nil
+	}
+	copied := *record
+	return &copied
+}
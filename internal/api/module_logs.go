@@ -0,0 +1,251 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/moby/moby/api/pkg/stdcopy"
+	"github.com/moby/moby/client"
+)
+
+const (
+	// defaultModuleLogTail is how many lines per container GET /modules/{name}/logs
+	// returns when tail is not given
+	defaultModuleLogTail = "100"
+	// maxModuleLogStreams caps concurrent follow=true log streams; each holds a Docker
+	// log stream open per container
+	maxModuleLogStreams = 8
+)
+
+// moduleLogLine is a line of output from one of a module's containers
+type moduleLogLine struct {
+	container string
+	stream    string // "stdout" or "stderr"
+	text      string
+}
+
+// prefixed renders the line with its container name, marking stderr
+func (l moduleLogLine) prefixed() string {
+	if l.stream == "stderr" {
+		return fmt.Sprintf("[%s:stderr] %s", l.container, l.text)
+	}
+	return fmt.Sprintf("[%s] %s", l.container, l.text)
+}
+
+// lineWriter splits what Docker writes for one container stream into lines
+type lineWriter struct {
+	container string
+	stream    string
+	emit      func(moduleLogLine) error
+	buf       bytes.Buffer
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.buf.Write(p)
+	for {
+		i := bytes.IndexByte(lw.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		text := string(bytes.TrimSuffix(lw.buf.Next(i + 1)[:i], []byte("\r")))
+		if err := lw.emit(moduleLogLine{container: lw.container, stream: lw.stream, text: text}); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// flush emits a trailing line that had no newline
+func (lw *lineWriter) flush() error {
+	if lw.buf.Len() == 0 {
+		return nil
+	}
+	text := lw.buf.String()
+	lw.buf.Reset()
+	return lw.emit(moduleLogLine{container: lw.container, stream: lw.stream, text: text})
+}
+
+// copyContainerLogs demultiplexes a container's log stream into lines. Module containers
+// run without a TTY, so stdout and stderr arrive multiplexed.
+func (h *ModuleHandlers) copyContainerLogs(ctx context.Context, name string, opts client.ContainerLogsOptions, emit func(moduleLogLine) error) error {
+	stream, err := h.docker.ContainerLogs(ctx, name, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	stdout := &lineWriter{container: name, stream: "stdout", emit: emit}
+	stderr := &lineWriter{container: name, stream: "stderr", emit: emit}
+	if _, err := stdcopy.StdCopy(stdout, stderr, stream); err != nil {
+		return err
+	}
+	if err := stdout.flush(); err != nil {
+		return err
+	}
+	return stderr.flush()
+}
+
+// GetModuleLogs handles GET /modules/{name}/logs
+// @ID getModuleLogs
+// @Summary Get module container logs
+// @Description Returns the logs of every container belonging to the module ({module}-*), one container after another, each line prefixed with "[<container>]" or "[<container>:stderr]". With follow=true, lines of all containers are streamed as Server-Sent Events named "stdout" or "stderr" as they are written; an "end" event is sent when a container's log ends (e.g. it stopped) and the stream closes once every container's has. At most 8 follow streams are served at once.
+// @Tags modules
+// @Produce plain,text/event-stream
+// @Param name path string true "Module ID"
+// @Param tail query string false "Lines per container from the end of the log, or \"all\" (default 100)"
+// @Param since query string false "Only lines since this RFC3339 timestamp, Unix timestamp or relative duration (e.g. 10m)"
+// @Param timestamps query bool false "Prefix each line with Docker's timestamp"
+// @Param follow query bool false "Stream new lines as Server-Sent Events"
+// @Success 200 {string} string "Log lines"
+// @Failure 400 {string} string "Invalid tail or since"
+// @Failure 404 {string} string "Module not found, or its containers are not created yet"
+// @Failure 429 {string} string "Too many follow streams"
+// @Failure 503 {string} string "Docker unavailable"
+// @Router /modules/{name}/logs [get]
+func (h *ModuleHandlers) GetModuleLogs(w http.ResponseWriter, r *http.Request) {
+	moduleID := mux.Vars(r)["name"]
+	query := r.URL.Query()
+
+	tail := query.Get("tail")
+	if tail == "" {
+		tail = defaultModuleLogTail
+	} else if n, err := strconv.Atoi(tail); tail != "all" && (err != nil || n < 0) {
+		http.Error(w, "tail must be a non-negative integer or \"all\"", http.StatusBadRequest)
+		return
+	}
+
+	containers, err := h.moduleContainers(r.Context(), moduleID)
+	if err != nil {
+		h.logger.Error("failed to list module containers", "module_id", moduleID, "error", err)
+		http.Error(w, fmt.Sprintf("failed to list containers: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	if len(containers) == 0 {
+		module, err := h.loadModule(r.Context(), moduleID, false)
+		if err != nil || module == nil {
+			http.Error(w, fmt.Sprintf("module '%s' not found", moduleID), http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("module '%s' has no containers yet; they are created when the module is applied", moduleID), http.StatusNotFound)
+		return
+	}
+
+	opts := client.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       tail,
+		Since:      query.Get("since"),
+		Timestamps: query.Get("timestamps") == "true",
+	}
+
+	if query.Get("follow") == "true" {
+		h.followModuleLogs(w, r, containers, opts)
+		return
+	}
+
+	// Docker rejects a malformed since before sending anything, so check the first
+	// container before committing to a 200
+	var out bytes.Buffer
+	write := func(line moduleLogLine) error {
+		_, err := fmt.Fprintln(&out, line.prefixed())
+		return err
+	}
+	if err := h.copyContainerLogs(r.Context(), containers[0].Name, opts, write); err != nil {
+		http.Error(w, fmt.Sprintf("failed to read logs of %s: %v", containers[0].Name, err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(out.Bytes())
+	for _, c := range containers[1:] {
+		out.Reset()
+		if err := h.copyContainerLogs(r.Context(), c.Name, opts, write); err != nil {
+			h.logger.Warn("failed to read container logs", "container", c.Name, "error", err)
+			fmt.Fprintf(&out, "[%s] failed to read logs: %v\n", c.Name, err)
+		}
+		if _, err := w.Write(out.Bytes()); err != nil {
+			return
+		}
+	}
+}
+
+// followModuleLogs streams the logs of all containers as Server-Sent Events until every
+// container's log ends or the client disconnects
+func (h *ModuleHandlers) followModuleLogs(w http.ResponseWriter, r *http.Request, containers []moduleContainer, opts client.ContainerLogsOptions) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	select {
+	case h.logStreams <- struct{}{}:
+		defer func() { <-h.logStreams }()
+	default:
+		http.Error(w, fmt.Sprintf("too many log streams; at most %d can be followed at once", maxModuleLogStreams), http.StatusTooManyRequests)
+		return
+	}
+
+	// Disconnecting cancels ctx, which closes every Docker log stream
+	ctx, cancel := context.WithCancel(r.Context())
+
+	opts.Follow = true
+	lines := make(chan moduleLogLine)
+	ended := make(chan moduleLogLine)
+
+	var wg sync.WaitGroup
+	for _, c := range containers {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			emit := func(line moduleLogLine) error {
+				select {
+				case lines <- line:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			reason := "log ended"
+			if err := h.copyContainerLogs(ctx, name, opts, emit); err != nil && ctx.Err() == nil {
+				reason = err.Error()
+			}
+			select {
+			case ended <- moduleLogLine{container: name, text: reason}:
+			case <-ctx.Done():
+			}
+		}(c.Name)
+	}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for open := len(containers); open > 0; {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case line := <-lines:
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", line.stream, line.prefixed())
+		case end := <-ended:
+			open--
+			_, err = fmt.Fprintf(w, "event: end\ndata: [%s] %s\n\n", end.container, end.text)
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
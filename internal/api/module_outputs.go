@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/terraform"
+
+	"github.com/gorilla/mux"
+)
+
+// ModuleOutput is one terraform output of a module. Values of outputs terraform marks
+// sensitive are left out.
+type ModuleOutput struct {
+	Name      string      `json:"name"`
+	Type      interface{} `json:"type,omitempty"`  // Terraform type, e.g. "string" or ["map","string"]
+	Value     interface{} `json:"value,omitempty"` // Omitted when sensitive
+	Sensitive bool        `json:"sensitive"`
+}
+
+// ModuleOutputsResponse is returned by GET /modules/{name}/outputs
+type ModuleOutputsResponse struct {
+	ModuleID string         `json:"module_id"`
+	Outputs  []ModuleOutput `json:"outputs"` // Sorted by name
+}
+
+// GetModuleOutputs handles GET /modules/{name}/outputs
+// @ID getModuleOutputs
+// @Summary List a module's terraform outputs
+// @Description Reads the outputs from the module's terraform state, e.g. to find out why a link reference does not resolve. Values of outputs marked sensitive are redacted.
+// @Tags modules
+// @Produce json
+// @Param name path string true "Module ID"
+// @Success 200 {object} ModuleOutputsResponse
+// @Failure 404 {string} string "Module not found"
+// @Failure 500 {string} string "Failed to read outputs"
+// @Router /modules/{name}/outputs [get]
+func (h *ModuleHandlers) GetModuleOutputs(w http.ResponseWriter, r *http.Request) {
+	moduleID := mux.Vars(r)["name"]
+	modulePath := filepath.Join(internalPaths.GetModulesDir(), moduleID)
+
+	if _, err := os.Stat(filepath.Join(modulePath, "main.tf")); err != nil {
+		http.Error(w, fmt.Sprintf("module '%s' not found", moduleID), http.StatusNotFound)
+		return
+	}
+
+	executor, err := terraform.NewExecutor(modulePath)
+	if err != nil {
+		h.logger.Error("failed to create terraform executor", "module_id", moduleID, "error", err)
+		http.Error(w, fmt.Sprintf("failed to read outputs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	outputs, err := executor.WithContext(r.Context()).Output()
+	if err != nil {
+		h.logger.Error("failed to read terraform outputs", "module_id", moduleID, "error", err)
+		http.Error(w, fmt.Sprintf("failed to read outputs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ModuleOutputsResponse{
+		ModuleID: moduleID,
+		Outputs:  make([]ModuleOutput, 0, len(outputs)),
+	}
+	for name, output := range outputs {
+		entry := ModuleOutput{
+			Name:      name,
+			Type:      output.Type,
+			Sensitive: output.Sensitive,
+		}
+		if !output.Sensitive {
+			entry.Value = output.Value
+		}
+		resp.Outputs = append(resp.Outputs, entry)
+	}
+	sort.Slice(resp.Outputs, func(i, j int) bool {
+		return resp.Outputs[i].Name < resp.Outputs[j].Name
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
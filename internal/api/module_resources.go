@@ -0,0 +1,35 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ReapplyModule runs terraform apply on an installed module with its current system
+// variables, including stored resource limits, and the configuration its links give it
+// (for job queue). A module in several links gets their configurations merged in link ID
+// order.
+func (h *LinkHandlers) ReapplyModule(ctx context.Context, moduleID string) error {
+	if _, err := os.Stat(filepath.Join(h.appsDir, moduleID, "main.tf")); err != nil {
+		return fmt.Errorf("module %s is not installed", moduleID)
+	}
+
+	links := h.linkStore.ListLinks()
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+
+	config := make(map[string]interface{})
+	for _, link := range links {
+		for key, value := range link.Modules[moduleID] {
+			config[key] = value
+		}
+	}
+
+	if _, err := h.applyModuleConfiguration(ctx, moduleID, config); err != nil {
+		return err
+	}
+	h.logger.Info("re-applied module", "module", moduleID, "inputs", len(config))
+	return nil
+}
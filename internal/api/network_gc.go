@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"zeropoint-agent/internal/network"
+)
+
+const (
+	moduleNetworkPrefix = "zeropoint-module-"
+	linkNetworkPrefix   = "zeropoint-link-"
+)
+
+// NetworkGCReport describes a network garbage collection run
+type NetworkGCReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Checked int               `json:"checked"`           // zeropoint-module-* and zeropoint-link-* networks found
+	Removed []string          `json:"removed"`           // Orphaned networks removed (or that would be, on a dry run)
+	Skipped []NetworkGCResult `json:"skipped,omitempty"` // Orphaned networks left alone because containers are attached
+	Failed  []NetworkGCResult `json:"failed,omitempty"`  // Networks that could not be inspected or removed
+}
+
+// NetworkGCResult is a network the garbage collector did not remove
+type NetworkGCResult struct {
+	Network    string   `json:"network"`
+	Reason     string   `json:"reason"`
+	Containers []string `json:"containers,omitempty"`
+}
+
+// NetworkGC removes Docker networks left behind by modules and links that no longer exist
+type NetworkGC struct {
+	appsDir        string
+	linkStore      *LinkStore
+	networkManager *network.Manager
+	logger         *slog.Logger
+}
+
+// NewNetworkGC creates a network garbage collector
+func NewNetworkGC(appsDir string, linkStore *LinkStore, logger *slog.Logger) *NetworkGC {
+	return &NetworkGC{
+		appsDir:        appsDir,
+		linkStore:      linkStore,
+		networkManager: linkStore.GetNetworkManager(),
+		logger:         logger,
+	}
+}
+
+// HandleNetworkGC handles POST /system/networks/gc
+// @ID collectNetworks
+// @Summary Remove orphaned zeropoint networks
+// @Description Removes zeropoint-module-* networks of modules that are no longer installed and zeropoint-link-* networks no link uses. Networks that still have containers attached are left alone and reported.
+// @Tags system
+// @Produce json
+// @Param dry_run query bool false "Report what would be removed without removing anything"
+// @Success 200 {object} NetworkGCReport
+// @Failure 503 {string} string "Docker unavailable"
+// @Router /system/networks/gc [post]
+func (g *NetworkGC) HandleNetworkGC(w http.ResponseWriter, r *http.Request) {
+	report, err := g.Collect(r.Context(), r.URL.Query().Get("dry_run") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// Collect finds zeropoint networks without a matching installed module or link and removes
+// those with no containers attached. With dryRun nothing is removed.
+func (g *NetworkGC) Collect(ctx context.Context, dryRun bool) (*NetworkGCReport, error) {
+	report := &NetworkGCReport{DryRun: dryRun, Removed: []string{}}
+
+	var names []string
+	for _, prefix := range []string{moduleNetworkPrefix, linkNetworkPrefix} {
+		found, err := g.networkManager.ListNetworks(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, found...)
+	}
+	report.Checked = len(names)
+
+	linkNetworks := make(map[string]bool)
+	for _, link := range g.linkStore.ListLinks() {
+		for _, networkName := range link.SharedNetworks {
+			linkNetworks[networkName] = true
+		}
+	}
+
+	for _, networkName := range names {
+		if g.inUse(networkName, linkNetworks) {
+			continue
+		}
+
+		containers, err := g.networkManager.AttachedContainers(ctx, networkName)
+		if err != nil {
+			report.Failed = append(report.Failed, NetworkGCResult{Network: networkName, Reason: err.Error()})
+			continue
+		}
+		if len(containers) > 0 {
+			report.Skipped = append(report.Skipped, NetworkGCResult{
+				Network:    networkName,
+				Reason:     "orphaned but containers are still attached",
+				Containers: containers,
+			})
+			continue
+		}
+
+		if dryRun {
+			report.Removed = append(report.Removed, networkName)
+			continue
+		}
+		if _, err := g.networkManager.RemoveNetwork(ctx, networkName); err != nil {
+			report.Failed = append(report.Failed, NetworkGCResult{Network: networkName, Reason: err.Error()})
+			continue
+		}
+		report.Removed = append(report.Removed, networkName)
+	}
+
+	g.logger.Info("network garbage collection finished",
+		"dry_run", dryRun,
+		"checked", report.Checked,
+		"removed", report.Removed,
+		"skipped", len(report.Skipped),
+		"failed", len(report.Failed))
+	for _, skipped := range report.Skipped {
+		g.logger.Warn("orphaned network has containers attached", "network", skipped.Network, "containers", skipped.Containers)
+	}
+	for _, failed := range report.Failed {
+		g.logger.Warn("network garbage collection failed", "network", failed.Network, "error", failed.Reason)
+	}
+
+	return report, nil
+}
+
+// inUse reports whether a network still belongs to an installed module or an active link
+func (g *NetworkGC) inUse(networkName string, linkNetworks map[string]bool) bool {
+	if moduleID, ok := strings.CutPrefix(networkName, moduleNetworkPrefix); ok {
+		// Anything but a definite "not installed" keeps the network
+		_, err := os.Stat(filepath.Join(g.appsDir, moduleID))
+		return !os.IsNotExist(err)
+	}
+	return linkNetworks[networkName]
+}
+
+// collectAtStartup runs one collection and logs any failure; Docker being unavailable must
+// not stop the agent from starting
+func (g *NetworkGC) collectAtStartup(ctx context.Context) {
+	if _, err := g.Collect(ctx, false); err != nil {
+		g.logger.Warn("startup network garbage collection failed", "error", err)
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+// ReadinessCheck is the result of a single readiness check
+type ReadinessCheck struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// ReadinessResponse is returned by GET /readyz
+type ReadinessResponse struct {
+	Ready  bool                      `json:"ready"`
+	Checks map[string]ReadinessCheck `json:"checks"`
+	Failed []string                  `json:"failed,omitempty"` // Names of the checks that did not pass
+}
+
+// livenessHandler handles GET /healthz (outside /api). It only reports that the
+// process is serving HTTP and never touches dependencies. The probes are not in the
+// swagger spec, whose base path is /api.
+func (e *apiEnv) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, "ok")
+}
+
+// readinessHandler handles GET /readyz (outside /api). It returns 503 with the failed
+// checks unless Docker responds, xDS has pushed a snapshot and Envoy is running.
+func (e *apiEnv) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	checks := map[string]error{
+		"docker": e.checkDocker(ctx),
+		"xds":    e.checkXDS(),
+		"envoy":  e.checkEnvoy(ctx),
+	}
+
+	resp := ReadinessResponse{
+		Ready:  true,
+		Checks: make(map[string]ReadinessCheck, len(checks)),
+	}
+	for _, name := range []string{"docker", "xds", "envoy"} {
+		if err := checks[name]; err != nil {
+			resp.Ready = false
+			resp.Failed = append(resp.Failed, name)
+			resp.Checks[name] = ReadinessCheck{OK: false, Error: err.Error()}
+		} else {
+			resp.Checks[name] = ReadinessCheck{OK: true}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// checkDocker verifies the Docker daemon responds
+func (e *apiEnv) checkDocker(ctx context.Context) error {
+	if e.docker == nil {
+		return fmt.Errorf("docker client not configured")
+	}
+	_, err := e.docker.Ping(ctx, client.PingOptions{})
+	return err
+}
+
+// checkXDS verifies the xDS server is listening and has pushed a snapshot
+func (e *apiEnv) checkXDS() error {
+	if e.xds == nil {
+		return fmt.Errorf("xds server not configured")
+	}
+	status := e.xds.Status()
+	if !status.Listening {
+		return fmt.Errorf("server not listening")
+	}
+	if status.SnapshotVersion == "" {
+		return fmt.Errorf("no snapshot pushed")
+	}
+	return nil
+}
+
+// checkEnvoy verifies the Envoy container is running
+func (e *apiEnv) checkEnvoy(ctx context.Context) error {
+	if e.envoy == nil {
+		return fmt.Errorf("envoy manager not configured")
+	}
+	state, err := e.envoy.ContainerState(ctx)
+	if err != nil {
+		return err
+	}
+	if state == "" {
+		return fmt.Errorf("container not found")
+	}
+	if state != "running" {
+		return fmt.Errorf("container %s", state)
+	}
+	return nil
+}
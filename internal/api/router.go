@@ -3,16 +3,24 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/audit"
 	"zeropoint-agent/internal/boot"
 	"zeropoint-agent/internal/catalog"
+	"zeropoint-agent/internal/config"
+	"zeropoint-agent/internal/envoy"
+	"zeropoint-agent/internal/metrics"
 	"zeropoint-agent/internal/modules"
 	"zeropoint-agent/internal/queue"
 	"zeropoint-agent/internal/xds"
@@ -23,6 +31,9 @@ import (
 
 type apiEnv struct {
 	docker    *client.Client
+	envoy     *envoy.Manager
+	xds       *xds.Server
+	jobs      *queue.Manager
 	modules   *ModuleHandlers
 	exposures *ExposureHandlers
 	inspect   *InspectHandlers
@@ -38,52 +49,131 @@ type HealthResponse struct {
 	Error  string `json:"error,omitempty"`
 }
 
-func NewRouter(dockerClient *client.Client, xdsServer *xds.Server, mdnsService MDNSService, bootMonitor *boot.BootMonitor, logger *slog.Logger) (http.Handler, error) {
+// ComponentHealth is the health of a single subsystem in the aggregate health report
+type ComponentHealth struct {
+	Status string `json:"status"` // ok, degraded, down
+	Detail string `json:"detail,omitempty"`
+}
+
+// AggregateHealthResponse is returned by GET /healthz
+type AggregateHealthResponse struct {
+	Status             string                     `json:"status"` // ok, degraded, down
+	Components         map[string]ComponentHealth `json:"components"`
+	FailedJobsLastHour int                        `json:"failed_jobs_last_hour"`
+}
+
+// reservedHostPorts lists the TCP ports the agent and Envoy listen on, which exposures
+// cannot request
+func reservedHostPorts(cfg *config.Config) map[uint32]string {
+	ports := map[uint32]string{
+		uint32(cfg.Port):            "the agent API",
+		uint32(cfg.Envoy.HTTPPort):  "Envoy HTTP",
+		uint32(cfg.Envoy.HTTPSPort): "Envoy HTTPS",
+		uint32(cfg.Envoy.XDSPort):   "the xDS server",
+	}
+	if _, port, err := net.SplitHostPort(cfg.Envoy.AdminAddr); err == nil {
+		if n, err := strconv.Atoi(port); err == nil {
+			ports[uint32(n)] = "the Envoy admin interface"
+		}
+	}
+	return ports
+}
+
+// NewRouter builds the API router and the job worker from the agent configuration. The
+// worker is returned unstarted; the caller starts it and drains it on shutdown.
+func NewRouter(cfg *config.Config, dockerClient *client.Client, envoyMgr *envoy.Manager, xdsServer *xds.Server, mdnsService MDNSService, bootMonitor *boot.BootMonitor, logger *slog.Logger) (http.Handler, *queue.Worker, error) {
 	modulesDir := internalPaths.GetModulesDir()
 
-	installer := modules.NewInstaller(dockerClient, modulesDir, logger)
+	credentialStore, err := modules.NewCredentialStore(logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize credential store: %w", err)
+	}
+
+	installer := modules.NewInstaller(dockerClient, modulesDir, credentialStore, logger)
 	uninstaller := modules.NewUninstaller(dockerClient, modulesDir, logger)
 
 	// Initialize exposure store
-	exposureStore, err := NewExposureStore(dockerClient, xdsServer, mdnsService, logger)
+	exposureStore, err := NewExposureStore(dockerClient, xdsServer, mdnsService, envoyMgr.AdminAddress(), cfg, logger)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	exposureStore.ReservePorts(reservedHostPorts(cfg))
+	exposureStore.StartUpstreamWatch(context.Background(), cfg.Envoy.DownUpstreamResponse)
 
 	// Initialize link store
 	linkStore, err := NewLinkStore(dockerClient, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize link store: %w", err)
+		return nil, nil, fmt.Errorf("failed to initialize link store: %w", err)
 	}
 
+	// Remove networks left behind by modules and links that no longer exist
+	networkGC := NewNetworkGC(modulesDir, linkStore, logger)
+	networkGC.collectAtStartup(context.Background())
+
 	// Initialize bundle store
 	bundleStore, err := NewBundleStore(logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize bundle store: %w", err)
+		return nil, nil, fmt.Errorf("failed to initialize bundle store: %w", err)
 	}
 
 	// Initialize catalog
-	catalogStore := catalog.NewStore(logger)
+	catalogStore := catalog.NewStore(cfg.Catalog.IndexURL, time.Duration(cfg.Catalog.SyncInterval), logger)
 	catalogResolver := catalog.NewResolver(catalogStore)
 	catalogHandlers := catalog.NewHandlers(catalogStore, catalogResolver, logger)
+	catalogStore.StartSync(context.Background())
 
 	// Initialize job queue manager
-	jobsDir := filepath.Join(internalPaths.GetStorageRoot(), "jobs")
-	queueManager, err := queue.NewManager(jobsDir, logger)
+	queueManager, err := queue.NewManager(cfg.JobsDir, cfg.Retention.MaxJobEvents, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize job queue: %w", err)
+		return nil, nil, fmt.Errorf("failed to initialize job queue: %w", err)
+	}
+	// Git tokens must not reach job logs through clone errors or terraform output
+	queueManager.SetRedactor(credentialStore.Redact)
+	queueManager.SetCallbackSecret(cfg.CallbackSecret)
+	if err := queueManager.SetCallbackAllowedNetworks(cfg.CallbackAllowedNetworks); err != nil {
+		return nil, nil, err
 	}
 
-	moduleHandlers := NewModuleHandlers(installer, uninstaller, dockerClient, logger)
+	moduleHandlers := NewModuleHandlers(installer, uninstaller, dockerClient, linkStore, logger)
+	exposureStore.SetModulePorts(moduleHandlers, cfg.ExposurePortCheck)
 	exposureHandlers := NewExposureHandlers(exposureStore, logger)
 	inspectHandlers := NewInspectHandlers(modulesDir, logger)
 	linkHandlers := NewLinkHandlers(modulesDir, linkStore, logger)
-	bundleHandlers := NewBundleHandlers(bundleStore, exposureStore, exposureHandlers, linkHandlers, uninstaller, logger)
+	moduleHandlers.SetLinkHandlers(linkHandlers)
+	bundleHandlers := NewBundleHandlers(bundleStore, exposureStore, exposureHandlers, linkHandlers, uninstaller, queueManager, logger)
 	bootHandlers := NewBootHandlers(bootMonitor)
-	queueHandlers := queue.NewHandlers(queueManager, catalogStore, bundleStore, logger)
+	storageHandlers := NewStorageHandlers(dockerClient, logger)
+	stateHandlers := NewStateHandlers(modulesDir, linkStore, exposureStore, bundleStore, queueManager, logger)
+	queueHandlers := queue.NewHandlers(queueManager, catalogStore, bundleStore, credentialStore, logger)
+	queueHandlers.SetListEvents(cfg.Retention.ListJobEvents)
+	credentialHandlers := NewCredentialHandlers(credentialStore, logger)
+
+	// Commands run in module containers through jobs unless the deployment forbids it
+	var moduleExec queue.ModuleExec = moduleHandlers
+	if cfg.ModuleExecDisabled {
+		queueHandlers.DisableModuleExec()
+		moduleExec = nil
+		logger.Info("module exec disabled by configuration")
+	}
+	configHandlers := NewConfigHandlers(cfg)
+
+	tokenStore, err := NewTokenStore(cfg.APITokenFile, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize API token store: %w", err)
+	}
+	authHandlers := NewAuthHandlers(tokenStore, logger)
+
+	auditLog, err := audit.NewLog(cfg.Retention.AuditMaxBytes, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize audit log: %w", err)
+	}
+	auditHandlers := audit.NewHandlers(auditLog, logger)
 
 	env := &apiEnv{
 		docker:    dockerClient,
+		envoy:     envoyMgr,
+		xds:       xdsServer,
+		jobs:      queueManager,
 		modules:   moduleHandlers,
 		exposures: exposureHandlers,
 		inspect:   inspectHandlers,
@@ -100,6 +190,7 @@ func NewRouter(dockerClient *client.Client, xdsServer *xds.Server, mdnsService M
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Always allow health, boot endpoints, and static files/index
 			if r.URL.Path == "/api/health" ||
+				r.URL.Path == "/api/healthz" ||
 				strings.HasPrefix(r.URL.Path, "/api/boot/") ||
 				r.URL.Path == "/api/boot" ||
 				r.URL.Path == "/" ||
@@ -127,58 +218,127 @@ func NewRouter(dockerClient *client.Client, xdsServer *xds.Server, mdnsService M
 	// API routes MUST be registered before the static file server
 	// Health endpoint
 	r.HandleFunc("/api/health", env.healthHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/healthz", env.aggregateHealthHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/envoy/status", env.envoyStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/envoy/logs", env.envoyLogsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/envoy/access-logs", env.envoyAccessLogsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/envoy/stats", env.envoyStatsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/envoy/clusters", env.envoyClustersHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/envoy/config-status", env.envoyConfigStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/envoy/resync", env.envoyResyncHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/envoy/restart", env.envoyRestartHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/xds/rollback", env.xdsRollbackHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/storage/usage", storageHandlers.GetStorageUsage).Methods(http.MethodGet)
+	r.HandleFunc("/api/config", configHandlers.GetConfig).Methods(http.MethodGet)
+	r.HandleFunc("/api/system/info", env.systemInfoHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/system/networks/gc", networkGC.HandleNetworkGC).Methods(http.MethodPost)
+	r.HandleFunc("/api/system/export", stateHandlers.ExportState).Methods(http.MethodGet)
+	r.HandleFunc("/api/system/import", stateHandlers.ImportState).Methods(http.MethodPost)
+
+	// Orchestrator probes live at the root so they bypass the boot check and static files
+	r.HandleFunc("/healthz", env.livenessHandler).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", env.readinessHandler).Methods(http.MethodGet)
+
+	// Prometheus metrics, at the root where scrapers look for them
+	metrics.RegisterQueue(queueManager.Stats)
+	metrics.RegisterExposures(exposureStore.CountByProtocol)
+	metrics.RegisterEnvoy(envoyMgr.IsRunning)
+	r.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 
 	// Boot monitoring endpoints (always available)
 	r.HandleFunc("/api/boot/status", bootHandlers.HandleBootStatus).Methods(http.MethodGet)
 	r.HandleFunc("/api/boot/logs", bootHandlers.HandleBootLogs).Methods(http.MethodGet)
-	r.HandleFunc("/api/boot/stream", bootHandlers.HandleBootStream)
+	r.HandleFunc("/api/boot/stream", bootHandlers.HandleBootStream).Methods(http.MethodGet)
 	// Per-service and marker endpoints
+	r.HandleFunc("/api/boot/services", bootHandlers.HandleBootServices).Methods(http.MethodGet)
+	r.HandleFunc("/api/boot/services/{service}", bootHandlers.HandleBootService).Methods(http.MethodGet)
 	r.HandleFunc("/api/boot/status/{service}", bootHandlers.HandleBootService).Methods(http.MethodGet)
 	r.HandleFunc("/api/boot/status/{service}/{marker}", bootHandlers.HandleBootMarker).Methods(http.MethodGet)
 
-	// Module endpoints
+	r.HandleFunc("/api/audit", auditHandlers.HandleList).Methods(http.MethodGet)
+
+	// API token endpoints
+	r.HandleFunc("/api/auth/tokens", authHandlers.ListTokens).Methods(http.MethodGet)
+	r.HandleFunc("/api/auth/tokens", authHandlers.CreateToken).Methods(http.MethodPost)
+	r.HandleFunc("/api/auth/tokens/{id}", authHandlers.DeleteToken).Methods(http.MethodDelete)
+
+	// Module endpoints (credentials first so they are not taken for a module name)
+	r.HandleFunc("/api/modules/credentials", credentialHandlers.ListCredentials).Methods(http.MethodGet)
+	r.HandleFunc("/api/modules/credentials", credentialHandlers.SetCredential).Methods(http.MethodPost)
+	r.HandleFunc("/api/modules/credentials/{host}", credentialHandlers.DeleteCredential).Methods(http.MethodDelete)
 	r.HandleFunc("/api/modules", moduleHandlers.ListModules).Methods(http.MethodGet)
+	r.HandleFunc("/api/modules/{name}", moduleHandlers.GetModule).Methods(http.MethodGet)
 	r.HandleFunc("/api/modules/{name}", moduleHandlers.InstallModule).Methods(http.MethodPost)
 	r.HandleFunc("/api/modules/{name}", moduleHandlers.UninstallModule).Methods(http.MethodDelete)
 	r.HandleFunc("/api/modules/{module_id}/inspect", inspectHandlers.InspectModule).Methods(http.MethodGet)
+	r.HandleFunc("/api/modules/{name}/health", moduleHandlers.GetModuleHealth).Methods(http.MethodGet)
+	r.HandleFunc("/api/modules/{name}/logs", moduleHandlers.GetModuleLogs).Methods(http.MethodGet)
+	r.HandleFunc("/api/modules/{name}/restart", moduleHandlers.RestartModule).Methods(http.MethodPost)
+	r.HandleFunc("/api/modules/{name}/stop", moduleHandlers.StopModule).Methods(http.MethodPost)
+	r.HandleFunc("/api/modules/{name}/start", moduleHandlers.StartModule).Methods(http.MethodPost)
+	r.HandleFunc("/api/modules/{name}/verify", moduleHandlers.VerifyModule).Methods(http.MethodGet)
+	r.HandleFunc("/api/modules/{name}/outputs", moduleHandlers.GetModuleOutputs).Methods(http.MethodGet)
+	r.HandleFunc("/api/modules/{name}/resources", queueHandlers.UpdateModuleResources).Methods(http.MethodPatch)
 
 	// Link endpoints
 	r.HandleFunc("/api/links", linkHandlers.ListLinks).Methods(http.MethodGet)
 	r.HandleFunc("/api/links/{id}", linkHandlers.GetLink).Methods(http.MethodGet)
+	r.HandleFunc("/api/links/{id}/graph", linkHandlers.GetLinkGraph).Methods(http.MethodGet)
 	r.HandleFunc("/api/links/{id}", linkHandlers.CreateOrUpdateLink).Methods(http.MethodPost)
+	r.HandleFunc("/api/links/{id}", linkHandlers.PatchLink).Methods(http.MethodPatch)
 	r.HandleFunc("/api/links/{id}", linkHandlers.DeleteLinkHTTP).Methods(http.MethodDelete)
+	r.HandleFunc("/api/links/{id}/refresh", linkHandlers.RefreshLink).Methods(http.MethodPost)
 
 	// Exposure endpoints
 	r.HandleFunc("/api/exposures", exposureHandlers.ListExposures).Methods(http.MethodGet)
+	r.HandleFunc("/api/exposures/reconcile", exposureHandlers.ReconcileExposures).Methods(http.MethodPost) // Before the {exposure_id} routes
+	r.HandleFunc("/api/exposures/check", exposureHandlers.CheckHostname).Methods(http.MethodGet)
 	r.HandleFunc("/api/exposures/{exposure_id}", exposureHandlers.CreateExposureHTTP).Methods(http.MethodPost)
 	r.HandleFunc("/api/exposures/{exposure_id}", exposureHandlers.GetExposure).Methods(http.MethodGet)
+	r.HandleFunc("/api/exposures/{exposure_id}", exposureHandlers.UpdateExposureHTTP).Methods(http.MethodPut)
 	r.HandleFunc("/api/exposures/{exposure_id}", exposureHandlers.DeleteExposureHTTP).Methods(http.MethodDelete)
+	r.HandleFunc("/api/exposures/{exposure_id}/stats", exposureHandlers.GetExposureStats).Methods(http.MethodGet)
 
 	// Bundle endpoints
 	r.HandleFunc("/api/bundles", bundleHandlers.ListBundles).Methods(http.MethodGet)
 	r.HandleFunc("/api/bundles/{bundle-id}", bundleHandlers.GetBundle).Methods(http.MethodGet)
+	r.HandleFunc("/api/bundles/{bundle-id}/status", bundleHandlers.GetBundleStatus).Methods(http.MethodGet)
 	r.HandleFunc("/api/bundles/{bundle-id}", bundleHandlers.DeleteBundle).Methods(http.MethodDelete)
 
 	// Catalog endpoints
 	r.HandleFunc("/api/catalogs/update", catalogHandlers.HandleUpdateCatalog).Methods(http.MethodPost)
+	r.HandleFunc("/api/catalogs/refresh", catalogHandlers.HandleRefreshCatalog).Methods(http.MethodPost)
+	r.HandleFunc("/api/catalogs/sync", queueHandlers.EnqueueSyncCatalog).Methods(http.MethodPost)
+	r.HandleFunc("/api/catalogs/status", catalogHandlers.HandleSyncStatus).Methods(http.MethodGet)
 	r.HandleFunc("/api/catalogs/modules", catalogHandlers.HandleListModules).Methods(http.MethodGet)
 	r.HandleFunc("/api/catalogs/modules/{module_name}", catalogHandlers.HandleGetModule).Methods(http.MethodGet)
 	r.HandleFunc("/api/catalogs/bundles", catalogHandlers.HandleListBundles).Methods(http.MethodGet)
 	r.HandleFunc("/api/catalogs/bundles/{bundle_name}", catalogHandlers.HandleGetBundle).Methods(http.MethodGet)
+	r.HandleFunc("/api/catalogs/bundles/{bundle_name}/validate", catalogHandlers.HandleValidateBundle).Methods(http.MethodPost)
 
 	// Job Queue endpoints
 	r.HandleFunc("/api/jobs", queueHandlers.ListJobs).Methods(http.MethodGet)
 	r.HandleFunc("/api/jobs", queueHandlers.DeleteJobs).Methods(http.MethodDelete)
+	r.HandleFunc("/api/jobs/cancel", queueHandlers.CancelJobs).Methods(http.MethodPost)
 	r.HandleFunc("/api/jobs/{id}", queueHandlers.GetJob).Methods(http.MethodGet)
+	r.HandleFunc("/api/jobs/{id}/logs", queueHandlers.JobLogs).Methods(http.MethodGet)
+	r.HandleFunc("/api/jobs/{id}/log", queueHandlers.DownloadJobLog).Methods(http.MethodGet)
 	r.HandleFunc("/api/jobs/{id}", queueHandlers.CancelJob).Methods(http.MethodDelete)
+	r.HandleFunc("/api/jobs/{id}/rerun", queueHandlers.RerunJob).Methods(http.MethodPost)
+	r.HandleFunc("/api/jobs/batch", queueHandlers.EnqueueBatch).Methods(http.MethodPost)
 	r.HandleFunc("/api/jobs/enqueue_install_module", queueHandlers.EnqueueInstall).Methods(http.MethodPost)
 	r.HandleFunc("/api/jobs/enqueue_uninstall_module", queueHandlers.EnqueueUninstall).Methods(http.MethodPost)
+	r.HandleFunc("/api/jobs/enqueue_upgrade_module", queueHandlers.EnqueueUpgrade).Methods(http.MethodPost)
+	r.HandleFunc("/api/jobs/enqueue_module_action", queueHandlers.EnqueueModuleAction).Methods(http.MethodPost)
+	r.HandleFunc("/api/jobs/enqueue_module_exec", queueHandlers.EnqueueModuleExec).Methods(http.MethodPost)
 	r.HandleFunc("/api/jobs/enqueue_create_exposure", queueHandlers.EnqueueCreateExposure).Methods(http.MethodPost)
 	r.HandleFunc("/api/jobs/enqueue_delete_exposure", queueHandlers.EnqueueDeleteExposure).Methods(http.MethodPost)
 	r.HandleFunc("/api/jobs/enqueue_create_link", queueHandlers.EnqueueCreateLink).Methods(http.MethodPost)
+	r.HandleFunc("/api/jobs/enqueue_update_link", queueHandlers.EnqueueUpdateLink).Methods(http.MethodPost)
 	r.HandleFunc("/api/jobs/enqueue_delete_link", queueHandlers.EnqueueDeleteLink).Methods(http.MethodPost)
 	r.HandleFunc("/api/jobs/enqueue_install_bundle", queueHandlers.EnqueueBundleInstall).Methods(http.MethodPost)
 	r.HandleFunc("/api/jobs/enqueue_uninstall_bundle", queueHandlers.EnqueueBundleUninstall).Methods(http.MethodPost)
+	r.HandleFunc("/api/jobs/enqueue_upgrade_bundle", queueHandlers.EnqueueBundleUpgrade).Methods(http.MethodPost)
 
 	// Web UI - serve static files as fallback after API routes
 	webDir := getWebDir()
@@ -186,19 +346,23 @@ func NewRouter(dockerClient *client.Client, xdsServer *xds.Server, mdnsService M
 		r.PathPrefix("/").Handler(http.FileServer(http.Dir(webDir)))
 	}
 
-	// Create router with middleware for boot checking
+	// Create router with middleware for boot checking, behind token authentication, with
+	// every mutating request audited
 	routerWithMiddleware := bootCheckMiddleware(r)
+	if cfg.AuthDisabled {
+		logger.Warn("API authentication disabled by configuration")
+	} else {
+		routerWithMiddleware = authMiddleware(tokenStore, routerWithMiddleware)
+	}
+	routerWithMiddleware = audit.Middleware(auditLog, routerWithMiddleware)
 
 	// Initialize job executor with handlers for direct execution
-	jobExecutor := queue.NewJobExecutor(installer, uninstaller, exposureHandlers, linkHandlers, catalogStore, bundleStore, logger)
+	jobExecutor := queue.NewJobExecutor(installer, uninstaller, exposureHandlers, linkHandlers, catalogStore, bundleStore, moduleHandlers, moduleHandlers, moduleExec, logger)
 
-	// Create and start the job worker
 	worker := queue.NewWorker(queueManager, jobExecutor, logger)
-	worker.Start(context.Background())
-	logger.Info("job worker started")
 
 	// Return router with middleware
-	return routerWithMiddleware, nil
+	return routerWithMiddleware, worker, nil
 }
 
 // HealthHandler handles GET /health requests
@@ -227,6 +391,317 @@ func (e *apiEnv) healthHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// aggregateHealthHandler handles GET /healthz requests
+// @ID getAggregateHealth
+// @Summary Aggregate health check
+// @Description Reports the health of the agent, Docker daemon, Envoy container and xDS server, plus failed jobs in the last hour. Returns 503 when Docker or Envoy is down.
+// @Tags system
+// @Produce json
+// @Success 200 {object} AggregateHealthResponse "All critical components are up"
+// @Failure 503 {object} AggregateHealthResponse "A critical component is down"
+// @Router /healthz [get]
+func (e *apiEnv) aggregateHealthHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	resp := AggregateHealthResponse{
+		Status: "ok",
+		Components: map[string]ComponentHealth{
+			"agent": {Status: "ok"},
+		},
+	}
+	critical := true
+
+	// Docker daemon
+	if _, err := e.docker.Ping(ctx, client.PingOptions{}); err != nil {
+		resp.Components["docker"] = ComponentHealth{Status: "down", Detail: err.Error()}
+		critical = false
+	} else {
+		resp.Components["docker"] = ComponentHealth{Status: "ok"}
+	}
+
+	// Envoy container
+	if e.envoy != nil {
+		state, err := e.envoy.ContainerState(ctx)
+		switch {
+		case err != nil:
+			resp.Components["envoy"] = ComponentHealth{Status: "down", Detail: err.Error()}
+			critical = false
+		case state == "":
+			resp.Components["envoy"] = ComponentHealth{Status: "down", Detail: "container not found"}
+			critical = false
+		case state != "running":
+			resp.Components["envoy"] = ComponentHealth{Status: "down", Detail: "container " + state}
+			critical = false
+		default:
+			resp.Components["envoy"] = ComponentHealth{Status: "ok"}
+		}
+	}
+
+	// xDS server
+	if e.xds != nil {
+		status := e.xds.Status()
+		switch {
+		case !status.Listening:
+			resp.Components["xds"] = ComponentHealth{Status: "down", Detail: "server not listening"}
+		case status.SnapshotVersion == "":
+			resp.Components["xds"] = ComponentHealth{Status: "degraded", Detail: "no snapshot pushed"}
+		default:
+			resp.Components["xds"] = ComponentHealth{Status: "ok", Detail: "snapshot " + status.SnapshotVersion}
+		}
+	}
+
+	// Recently failed jobs
+	if e.jobs != nil {
+		failed, err := e.jobs.CountFailedSince(time.Now().Add(-time.Hour))
+		if err != nil {
+			resp.Components["jobs"] = ComponentHealth{Status: "degraded", Detail: err.Error()}
+		} else {
+			resp.FailedJobsLastHour = failed
+			resp.Components["jobs"] = ComponentHealth{Status: "ok"}
+		}
+	}
+
+	for _, c := range resp.Components {
+		if c.Status != "ok" {
+			resp.Status = "degraded"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !critical {
+		resp.Status = "down"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// envoyStatusHandler handles GET /envoy/status requests
+// @ID getEnvoyStatus
+// @Summary Envoy proxy status
+// @Description Returns the Envoy container state as last observed by the health monitor, including recovery counts and the last error
+// @Tags system
+// @Produce json
+// @Success 200 {object} envoy.Status
+// @Router /envoy/status [get]
+func (e *apiEnv) envoyStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e.envoy.GetStatus())
+}
+
+// EnvoyLogsResponse lists captured Envoy log entries
+type EnvoyLogsResponse struct {
+	Entries []envoy.LogEntry `json:"entries"`
+}
+
+// envoyLogsHandler handles GET /envoy/logs requests
+// @ID getEnvoyLogs
+// @Summary Recent Envoy warnings and errors
+// @Description Returns warning, error and critical lines captured from the Envoy container log, oldest first
+// @Tags system
+// @Produce json
+// @Param level query string false "Minimum level: warning, error or critical"
+// @Success 200 {object} EnvoyLogsResponse
+// @Failure 400 {string} string "Invalid level"
+// @Router /envoy/logs [get]
+func (e *apiEnv) envoyLogsHandler(w http.ResponseWriter, r *http.Request) {
+	level := r.URL.Query().Get("level")
+	if level != "" && !envoy.ValidLogLevel(level) {
+		http.Error(w, "level must be one of trace, debug, info, warning, error, critical", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EnvoyLogsResponse{Entries: e.envoy.Logs(level)})
+}
+
+// EnvoyAccessLogsResponse is returned by GET /envoy/access-logs
+type EnvoyAccessLogsResponse struct {
+	Lines []string `json:"lines"` // One JSON object per line unless ZEROPOINT_ENVOY_ACCESS_LOG_FORMAT=text
+}
+
+// envoyAccessLogsHandler handles GET /envoy/access-logs requests
+// @ID getEnvoyAccessLogs
+// @Summary Recent Envoy access log lines
+// @Description Tails the access log Envoy writes to its container stdout, oldest first. Empty when ZEROPOINT_ENVOY_ACCESS_LOG points elsewhere or turns access logging off.
+// @Tags system
+// @Produce json
+// @Param tail query int false "Number of lines (default 100, max 5000)"
+// @Success 200 {object} EnvoyAccessLogsResponse
+// @Failure 400 {string} string "Invalid tail"
+// @Failure 503 {string} string "Envoy container logs unavailable"
+// @Router /envoy/access-logs [get]
+func (e *apiEnv) envoyAccessLogsHandler(w http.ResponseWriter, r *http.Request) {
+	tail := 100
+	if v := r.URL.Query().Get("tail"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 5000 {
+			http.Error(w, "tail must be between 1 and 5000", http.StatusBadRequest)
+			return
+		}
+		tail = n
+	}
+
+	lines, err := e.envoy.AccessLogs(r.Context(), tail)
+	if err != nil {
+		e.logger.Error("failed to read envoy access logs", "error", err)
+		http.Error(w, fmt.Sprintf("failed to read envoy logs: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EnvoyAccessLogsResponse{Lines: lines})
+}
+
+// envoyStatsHandler handles GET /envoy/stats requests
+// @ID getEnvoyStats
+// @Summary Envoy statistics
+// @Description Relays the Envoy admin /stats endpoint. Only the format, filter, usedonly and type query parameters are passed on.
+// @Tags system
+// @Produce json
+// @Produce plain
+// @Param format query string false "Output format, e.g. json or prometheus"
+// @Param filter query string false "Regular expression selecting stat names"
+// @Param usedonly query string false "Only stats that have been updated"
+// @Param type query string false "Stat type: Counters, Gauges, Histograms or TextReadouts"
+// @Success 200 {string} string "Envoy stats"
+// @Failure 503 {string} string "Envoy admin unreachable"
+// @Router /envoy/stats [get]
+func (e *apiEnv) envoyStatsHandler(w http.ResponseWriter, r *http.Request) {
+	e.relayEnvoyAdmin(w, r, "/stats")
+}
+
+// envoyClustersHandler handles GET /envoy/clusters requests
+// @ID getEnvoyClusters
+// @Summary Envoy upstream clusters
+// @Description Relays the Envoy admin /clusters endpoint with cluster membership, health and connection counts per upstream host
+// @Tags system
+// @Produce json
+// @Produce plain
+// @Param format query string false "Output format: json or text"
+// @Success 200 {string} string "Envoy clusters"
+// @Failure 503 {string} string "Envoy admin unreachable"
+// @Router /envoy/clusters [get]
+func (e *apiEnv) envoyClustersHandler(w http.ResponseWriter, r *http.Request) {
+	e.relayEnvoyAdmin(w, r, "/clusters")
+}
+
+// relayEnvoyAdmin writes the Envoy admin response for path back to the client
+func (e *apiEnv) relayEnvoyAdmin(w http.ResponseWriter, r *http.Request, path string) {
+	resp, err := e.envoy.AdminGet(r.Context(), path, r.URL.Query())
+	if err != nil {
+		e.logger.Error("failed to query envoy admin", "path", path, "error", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	if resp.ContentType != "" {
+		w.Header().Set("Content-Type", resp.ContentType)
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(resp.Body)
+}
+
+// envoyConfigStatusHandler handles GET /envoy/config-status requests
+// @ID getEnvoyConfigStatus
+// @Summary Envoy configuration acceptance
+// @Description Reports whether Envoy accepted the latest xDS snapshot, with the last acknowledged version and last rejection per resource type
+// @Tags system
+// @Produce json
+// @Success 200 {object} xds.ConfigStatus
+// @Router /envoy/config-status [get]
+func (e *apiEnv) envoyConfigStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e.xds.ConfigStatus())
+}
+
+// envoyResyncHandler handles POST /envoy/resync requests
+// @ID resyncEnvoy
+// @Summary Re-push the Envoy configuration
+// @Description Rebuilds the xDS snapshot from the stored exposures and pushes it under a new version, even if nothing changed
+// @Tags system
+// @Produce json
+// @Success 200 {object} xds.Status
+// @Failure 500 {string} string "Failed to push snapshot"
+// @Router /envoy/resync [post]
+func (e *apiEnv) envoyResyncHandler(w http.ResponseWriter, r *http.Request) {
+	if err := e.exposures.store.ForceSync(r.Context()); err != nil {
+		e.logger.Error("failed to resync envoy", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e.xds.Status())
+}
+
+// EnvoyRestartResponse is returned by POST /envoy/restart
+type EnvoyRestartResponse struct {
+	State string `json:"state"` // Docker state of the new container
+}
+
+// envoyRestartHandler handles POST /envoy/restart requests
+// @ID restartEnvoy
+// @Summary Recreate the Envoy container
+// @Description Replaces the Envoy container with a new one using the configured image, ports and bootstrap config, then re-pushes the configuration. The previous container is restored if the new one fails to start.
+// @Tags system
+// @Produce json
+// @Success 200 {object} EnvoyRestartResponse
+// @Failure 500 {string} string "Failed to recreate container"
+// @Router /envoy/restart [post]
+func (e *apiEnv) envoyRestartHandler(w http.ResponseWriter, r *http.Request) {
+	if err := e.envoy.Recreate(r.Context()); err != nil {
+		e.logger.Error("failed to recreate envoy", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The new Envoy fetches the current snapshot on connect; a fresh version makes sure of it
+	if err := e.xds.Resync(r.Context()); err != nil {
+		e.logger.Warn("failed to resync envoy after restart", "error", err)
+	}
+
+	state, err := e.envoy.ContainerState(r.Context())
+	if err != nil {
+		e.logger.Warn("failed to read envoy container state", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EnvoyRestartResponse{State: state})
+}
+
+// XDSRollbackResponse describes a snapshot rollback
+type XDSRollbackResponse struct {
+	RestoredVersion string `json:"restored_version"` // Earlier snapshot that Envoy had accepted
+	Version         string `json:"version"`          // Version the restored snapshot was pushed as
+}
+
+// xdsRollbackHandler handles POST /xds/rollback requests
+// @ID rollbackXDSSnapshot
+// @Summary Roll back the Envoy configuration
+// @Description Re-pushes the most recent earlier snapshot that Envoy accepted. The next exposure change rebuilds the configuration from stored exposures again.
+// @Tags system
+// @Produce json
+// @Success 200 {object} XDSRollbackResponse
+// @Failure 409 {string} string "No earlier accepted snapshot"
+// @Failure 500 {string} string "Failed to push snapshot"
+// @Router /xds/rollback [post]
+func (e *apiEnv) xdsRollbackHandler(w http.ResponseWriter, r *http.Request) {
+	restored, version, err := e.xds.RollbackSnapshot(r.Context())
+	if errors.Is(err, xds.ErrNoGoodSnapshot) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		e.logger.Error("failed to roll back xDS snapshot", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(XDSRollbackResponse{RestoredVersion: restored, Version: version})
+}
+
 // getWebDir finds the web UI directory
 func getWebDir() string {
 	// Try relative to executable
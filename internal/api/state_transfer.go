@@ -0,0 +1,806 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"zeropoint-agent/internal/audit"
+	"zeropoint-agent/internal/catalog"
+	"zeropoint-agent/internal/modules"
+	"zeropoint-agent/internal/queue"
+)
+
+// stateDocumentVersion is bumped whenever StateDocument changes incompatibly
+const stateDocumentVersion = 1
+
+// Conflict policies for importing items that already exist on this host
+const (
+	conflictSkip      = "skip"
+	conflictOverwrite = "overwrite"
+)
+
+// StateDocument is the declarative state of an agent, as exported by GET /system/export
+// and accepted by POST /system/import. It holds what is needed to recreate modules, links,
+// exposures and bundles on another host, not their runtime state or data. Secrets are
+// redacted: basic auth hashes of exposures, and link configuration values under field
+// names the audit log redacts.
+type StateDocument struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Modules    []ExportedModule `json:"modules"`
+	Links      []ExportedLink   `json:"links"`
+	Exposures  []*Exposure      `json:"exposures"`
+	Bundles    []ExportedBundle `json:"bundles"`
+	Warnings   []string         `json:"warnings,omitempty"` // State that could not be exported
+}
+
+// ExportedModule is an installed module and the git revision it was installed from
+type ExportedModule struct {
+	ID            string             `json:"id"`
+	Source        string             `json:"source"` // Git URL with commit SHA (e.g., https://github.com/org/repo.git@<sha>)
+	Tags          []string           `json:"tags,omitempty"`
+	CredentialRef string             `json:"credential_ref,omitempty"` // Must exist in the importing host's credential store
+	Resources     *modules.Resources `json:"resources,omitempty"`
+}
+
+// ExportedLink is a link and the module configuration it applies
+type ExportedLink struct {
+	ID      string                            `json:"id"`
+	Modules map[string]map[string]interface{} `json:"modules"`
+	Tags    []string                          `json:"tags,omitempty"`
+}
+
+// ExportedBundle is an installed bundle and the components it owns
+type ExportedBundle struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Definition *catalog.CatalogBundle `json:"definition,omitempty"`
+	Modules    []string               `json:"modules,omitempty"`
+	Links      []string               `json:"links,omitempty"`
+	Exposures  []string               `json:"exposures,omitempty"`
+}
+
+// ImportRequest is the body of POST /system/import
+type ImportRequest struct {
+	Document   StateDocument `json:"document"`
+	OnConflict string        `json:"on_conflict,omitempty"` // "skip" (default) or "overwrite"
+	DryRun     bool          `json:"dry_run,omitempty"`     // Return the plan without enqueueing anything
+}
+
+// ImportItem is what an import does with one item of the document
+type ImportItem struct {
+	Kind   string   `json:"kind"`             // module, link, exposure or bundle
+	ID     string   `json:"id"`               // Item ID
+	Action string   `json:"action"`           // create, overwrite, skip or error
+	Reason string   `json:"reason,omitempty"` // Why the item is skipped or cannot be imported
+	Jobs   []string `json:"jobs,omitempty"`   // Job types enqueued for the item, in order
+	After  []string `json:"after,omitempty"`  // Items whose jobs must finish first, as kind/id
+	JobIDs []string `json:"job_ids,omitempty"`
+}
+
+// ImportResponse is returned by POST /system/import
+type ImportResponse struct {
+	DryRun bool         `json:"dry_run"`
+	Items  []ImportItem `json:"items"`
+	Jobs   int          `json:"jobs"` // Jobs enqueued, or that would be on a dry run
+}
+
+// StateHandlers exports the agent's declarative state and imports it on another host
+type StateHandlers struct {
+	appsDir       string
+	linkStore     *LinkStore
+	exposureStore *ExposureStore
+	bundleStore   *BundleStore
+	manager       *queue.Manager
+	logger        *slog.Logger
+}
+
+// NewStateHandlers creates state export and import handlers
+func NewStateHandlers(appsDir string, linkStore *LinkStore, exposureStore *ExposureStore, bundleStore *BundleStore, manager *queue.Manager, logger *slog.Logger) *StateHandlers {
+	return &StateHandlers{
+		appsDir:       appsDir,
+		linkStore:     linkStore,
+		exposureStore: exposureStore,
+		bundleStore:   bundleStore,
+		manager:       manager,
+		logger:        logger,
+	}
+}
+
+// ExportState handles GET /system/export
+// @ID exportState
+// @Summary Export declarative agent state
+// @Description Returns a versioned document of installed modules with their sources and commit SHAs, links, exposures and bundles, for POST /system/import on another host. Module data, terraform state and git credentials are not included; basic auth hashes and secret link configuration values are redacted. Modules installed from a local path cannot be recreated and are listed under warnings. Requires an admin token.
+// @Tags system
+// @Produce json
+// @Success 200 {object} StateDocument
+// @Failure 500 {string} string "Internal server error"
+// @Router /system/export [get]
+func (h *StateHandlers) ExportState(w http.ResponseWriter, r *http.Request) {
+	doc, err := h.Export()
+	if err != nil {
+		h.logger.Error("failed to export state", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// Export builds the state document of this host
+func (h *StateHandlers) Export() (*StateDocument, error) {
+	doc := &StateDocument{
+		Version:    stateDocumentVersion,
+		ExportedAt: time.Now().UTC(),
+		Modules:    []ExportedModule{},
+		Links:      []ExportedLink{},
+		Exposures:  []*Exposure{},
+		Bundles:    []ExportedBundle{},
+	}
+
+	moduleIDs, err := h.installedModuleIDs()
+	if err != nil {
+		return nil, err
+	}
+	for _, moduleID := range moduleIDs {
+		metadata, err := modules.LoadMetadata(filepath.Join(h.appsDir, moduleID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load metadata of module %s: %w", moduleID, err)
+		}
+		if metadata == nil || metadata.Ref == "" {
+			doc.Warnings = append(doc.Warnings, fmt.Sprintf("module %s was not installed from git and is not exported", moduleID))
+			continue
+		}
+		doc.Modules = append(doc.Modules, ExportedModule{
+			ID:            moduleID,
+			Source:        metadata.Source + "@" + metadata.Ref,
+			Tags:          metadata.Tags,
+			CredentialRef: metadata.CredentialRef,
+			Resources:     metadata.Resources,
+		})
+	}
+
+	for _, link := range h.linkStore.ListLinks() {
+		config, err := redactedLinkConfig(link.Modules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export link %s: %w", link.ID, err)
+		}
+		doc.Links = append(doc.Links, ExportedLink{ID: link.ID, Modules: config, Tags: link.Tags})
+	}
+	sort.Slice(doc.Links, func(i, j int) bool { return doc.Links[i].ID < doc.Links[j].ID })
+
+	for _, exposure := range h.exposureStore.ListExposures() {
+		exported := *exposure
+		if exposure.Auth != nil {
+			users := make([]string, 0, len(exposure.Auth.Users))
+			for _, user := range exposure.Auth.usernames() {
+				users = append(users, user+":"+audit.RedactedValue)
+			}
+			exported.Auth = &ExposureAuth{Users: users}
+		}
+		doc.Exposures = append(doc.Exposures, &exported)
+	}
+
+	for _, bundle := range h.bundleStore.ListBundles() {
+		doc.Bundles = append(doc.Bundles, ExportedBundle{
+			ID:         bundle.ID,
+			Name:       bundle.Name,
+			Definition: bundle.Definition,
+			Modules:    ownedComponents(bundle.Components.Modules),
+			Links:      ownedComponents(bundle.Components.Links),
+			Exposures:  ownedComponents(bundle.Components.Exposures),
+		})
+	}
+	sort.Slice(doc.Bundles, func(i, j int) bool { return doc.Bundles[i].ID < doc.Bundles[j].ID })
+
+	return doc, nil
+}
+
+// redactedLinkConfig returns a copy of a link's module configuration with secret values
+// redacted
+func redactedLinkConfig(config map[string]map[string]interface{}) (map[string]map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var copied map[string]interface{}
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+	audit.Redact(copied)
+
+	redacted := make(map[string]map[string]interface{}, len(copied))
+	for moduleID, values := range copied {
+		redacted[moduleID], _ = values.(map[string]interface{})
+	}
+	return redacted, nil
+}
+
+// hasRedactedValue reports whether a decoded JSON value still holds a redacted secret
+func hasRedactedValue(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return strings.Contains(v, audit.RedactedValue)
+	case map[string]interface{}:
+		for _, field := range v {
+			if hasRedactedValue(field) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if hasRedactedValue(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ownedComponents returns the IDs of bundle components that were not deleted since
+func ownedComponents(components []BundleComponentStatus) []string {
+	var ids []string
+	for _, component := range components {
+		if component.Status != "deleted" {
+			ids = append(ids, component.ID)
+		}
+	}
+	return ids
+}
+
+// installedModuleIDs lists module directories that contain a main.tf, sorted by ID
+func (h *StateHandlers) installedModuleIDs() ([]string, error) {
+	entries, err := os.ReadDir(h.appsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read modules directory: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() && h.moduleInstalled(entry.Name()) {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (h *StateHandlers) moduleInstalled(moduleID string) bool {
+	_, err := os.Stat(filepath.Join(h.appsDir, moduleID, "main.tf"))
+	return err == nil
+}
+
+// ImportState handles POST /system/import
+// @ID importState
+// @Summary Import declarative agent state
+// @Description Validates a document from GET /system/export and enqueues install_module, create_link and create_exposure jobs to recreate it, each waiting for the modules it needs. Bundles get a bundle_install job tracking their components. Items that already exist are skipped, or with on_conflict=overwrite upgraded, updated or recreated; every item's action is reported. Items that cannot be imported, such as an exposure whose hostname another exposure uses, are reported with action "error" and nothing is enqueued for them. With dry_run nothing is enqueued.
+// @Tags system
+// @Accept json
+// @Produce json
+// @Param dry_run query bool false "Return the plan without enqueueing anything"
+// @Param body body ImportRequest true "State document and conflict policy"
+// @Success 200 {object} ImportResponse "Dry run plan"
+// @Success 201 {object} ImportResponse "Jobs enqueued"
+// @Failure 400 {string} string "Invalid document"
+// @Failure 500 {string} string "Internal server error"
+// @Router /system/import [post]
+func (h *StateHandlers) ImportState(w http.ResponseWriter, r *http.Request) {
+	var req ImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("dry_run") == "true" {
+		req.DryRun = true
+	}
+
+	switch req.OnConflict {
+	case "":
+		req.OnConflict = conflictSkip
+	case conflictSkip, conflictOverwrite:
+	default:
+		http.Error(w, "on_conflict must be \"skip\" or \"overwrite\"", http.StatusBadRequest)
+		return
+	}
+
+	if problems := validateStateDocument(&req.Document); len(problems) > 0 {
+		http.Error(w, "invalid document: "+strings.Join(problems, "; "), http.StatusBadRequest)
+		return
+	}
+
+	plan := h.planImport(&req.Document, req.OnConflict)
+	resp := &ImportResponse{DryRun: req.DryRun, Items: plan.items, Jobs: len(plan.jobs)}
+
+	status := http.StatusOK
+	if !req.DryRun && len(plan.jobs) > 0 {
+		if err := h.enqueuePlan(plan); err != nil {
+			h.logger.Error("failed to enqueue import", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// validateStateDocument returns every problem that makes the document unusable as a whole
+func validateStateDocument(doc *StateDocument) []string {
+	var problems []string
+	if doc.Version != stateDocumentVersion {
+		problems = append(problems, fmt.Sprintf("unsupported version %d, expected %d", doc.Version, stateDocumentVersion))
+	}
+
+	seen := make(map[string]bool)
+	checkID := func(kind, id string) bool {
+		if id == "" {
+			problems = append(problems, kind+" without id")
+			return false
+		}
+		if seen[kind+"/"+id] {
+			problems = append(problems, fmt.Sprintf("duplicate %s %s", kind, id))
+			return false
+		}
+		seen[kind+"/"+id] = true
+		return true
+	}
+
+	for _, module := range doc.Modules {
+		if !checkID("module", module.ID) {
+			continue
+		}
+		if err := modules.ValidateSource(module.Source); err != nil {
+			problems = append(problems, fmt.Sprintf("module %s: %v", module.ID, err))
+		}
+		if err := module.Resources.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("module %s: %v", module.ID, err))
+		}
+	}
+	for _, link := range doc.Links {
+		if checkID("link", link.ID) && len(link.Modules) == 0 {
+			problems = append(problems, fmt.Sprintf("link %s has no modules", link.ID))
+		}
+	}
+	for _, exposure := range doc.Exposures {
+		if exposure == nil {
+			problems = append(problems, "null exposure")
+			continue
+		}
+		if checkID("exposure", exposure.ID) && (exposure.ModuleID == "" || exposure.Protocol == "" || exposure.ContainerPort == 0) {
+			problems = append(problems, fmt.Sprintf("exposure %s: module_id, protocol and container_port are required", exposure.ID))
+		}
+	}
+	for _, bundle := range doc.Bundles {
+		checkID("bundle", bundle.ID)
+	}
+	return problems
+}
+
+// importPlan is the outcome of planning an import: what happens to each item, and the
+// jobs to enqueue in order
+type importPlan struct {
+	items []ImportItem
+	jobs  []plannedJob
+}
+
+// plannedJob is a job an import will enqueue; dependencies refer to earlier planned jobs
+type plannedJob struct {
+	item      int // Index of the item in importPlan.items
+	cmd       queue.Command
+	dependsOn []int
+	bundle    *ExportedBundle   // Set on bundle_install jobs, whose bundle record is created once enqueued
+	statuses  map[string]string // Initial status of each bundle component, keyed by kind/id
+}
+
+// add appends a job for an item and returns its index
+func (p *importPlan) add(item int, cmd queue.Command, dependsOn []int) int {
+	p.jobs = append(p.jobs, plannedJob{item: item, cmd: cmd, dependsOn: dependsOn})
+	p.items[item].Jobs = append(p.items[item].Jobs, string(cmd.Type))
+	return len(p.jobs) - 1
+}
+
+// planImport decides what to do with every item of a validated document, given what
+// already exists on this host
+func (h *StateHandlers) planImport(doc *StateDocument, onConflict string) *importPlan {
+	plan := &importPlan{items: []ImportItem{}}
+	newItem := func(kind, id string) int {
+		plan.items = append(plan.items, ImportItem{Kind: kind, ID: id})
+		return len(plan.items) - 1
+	}
+
+	// Jobs that create each component, and components that will exist without one
+	componentJob := make(map[string]int)
+	available := make(map[string]bool)
+
+	// Modules install one after another, like bundle installs, so terraform runs and
+	// image pulls don't compete
+	var moduleJobs []int
+	docModules := append([]ExportedModule(nil), doc.Modules...)
+	sort.Slice(docModules, func(i, j int) bool { return docModules[i].ID < docModules[j].ID })
+	for _, module := range docModules {
+		idx := newItem("module", module.ID)
+		item := &plan.items[idx]
+
+		var cmd queue.Command
+		if h.moduleInstalled(module.ID) {
+			available["module/"+module.ID] = true
+			if onConflict == conflictSkip {
+				item.Action, item.Reason = "skip", "module is already installed"
+				continue
+			}
+			metadata, err := modules.LoadMetadata(filepath.Join(h.appsDir, module.ID))
+			if err != nil || metadata == nil {
+				item.Action, item.Reason = "error", "installed module was not installed from git and cannot be upgraded"
+				continue
+			}
+			if metadata.Source+"@"+metadata.Ref == module.Source {
+				item.Action, item.Reason = "skip", "module is already installed at this revision"
+				continue
+			}
+			item.Action = "overwrite"
+			cmd = queue.Command{
+				Type: queue.CmdUpgradeModule,
+				Args: map[string]interface{}{
+					"module_id":      module.ID,
+					"source":         module.Source,
+					"credential_ref": module.CredentialRef,
+				},
+			}
+		} else {
+			item.Action = "create"
+			cmd = queue.Command{
+				Type: queue.CmdInstallModule,
+				Args: map[string]interface{}{
+					"module_id":      module.ID,
+					"source":         module.Source,
+					"tags":           module.Tags,
+					"credential_ref": module.CredentialRef,
+				},
+			}
+			if module.Resources != nil {
+				cmd.Args["resources"] = module.Resources
+			}
+		}
+
+		for _, prev := range moduleJobs {
+			item.After = append(item.After, "module/"+plan.items[plan.jobs[prev].item].ID)
+		}
+		job := plan.add(idx, cmd, append([]int(nil), moduleJobs...))
+		moduleJobs = append(moduleJobs, job)
+		if cmd.Type == queue.CmdInstallModule {
+			componentJob["module/"+module.ID] = job
+		}
+		available["module/"+module.ID] = true
+	}
+
+	// moduleDeps resolves the modules an item needs to the jobs it must wait for, or
+	// returns the first one that will not exist
+	moduleDeps := func(idx int, moduleIDs []string) ([]int, string) {
+		sort.Strings(moduleIDs)
+		var deps []int
+		for _, moduleID := range moduleIDs {
+			if !available["module/"+moduleID] {
+				return nil, moduleID
+			}
+			if job, ok := componentJob["module/"+moduleID]; ok {
+				deps = append(deps, job)
+				plan.items[idx].After = append(plan.items[idx].After, "module/"+moduleID)
+			}
+		}
+		return deps, ""
+	}
+
+	links := append([]ExportedLink(nil), doc.Links...)
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+	for _, link := range links {
+		idx := newItem("link", link.ID)
+		item := &plan.items[idx]
+
+		item.Action = "create"
+		if _, err := h.linkStore.GetLink(link.ID); err == nil {
+			available["link/"+link.ID] = true
+			if onConflict == conflictSkip {
+				item.Action, item.Reason = "skip", "link already exists"
+				continue
+			}
+			item.Action = "overwrite"
+		}
+
+		var moduleIDs []string
+		redacted := false
+		for moduleID, config := range link.Modules {
+			moduleIDs = append(moduleIDs, moduleID)
+			redacted = redacted || hasRedactedValue(map[string]interface{}(config))
+		}
+		if redacted {
+			item.Action, item.Reason = "error", "link configuration holds redacted secrets; fill them in before importing"
+			continue
+		}
+		deps, missing := moduleDeps(idx, moduleIDs)
+		if missing != "" {
+			item.Action, item.Reason, item.After = "error", fmt.Sprintf("module %s is neither installed nor imported", missing), nil
+			continue
+		}
+
+		componentJob["link/"+link.ID] = plan.add(idx, queue.Command{
+			Type: queue.CmdCreateLink,
+			Args: map[string]interface{}{
+				"link_id": link.ID,
+				"modules": link.Modules,
+				"tags":    link.Tags,
+			},
+		}, deps)
+		available["link/"+link.ID] = true
+	}
+
+	exposures := append([]*Exposure(nil), doc.Exposures...)
+	sort.Slice(exposures, func(i, j int) bool { return exposures[i].ID < exposures[j].ID })
+	// Imported exposures must not conflict with existing ones or with each other
+	others := h.exposureStore.ListExposures()
+	for _, exposure := range exposures {
+		idx := newItem("exposure", exposure.ID)
+		item := &plan.items[idx]
+
+		item.Action = "create"
+		if _, err := h.exposureStore.GetExposure(exposure.ID); err == nil {
+			available["exposure/"+exposure.ID] = true
+			if onConflict == conflictSkip {
+				item.Action, item.Reason = "skip", "exposure already exists"
+				continue
+			}
+			item.Action = "overwrite"
+		}
+		if err := normalizeExposureNames(exposure); err != nil {
+			item.Action, item.Reason = "error", err.Error()
+			continue
+		}
+		if exposure.Auth != nil && hasRedactedValue(strings.Join(exposure.Auth.Users, "\n")) {
+			item.Action, item.Reason = "error", "basic auth hashes are redacted; fill them in before importing"
+			continue
+		}
+		if reason := exposureConflictReason(exposure, others); reason != "" {
+			item.Action, item.Reason = "error", reason
+			continue
+		}
+
+		deps, missing := moduleDeps(idx, []string{exposure.ModuleID})
+		if missing != "" {
+			item.Action, item.Reason, item.After = "error", fmt.Sprintf("module %s is neither installed nor imported", missing), nil
+			continue
+		}
+
+		if item.Action == "overwrite" {
+			deleteJob := plan.add(idx, queue.Command{
+				Type: queue.CmdDeleteExposure,
+				Args: map[string]interface{}{"exposure_id": exposure.ID},
+			}, nil)
+			deps = append(deps, deleteJob)
+		}
+		componentJob["exposure/"+exposure.ID] = plan.add(idx, queue.Command{
+			Type: queue.CmdCreateExposure,
+			Args: exposureJobArgs(exposure),
+		}, deps)
+		available["exposure/"+exposure.ID] = true
+		others = append(others, exposure)
+	}
+
+	bundles := append([]ExportedBundle(nil), doc.Bundles...)
+	sort.Slice(bundles, func(i, j int) bool { return bundles[i].ID < bundles[j].ID })
+	for b := range bundles {
+		bundle := &bundles[b]
+		idx := newItem("bundle", bundle.ID)
+		item := &plan.items[idx]
+
+		item.Action = "create"
+		if _, err := h.bundleStore.GetBundle(bundle.ID); err == nil {
+			if onConflict == conflictSkip {
+				item.Action, item.Reason = "skip", "bundle already exists"
+				continue
+			}
+			item.Action = "overwrite"
+		}
+
+		// Components this import creates are queued; the rest are recorded as they are
+		statuses := make(map[string]string)
+		var deps []int
+		for _, ref := range bundleComponentRefs(bundle) {
+			switch job, ok := componentJob[ref]; {
+			case ok:
+				statuses[ref] = "queued"
+				deps = append(deps, job)
+				item.After = append(item.After, ref)
+			case available[ref]:
+				statuses[ref] = "completed"
+			default:
+				statuses[ref] = "failed"
+				item.Reason = fmt.Sprintf("%s is neither present nor imported and is recorded as failed", ref)
+			}
+		}
+
+		name := bundle.Name
+		if name == "" {
+			name = bundle.ID
+		}
+		job := plan.add(idx, queue.Command{
+			Type: queue.CmdBundleInstall,
+			Args: map[string]interface{}{
+				"bundle_id":           bundle.ID,
+				"bundle_name":         name,
+				"rollback_on_failure": false,
+			},
+		}, deps)
+		plan.jobs[job].bundle = bundle
+		plan.jobs[job].statuses = statuses
+	}
+
+	return plan
+}
+
+// bundleComponentRefs lists a bundle's components as kind/id
+func bundleComponentRefs(bundle *ExportedBundle) []string {
+	var refs []string
+	for _, id := range bundle.Modules {
+		refs = append(refs, "module/"+id)
+	}
+	for _, id := range bundle.Links {
+		refs = append(refs, "link/"+id)
+	}
+	for _, id := range bundle.Exposures {
+		refs = append(refs, "exposure/"+id)
+	}
+	return refs
+}
+
+// normalizeExposureNames normalizes and validates an imported exposure's hostname and
+// aliases the way creating an exposure does
+func normalizeExposureNames(exposure *Exposure) error {
+	spec := ExposureSpec{Protocol: exposure.Protocol, Hostname: exposure.Hostname, Aliases: exposure.Aliases}
+	aliases, err := specAliases(&spec)
+	if err != nil {
+		return err
+	}
+	exposure.Hostname, exposure.Aliases = spec.Hostname, aliases
+	return nil
+}
+
+// exposureConflictReason returns why an imported exposure conflicts with one of others,
+// or "" if it doesn't
+func exposureConflictReason(exposure *Exposure, others []*Exposure) string {
+	for _, other := range others {
+		if err := exposureConflict(exposure, other); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+// exposureJobArgs converts an exported exposure to create_exposure job arguments
+func exposureJobArgs(exposure *Exposure) map[string]interface{} {
+	args := map[string]interface{}{
+		"exposure_id":     exposure.ID,
+		"module_id":       exposure.ModuleID,
+		"protocol":        exposure.Protocol,
+		"hostname":        exposure.Hostname,
+		"aliases":         exposure.Aliases,
+		"path_prefix":     exposure.PathPrefix,
+		"prefix_rewrite":  exposure.PrefixRewrite,
+		"container_port":  exposure.ContainerPort,
+		"host_port":       exposure.HostPort,
+		"backends":        exposure.Backends,
+		"request_timeout": exposure.RequestTimeout,
+		"num_retries":     exposure.NumRetries,
+		"retry_on":        exposure.RetryOn,
+		"tags":            exposure.Tags,
+	}
+	if exposure.AllowUndeclaredPort {
+		args["allow_undeclared_port"] = true
+	}
+	if exposure.Auth != nil {
+		args["auth_users"] = exposure.Auth.Users
+	}
+	if exposure.TLS != nil {
+		args["tls_cert_file"] = exposure.TLS.CertFile
+		args["tls_key_file"] = exposure.TLS.KeyFile
+		args["tls_cert_name"] = exposure.TLS.CertName
+		args["redirect_https"] = exposure.TLS.RedirectHTTPS
+	}
+	if exposure.Options != nil {
+		args["websocket"] = exposure.Options.WebSocket
+		args["grpc"] = exposure.Options.GRPC
+	}
+	if exposure.Affinity != nil {
+		args["affinity_cookie"] = exposure.Affinity.Cookie
+		args["affinity_cookie_ttl"] = exposure.Affinity.CookieTTL
+		args["affinity_header"] = exposure.Affinity.Header
+	}
+	return args
+}
+
+// enqueuePlan enqueues the planned jobs in order and records imported bundles. If any job
+// cannot be enqueued, the ones already enqueued are deleted again so no partial import is
+// left queued.
+func (h *StateHandlers) enqueuePlan(plan *importPlan) error {
+	jobIDs := make([]string, 0, len(plan.jobs))
+	for _, job := range plan.jobs {
+		dependsOn := make([]string, 0, len(job.dependsOn))
+		for _, dep := range job.dependsOn {
+			dependsOn = append(dependsOn, jobIDs[dep])
+		}
+
+		// A bundle is recorded whatever happens to its components
+		jobID, _, err := h.manager.EnqueueWithOptions(job.cmd, queue.EnqueueOptions{
+			DependsOn:              dependsOn,
+			RunOnDependencyFailure: job.bundle != nil,
+		})
+		if err != nil {
+			h.discardJobs(jobIDs)
+			item := plan.items[job.item]
+			return fmt.Errorf("failed to enqueue %s for %s %s: %w", job.cmd.Type, item.Kind, item.ID, err)
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+
+	for i, job := range plan.jobs {
+		plan.items[job.item].JobIDs = append(plan.items[job.item].JobIDs, jobIDs[i])
+		if job.bundle != nil {
+			h.recordBundle(job.bundle, job.statuses, jobIDs[i])
+		}
+	}
+	return nil
+}
+
+// recordBundle replaces the bundle record of an imported bundle
+func (h *StateHandlers) recordBundle(bundle *ExportedBundle, statuses map[string]string, jobID string) {
+	_ = h.bundleStore.DeleteBundle(bundle.ID)
+
+	name := bundle.Name
+	if name == "" {
+		name = bundle.ID
+	}
+	h.bundleStore.CreateBundle(bundle.ID, name, jobID)
+	if bundle.Definition != nil {
+		_ = h.bundleStore.SetDefinition(bundle.ID, *bundle.Definition)
+	}
+
+	errMsg := func(status string) string {
+		if status == "failed" {
+			return "not present after import"
+		}
+		return ""
+	}
+	for _, id := range bundle.Modules {
+		status := statuses["module/"+id]
+		_ = h.bundleStore.AddModuleComponent(bundle.ID, id, status, errMsg(status))
+	}
+	for _, id := range bundle.Links {
+		status := statuses["link/"+id]
+		_ = h.bundleStore.AddLinkComponent(bundle.ID, id, status, errMsg(status))
+	}
+	for _, id := range bundle.Exposures {
+		status := statuses["exposure/"+id]
+		_ = h.bundleStore.AddExposureComponent(bundle.ID, id, status, errMsg(status))
+	}
+}
+
+// discardJobs deletes jobs of an import that could not be enqueued completely, newest
+// first so dependents go before the jobs they depend on
+func (h *StateHandlers) discardJobs(jobIDs []string) {
+	for i := len(jobIDs) - 1; i >= 0; i-- {
+		if err := h.manager.Delete(jobIDs[i]); err != nil {
+			h.logger.Warn("failed to discard import job", "job_id", jobIDs[i], "error", err)
+		}
+	}
+}
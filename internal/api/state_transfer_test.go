@@ -0,0 +1,57 @@
+package api
+
+import "testing"
+
+func TestImportedExposureConflicts(t *testing.T) {
+	existing := []*Exposure{
+		{ID: "wiki", Protocol: "http", Hostname: "wiki.example.com"},
+	}
+
+	cases := []struct {
+		name     string
+		exposure *Exposure
+		others   []*Exposure
+		conflict bool
+	}{
+		{"hostname differing in case", &Exposure{ID: "a", Protocol: "http", Hostname: " Wiki.Example.com"}, existing, true},
+		{"hostname with .local", &Exposure{ID: "b", Protocol: "http", Hostname: "wiki.example.com.local"}, existing, true},
+		{"alias of another exposure", &Exposure{ID: "c", Protocol: "http", Hostname: "docs.example.com", Aliases: []string{"WIKI.example.com"}}, existing, true},
+		{"same id is replaced", &Exposure{ID: "wiki", Protocol: "http", Hostname: "wiki.example.com"}, existing, false},
+		{"free hostname", &Exposure{ID: "d", Protocol: "http", Hostname: "git.example.com"}, existing, false},
+		{
+			"another imported exposure",
+			&Exposure{ID: "e", Protocol: "http", Hostname: "git.example.com"},
+			[]*Exposure{{ID: "f", Protocol: "http", Hostname: "git.example.com"}},
+			true,
+		},
+	}
+	for _, tc := range cases {
+		if err := normalizeExposureNames(tc.exposure); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		reason := exposureConflictReason(tc.exposure, tc.others)
+		if (reason != "") != tc.conflict {
+			t.Errorf("%s: conflict reason %q, want conflict %v", tc.name, reason, tc.conflict)
+		}
+	}
+}
+
+func TestRedactedLinkConfig(t *testing.T) {
+	config := map[string]map[string]interface{}{
+		"db": {"user": "app", "password": "hunter2"},
+	}
+
+	redacted, err := redactedLinkConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redacted["db"]["password"] == "hunter2" || redacted["db"]["user"] != "app" {
+		t.Errorf("redacted config = %v, want only the password redacted", redacted)
+	}
+	if config["db"]["password"] != "hunter2" {
+		t.Error("redactedLinkConfig modified the link's configuration")
+	}
+	if !hasRedactedValue(map[string]interface{}(redacted["db"])) {
+		t.Error("hasRedactedValue did not find the redacted password")
+	}
+}
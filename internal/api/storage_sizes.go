@@ -0,0 +1,243 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	internalPaths "zeropoint-agent/internal"
+
+	"github.com/moby/moby/api/types/mount"
+	"github.com/moby/moby/client"
+)
+
+const (
+	// storageSizeTTL is how long module sizes are served before they are measured again.
+	// Walking module data can take minutes on large disks, so a stale report is served
+	// while the next one is measured in the background.
+	storageSizeTTL = 15 * time.Minute
+
+	// storageDockerTimeout bounds the Docker disk usage query
+	storageDockerTimeout = 30 * time.Second
+)
+
+// ModuleStorageUsage is the disk space attributable to one installed module
+type ModuleStorageUsage struct {
+	ModuleID       string   `json:"module_id"`
+	StoragePath    string   `json:"storage_path"`    // The module's zp_module_storage directory
+	StorageBytes   int64    `json:"storage_bytes"`   // Size of the storage directory
+	ContainerBytes int64    `json:"container_bytes"` // Writable layers of the module's containers
+	ImageBytes     int64    `json:"image_bytes"`     // Images the module's containers run; an image shared by modules counts for each
+	VolumeBytes    int64    `json:"volume_bytes"`    // Docker volumes mounted by the module's containers
+	Volumes        []string `json:"volumes,omitempty"`
+}
+
+// moduleSizes is a measurement of every module's disk usage
+type moduleSizes struct {
+	modules  []ModuleStorageUsage
+	warnings []string
+	sizedAt  time.Time
+}
+
+// moduleSizes returns the last measurement, measuring synchronously when there is none
+// or refresh is set. An expired measurement is returned as is while a new one is taken
+// in the background.
+func (h *StorageHandlers) moduleSizes(refresh bool) *moduleSizes {
+	h.sizeMu.Lock()
+	cached, measuring := h.sizes, h.measuring
+	stale := cached == nil || time.Since(cached.sizedAt) >= storageSizeTTL
+	if stale && !refresh && cached != nil && !measuring {
+		h.measuring = true
+		go func() {
+			sizes := h.measureModules()
+			h.sizeMu.Lock()
+			h.sizes, h.measuring = sizes, false
+			h.sizeMu.Unlock()
+		}()
+	}
+	h.sizeMu.Unlock()
+
+	if cached != nil && !refresh {
+		return cached
+	}
+
+	sizes := h.measureModules()
+	h.sizeMu.Lock()
+	h.sizes = sizes
+	h.sizeMu.Unlock()
+	return sizes
+}
+
+// measureModules sizes each installed module's storage directory and attributes Docker
+// containers, images and volumes to modules by container name ({module}-*). Failures
+// leave the affected numbers out and are reported as warnings.
+func (h *StorageHandlers) measureModules() *moduleSizes {
+	sizes := &moduleSizes{sizedAt: time.Now()}
+
+	moduleIDs, err := installedModuleIDs()
+	if err != nil {
+		sizes.warnings = append(sizes.warnings, fmt.Sprintf("failed to list modules: %v", err))
+	}
+
+	byID := make(map[string]*ModuleStorageUsage, len(moduleIDs))
+	for _, moduleID := range moduleIDs {
+		path := filepath.Join(internalPaths.GetDataDir(), moduleID)
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		usage := ModuleStorageUsage{ModuleID: moduleID, StoragePath: path}
+
+		bytes, skipped, firstErr := dirSize(path)
+		usage.StorageBytes = bytes
+		if skipped > 0 {
+			sizes.warnings = append(sizes.warnings, fmt.Sprintf("module %s: %d entries in %s could not be read, size is partial (first error: %v)", moduleID, skipped, path, firstErr))
+		}
+
+		sizes.modules = append(sizes.modules, usage)
+	}
+	for i := range sizes.modules {
+		byID[sizes.modules[i].ModuleID] = &sizes.modules[i]
+	}
+
+	if h.docker == nil || len(moduleIDs) == 0 {
+		return sizes
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storageDockerTimeout)
+	defer cancel()
+	if err := h.attributeDockerUsage(ctx, moduleIDs, byID); err != nil {
+		h.logger.Warn("failed to query docker disk usage", "error", err)
+		sizes.warnings = append(sizes.warnings, fmt.Sprintf("docker disk usage unavailable: %v", err))
+	}
+	return sizes
+}
+
+// attributeDockerUsage adds container, image and volume sizes to the modules owning them
+func (h *StorageHandlers) attributeDockerUsage(ctx context.Context, moduleIDs []string, byID map[string]*ModuleStorageUsage) error {
+	du, err := h.docker.DiskUsage(ctx, client.DiskUsageOptions{Containers: true, Images: true, Volumes: true, Verbose: true})
+	if err != nil {
+		return err
+	}
+
+	imageSizes := make(map[string]int64, len(du.Images.Items))
+	for _, img := range du.Images.Items {
+		imageSizes[img.ID] = img.Size
+	}
+	volumeSizes := make(map[string]int64, len(du.Volumes.Items))
+	for _, vol := range du.Volumes.Items {
+		if vol.UsageData != nil && vol.UsageData.Size >= 0 {
+			volumeSizes[vol.Name] = vol.UsageData.Size
+		}
+	}
+
+	images := make(map[string]map[string]bool)
+	volumes := make(map[string]map[string]bool)
+	for _, c := range du.Containers.Items {
+		moduleID := owningModule(c.Names, moduleIDs)
+		usage, ok := byID[moduleID]
+		if !ok {
+			continue
+		}
+		usage.ContainerBytes += c.SizeRw
+
+		if images[moduleID] == nil {
+			images[moduleID] = make(map[string]bool)
+			volumes[moduleID] = make(map[string]bool)
+		}
+		if !images[moduleID][c.ImageID] {
+			images[moduleID][c.ImageID] = true
+			usage.ImageBytes += imageSizes[c.ImageID]
+		}
+		for _, m := range c.Mounts {
+			if m.Type != mount.TypeVolume || volumes[moduleID][m.Name] {
+				continue
+			}
+			volumes[moduleID][m.Name] = true
+			usage.VolumeBytes += volumeSizes[m.Name]
+			usage.Volumes = append(usage.Volumes, m.Name)
+		}
+	}
+	for _, usage := range byID {
+		sort.Strings(usage.Volumes)
+	}
+	return nil
+}
+
+// owningModule returns the module a container belongs to by its {module}-* name. The
+// longest matching module ID wins, so "web-ui-main" belongs to "web-ui" rather than "web".
+func owningModule(names []string, moduleIDs []string) string {
+	owner := ""
+	for _, name := range names {
+		name = strings.TrimPrefix(name, "/")
+		for _, moduleID := range moduleIDs {
+			if strings.HasPrefix(name, moduleID+"-") && len(moduleID) > len(owner) {
+				owner = moduleID
+			}
+		}
+	}
+	return owner
+}
+
+// installedModuleIDs lists the modules with a terraform configuration, sorted
+func installedModuleIDs() ([]string, error) {
+	entries, err := os.ReadDir(internalPaths.GetModulesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var moduleIDs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(internalPaths.GetModulesDir(), entry.Name(), "main.tf")); err == nil {
+			moduleIDs = append(moduleIDs, entry.Name())
+		}
+	}
+	sort.Strings(moduleIDs)
+	return moduleIDs, nil
+}
+
+// dirSize sums the sizes of the regular files under path without following symlinks.
+// Entries that cannot be read are skipped and counted; a missing directory is empty.
+func dirSize(path string) (size int64, skipped int, firstErr error) {
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == path && os.IsNotExist(err) {
+				return fs.SkipDir
+			}
+			skipped++
+			if firstErr == nil {
+				firstErr = err
+			}
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			skipped++
+			if firstErr == nil {
+				firstErr = err
+			}
+			return nil
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil && firstErr == nil {
+		skipped++
+		firstErr = err
+	}
+	return size, skipped, firstErr
+}
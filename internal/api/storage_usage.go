@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/system"
+
+	"github.com/moby/moby/client"
+)
+
+// storageUsageTTL is how long a usage report is served from cache
+const storageUsageTTL = 30 * time.Second
+
+// PathUsage reports the filesystem usage behind one storage path
+type PathUsage struct {
+	Name           string `json:"name"`                      // storage_root, modules, module_data, certs or module:<id>
+	Path           string `json:"path"`                      // Absolute path on the host
+	Available      bool   `json:"available"`                 // False if the path is missing or cannot be read
+	Device         uint64 `json:"device,omitempty"`          // Filesystem device ID; paths sharing it share capacity
+	TotalBytes     uint64 `json:"total_bytes,omitempty"`     // Filesystem size
+	UsedBytes      uint64 `json:"used_bytes,omitempty"`      // Filesystem space in use
+	AvailableBytes uint64 `json:"available_bytes,omitempty"` // Filesystem space left for the agent
+	Error          string `json:"error,omitempty"`           // Why the path is unavailable
+}
+
+// StorageUsageResponse is returned by GET /storage/usage
+type StorageUsageResponse struct {
+	Paths     []PathUsage          `json:"paths"`
+	CheckedAt time.Time            `json:"checked_at"`
+	Modules   []ModuleStorageUsage `json:"modules"`
+	SizedAt   time.Time            `json:"sized_at"`           // When module sizes were measured
+	Warnings  []string             `json:"warnings,omitempty"` // Parts of the module sizes that could not be measured
+}
+
+// StorageHandlers reports disk usage of the agent's storage locations
+type StorageHandlers struct {
+	docker *client.Client
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	cached *StorageUsageResponse
+
+	sizeMu    sync.Mutex
+	sizes     *moduleSizes
+	measuring bool
+}
+
+// NewStorageHandlers creates a new storage handlers instance
+func NewStorageHandlers(docker *client.Client, logger *slog.Logger) *StorageHandlers {
+	return &StorageHandlers{docker: docker, logger: logger}
+}
+
+// GetStorageUsage handles GET /storage/usage
+// @ID getStorageUsage
+// @Summary Get storage usage
+// @Description Reports total, used and available bytes of the filesystems behind the agent's storage root, module directories, per-module data directories and certificate directory. Results are cached for 30 seconds; missing paths are reported as unavailable. Also reports per-module disk usage: the size of each module's storage directory and the Docker containers, images and volumes attributed to it. Module sizes are measured every 15 minutes in the background, or immediately with refresh=true; paths that cannot be read are listed in warnings and their sizes are partial.
+// @Tags system
+// @Produce json
+// @Param refresh query bool false "Measure module sizes now instead of serving the cached measurement"
+// @Success 200 {object} StorageUsageResponse
+// @Router /storage/usage [get]
+func (h *StorageHandlers) GetStorageUsage(w http.ResponseWriter, r *http.Request) {
+	refresh := r.URL.Query().Get("refresh") == "true"
+
+	response := *h.usage()
+	sizes := h.moduleSizes(refresh)
+	response.Modules = sizes.modules
+	response.SizedAt = sizes.sizedAt
+	response.Warnings = sizes.warnings
+	if response.Modules == nil {
+		response.Modules = []ModuleStorageUsage{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// usage returns the cached report, collecting a new one once it has expired
+func (h *StorageHandlers) usage() *StorageUsageResponse {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil && time.Since(h.cached.CheckedAt) < storageUsageTTL {
+		return h.cached
+	}
+
+	response := &StorageUsageResponse{CheckedAt: time.Now()}
+	for _, location := range h.storageLocations() {
+		response.Paths = append(response.Paths, pathUsage(location.name, location.path))
+	}
+	h.cached = response
+	return response
+}
+
+type storageLocation struct {
+	name string
+	path string
+}
+
+// storageLocations lists the fixed storage paths followed by one entry per module data directory
+func (h *StorageHandlers) storageLocations() []storageLocation {
+	locations := []storageLocation{
+		{"storage_root", internalPaths.GetStorageRoot()},
+		{"modules", internalPaths.GetModulesDir()},
+		{"module_data", internalPaths.GetDataDir()},
+		{"certs", internalPaths.GetCertsDir()},
+	}
+
+	entries, err := os.ReadDir(internalPaths.GetDataDir())
+	if err != nil && !os.IsNotExist(err) {
+		h.logger.Warn("failed to list module data directories", "error", err)
+	}
+	var moduleIDs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			moduleIDs = append(moduleIDs, entry.Name())
+		}
+	}
+	sort.Strings(moduleIDs)
+	for _, moduleID := range moduleIDs {
+		locations = append(locations, storageLocation{"module:" + moduleID, filepath.Join(internalPaths.GetDataDir(), moduleID)})
+	}
+	return locations
+}
+
+// pathUsage stats a single path; failures are reported in the entry rather than returned
+func pathUsage(name, path string) PathUsage {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	entry := PathUsage{Name: name, Path: path}
+
+	usage, err := system.GetDiskUsage(path)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Available = true
+	entry.Device = usage.Device
+	entry.TotalBytes = usage.TotalBytes
+	entry.UsedBytes = usage.UsedBytes
+	entry.AvailableBytes = usage.AvailableBytes
+	return entry
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+
+	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/system"
+
+	"github.com/moby/moby/client"
+)
+
+// systemInfoDockerTimeout bounds the Docker version lookup so a hung daemon does not block the report
+const systemInfoDockerTimeout = 3 * time.Second
+
+// SystemInfoResponse is returned by GET /system/info
+type SystemInfoResponse struct {
+	OS        string `json:"os"`
+	Arch      string `json:"arch"` // Passed to modules as zp_arch
+	CPUs      int    `json:"cpus"`
+	GPUVendor string `json:"gpu_vendor"` // Passed to modules as zp_gpu_vendor; "nvidia", "amd", "intel" or "" if none
+	GPUSource string `json:"gpu_source"` // "detected", or "override" when set through gpu_vendor / ZEROPOINT_GPU_VENDOR
+
+	DockerVersion    string `json:"docker_version,omitempty"`     // Docker daemon version
+	DockerAPIVersion string `json:"docker_api_version,omitempty"` // API version negotiated with the daemon
+	DockerError      string `json:"docker_error,omitempty"`       // Why the daemon could not be queried
+
+	MemoryTotalBytes     uint64 `json:"memory_total_bytes,omitempty"`
+	MemoryAvailableBytes uint64 `json:"memory_available_bytes,omitempty"`
+
+	StorageTotalBytes     uint64 `json:"storage_total_bytes,omitempty"`     // Filesystem holding the storage root
+	StorageAvailableBytes uint64 `json:"storage_available_bytes,omitempty"` // Space left on it for modules
+}
+
+// systemInfoHandler handles GET /system/info
+// @ID getSystemInfo
+// @Summary Get host system information
+// @Description Reports what a node offers for scheduling module installs: platform, GPU vendor (the same values modules receive as zp_arch and zp_gpu_vendor), Docker version, memory and space on the storage root. Parts that cannot be read are omitted; a Docker failure is reported in docker_error.
+// @Tags system
+// @Produce json
+// @Success 200 {object} SystemInfoResponse
+// @Router /system/info [get]
+func (e *apiEnv) systemInfoHandler(w http.ResponseWriter, r *http.Request) {
+	resp := SystemInfoResponse{
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		GPUVendor: system.DetectGPU(),
+		GPUSource: system.GPUVendorSource(),
+	}
+
+	if e.docker != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), systemInfoDockerTimeout)
+		version, err := e.docker.ServerVersion(ctx, client.ServerVersionOptions{})
+		cancel()
+		if err != nil {
+			resp.DockerError = err.Error()
+		} else {
+			resp.DockerVersion = version.Version
+			resp.DockerAPIVersion = e.docker.ClientVersion()
+		}
+	}
+
+	if mem, err := system.GetMemoryInfo(); err != nil {
+		e.logger.Warn("failed to read memory info", "error", err)
+	} else {
+		resp.MemoryTotalBytes = mem.TotalBytes
+		resp.MemoryAvailableBytes = mem.AvailableBytes
+	}
+
+	if usage, err := system.GetDiskUsage(internalPaths.GetStorageRoot()); err != nil {
+		e.logger.Warn("failed to read storage usage", "error", err)
+	} else {
+		resp.StorageTotalBytes = usage.TotalBytes
+		resp.StorageAvailableBytes = usage.AvailableBytes
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultQueryLimit = 100
+
+// Handlers serves the audit log
+type Handlers struct {
+	log    *Log
+	logger *slog.Logger
+}
+
+// NewHandlers creates new audit handlers
+func NewHandlers(log *Log, logger *slog.Logger) *Handlers {
+	return &Handlers{
+		log:    log,
+		logger: logger,
+	}
+}
+
+// ListResponse is returned by GET /audit
+type ListResponse struct {
+	Entries []Entry `json:"entries"`
+	Total   int     `json:"total"` // Entries matching the filters, before limit/offset
+}
+
+// HandleList handles GET /audit
+// @ID listAudit
+// @Summary List audit log entries
+// @Description Lists recorded POST, PUT, PATCH and DELETE requests, newest first. Sensitive request body fields are redacted. Requires the admin scope.
+// @Tags audit
+// @Produce json
+// @Param since query string false "Only entries at or after this RFC 3339 time"
+// @Param until query string false "Only entries at or before this RFC 3339 time"
+// @Param path query string false "Only entries whose path starts with this prefix"
+// @Param method query string false "Only entries with this HTTP method"
+// @Param limit query int false "Maximum number of entries to return (default: 100)"
+// @Param offset query int false "Number of entries to skip (default: 0)"
+// @Success 200 {object} ListResponse
+// @Failure 400 {string} string "Invalid filter or pagination"
+// @Failure 500 {string} string "Internal server error"
+// @Router /audit [get]
+func (h *Handlers) HandleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var filter Filter
+	for name, bound := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, name+" must be an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			*bound = t
+		}
+	}
+	filter.PathPrefix = query.Get("path")
+	filter.Method = query.Get("method")
+
+	limit := defaultQueryLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	entries, err := h.log.Query(filter)
+	if err != nil {
+		h.logger.Error("failed to query audit log", "error", err)
+		http.Error(w, "failed to read audit log", http.StatusInternalServerError)
+		return
+	}
+
+	total := len(entries)
+	if offset > len(entries) {
+		offset = len(entries)
+	}
+	entries = entries[offset:]
+	if limit < len(entries) {
+		entries = entries[:limit]
+	}
+	if entries == nil {
+		entries = []Entry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListResponse{Entries: entries, Total: total})
+}
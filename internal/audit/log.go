@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	internalPaths "zeropoint-agent/internal"
+)
+
+const (
+	logFileName  = "audit.jsonl"
+	rotatedFiles = 5 // audit.jsonl.1 (newest) to audit.jsonl.5 (oldest)
+)
+
+// Entry records one mutating API request
+type Entry struct {
+	Timestamp  time.Time       `json:"timestamp"`
+	RemoteAddr string          `json:"remote_addr"`
+	Principal  string          `json:"principal,omitempty"` // API token that made the request
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	Query      string          `json:"query,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"` // JSON body with sensitive fields redacted
+	Status     int             `json:"status"`
+	JobID      string          `json:"job_id,omitempty"` // Job created or returned by the request
+	DurationMs int64           `json:"duration_ms"`
+}
+
+// Filter selects audit entries
+type Filter struct {
+	Since      time.Time // Zero for no lower bound
+	Until      time.Time // Zero for no upper bound
+	PathPrefix string
+	Method     string
+}
+
+// Log appends audit entries to a size-rotated JSONL file under the storage root
+type Log struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	logger   *slog.Logger
+}
+
+// NewLog opens the audit log in the storage root, rotating it once it reaches maxBytes
+func NewLog(maxBytes int64, logger *slog.Logger) (*Log, error) {
+	dir := filepath.Join(internalPaths.GetStorageRoot(), "audit")
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	return &Log{
+		path:     filepath.Join(dir, logFileName),
+		maxBytes: maxBytes,
+		logger:   logger,
+	}, nil
+}
+
+// Append writes an entry, rotating the file first if it reached the size limit
+func (l *Log) Append(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if info, err := os.Stat(l.path); err == nil && info.Size()+int64(len(line)) > l.maxBytes {
+		l.rotate()
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// rotate shifts audit.jsonl to audit.jsonl.1 and so on, dropping the oldest (caller must
+// hold the lock)
+func (l *Log) rotate() {
+	for i := rotatedFiles - 1; i >= 1; i-- {
+		_ = os.Rename(l.rotatedPath(i), l.rotatedPath(i+1))
+	}
+	if err := os.Rename(l.path, l.rotatedPath(1)); err != nil {
+		l.logger.Warn("failed to rotate audit log", "error", err)
+	}
+}
+
+func (l *Log) rotatedPath(n int) string {
+	return fmt.Sprintf("%s.%d", l.path, n)
+}
+
+// Query returns the entries matching the filter, newest first
+func (l *Log) Query(filter Filter) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Oldest file first, so entries are read in the order they were written
+	paths := make([]string, 0, rotatedFiles+1)
+	for i := rotatedFiles; i >= 1; i-- {
+		paths = append(paths, l.rotatedPath(i))
+	}
+	paths = append(paths, l.path)
+
+	var entries []Entry
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+		for scanner.Scan() {
+			var entry Entry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			if filter.matches(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+func (f Filter) matches(entry Entry) bool {
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Timestamp.After(f.Until) {
+		return false
+	}
+	if f.PathPrefix != "" && !strings.HasPrefix(entry.Path, f.PathPrefix) {
+		return false
+	}
+	if f.Method != "" && !strings.EqualFold(entry.Method, f.Method) {
+		return false
+	}
+	return true
+}
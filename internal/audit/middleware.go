@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxCapturedBytes bounds how much of a request or response body is inspected
+const maxCapturedBytes = 64 << 10
+
+type contextKey struct{}
+
+// SetPrincipal records who made a request once authentication identified them
+func SetPrincipal(r *http.Request, principal string) {
+	if entry, ok := r.Context().Value(contextKey{}).(*Entry); ok {
+		entry.Principal = principal
+	}
+}
+
+// Middleware records every POST, PUT, PATCH and DELETE request in the audit log. It must
+// wrap authentication so rejected requests are recorded too.
+func Middleware(log *Log, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		entry := &Entry{
+			Timestamp:  start.UTC(),
+			RemoteAddr: r.RemoteAddr,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Query:      r.URL.RawQuery,
+		}
+
+		// Read the start of the body for the entry and hand the handler all of it
+		if r.Body != nil {
+			head, _ := io.ReadAll(io.LimitReader(r.Body, maxCapturedBytes))
+			entry.Body = redactedBody(head)
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), contextKey{}, entry)))
+
+		entry.Status = recorder.status
+		entry.JobID = jobIDFromResponse(recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		entry.DurationMs = time.Since(start).Milliseconds()
+		if err := log.Append(*entry); err != nil {
+			log.logger.Error("failed to append audit entry", "method", entry.Method, "path", entry.Path, "error", err)
+		}
+	})
+}
+
+// redactedBody returns a JSON body with registered fields redacted, or nil for bodies that
+// are empty, not JSON or too large to parse
+func redactedBody(body []byte) json.RawMessage {
+	var value interface{}
+	if len(body) == 0 || json.Unmarshal(body, &value) != nil {
+		return nil
+	}
+	redactedJSON, err := json.Marshal(Redact(value))
+	if err != nil {
+		return nil
+	}
+	return redactedJSON
+}
+
+// jobIDFromResponse finds the job in a JSON response: a job itself, or an object with a
+// job_id field
+func jobIDFromResponse(contentType string, body []byte) string {
+	if !strings.HasPrefix(contentType, "application/json") {
+		return ""
+	}
+	var response struct {
+		ID      string          `json:"id"`
+		Command json.RawMessage `json:"command"`
+		JobID   string          `json:"job_id"`
+	}
+	if json.Unmarshal(body, &response) != nil {
+		return ""
+	}
+	if response.JobID != "" {
+		return response.JobID
+	}
+	if len(response.Command) > 0 {
+		return response.ID
+	}
+	return ""
+}
+
+// responseRecorder captures the status and the start of the response body
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	if remaining := maxCapturedBytes - r.body.Len(); remaining > 0 {
+		r.body.Write(b[:min(len(b), remaining)])
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses working through the recorder
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
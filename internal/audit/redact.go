@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"strings"
+	"sync"
+)
+
+// RedactedValue replaces the value of every redacted field
+const RedactedValue = "[REDACTED]"
+
+// Field names whose values never reach the audit log, matched case-insensitively at any
+// depth of a JSON request body
+var redacted = struct {
+	mu       sync.RWMutex
+	fields   map[string]bool
+	scrubbed map[string]func(string) string // Fields whose string values are cleaned up instead
+}{
+	scrubbed: make(map[string]func(string) string),
+	fields: map[string]bool{
+		"password":    true,
+		"passphrase":  true,
+		"luks_key":    true,
+		"token":       true,
+		"secret":      true,
+		"private_key": true,
+	},
+}
+
+// RegisterRedactedFields adds request body fields to redact in audit entries. Handlers
+// accepting sensitive fields beyond the defaults register them here.
+func RegisterRedactedFields(names ...string) {
+	redacted.mu.Lock()
+	defer redacted.mu.Unlock()
+
+	for _, name := range names {
+		redacted.fields[strings.ToLower(name)] = true
+	}
+}
+
+// RegisterScrubbedFields passes the string values of request body fields through scrub
+// before they are written to the audit log, for fields such as URLs where only part of the
+// value is sensitive
+func RegisterScrubbedFields(scrub func(string) string, names ...string) {
+	redacted.mu.Lock()
+	defer redacted.mu.Unlock()
+
+	for _, name := range names {
+		redacted.scrubbed[strings.ToLower(name)] = scrub
+	}
+}
+
+// Redact replaces the values of registered fields, and scrubs those of scrubbed fields, in a
+// decoded JSON value, in place
+func Redact(value interface{}) interface{} {
+	redacted.mu.RLock()
+	defer redacted.mu.RUnlock()
+
+	return redactValue(value)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			name := strings.ToLower(key)
+			if redacted.fields[name] {
+				v[key] = RedactedValue
+			} else if s, ok := field.(string); ok && redacted.scrubbed[name] != nil {
+				v[key] = redacted.scrubbed[name](s)
+			} else {
+				v[key] = redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return value
+}
@@ -18,12 +18,13 @@ type BootMonitor struct {
 	phases           map[string]*PhaseStatus   // keyed by phase name
 	services         map[string]*ServiceStatus // keyed by service name
 	phaseOrder       []string                  // order of phases discovered from logs
-	allLogs          []LogEntry                // all captured logs
+	allLogs          []LogEntry                // most recent captured logs, at most maxLogs
+	maxLogs          int
 	isComplete       bool
 	isBootFailed     bool
 	completedAt      *time.Time
 	failedServices   map[string]string // service → error message
-	subscribers      map[int]chan StatusUpdate
+	subscribers      map[int]*subscriber
 	nextSubscriberID int
 	startTime        time.Time
 	needsReboot      bool
@@ -31,18 +32,23 @@ type BootMonitor struct {
 	markers          *orderedmap.OrderedMap[string, []MarkerEntry] // service name → ordered list of markers
 }
 
-// NewBootMonitor creates a new boot monitor
-func NewBootMonitor(logger *slog.Logger) *BootMonitor {
+// Subscribers whose channel was full for this many broadcasts in a row are dropped
+const maxSubscriberMisses = 50
+
+// NewBootMonitor creates a new boot monitor that reads persistent markers from markerDir
+// and keeps up to maxLogs boot log entries in memory. Markers are kept regardless.
+func NewBootMonitor(markerDir string, maxLogs int, logger *slog.Logger) *BootMonitor {
 	m := &BootMonitor{
 		logger:         logger,
 		phases:         make(map[string]*PhaseStatus),
 		services:       make(map[string]*ServiceStatus),
 		phaseOrder:     []string{}, // Will be built dynamically from journal
 		allLogs:        make([]LogEntry, 0, 1000),
+		maxLogs:        maxLogs,
 		failedServices: make(map[string]string),
-		subscribers:    make(map[int]chan StatusUpdate),
+		subscribers:    make(map[int]*subscriber),
 		startTime:      time.Now(),
-		markerDir:      "/etc/zeropoint",
+		markerDir:      markerDir,
 		markers:        orderedmap.New[string, []MarkerEntry](),
 	}
 
@@ -82,7 +88,6 @@ func (m *BootMonitor) ResetState() {
 	m.completedAt = nil
 	m.failedServices = make(map[string]string)
 	m.needsReboot = false
-	m.markerDir = m.markerDir // keep same markerDir
 	m.markers = orderedmap.New[string, []MarkerEntry]()
 
 	// Build a snapshot while still holding the lock (getStatusSnapshot assumes lock held)
@@ -290,31 +295,67 @@ func (m *BootMonitor) GetStatus() BootStatus {
 	}
 }
 
-// Subscribe returns a channel that receives status updates
-func (m *BootMonitor) Subscribe() <-chan StatusUpdate {
+// subscriber is a registered update channel and how many broadcasts in a row it missed
+type subscriber struct {
+	ch     chan StatusUpdate
+	misses int
+}
+
+// Subscription receives boot status updates until it is closed. C is closed when the
+// subscription ends, including when the monitor drops a subscriber that stopped reading.
+type Subscription struct {
+	C <-chan StatusUpdate
+
+	id      int
+	monitor *BootMonitor
+}
+
+// Close ends the subscription; it is safe to call more than once
+func (s *Subscription) Close() {
+	s.monitor.unsubscribe(s.id)
+}
+
+// Subscribe registers for status updates. Callers must Close the subscription once they
+// stop reading.
+func (m *BootMonitor) Subscribe() *Subscription {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	ch := make(chan StatusUpdate, 10)
 	m.nextSubscriberID++
-	m.subscribers[m.nextSubscriberID] = ch
-	return ch
+	m.subscribers[m.nextSubscriberID] = &subscriber{ch: ch}
+	return &Subscription{C: ch, id: m.nextSubscriberID, monitor: m}
 }
 
-// broadcastUpdate sends a StatusUpdate to all subscribers
-func (m *BootMonitor) broadcastUpdate(update StatusUpdate) {
+// unsubscribe removes a subscriber and closes its channel
+func (m *BootMonitor) unsubscribe(id int) {
 	m.mu.Lock()
-	subs := make(map[int]chan StatusUpdate)
-	for k, v := range m.subscribers {
-		subs[k] = v
+	defer m.mu.Unlock()
+
+	if sub, ok := m.subscribers[id]; ok {
+		delete(m.subscribers, id)
+		close(sub.ch)
 	}
-	m.mu.Unlock()
+}
 
-	for _, ch := range subs {
+// broadcastUpdate sends a StatusUpdate to all subscribers without blocking. A subscriber
+// that misses maxSubscriberMisses updates in a row is assumed gone and dropped.
+func (m *BootMonitor) broadcastUpdate(update StatusUpdate) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, sub := range m.subscribers {
 		select {
-		case ch <- update:
+		case sub.ch <- update:
+			sub.misses = 0
 		default:
 			// Don't block if subscriber is slow
+			sub.misses++
+			if sub.misses >= maxSubscriberMisses {
+				m.logger.Warn("dropping boot status subscriber that stopped reading", "subscriber", id)
+				delete(m.subscribers, id)
+				close(sub.ch)
+			}
 		}
 	}
 }
@@ -332,6 +373,11 @@ func (m *BootMonitor) broadcast(status BootStatus) {
 func (m *BootMonitor) updateServiceStatus(entry LogEntry) {
 	m.mu.Lock()
 	m.allLogs = append(m.allLogs, entry)
+	if len(m.allLogs) > m.maxLogs {
+		// Reslicing drops the oldest entries; append reallocates once capacity runs out,
+		// so memory stays within a small multiple of the limit
+		m.allLogs = m.allLogs[len(m.allLogs)-m.maxLogs:]
+	}
 	m.mu.Unlock()
 
 	// Update marker tracker (only affects marker entries)
@@ -0,0 +1,76 @@
+package boot
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func newTestMonitor(t *testing.T, maxLogs int) *BootMonitor {
+	t.Helper()
+	return NewBootMonitor(t.TempDir(), maxLogs, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestBootMonitorSubscriptionChurn(t *testing.T) {
+	m := newTestMonitor(t, 100)
+
+	for i := 0; i < 5000; i++ {
+		sub := m.Subscribe()
+		if i%2 == 0 {
+			m.updateServiceStatus(LogEntry{Timestamp: time.Now(), Service: "test", Message: "churn", Level: "info"})
+		}
+		sub.Close()
+		sub.Close()
+	}
+
+	if n := len(m.subscribers); n != 0 {
+		t.Errorf("%d subscribers left after closing every subscription, want 0", n)
+	}
+}
+
+func TestBootMonitorDropsStalledSubscriber(t *testing.T) {
+	m := newTestMonitor(t, 100)
+
+	stalled := m.Subscribe()
+	reading := m.Subscribe()
+	defer reading.Close()
+
+	for i := 0; i < cap(stalled.C)+maxSubscriberMisses; i++ {
+		m.updateServiceStatus(LogEntry{Timestamp: time.Now(), Service: "test", Message: "busy", Level: "info"})
+		<-reading.C
+	}
+
+	if n := len(m.subscribers); n != 1 {
+		t.Errorf("%d subscribers registered, want only the reading one", n)
+	}
+
+	// The stalled subscription's channel is closed once its buffered updates are drained
+	received := 0
+	for range stalled.C {
+		received++
+	}
+	if received != cap(stalled.C) {
+		t.Errorf("stalled subscriber received %d updates, want %d", received, cap(stalled.C))
+	}
+	stalled.Close()
+}
+
+func TestBootMonitorLogRetention(t *testing.T) {
+	m := newTestMonitor(t, 100)
+
+	for i := 0; i < 10000; i++ {
+		m.updateServiceStatus(LogEntry{Timestamp: time.Now(), Service: "test", Message: "line", Level: "info"})
+	}
+	m.updateServiceStatus(LogEntry{Timestamp: time.Now(), Service: "test", Message: "last", Level: "info"})
+
+	if n := len(m.allLogs); n != 100 {
+		t.Errorf("kept %d log entries, want 100", n)
+	}
+	if c := cap(m.allLogs); c > 4*1000 {
+		t.Errorf("log buffer capacity grew to %d", c)
+	}
+	if last := m.allLogs[len(m.allLogs)-1]; last.Message != "last" {
+		t.Errorf("newest log entry = %q, want the last one appended", last.Message)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
@@ -73,25 +74,65 @@ func (h *Handlers) HandleUpdateCatalog(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleRefreshCatalog handles POST /catalogs/refresh
+// @ID refreshCatalog
+// @Summary Refresh catalog
+// @Description Pulls the catalog repository and reloads all module and bundle definitions. Lookups keep seeing the previous catalog until the new one is fully loaded.
+// @Tags catalog
+// @Produce json
+// @Param pull query bool false "Pull from the remote repository before reloading" default(true)
+// @Success 200 {object} RefreshResponse "Catalog reloaded"
+// @Failure 500 {string} string "Internal server error"
+// @Router /catalogs/refresh [post]
+func (h *Handlers) HandleRefreshCatalog(w http.ResponseWriter, r *http.Request) {
+	pull := r.URL.Query().Get("pull") != "false"
+	h.logger.Info("refreshing catalog via API", "pull", pull)
+
+	result, err := h.store.Refresh(pull)
+	if err != nil {
+		h.logger.Error("failed to refresh catalog", "error", err)
+		http.Error(w, fmt.Sprintf("Failed to refresh catalog: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := RefreshResponse{
+		Status:      "success",
+		Pulled:      pull,
+		ModuleCount: result.ModuleCount,
+		BundleCount: result.BundleCount,
+		Skipped:     result.Skipped,
+		Timestamp:   time.Now(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
 // HandleListModules handles GET /catalogs/modules
 // @ID listCatalogModules
 // @Summary List catalog modules
-// @Description Returns all available modules from the catalog with their metadata
+// @Description Returns available modules from the catalog, optionally filtered by a search term. The total number of matches is returned in the X-Total-Count header.
 // @Tags catalog
 // @Produce json
+// @Param q query string false "Case-insensitive substring of the name, description or a tag"
+// @Param sort query string false "Sort order: name or -name" default(name)
+// @Param offset query int false "Number of matching modules to skip" default(0)
 // @Param limit query int false "Maximum number of modules to return" default(50)
 // @Success 200 {array} ModuleResponse "List of modules with metadata and install requests"
+// @Failure 400 {string} string "Invalid query parameter"
 // @Failure 500 {string} string "Internal server error"
 // @Router /catalogs/modules [get]
 func (h *Handlers) HandleListModules(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("listing catalog modules")
 
 	// Parse query parameters
-	limit := 50 // default
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
+	lq, err := parseListQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	modules, err := h.store.GetModules()
@@ -101,23 +142,34 @@ func (h *Handlers) HandleListModules(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Apply limit
-	if len(modules) > limit {
-		modules = modules[:limit]
+	// Filter, then sort (the store returns modules sorted by name)
+	matched := make([]CatalogModule, 0, len(modules))
+	for _, module := range modules {
+		if lq.matches(module.Name, module.Description, module.Tags) {
+			matched = append(matched, module)
+		}
 	}
+	if lq.descending {
+		slices.Reverse(matched)
+	}
+
+	// Apply pagination
+	start, end := lq.window(len(matched))
 
 	// Convert to module responses
-	var responses []ModuleResponse
-	for _, module := range modules {
+	responses := make([]ModuleResponse, 0, end-start)
+	for _, module := range matched[start:end] {
 		responses = append(responses, ModuleResponse{
 			Name:        module.Name,
 			Source:      module.Source,
 			Type:        module.Type,
 			Description: module.Description,
+			Tags:        module.Tags,
 		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(matched)))
 	if err := json.NewEncoder(w).Encode(responses); err != nil {
 		h.logger.Error("failed to encode response", "error", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
@@ -127,22 +179,25 @@ func (h *Handlers) HandleListModules(w http.ResponseWriter, r *http.Request) {
 // HandleListBundles handles GET /catalogs/bundles
 // @ID listCatalogBundles
 // @Summary List catalog bundles
-// @Description Returns all available bundles from the catalog with their metadata
+// @Description Returns available bundles from the catalog with their component counts, optionally filtered by a search term. The total number of matches is returned in the X-Total-Count header.
 // @Tags catalog
 // @Produce json
+// @Param q query string false "Case-insensitive substring of the name, description or a tag"
+// @Param sort query string false "Sort order: name or -name" default(name)
+// @Param offset query int false "Number of matching bundles to skip" default(0)
 // @Param limit query int false "Maximum number of bundles to return" default(50)
 // @Success 200 {array} BundleResponse "List of bundles with metadata and install plans"
+// @Failure 400 {string} string "Invalid query parameter"
 // @Failure 500 {string} string "Internal server error"
 // @Router /catalogs/bundles [get]
 func (h *Handlers) HandleListBundles(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("listing catalog bundles")
 
 	// Parse query parameters
-	limit := 50 // default
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
+	lq, err := parseListQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	bundles, err := h.store.GetBundles()
@@ -152,24 +207,28 @@ func (h *Handlers) HandleListBundles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Apply limit
-	if len(bundles) > limit {
-		bundles = bundles[:limit]
+	// Filter, then sort (the store returns bundles sorted by name)
+	matched := make([]CatalogBundle, 0, len(bundles))
+	for _, bundle := range bundles {
+		if lq.matches(bundle.Name, bundle.Description, bundle.Tags) {
+			matched = append(matched, bundle)
+		}
 	}
+	if lq.descending {
+		slices.Reverse(matched)
+	}
+
+	// Apply pagination
+	start, end := lq.window(len(matched))
 
 	// Convert to bundle responses
-	var responses []BundleResponse
-	for _, bundle := range bundles {
-		responses = append(responses, BundleResponse{
-			Name:        bundle.Name,
-			Description: bundle.Description,
-			Modules:     bundle.Modules,
-			Links:       bundle.Links,
-			Exposures:   bundle.Exposures,
-		})
+	responses := make([]BundleResponse, 0, end-start)
+	for _, bundle := range matched[start:end] {
+		responses = append(responses, toBundleResponse(bundle))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(matched)))
 	if err := json.NewEncoder(w).Encode(responses); err != nil {
 		h.logger.Error("failed to encode response", "error", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
@@ -206,6 +265,7 @@ func (h *Handlers) HandleGetModule(w http.ResponseWriter, r *http.Request) {
 		Source:      module.Source,
 		Type:        module.Type,
 		Description: module.Description,
+		Tags:        module.Tags,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -241,12 +301,46 @@ func (h *Handlers) HandleGetBundle(w http.ResponseWriter, r *http.Request) {
 	// Get the install plan
 	// No need to resolve install plan for flattened response
 
-	response := BundleResponse{
-		Name:        bundle.Name,
-		Description: bundle.Description,
-		Modules:     bundle.Modules,
-		Links:       bundle.Links,
-		Exposures:   bundle.Exposures,
+	response := toBundleResponse(*bundle)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// HandleValidateBundle handles POST /catalogs/bundles/{bundle_name}/validate
+// @ID validateCatalogBundle
+// @Summary Validate catalog bundle
+// @Description Checks that every module the bundle installs, links or exposes exists in the catalog and that its exposures are sane, without enqueueing anything
+// @Tags catalog
+// @Produce json
+// @Param bundle_name path string true "Bundle name"
+// @Success 200 {object} ValidateResponse "Validation result with all problems found"
+// @Failure 404 {string} string "Bundle not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /catalogs/bundles/{bundle_name}/validate [post]
+func (h *Handlers) HandleValidateBundle(w http.ResponseWriter, r *http.Request) {
+	bundleName := mux.Vars(r)["bundle_name"]
+
+	bundle, err := h.store.GetBundle(bundleName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Bundle not found: %v", err), http.StatusNotFound)
+		return
+	}
+
+	problems, err := h.store.ValidateBundle(bundle)
+	if err != nil {
+		h.logger.Error("failed to validate bundle", "bundle", bundleName, "error", err)
+		http.Error(w, fmt.Sprintf("Failed to validate bundle: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := ValidateResponse{
+		Bundle:   bundleName,
+		Valid:    len(problems) == 0,
+		Problems: problems,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -255,3 +349,19 @@ func (h *Handlers) HandleGetBundle(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
+
+// HandleSyncStatus handles GET /catalogs/status
+// @ID getCatalogSyncStatus
+// @Summary Catalog sync status
+// @Description Reports the remote index URL, the periodic sync interval and the outcome of the last sync
+// @Tags catalog
+// @Produce json
+// @Success 200 {object} SyncStatus "Sync status"
+// @Router /catalogs/status [get]
+func (h *Handlers) HandleSyncStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.store.GetSyncStatus()); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
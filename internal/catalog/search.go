@@ -0,0 +1,87 @@
+package catalog
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// listQuery holds the search, sort and pagination parameters of a catalog listing
+type listQuery struct {
+	q          string
+	descending bool
+	offset     int
+	limit      int
+}
+
+// parseListQuery reads q, sort, offset and limit. Only sorting by name is supported;
+// "-name" reverses the order.
+func parseListQuery(r *http.Request) (listQuery, error) {
+	query := r.URL.Query()
+	lq := listQuery{
+		q:     strings.ToLower(strings.TrimSpace(query.Get("q"))),
+		limit: 50, // default
+	}
+
+	switch query.Get("sort") {
+	case "", "name":
+	case "-name":
+		lq.descending = true
+	default:
+		return lq, fmt.Errorf("sort must be 'name' or '-name'")
+	}
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return lq, fmt.Errorf("offset must be a non-negative integer")
+		}
+		lq.offset = offset
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			lq.limit = parsedLimit
+		}
+	}
+
+	return lq, nil
+}
+
+// matches reports whether q is a substring of the name, description or any tag
+func (lq listQuery) matches(name, description string, tags []string) bool {
+	if lq.q == "" {
+		return true
+	}
+	if strings.Contains(strings.ToLower(name), lq.q) || strings.Contains(strings.ToLower(description), lq.q) {
+		return true
+	}
+	for _, tag := range tags {
+		if strings.Contains(strings.ToLower(tag), lq.q) {
+			return true
+		}
+	}
+	return false
+}
+
+// window returns the [start, end) bounds of the requested page over n items
+func (lq listQuery) window(n int) (int, int) {
+	start := min(lq.offset, n)
+	end := min(start+lq.limit, n)
+	return start, end
+}
+
+// toBundleResponse flattens a bundle and counts its components
+func toBundleResponse(bundle CatalogBundle) BundleResponse {
+	return BundleResponse{
+		Name:          bundle.Name,
+		Description:   bundle.Description,
+		Modules:       bundle.Modules,
+		Links:         bundle.Links,
+		Exposures:     bundle.Exposures,
+		Tags:          bundle.Tags,
+		ModuleCount:   len(bundle.Modules),
+		LinkCount:     len(bundle.Links),
+		ExposureCount: len(bundle.Exposures),
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	internalPaths "zeropoint-agent/internal"
 
@@ -20,26 +23,69 @@ const (
 	bundlesDir        = "bundles"
 )
 
-// Store manages the local catalog repository and provides access to modules and bundles
+// Store manages the local catalog repository and provides access to modules and bundles.
+// Definitions are parsed into memory and swapped in as a whole, so readers never see a
+// partially loaded catalog while it is being refreshed.
 type Store struct {
 	catalogPath string
 	logger      *slog.Logger
-	mutex       sync.RWMutex
+	mutex       sync.RWMutex // guards the loaded definitions
+	updateMu    sync.Mutex   // serializes git operations and reloads
+
+	loaded  bool
+	modules map[string]CatalogModule // keyed by file name without .yaml
+	bundles map[string]CatalogBundle // keyed by file name without .yaml
+
+	// Remote index sync (see sync.go)
+	indexURL     string
+	indexPath    string
+	syncInterval time.Duration
+	index        *indexFile // last applied remote index (caller must hold updateMu)
+	syncStatus   SyncStatus // guarded by mutex
 }
 
-// NewStore creates a new catalog store
-func NewStore(logger *slog.Logger) *Store {
+// LoadResult summarizes a catalog load
+type LoadResult struct {
+	ModuleCount int      `json:"modules_count"`
+	BundleCount int      `json:"bundles_count"`
+	Skipped     []string `json:"skipped,omitempty"` // Files that failed to parse
+}
+
+// NewStore creates a new catalog store. indexURL is the remote index synced every
+// syncInterval; an empty URL disables syncing and a zero interval only syncs on request.
+func NewStore(indexURL string, syncInterval time.Duration, logger *slog.Logger) *Store {
 	return &Store{
-		catalogPath: filepath.Join(internalPaths.GetStorageRoot(), catalogDir),
-		logger:      logger,
+		catalogPath:  filepath.Join(internalPaths.GetStorageRoot(), catalogDir),
+		logger:       logger,
+		indexURL:     indexURL,
+		indexPath:    filepath.Join(internalPaths.GetStorageRoot(), indexFileName),
+		syncInterval: syncInterval,
 	}
 }
 
-// Update clones or pulls the latest catalog from the remote repository
+// Update clones or pulls the latest catalog from the remote repository and reloads it
 func (s *Store) Update() error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	_, err := s.Refresh(true)
+	return err
+}
+
+// Refresh re-reads the catalog definitions into memory, first pulling from the remote
+// repository when pull is set. The new definitions replace the old ones atomically.
+func (s *Store) Refresh(pull bool) (*LoadResult, error) {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
 
+	if pull {
+		if err := s.pull(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.reload()
+}
+
+// pull clones the catalog, or pulls the latest changes if it already exists
+func (s *Store) pull() error {
 	s.logger.Info("updating catalog", "path", s.catalogPath)
 
 	// Check if catalog directory exists
@@ -70,119 +116,206 @@ func (s *Store) Update() error {
 	return nil
 }
 
-// GetModules returns all modules from the catalog
-func (s *Store) GetModules() ([]CatalogModule, error) {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+// reload parses every definition on disk, overlays the synced remote index and swaps
+// them in (caller must hold updateMu)
+func (s *Store) reload() (*LoadResult, error) {
+	if s.index == nil {
+		s.index = s.loadIndexFile()
+	}
 
-	modulesPath := filepath.Join(s.catalogPath, modulesDir)
-	entries, err := os.ReadDir(modulesPath)
+	modules, bundles, result, err := s.readCatalog(s.index)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.modules = modules
+	s.bundles = bundles
+	s.loaded = true
+	s.mutex.Unlock()
+
+	s.logger.Info("catalog loaded", "modules", result.ModuleCount, "bundles", result.BundleCount, "skipped", len(result.Skipped))
+	return result, nil
+}
+
+// readCatalog parses the definitions in the catalog repository and overlays the entries of
+// a synced remote index, which win over repository definitions of the same name
+func (s *Store) readCatalog(index *indexFile) (map[string]CatalogModule, map[string]CatalogBundle, *LoadResult, error) {
+	result := &LoadResult{}
+
+	modules := make(map[string]CatalogModule)
+	err := s.readDefinitions(modulesDir, result, func(name, path string) error {
+		module, err := s.parseModule(path)
+		if err == nil {
+			modules[name] = module
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	bundles := make(map[string]CatalogBundle)
+	err = s.readDefinitions(bundlesDir, result, func(name, path string) error {
+		bundle, err := s.parseBundle(path)
+		if err == nil {
+			bundles[name] = bundle
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if index != nil {
+		for _, module := range index.Index.Modules {
+			modules[module.Name] = module
+		}
+		for _, bundle := range index.Index.Bundles {
+			bundles[bundle.Name] = bundle
+		}
+	}
+
+	result.ModuleCount = len(modules)
+	result.BundleCount = len(bundles)
+	return modules, bundles, result, nil
+}
+
+// readDefinitions calls parse for every YAML file in a catalog subdirectory. Files that
+// fail to parse are logged and recorded in result.Skipped. A missing directory means the
+// catalog has not been cloned yet and yields no definitions.
+func (s *Store) readDefinitions(dir string, result *LoadResult, parse func(name, path string) error) error {
+	dirPath := filepath.Join(s.catalogPath, dir)
+	entries, err := os.ReadDir(dirPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// Catalog not initialized yet - return empty list instead of error
-			s.logger.Debug("modules directory not found, returning empty list", "path", modulesPath)
-			return []CatalogModule{}, nil
+			s.logger.Debug("catalog directory not found", "path", dirPath)
+			return nil
 		}
-		return nil, fmt.Errorf("failed to read modules directory: %w", err)
+		return fmt.Errorf("failed to read %s directory: %w", dir, err)
 	}
 
-	var modules []CatalogModule
 	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".yaml" {
-			modulePath := filepath.Join(modulesPath, entry.Name())
-			module, err := s.parseModule(modulePath)
-			if err != nil {
-				s.logger.Warn("failed to parse module", "file", entry.Name(), "error", err)
-				continue
-			}
-			modules = append(modules, module)
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		if err := parse(name, filepath.Join(dirPath, entry.Name())); err != nil {
+			s.logger.Warn("failed to parse catalog definition", "file", filepath.Join(dir, entry.Name()), "error", err)
+			result.Skipped = append(result.Skipped, filepath.Join(dir, entry.Name()))
 		}
 	}
+	return nil
+}
+
+// ensureLoaded loads the catalog from disk on first use
+func (s *Store) ensureLoaded() error {
+	s.mutex.RLock()
+	loaded := s.loaded
+	s.mutex.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	// Another caller may have loaded it while we waited
+	s.mutex.RLock()
+	loaded = s.loaded
+	s.mutex.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	_, err := s.reload()
+	return err
+}
+
+// GetModules returns all modules from the catalog, sorted by name
+func (s *Store) GetModules() ([]CatalogModule, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	modules := make([]CatalogModule, 0, len(s.modules))
+	for _, module := range s.modules {
+		modules = append(modules, module)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Name < modules[j].Name
+	})
 
 	return modules, nil
 }
 
 // GetModule returns a specific module by name
 func (s *Store) GetModule(name string) (*CatalogModule, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	modulePath := filepath.Join(s.catalogPath, modulesDir, name+".yaml")
-	if _, err := os.Stat(modulePath); os.IsNotExist(err) {
+	module, ok := s.modules[name]
+	if !ok {
 		return nil, fmt.Errorf("module '%s' not found in catalog", name)
 	}
 
-	module, err := s.parseModule(modulePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse module '%s': %w", name, err)
-	}
-
 	return &module, nil
 }
 
-// GetBundles returns all bundles from the catalog
+// GetBundles returns all bundles from the catalog, sorted by name
 func (s *Store) GetBundles() ([]CatalogBundle, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	bundlesPath := filepath.Join(s.catalogPath, bundlesDir)
-	entries, err := os.ReadDir(bundlesPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Catalog not initialized yet - return empty list instead of error
-			s.logger.Debug("bundles directory not found, returning empty list", "path", bundlesPath)
-			return []CatalogBundle{}, nil
-		}
-		return nil, fmt.Errorf("failed to read bundles directory: %w", err)
-	}
-
-	var bundles []CatalogBundle
-	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".yaml" {
-			bundlePath := filepath.Join(bundlesPath, entry.Name())
-			bundle, err := s.parseBundle(bundlePath)
-			if err != nil {
-				s.logger.Warn("failed to parse bundle", "file", entry.Name(), "error", err)
-				continue
-			}
-			bundles = append(bundles, bundle)
-		}
+	bundles := make([]CatalogBundle, 0, len(s.bundles))
+	for _, bundle := range s.bundles {
+		bundles = append(bundles, bundle)
 	}
+	sort.Slice(bundles, func(i, j int) bool {
+		return bundles[i].Name < bundles[j].Name
+	})
 
 	return bundles, nil
 }
 
 // GetBundle returns a specific bundle by name
 func (s *Store) GetBundle(name string) (*CatalogBundle, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	bundlePath := filepath.Join(s.catalogPath, bundlesDir, name+".yaml")
-	if _, err := os.Stat(bundlePath); os.IsNotExist(err) {
+	bundle, ok := s.bundles[name]
+	if !ok {
 		return nil, fmt.Errorf("bundle '%s' not found in catalog", name)
 	}
 
-	bundle, err := s.parseBundle(bundlePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse bundle '%s': %w", name, err)
-	}
-
 	return &bundle, nil
 }
 
 // GetStats returns statistics about the catalog
 func (s *Store) GetStats() (int, int, error) {
-	modules, err := s.GetModules()
-	if err != nil {
+	if err := s.ensureLoaded(); err != nil {
 		return 0, 0, err
 	}
 
-	bundles, err := s.GetBundles()
-	if err != nil {
-		return 0, 0, err
-	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 
-	return len(modules), len(bundles), nil
+	return len(s.modules), len(s.bundles), nil
 }
 
 // parseModule parses a module YAML file
@@ -0,0 +1,330 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"zeropoint-agent/internal/modules"
+)
+
+const (
+	indexFileName = "catalog-index.json"
+	maxIndexSize  = 16 << 20
+)
+
+// CatalogIndex is the document served at the remote index URL
+type CatalogIndex struct {
+	Modules []CatalogModule `json:"modules"`
+	Bundles []CatalogBundle `json:"bundles"`
+}
+
+// indexFile is the last applied index, persisted with the validators used for conditional
+// requests
+type indexFile struct {
+	ETag         string       `json:"etag,omitempty"`
+	LastModified string       `json:"last_modified,omitempty"`
+	FetchedAt    time.Time    `json:"fetched_at"`
+	Index        CatalogIndex `json:"index"`
+}
+
+// SyncResult summarizes what a sync changed in the catalog
+type SyncResult struct {
+	NotModified    bool     `json:"not_modified"` // The index was unchanged since the last sync
+	ModulesAdded   []string `json:"modules_added,omitempty"`
+	ModulesUpdated []string `json:"modules_updated,omitempty"`
+	ModulesRemoved []string `json:"modules_removed,omitempty"`
+	BundlesAdded   []string `json:"bundles_added,omitempty"`
+	BundlesUpdated []string `json:"bundles_updated,omitempty"`
+	BundlesRemoved []string `json:"bundles_removed,omitempty"`
+	Problems       []string `json:"problems,omitempty"` // Validation problems that caused the index to be rejected
+}
+
+// SyncStatus describes the remote index sync
+type SyncStatus struct {
+	IndexURL        string      `json:"index_url,omitempty"` // Empty when syncing is not configured
+	IntervalSeconds int         `json:"interval_seconds"`    // 0 if periodic sync is off
+	LastAttempt     *time.Time  `json:"last_attempt,omitempty"`
+	LastSuccess     *time.Time  `json:"last_success,omitempty"`
+	LastError       string      `json:"last_error,omitempty"`
+	LastResult      *SyncResult `json:"last_result,omitempty"`
+	ETag            string      `json:"etag,omitempty"`
+	LastModified    string      `json:"last_modified,omitempty"`
+}
+
+// GetSyncStatus returns the state of the remote index sync
+func (s *Store) GetSyncStatus() SyncStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	status := s.syncStatus
+	status.IndexURL = s.indexURL
+	status.IntervalSeconds = int(s.syncInterval / time.Second)
+	return status
+}
+
+// StartSync syncs the remote index now and then every sync interval until ctx is done. It
+// is a no-op when no index URL is configured or the interval is 0.
+func (s *Store) StartSync(ctx context.Context) {
+	if s.indexURL == "" || s.syncInterval <= 0 {
+		return
+	}
+
+	s.logger.Info("starting catalog sync", "url", s.indexURL, "interval", s.syncInterval)
+	go func() {
+		for {
+			if _, err := s.Sync(ctx); err != nil {
+				s.logger.Error("catalog sync failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.syncInterval):
+			}
+		}
+	}()
+}
+
+// Sync fetches the remote index and applies its changes to the catalog. The index is
+// validated as a whole: if any entry is invalid nothing is applied and the problems are
+// returned in the result along with the error.
+func (s *Store) Sync(ctx context.Context) (*SyncResult, error) {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	result, err := s.sync(ctx)
+
+	now := time.Now()
+	s.mutex.Lock()
+	s.syncStatus.LastAttempt = &now
+	s.syncStatus.LastResult = result
+	if err != nil {
+		s.syncStatus.LastError = err.Error()
+	} else {
+		s.syncStatus.LastError = ""
+		s.syncStatus.LastSuccess = &now
+	}
+	if s.index != nil {
+		s.syncStatus.ETag = s.index.ETag
+		s.syncStatus.LastModified = s.index.LastModified
+	}
+	s.mutex.Unlock()
+
+	return result, err
+}
+
+// sync does the work of Sync (caller must hold updateMu)
+func (s *Store) sync(ctx context.Context) (*SyncResult, error) {
+	if s.indexURL == "" {
+		return nil, fmt.Errorf("catalog index URL not configured (set catalog.index_url or ZEROPOINT_CATALOG_INDEX_URL)")
+	}
+	if !strings.HasPrefix(s.indexURL, "https://") {
+		return nil, fmt.Errorf("catalog index URL must use https")
+	}
+
+	s.mutex.RLock()
+	loaded := s.loaded
+	s.mutex.RUnlock()
+	if !loaded {
+		if _, err := s.reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	fetched, err := s.fetchIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if fetched == nil {
+		s.logger.Info("catalog index not modified")
+		return &SyncResult{NotModified: true}, nil
+	}
+
+	modules, bundles, _, err := s.readCatalog(fetched)
+	if err != nil {
+		return nil, err
+	}
+	if problems := validateIndex(&fetched.Index, modules); len(problems) > 0 {
+		return &SyncResult{Problems: problems}, fmt.Errorf("catalog index rejected: %d invalid entries", len(problems))
+	}
+
+	s.mutex.RLock()
+	result := &SyncResult{}
+	result.ModulesAdded, result.ModulesUpdated, result.ModulesRemoved = diffDefinitions(s.modules, modules)
+	result.BundlesAdded, result.BundlesUpdated, result.BundlesRemoved = diffDefinitions(s.bundles, bundles)
+	s.mutex.RUnlock()
+
+	if err := s.saveIndexFile(fetched); err != nil {
+		return nil, err
+	}
+	s.index = fetched
+
+	s.mutex.Lock()
+	s.modules = modules
+	s.bundles = bundles
+	s.mutex.Unlock()
+
+	s.logger.Info("catalog index synced",
+		"modules_added", len(result.ModulesAdded), "modules_updated", len(result.ModulesUpdated), "modules_removed", len(result.ModulesRemoved),
+		"bundles_added", len(result.BundlesAdded), "bundles_updated", len(result.BundlesUpdated), "bundles_removed", len(result.BundlesRemoved))
+	return result, nil
+}
+
+// fetchIndex downloads the remote index, sending the validators of the last applied index.
+// It returns nil if the server reports the index as not modified.
+func (s *Store) fetchIndex(ctx context.Context) (*indexFile, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.indexURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid catalog index URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.index != nil {
+		if s.index.ETag != "" {
+			req.Header.Set("If-None-Match", s.index.ETag)
+		}
+		if s.index.LastModified != "" {
+			req.Header.Set("If-Modified-Since", s.index.LastModified)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch catalog index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("catalog index returned %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIndexSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog index: %w", err)
+	}
+	if len(data) > maxIndexSize {
+		return nil, fmt.Errorf("catalog index exceeds %d bytes", maxIndexSize)
+	}
+
+	fetched := &indexFile{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+	}
+	if err := json.Unmarshal(data, &fetched.Index); err != nil {
+		return nil, fmt.Errorf("invalid catalog index: %w", err)
+	}
+	return fetched, nil
+}
+
+// validateIndex checks every entry of an index. Bundles are checked against the modules the
+// catalog will have once the index is applied.
+func validateIndex(index *CatalogIndex, catalogModules map[string]CatalogModule) []string {
+	var problems []string
+
+	seen := make(map[string]bool)
+	for i, module := range index.Modules {
+		if module.Name == "" {
+			problems = append(problems, fmt.Sprintf("modules[%d]: name is required", i))
+			continue
+		}
+		if seen[module.Name] {
+			problems = append(problems, fmt.Sprintf("module %s: listed more than once", module.Name))
+		}
+		seen[module.Name] = true
+		if err := modules.ValidateSource(module.Source); err != nil {
+			problems = append(problems, fmt.Sprintf("module %s: %v", module.Name, err))
+		}
+	}
+
+	seen = make(map[string]bool)
+	inCatalog := func(moduleName string) bool {
+		_, ok := catalogModules[moduleName]
+		return ok
+	}
+	for i, bundle := range index.Bundles {
+		if bundle.Name == "" {
+			problems = append(problems, fmt.Sprintf("bundles[%d]: name is required", i))
+			continue
+		}
+		if seen[bundle.Name] {
+			problems = append(problems, fmt.Sprintf("bundle %s: listed more than once", bundle.Name))
+		}
+		seen[bundle.Name] = true
+		for _, problem := range validateBundle(&bundle, inCatalog) {
+			problems = append(problems, fmt.Sprintf("bundle %s: %s", bundle.Name, problem))
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+// diffDefinitions returns the sorted names added, changed and removed between two sets of
+// catalog definitions
+func diffDefinitions[T any](current, next map[string]T) (added, updated, removed []string) {
+	for name, definition := range next {
+		old, ok := current[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case !reflect.DeepEqual(old, definition):
+			updated = append(updated, name)
+		}
+	}
+	for name := range current {
+		if _, ok := next[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(updated)
+	sort.Strings(removed)
+	return added, updated, removed
+}
+
+// loadIndexFile reads the last applied index, or returns nil if there is none
+func (s *Store) loadIndexFile() *indexFile {
+	data, err := os.ReadFile(s.indexPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			s.logger.Warn("failed to read synced catalog index", "error", err)
+		}
+		return nil
+	}
+
+	var index indexFile
+	if err := json.Unmarshal(data, &index); err != nil {
+		s.logger.Warn("failed to load synced catalog index, ignoring it", "error", err)
+		return nil
+	}
+	return &index
+}
+
+// saveIndexFile persists an applied index
+func (s *Store) saveIndexFile(index *indexFile) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Atomic write: write to temp file, then rename
+	tmpPath := s.indexPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write catalog index: %w", err)
+	}
+	return os.Rename(tmpPath, s.indexPath)
+}
@@ -8,10 +8,11 @@ import (
 
 // CatalogModule represents a module definition from the catalog
 type CatalogModule struct {
-	Name        string `yaml:"name" json:"name"`
-	Source      string `yaml:"source" json:"source"`
-	Type        string `yaml:"type,omitempty" json:"type,omitempty"`
-	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Name        string   `yaml:"name" json:"name"`
+	Source      string   `yaml:"source" json:"source"`
+	Type        string   `yaml:"type,omitempty" json:"type,omitempty"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Tags        []string `yaml:"tags,omitempty" json:"tags,omitempty"`
 }
 
 // CatalogBundle represents a bundle definition from the catalog
@@ -21,6 +22,7 @@ type CatalogBundle struct {
 	Modules     []string                  `yaml:"modules" json:"modules"`
 	Links       map[string][]BundleLink   `yaml:"links,omitempty" json:"links,omitempty"`
 	Exposures   map[string]BundleExposure `yaml:"exposures,omitempty" json:"exposures,omitempty"`
+	Tags        []string                  `yaml:"tags,omitempty" json:"tags,omitempty"`
 }
 
 // BundleLink represents a link definition within a bundle
@@ -35,6 +37,10 @@ type BundleExposure struct {
 	Protocol    string `yaml:"protocol" json:"protocol"`
 	ModulePort  int    `yaml:"module_port" json:"module_port"`
 	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+
+	// Expose module_port even if the module does not declare it in its {container}_ports
+	// outputs
+	AllowUndeclaredPort bool `yaml:"allow_undeclared_port,omitempty" json:"allow_undeclared_port,omitempty"`
 }
 
 // BundleInstallPlan represents the install plan for a bundle
@@ -45,10 +51,11 @@ type BundleInstallPlan struct {
 
 // ModuleResponse represents the response for getting a specific module
 type ModuleResponse struct {
-	Name        string `json:"name"`
-	Source      string `json:"source"`
-	Type        string `json:"type,omitempty"`
-	Description string `json:"description,omitempty"`
+	Name        string   `json:"name"`
+	Source      string   `json:"source"`
+	Type        string   `json:"type,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
 }
 
 // BundleResponse represents the response for getting a specific bundle
@@ -58,6 +65,11 @@ type BundleResponse struct {
 	Modules     []string                  `json:"modules"`
 	Links       map[string][]BundleLink   `json:"links,omitempty"`
 	Exposures   map[string]BundleExposure `json:"exposures,omitempty"`
+	Tags        []string                  `json:"tags,omitempty"`
+	// Component counts, so a bundle card can be rendered from the listing alone
+	ModuleCount   int `json:"module_count"`
+	LinkCount     int `json:"link_count"`
+	ExposureCount int `json:"exposure_count"`
 }
 
 // UpdateResponse represents the response for catalog update
@@ -68,3 +80,20 @@ type UpdateResponse struct {
 	BundleCount int       `json:"bundles_count"`
 	Timestamp   time.Time `json:"timestamp"`
 }
+
+// RefreshResponse represents the response for a catalog refresh
+type RefreshResponse struct {
+	Status      string    `json:"status"`
+	Pulled      bool      `json:"pulled"` // Whether the remote repository was pulled before reloading
+	ModuleCount int       `json:"modules_count"`
+	BundleCount int       `json:"bundles_count"`
+	Skipped     []string  `json:"skipped,omitempty"` // Definition files that failed to parse
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// ValidateResponse represents the result of validating a bundle definition
+type ValidateResponse struct {
+	Bundle   string   `json:"bundle"`
+	Valid    bool     `json:"valid"`
+	Problems []string `json:"problems,omitempty"`
+}
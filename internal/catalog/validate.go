@@ -0,0 +1,89 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidateBundle checks that every module a bundle installs, links or exposes exists in the
+// catalog and that its exposures are sane. All problems are returned, sorted; an error is
+// only returned if the catalog itself cannot be read.
+func (s *Store) ValidateBundle(bundle *CatalogBundle) ([]string, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return validateBundle(bundle, func(moduleName string) bool {
+		_, ok := s.modules[moduleName]
+		return ok
+	}), nil
+}
+
+// validateBundle returns the problems of a bundle, sorted, given a lookup of the catalog's modules
+func validateBundle(bundle *CatalogBundle, inCatalog func(moduleName string) bool) []string {
+	var problems []string
+	if len(bundle.Modules) == 0 {
+		problems = append(problems, "bundle has no modules")
+	}
+	seen := make(map[string]bool)
+	for _, moduleName := range bundle.Modules {
+		if seen[moduleName] {
+			problems = append(problems, fmt.Sprintf("module %s is listed more than once", moduleName))
+			continue
+		}
+		seen[moduleName] = true
+		if !inCatalog(moduleName) {
+			problems = append(problems, fmt.Sprintf("module %s not found in catalog", moduleName))
+		}
+	}
+
+	for linkID, entries := range bundle.Links {
+		for _, entry := range entries {
+			if entry.Module == "" {
+				problems = append(problems, fmt.Sprintf("link %s: entry without a module", linkID))
+				continue
+			}
+			if !inCatalog(entry.Module) {
+				problems = append(problems, fmt.Sprintf("link %s: module %s not found in catalog", linkID, entry.Module))
+			}
+			for input, value := range entry.Bind {
+				fromModule, ok := bindReference(value)
+				if ok && !inCatalog(fromModule) {
+					problems = append(problems, fmt.Sprintf("link %s: %s.%s references module %s which is not in the catalog", linkID, entry.Module, input, fromModule))
+				}
+			}
+		}
+	}
+
+	for exposureID, exposure := range bundle.Exposures {
+		if exposure.Module == "" {
+			problems = append(problems, fmt.Sprintf("exposure %s: module is required", exposureID))
+		} else if !inCatalog(exposure.Module) {
+			problems = append(problems, fmt.Sprintf("exposure %s: module %s not found in catalog", exposureID, exposure.Module))
+		}
+		switch exposure.Protocol {
+		case "http", "tcp", "udp":
+		default:
+			problems = append(problems, fmt.Sprintf("exposure %s: protocol must be 'http', 'tcp' or 'udp', got %q", exposureID, exposure.Protocol))
+		}
+		if exposure.ModulePort < 1 || exposure.ModulePort > 65535 {
+			problems = append(problems, fmt.Sprintf("exposure %s: module_port %d is out of range (1-65535)", exposureID, exposure.ModulePort))
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+// bindReference returns the module of a "${module.output}" binding
+func bindReference(value string) (string, bool) {
+	if !strings.HasPrefix(value, "${") || !strings.HasSuffix(value, "}") {
+		return "", false
+	}
+	moduleName, _, ok := strings.Cut(value[2:len(value)-1], ".")
+	return moduleName, ok && moduleName != ""
+}
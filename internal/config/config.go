@@ -0,0 +1,439 @@
+// Package config loads the agent configuration: built-in defaults, overlaid by the YAML
+// file at /etc/zeropoint/agent.yaml (or the --config flag), overlaid by environment
+// variables. The result is validated once at startup and handed to the components that
+// need it.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is where the agent looks for its configuration file
+const DefaultPath = "/etc/zeropoint/agent.yaml"
+
+// Exposure port check modes
+const (
+	PortCheckStrict = "strict" // Reject undeclared container ports unless allow_undeclared_port is set
+	PortCheckWarn   = "warn"   // Log undeclared container ports and create the exposure anyway
+	PortCheckOff    = "off"    // Do not check container ports
+)
+
+// Config is the agent configuration
+type Config struct {
+	Port            int      `yaml:"port" json:"port"`                           // API port; ZEROPOINT_AGENT_PORT
+	StorageRoot     string   `yaml:"storage_root" json:"storage_root"`           // State lives under <storage_root>/data; MODULE_STORAGE_ROOT
+	JobsDir         string   `yaml:"jobs_dir" json:"jobs_dir"`                   // Defaults to <storage_root>/data/jobs; ZEROPOINT_JOBS_DIR
+	CertsDir        string   `yaml:"certs_dir" json:"certs_dir"`                 // Named TLS certificates; ZEROPOINT_CERTS_DIR
+	MarkerDir       string   `yaml:"marker_dir" json:"marker_dir"`               // Boot service markers; ZEROPOINT_MARKER_DIR
+	BootLog         string   `yaml:"boot_log" json:"boot_log"`                   // FIFO the boot services log to; ZEROPOINT_BOOT_LOG
+	JobDrainTimeout Duration `yaml:"job_drain_timeout" json:"job_drain_timeout"` // ZEROPOINT_JOB_DRAIN_TIMEOUT (seconds)
+	GPUVendor       string   `yaml:"gpu_vendor" json:"gpu_vendor"`               // Skips GPU detection: nvidia, amd, intel or none; ZEROPOINT_GPU_VENDOR
+	CallbackSecret  string   `yaml:"callback_secret" json:"callback_secret"`     // Signs job callback deliveries; unsigned if empty; ZEROPOINT_CALLBACK_SECRET
+	APITokenFile    string   `yaml:"api_token_file" json:"api_token_file"`       // Bootstrap admin API token; ZEROPOINT_API_TOKEN_FILE
+
+	// Turn API authentication off, only meant for local development; ZEROPOINT_AUTH_DISABLED
+	AuthDisabled bool `yaml:"auth_disabled" json:"auth_disabled"`
+
+	// How long an operation waits for another one to release a module;
+	// ZEROPOINT_MODULE_LOCK_TIMEOUT (seconds)
+	ModuleLockTimeout Duration `yaml:"module_lock_timeout" json:"module_lock_timeout"`
+
+	// Patterns of allowed module sources such as github.com/myorg/* or gitlab.example.com;
+	// empty allows every source; ZEROPOINT_MODULE_SOURCE_ALLOWLIST (comma-separated)
+	ModuleSourceAllowlist []string `yaml:"module_source_allowlist" json:"module_source_allowlist"`
+
+	// Host ports TCP and UDP exposures are allocated from; ZEROPOINT_EXPOSURE_PORT_MIN and
+	// ZEROPOINT_EXPOSURE_PORT_MAX
+	ExposurePortMin int `yaml:"exposure_port_min" json:"exposure_port_min"`
+	ExposurePortMax int `yaml:"exposure_port_max" json:"exposure_port_max"`
+
+	// What happens when an exposure's container_port is not among the module's declared
+	// ports: strict, warn or off; ZEROPOINT_EXPOSURE_PORT_CHECK
+	ExposurePortCheck string `yaml:"exposure_port_check" json:"exposure_port_check"`
+
+	// Reject module_exec jobs, which run arbitrary commands in module containers;
+	// ZEROPOINT_MODULE_EXEC_DISABLED
+	ModuleExecDisabled bool `yaml:"module_exec_disabled" json:"module_exec_disabled"`
+
+	// Private, loopback and link-local networks job callbacks may still be delivered to, as
+	// CIDRs such as 10.0.0.0/8; ZEROPOINT_CALLBACK_ALLOWED_NETWORKS (comma-separated)
+	CallbackAllowedNetworks []string `yaml:"callback_allowed_networks" json:"callback_allowed_networks"`
+
+	Envoy     EnvoyConfig     `yaml:"envoy" json:"envoy"`
+	MDNS      MDNSConfig      `yaml:"mdns" json:"mdns"`
+	Catalog   CatalogConfig   `yaml:"catalog" json:"catalog"`
+	Retention RetentionConfig `yaml:"retention" json:"retention"`
+}
+
+// EnvoyConfig configures the Envoy container and the xDS server it connects to
+type EnvoyConfig struct {
+	Image           string   `yaml:"image" json:"image"`                         // ZEROPOINT_ENVOY_IMAGE
+	HTTPPort        int      `yaml:"http_port" json:"http_port"`                 // ZEROPOINT_ENVOY_HTTP_PORT
+	HTTPSPort       int      `yaml:"https_port" json:"https_port"`               // ZEROPOINT_ENVOY_HTTPS_PORT
+	XDSPort         int      `yaml:"xds_port" json:"xds_port"`                   // ZEROPOINT_XDS_PORT
+	AdminAddr       string   `yaml:"admin_addr" json:"admin_addr"`               // ZEROPOINT_ENVOY_ADMIN_ADDR
+	MonitorInterval Duration `yaml:"monitor_interval" json:"monitor_interval"`   // ZEROPOINT_ENVOY_MONITOR_INTERVAL (seconds)
+	MonitorDisabled bool     `yaml:"monitor_disabled" json:"monitor_disabled"`   // ZEROPOINT_ENVOY_MONITOR_DISABLED
+	LogBuffer       int      `yaml:"log_buffer" json:"log_buffer"`               // Lines of Envoy output kept; ZEROPOINT_ENVOY_LOG_BUFFER
+	AckTimeout      Duration `yaml:"ack_timeout" json:"ack_timeout"`             // Wait for Envoy to apply a snapshot, 0 disables; ZEROPOINT_XDS_ACK_TIMEOUT (seconds)
+	AccessLog       string   `yaml:"access_log" json:"access_log"`               // Path in the Envoy container, or off; ZEROPOINT_ENVOY_ACCESS_LOG
+	AccessLogFormat string   `yaml:"access_log_format" json:"access_log_format"` // json or text; ZEROPOINT_ENVOY_ACCESS_LOG_FORMAT
+
+	// Answer HTTP exposures whose containers are all stopped with a 503 from Envoy instead
+	// of proxying into connect errors; ZEROPOINT_ENVOY_DOWN_UPSTREAM_RESPONSE
+	DownUpstreamResponse bool `yaml:"down_upstream_response" json:"down_upstream_response"`
+}
+
+// MDNSConfig configures the mDNS announcements of the agent and of exposures
+type MDNSConfig struct {
+	TTL       int    `yaml:"ttl" json:"ttl"`             // Record TTL in seconds; ZEROPOINT_MDNS_TTL
+	Interface string `yaml:"interface" json:"interface"` // Announce only on this interface; ZEROPOINT_INTERFACE
+	IP        string `yaml:"ip" json:"ip"`               // Announce only on the interface with this address; ZEROPOINT_IP
+}
+
+// CatalogConfig configures the remote catalog index sync
+type CatalogConfig struct {
+	IndexURL     string   `yaml:"index_url" json:"index_url"`         // Empty disables syncing; ZEROPOINT_CATALOG_INDEX_URL
+	SyncInterval Duration `yaml:"sync_interval" json:"sync_interval"` // 0 only syncs on request; ZEROPOINT_CATALOG_SYNC_INTERVAL (seconds)
+}
+
+// RetentionConfig bounds what the agent keeps around
+type RetentionConfig struct {
+	MaxJobEvents  int   `yaml:"max_job_events" json:"max_job_events"`   // Events per job before compaction, 0 disables; ZEROPOINT_MAX_JOB_EVENTS
+	ListJobEvents int   `yaml:"list_job_events" json:"list_job_events"` // Most recent events per job in GET /jobs, 0 leaves them empty; ZEROPOINT_LIST_JOB_EVENTS
+	BootLogLimit  int   `yaml:"boot_log_limit" json:"boot_log_limit"`   // Boot log entries kept in memory; ZEROPOINT_BOOT_LOG_LIMIT
+	AuditMaxBytes int64 `yaml:"audit_max_bytes" json:"audit_max_bytes"` // Audit log size before rotation; ZEROPOINT_AUDIT_MAX_BYTES
+}
+
+// Duration is a time.Duration written as a Go duration string, e.g. "30s", in YAML and JSON
+type Duration time.Duration
+
+// MarshalText implements encoding.TextMarshaler
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Default returns the built-in configuration
+func Default() *Config {
+	return &Config{
+		Port:            2370,
+		StorageRoot:     ".",
+		CertsDir:        "/etc/zeropoint/certs",
+		MarkerDir:       "/etc/zeropoint",
+		BootLog:         "/tmp/zeropoint-log",
+		JobDrainTimeout: Duration(30 * time.Second),
+		APITokenFile:    "/etc/zeropoint/api-token",
+
+		ModuleLockTimeout: Duration(5 * time.Minute),
+		ExposurePortCheck: PortCheckStrict,
+		ExposurePortMin:   10000,
+		ExposurePortMax:   60000,
+
+		Envoy: EnvoyConfig{
+			Image:           "envoyproxy/envoy:v1.31-latest",
+			HTTPPort:        80,
+			HTTPSPort:       443,
+			XDSPort:         18000,
+			AdminAddr:       "127.0.0.1:9901",
+			MonitorInterval: Duration(15 * time.Second),
+			LogBuffer:       500,
+			AckTimeout:      Duration(2 * time.Second),
+			AccessLog:       "/dev/stdout",
+			AccessLogFormat: "json",
+		},
+		MDNS: MDNSConfig{
+			TTL: 75,
+		},
+		Catalog: CatalogConfig{
+			SyncInterval: Duration(time.Hour),
+		},
+		Retention: RetentionConfig{
+			MaxJobEvents:  5000,
+			ListJobEvents: 20,
+			BootLogLimit:  5000,
+			AuditMaxBytes: 10 << 20,
+		},
+	}
+}
+
+// Load builds the effective configuration from the file at path and the environment, and
+// validates it. A missing file is only an error when required is set, i.e. when the path
+// was given explicitly.
+func Load(path string, required bool) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := cfg.parse(data); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && !required:
+	default:
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if cfg.JobsDir == "" {
+		cfg.JobsDir = filepath.Join(cfg.StorageRoot, "data", "jobs")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// parse overlays the YAML document on the configuration. Unknown keys are rejected so a
+// misspelled setting does not go unnoticed.
+func (c *Config) parse(data []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// applyEnv overlays the environment variables that were set
+func (c *Config) applyEnv() error {
+	var problems []string
+	str := func(key string, dst *string) {
+		if v := os.Getenv(key); v != "" {
+			*dst = v
+		}
+	}
+	num := func(key string, dst *int) {
+		if v := os.Getenv(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s=%q is not an integer", key, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	list := func(key string, dst *[]string) {
+		if v := os.Getenv(key); v != "" {
+			*dst = nil
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*dst = append(*dst, item)
+				}
+			}
+		}
+	}
+	seconds := func(key string, dst *Duration) {
+		if v := os.Getenv(key); v != "" {
+			secs, err := strconv.Atoi(v)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s=%q is not a number of seconds", key, v))
+				return
+			}
+			*dst = Duration(time.Duration(secs) * time.Second)
+		}
+	}
+
+	num("ZEROPOINT_AGENT_PORT", &c.Port)
+	str("MODULE_STORAGE_ROOT", &c.StorageRoot)
+	str("ZEROPOINT_JOBS_DIR", &c.JobsDir)
+	str("ZEROPOINT_CERTS_DIR", &c.CertsDir)
+	str("ZEROPOINT_MARKER_DIR", &c.MarkerDir)
+	str("ZEROPOINT_BOOT_LOG", &c.BootLog)
+	seconds("ZEROPOINT_JOB_DRAIN_TIMEOUT", &c.JobDrainTimeout)
+	str("ZEROPOINT_GPU_VENDOR", &c.GPUVendor)
+	str("ZEROPOINT_CALLBACK_SECRET", &c.CallbackSecret)
+	str("ZEROPOINT_EXPOSURE_PORT_CHECK", &c.ExposurePortCheck)
+	str("ZEROPOINT_API_TOKEN_FILE", &c.APITokenFile)
+	if v := os.Getenv("ZEROPOINT_AUTH_DISABLED"); v != "" {
+		disabled, err := strconv.ParseBool(v)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("ZEROPOINT_AUTH_DISABLED=%q is not a boolean", v))
+		} else {
+			c.AuthDisabled = disabled
+		}
+	}
+	seconds("ZEROPOINT_MODULE_LOCK_TIMEOUT", &c.ModuleLockTimeout)
+	list("ZEROPOINT_MODULE_SOURCE_ALLOWLIST", &c.ModuleSourceAllowlist)
+	num("ZEROPOINT_EXPOSURE_PORT_MIN", &c.ExposurePortMin)
+	num("ZEROPOINT_EXPOSURE_PORT_MAX", &c.ExposurePortMax)
+	if os.Getenv("ZEROPOINT_MODULE_EXEC_DISABLED") != "" {
+		c.ModuleExecDisabled = true
+	}
+	list("ZEROPOINT_CALLBACK_ALLOWED_NETWORKS", &c.CallbackAllowedNetworks)
+
+	str("ZEROPOINT_ENVOY_IMAGE", &c.Envoy.Image)
+	num("ZEROPOINT_ENVOY_HTTP_PORT", &c.Envoy.HTTPPort)
+	num("ZEROPOINT_ENVOY_HTTPS_PORT", &c.Envoy.HTTPSPort)
+	num("ZEROPOINT_XDS_PORT", &c.Envoy.XDSPort)
+	str("ZEROPOINT_ENVOY_ADMIN_ADDR", &c.Envoy.AdminAddr)
+	seconds("ZEROPOINT_ENVOY_MONITOR_INTERVAL", &c.Envoy.MonitorInterval)
+	if os.Getenv("ZEROPOINT_ENVOY_MONITOR_DISABLED") != "" {
+		c.Envoy.MonitorDisabled = true
+	}
+	num("ZEROPOINT_ENVOY_LOG_BUFFER", &c.Envoy.LogBuffer)
+	seconds("ZEROPOINT_XDS_ACK_TIMEOUT", &c.Envoy.AckTimeout)
+	str("ZEROPOINT_ENVOY_ACCESS_LOG", &c.Envoy.AccessLog)
+	str("ZEROPOINT_ENVOY_ACCESS_LOG_FORMAT", &c.Envoy.AccessLogFormat)
+	if os.Getenv("ZEROPOINT_ENVOY_DOWN_UPSTREAM_RESPONSE") != "" {
+		c.Envoy.DownUpstreamResponse = true
+	}
+
+	num("ZEROPOINT_MDNS_TTL", &c.MDNS.TTL)
+	str("ZEROPOINT_INTERFACE", &c.MDNS.Interface)
+	str("ZEROPOINT_IP", &c.MDNS.IP)
+
+	str("ZEROPOINT_CATALOG_INDEX_URL", &c.Catalog.IndexURL)
+	seconds("ZEROPOINT_CATALOG_SYNC_INTERVAL", &c.Catalog.SyncInterval)
+
+	num("ZEROPOINT_MAX_JOB_EVENTS", &c.Retention.MaxJobEvents)
+	num("ZEROPOINT_LIST_JOB_EVENTS", &c.Retention.ListJobEvents)
+	num("ZEROPOINT_BOOT_LOG_LIMIT", &c.Retention.BootLogLimit)
+	if v := os.Getenv("ZEROPOINT_AUDIT_MAX_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("ZEROPOINT_AUDIT_MAX_BYTES=%q is not an integer", v))
+		} else {
+			c.Retention.AuditMaxBytes = n
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid environment: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Validate checks the configuration and lists every problem found
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	ports := map[string]int{
+		"port":             c.Port,
+		"envoy.http_port":  c.Envoy.HTTPPort,
+		"envoy.https_port": c.Envoy.HTTPSPort,
+		"envoy.xds_port":   c.Envoy.XDSPort,
+	}
+	owners := make(map[int]string)
+	for _, name := range []string{"port", "envoy.http_port", "envoy.https_port", "envoy.xds_port"} {
+		port := ports[name]
+		check(port > 0 && port <= 65535, "%s: %d is not a valid port", name, port)
+		if other, taken := owners[port]; taken {
+			check(false, "%s: port %d is already used by %s", name, port, other)
+		}
+		owners[port] = name
+	}
+
+	check(c.StorageRoot != "", "storage_root: must not be empty")
+	check(c.JobsDir != "", "jobs_dir: must not be empty")
+	check(c.CertsDir != "", "certs_dir: must not be empty")
+	check(c.MarkerDir != "", "marker_dir: must not be empty")
+	check(c.BootLog != "", "boot_log: must not be empty")
+	check(c.JobDrainTimeout >= 0, "job_drain_timeout: must not be negative")
+	check(c.APITokenFile != "", "api_token_file: must not be empty")
+	check(c.ModuleLockTimeout >= 0, "module_lock_timeout: must not be negative")
+	check(c.ExposurePortMin >= 1 && c.ExposurePortMax <= 65535 && c.ExposurePortMin <= c.ExposurePortMax,
+		"exposure_port_min, exposure_port_max: %d-%d is not a valid port range", c.ExposurePortMin, c.ExposurePortMax)
+	switch c.GPUVendor {
+	case "", "nvidia", "amd", "intel", "none":
+	default:
+		check(false, "gpu_vendor: %q is not one of nvidia, amd, intel or none", c.GPUVendor)
+	}
+	switch c.ExposurePortCheck {
+	case PortCheckStrict, PortCheckWarn, PortCheckOff:
+	default:
+		check(false, "exposure_port_check: %q is not one of strict, warn or off", c.ExposurePortCheck)
+	}
+	for _, network := range c.CallbackAllowedNetworks {
+		_, err := netip.ParsePrefix(network)
+		check(err == nil, "callback_allowed_networks: %q is not a CIDR", network)
+	}
+
+	check(c.Envoy.Image != "", "envoy.image: must not be empty")
+	if _, _, err := net.SplitHostPort(c.Envoy.AdminAddr); err != nil {
+		check(false, "envoy.admin_addr: %q is not a host:port address", c.Envoy.AdminAddr)
+	}
+	check(c.Envoy.MonitorDisabled || c.Envoy.MonitorInterval > 0, "envoy.monitor_interval: must be positive unless the monitor is disabled")
+	check(c.Envoy.LogBuffer > 0, "envoy.log_buffer: must be positive")
+	check(c.Envoy.AckTimeout >= 0, "envoy.ack_timeout: must not be negative")
+	check(c.Envoy.AccessLog != "", "envoy.access_log: must not be empty, use off to disable access logs")
+	switch strings.ToLower(c.Envoy.AccessLogFormat) {
+	case "json", "text":
+	default:
+		check(false, "envoy.access_log_format: %q is not one of json or text", c.Envoy.AccessLogFormat)
+	}
+
+	check(c.MDNS.TTL > 0, "mdns.ttl: must be positive")
+	check(c.MDNS.IP == "" || net.ParseIP(c.MDNS.IP) != nil, "mdns.ip: %q is not an IP address", c.MDNS.IP)
+
+	check(c.Catalog.IndexURL == "" || strings.HasPrefix(c.Catalog.IndexURL, "https://"), "catalog.index_url: must use https")
+	check(c.Catalog.SyncInterval >= 0, "catalog.sync_interval: must not be negative")
+
+	check(c.Retention.MaxJobEvents >= 0, "retention.max_job_events: must not be negative")
+	check(c.Retention.ListJobEvents >= 0, "retention.list_job_events: must not be negative")
+	check(c.Retention.BootLogLimit > 0, "retention.boot_log_limit: must be positive")
+	check(c.Retention.AuditMaxBytes > 0, "retention.audit_max_bytes: must be positive")
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Redacted returns a copy that is safe to show: credentials embedded in URLs and the
+// callback secret are masked
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	if c.CallbackSecret != "" {
+		redacted.CallbackSecret = "redacted"
+	}
+	redacted.Catalog.IndexURL = redactURL(c.Catalog.IndexURL)
+	return &redacted
+}
+
+// redactURL masks the password and query of a URL, where access tokens usually sit
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return raw
+	}
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), "redacted")
+		}
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "redacted"
+	}
+	return u.String()
+}
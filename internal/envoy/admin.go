@@ -0,0 +1,93 @@
+package envoy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+const (
+	adminPort = "9901"
+
+	// maxAdminResponse bounds how much of an admin response is relayed
+	maxAdminResponse = 8 << 20
+)
+
+// adminPaths lists the read-only admin endpoints reachable through AdminGet, with the
+// query parameters each one accepts. Everything else (e.g. /quitquitquit, /logging,
+// /runtime_modify) stays unreachable.
+var adminPaths = map[string][]string{
+	"/stats":    {"format", "filter", "usedonly", "type"},
+	"/clusters": {"format"},
+}
+
+// AdminResponse is a relayed Envoy admin response
+type AdminResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// AdminGet sends a GET for an allowed admin path to Envoy, dropping query parameters the
+// path does not accept. The admin interface is reached at the container's address on
+// zeropoint-network, falling back to ZEROPOINT_ENVOY_ADMIN_ADDR.
+func (m *Manager) AdminGet(ctx context.Context, path string, query url.Values) (*AdminResponse, error) {
+	allowed, ok := adminPaths[path]
+	if !ok {
+		return nil, fmt.Errorf("admin path %s is not allowed", path)
+	}
+
+	filtered := url.Values{}
+	for _, key := range allowed {
+		if values, ok := query[key]; ok {
+			filtered[key] = values
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	target := url.URL{Scheme: "http", Host: m.containerAdminAddress(ctx), Path: path, RawQuery: filtered.Encode()}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("envoy admin unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAdminResponse))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read envoy admin response: %w", err)
+	}
+
+	return &AdminResponse{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	}, nil
+}
+
+// containerAdminAddress returns host:port of the admin interface on the Envoy container's
+// zeropoint-network address
+func (m *Manager) containerAdminAddress(ctx context.Context) string {
+	result, err := m.docker.ContainerInspect(ctx, containerName, client.ContainerInspectOptions{})
+	if err != nil || result.Container.NetworkSettings == nil {
+		return m.adminAddr
+	}
+
+	endpoint, ok := result.Container.NetworkSettings.Networks["zeropoint-network"]
+	if !ok || endpoint == nil || !endpoint.IPAddress.IsValid() {
+		return m.adminAddr
+	}
+	return net.JoinHostPort(endpoint.IPAddress.String(), adminPort)
+}
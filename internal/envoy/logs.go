@@ -0,0 +1,212 @@
+package envoy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/moby/moby/api/pkg/stdcopy"
+	"github.com/moby/moby/client"
+)
+
+const (
+	// Lines replayed from the container log when the tail first attaches, so errors
+	// logged before the agent started are not lost
+	initialLogTail = "200"
+	// Delay before re-attaching after the log stream ends (e.g. Envoy restarted)
+	logReattachDelay = 5 * time.Second
+)
+
+// logLevels orders Envoy log levels by severity
+var logLevels = map[string]int{
+	"trace":    0,
+	"debug":    1,
+	"info":     2,
+	"warning":  3,
+	"error":    4,
+	"critical": 5,
+}
+
+// envoyLogLine matches Envoy's default log format:
+// [2024-01-02 15:04:05.000][1][warning][config] [source/file.cc:123] message
+var envoyLogLine = regexp.MustCompile(`^\[([^\]]+)\]\[\d+\]\[(\w+)\]\[([^\]]*)\] (.*)$`)
+
+// LogEntry is a warning or error line captured from the Envoy container
+type LogEntry struct {
+	Time      string `json:"time"`      // Timestamp as logged by Envoy
+	Level     string `json:"level"`     // "warning", "error" or "critical"
+	Component string `json:"component"` // Envoy logger name, e.g. "config" or "upstream"
+	Message   string `json:"message"`
+}
+
+// logBuffer is a bounded ring of recent Envoy log entries
+type logBuffer struct {
+	mu      sync.RWMutex
+	entries []LogEntry
+	next    int
+	full    bool
+}
+
+func newLogBuffer(size int) logBuffer {
+	if size <= 0 {
+		size = 500
+	}
+	return logBuffer{entries: make([]LogEntry, size)}
+}
+
+func (b *logBuffer) add(entry LogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// snapshot returns the buffered entries, oldest first
+func (b *logBuffer) snapshot() []LogEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if !b.full {
+		return append([]LogEntry(nil), b.entries[:b.next]...)
+	}
+	result := make([]LogEntry, 0, len(b.entries))
+	result = append(result, b.entries[b.next:]...)
+	return append(result, b.entries[:b.next]...)
+}
+
+// Logs returns captured Envoy log entries at or above minLevel, oldest first.
+// An empty minLevel returns everything that was captured (warnings and above).
+func (m *Manager) Logs(minLevel string) []LogEntry {
+	threshold := logLevels[minLevel]
+
+	entries := m.logs.snapshot()
+	filtered := make([]LogEntry, 0, len(entries))
+	for _, entry := range entries {
+		if logLevels[entry.Level] >= threshold {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// ValidLogLevel reports whether level is an Envoy log level
+func ValidLogLevel(level string) bool {
+	_, ok := logLevels[level]
+	return ok
+}
+
+// StartLogTail follows the Envoy container's logs in the background, keeping recent
+// warnings and errors (such as rejected configuration) in a bounded buffer
+func (m *Manager) StartLogTail(ctx context.Context) {
+	m.logger.Info("starting envoy log tail", "buffer", len(m.logs.entries))
+	go m.runLogTail(ctx)
+}
+
+// runLogTail attaches to the container log stream, re-attaching whenever it ends
+func (m *Manager) runLogTail(ctx context.Context) {
+	opts := client.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Tail:       initialLogTail,
+	}
+
+	for {
+		attachedAt := time.Now()
+		if err := m.tailLogs(ctx, opts); err != nil && ctx.Err() == nil {
+			m.logger.Debug("envoy log stream ended", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(logReattachDelay):
+		}
+
+		// Only pick up lines written since the previous stream was opened
+		opts.Tail = ""
+		opts.Since = strconv.FormatInt(attachedAt.Unix(), 10)
+	}
+}
+
+// tailLogs streams container logs until the stream ends or ctx is cancelled
+func (m *Manager) tailLogs(ctx context.Context, opts client.ContainerLogsOptions) error {
+	stream, err := m.docker.ContainerLogs(ctx, containerName, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	// The container runs without a TTY, so stdout and stderr are multiplexed
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(pw, pw, stream)
+		pw.CloseWithError(err)
+	}()
+	defer pr.Close()
+
+	scanner := bufio.NewScanner(pr)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if entry, ok := parseLogLine(scanner.Text()); ok {
+			m.logs.add(entry)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseLogLine extracts warning-and-above entries; access log and lower-level lines are skipped
+func parseLogLine(line string) (LogEntry, bool) {
+	match := envoyLogLine.FindStringSubmatch(line)
+	if match == nil {
+		return LogEntry{}, false
+	}
+	level := match[2]
+	if logLevels[level] < logLevels["warning"] {
+		return LogEntry{}, false
+	}
+	return LogEntry{
+		Time:      match[1],
+		Level:     level,
+		Component: match[3],
+		Message:   match[4],
+	}, true
+}
+
+// AccessLogs returns the last tail lines Envoy wrote to stdout, where access logs go by
+// default; Envoy's own log goes to stderr and is left out
+func (m *Manager) AccessLogs(ctx context.Context, tail int) ([]string, error) {
+	stream, err := m.docker.ContainerLogs(ctx, containerName, client.ContainerLogsOptions{
+		ShowStdout: true,
+		Tail:       strconv.Itoa(tail),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(pw, io.Discard, stream)
+		pw.CloseWithError(err)
+	}()
+	defer pr.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(pr)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
-	"strconv"
+	"net/netip"
+	"sync"
+	"time"
+
+	"zeropoint-agent/internal/config"
 
 	"github.com/moby/moby/api/types/container"
 	"github.com/moby/moby/api/types/network"
@@ -15,7 +18,9 @@ import (
 
 const (
 	containerName = "zeropoint-envoy"
-	defaultImage  = "envoyproxy/envoy:v1.31-latest"
+
+	// Name the previous container is parked under while Recreate starts its replacement
+	previousContainerName = containerName + "-previous"
 )
 
 // Manager handles the lifecycle of the Envoy proxy container
@@ -26,22 +31,41 @@ type Manager struct {
 	httpsPort int
 	xdsPort   int
 	image     string
+	adminAddr string
+
+	lifecycle sync.Mutex // serializes EnsureRunning and Recreate
+
+	monitorInterval time.Duration
+	monitorEnabled  bool
+	monitor         monitorState
+
+	logs logBuffer
 }
 
 // NewManager creates a new Envoy manager
-func NewManager(docker *client.Client, logger *slog.Logger) *Manager {
+func NewManager(docker *client.Client, cfg config.EnvoyConfig, logger *slog.Logger) *Manager {
 	return &Manager{
 		docker:    docker,
 		logger:    logger,
-		httpPort:  getEnvInt("ZEROPOINT_ENVOY_HTTP_PORT", 80),
-		httpsPort: getEnvInt("ZEROPOINT_ENVOY_HTTPS_PORT", 443),
-		xdsPort:   getEnvInt("ZEROPOINT_XDS_PORT", 18000),
-		image:     getEnvString("ZEROPOINT_ENVOY_IMAGE", defaultImage),
+		httpPort:  cfg.HTTPPort,
+		httpsPort: cfg.HTTPSPort,
+		xdsPort:   cfg.XDSPort,
+		image:     cfg.Image,
+		adminAddr: cfg.AdminAddr,
+
+		monitorInterval: time.Duration(cfg.MonitorInterval),
+		monitorEnabled:  !cfg.MonitorDisabled,
+
+		logs: newLogBuffer(cfg.LogBuffer),
 	}
 }
 
-// EnsureRunning ensures the Envoy container is running
+// EnsureRunning ensures the Envoy container is running. A container created from a
+// different image than the configured one is recreated.
 func (m *Manager) EnsureRunning(ctx context.Context) error {
+	m.lifecycle.Lock()
+	defer m.lifecycle.Unlock()
+
 	m.logger.Info("ensuring envoy container is running")
 
 	// Check if container exists
@@ -55,11 +79,13 @@ func (m *Manager) EnsureRunning(ctx context.Context) error {
 	// Find our container by name
 	var containerID string
 	var containerState string
+	var containerImage string
 	for _, c := range result.Items {
 		for _, name := range c.Names {
 			if name == "/"+containerName || name == containerName {
 				containerID = c.ID
 				containerState = string(c.State)
+				containerImage = c.Image
 				break
 			}
 		}
@@ -70,6 +96,11 @@ func (m *Manager) EnsureRunning(ctx context.Context) error {
 
 	if containerID != "" {
 		// Container exists
+		if containerImage != m.image {
+			m.logger.Info("envoy image changed, recreating container", "id", containerID[:12], "from", containerImage, "to", m.image)
+			return m.recreate(ctx, containerID, containerState == "running")
+		}
+
 		if containerState == "running" {
 			m.logger.Info("envoy container already running", "id", containerID[:12])
 			return nil
@@ -89,6 +120,123 @@ func (m *Manager) EnsureRunning(ctx context.Context) error {
 	return m.createAndStart(ctx)
 }
 
+// Recreate replaces the Envoy container with a new one built from the current image, ports
+// and bootstrap config. The old container is stopped and set aside rather than removed, and
+// is only removed once the new one has started; if it fails to start, the old one is put back.
+func (m *Manager) Recreate(ctx context.Context) error {
+	m.lifecycle.Lock()
+	defer m.lifecycle.Unlock()
+
+	m.logger.Info("recreating envoy container", "image", m.image)
+
+	result, err := m.docker.ContainerList(ctx, client.ContainerListOptions{
+		All: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	for _, c := range result.Items {
+		for _, name := range c.Names {
+			if name == "/"+containerName || name == containerName {
+				return m.recreate(ctx, c.ID, string(c.State) == "running")
+			}
+		}
+	}
+
+	// Nothing to replace
+	return m.createAndStart(ctx)
+}
+
+// recreate swaps the container with the given ID for a freshly created one.
+// The new container needs the same name and host ports, so the old one has to be stopped
+// and renamed first.
+func (m *Manager) recreate(ctx context.Context, oldID string, wasRunning bool) error {
+	// Make sure the image is there before taking the running proxy down
+	if err := m.ensureImage(ctx); err != nil {
+		return err
+	}
+
+	// Clear out a container left behind by an interrupted recreate
+	if _, err := m.docker.ContainerRemove(ctx, previousContainerName, client.ContainerRemoveOptions{Force: true}); err == nil {
+		m.logger.Warn("removed leftover envoy container", "name", previousContainerName)
+	}
+
+	if wasRunning {
+		if _, err := m.docker.ContainerStop(ctx, oldID, client.ContainerStopOptions{}); err != nil {
+			return fmt.Errorf("failed to stop envoy container: %w", err)
+		}
+	}
+	if _, err := m.docker.ContainerRename(ctx, oldID, client.ContainerRenameOptions{NewName: previousContainerName}); err != nil {
+		m.restorePrevious(ctx, oldID, wasRunning, false)
+		return fmt.Errorf("failed to rename envoy container: %w", err)
+	}
+
+	if err := m.createAndStart(ctx); err != nil {
+		m.logger.Error("replacement envoy container failed, restoring previous one", "error", err)
+		m.restorePrevious(ctx, oldID, wasRunning, true)
+		return fmt.Errorf("failed to recreate envoy container: %w", err)
+	}
+
+	if _, err := m.docker.ContainerRemove(ctx, oldID, client.ContainerRemoveOptions{Force: true}); err != nil {
+		m.logger.Warn("failed to remove previous envoy container", "id", oldID[:12], "error", err)
+	}
+
+	m.logger.Info("envoy container recreated", "previous", oldID[:12])
+	return nil
+}
+
+// restorePrevious puts the container set aside by recreate back in place, removing a
+// replacement that was created but failed to start
+func (m *Manager) restorePrevious(ctx context.Context, oldID string, wasRunning, renamed bool) {
+	if renamed {
+		if _, err := m.docker.ContainerRemove(ctx, containerName, client.ContainerRemoveOptions{Force: true}); err != nil {
+			m.logger.Debug("no replacement envoy container to remove", "error", err)
+		}
+		if _, err := m.docker.ContainerRename(ctx, oldID, client.ContainerRenameOptions{NewName: containerName}); err != nil {
+			m.logger.Error("failed to restore previous envoy container name", "id", oldID[:12], "error", err)
+		}
+	}
+
+	if wasRunning {
+		if _, err := m.docker.ContainerStart(ctx, oldID, client.ContainerStartOptions{}); err != nil {
+			m.logger.Error("failed to restart previous envoy container", "id", oldID[:12], "error", err)
+			return
+		}
+	}
+	m.logger.Info("previous envoy container restored", "id", oldID[:12])
+}
+
+// ContainerState returns the Docker state of the Envoy container (e.g. "running", "exited"),
+// or an empty string if the container does not exist
+func (m *Manager) ContainerState(ctx context.Context) (string, error) {
+	result, err := m.docker.ContainerList(ctx, client.ContainerListOptions{
+		All: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	for _, c := range result.Items {
+		for _, name := range c.Names {
+			if name == "/"+containerName || name == containerName {
+				return string(c.State), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// IsRunning reports whether the Envoy container is running, for the metrics
+func (m *Manager) IsRunning() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	state, err := m.ContainerState(ctx)
+	return err == nil && state == "running"
+}
+
 // Stop stops the Envoy container (does not remove it)
 func (m *Manager) Stop(ctx context.Context) error {
 	m.logger.Info("stopping envoy container")
@@ -179,8 +327,9 @@ func (m *Manager) createAndStart(ctx context.Context) error {
 				network.MustParsePort(fmt.Sprintf("%d/tcp", m.httpsPort)): []network.PortBinding{
 					{HostPort: fmt.Sprintf("%d", m.httpsPort)},
 				},
+				// Admin stays on loopback; the API relays a read-only subset of it
 				network.MustParsePort("9901/tcp"): []network.PortBinding{
-					{HostPort: "9901"},
+					{HostIP: netip.MustParseAddr("127.0.0.1"), HostPort: "9901"},
 				},
 			},
 			Binds: []string{
@@ -247,22 +396,6 @@ func (m *Manager) ensureImage(ctx context.Context) error {
 	return nil
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if val := os.Getenv(key); val != "" {
-		if intVal, err := strconv.Atoi(val); err == nil {
-			return intVal
-		}
-	}
-	return defaultValue
-}
-
-func getEnvString(key, defaultValue string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
-	}
-	return defaultValue
-}
-
 // ensureZeropointNetwork connects Envoy to the zeropoint-network
 func (m *Manager) ensureZeropointNetwork(ctx context.Context, containerID string) error {
 	networkName := "zeropoint-network"
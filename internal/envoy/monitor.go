@@ -0,0 +1,189 @@
+package envoy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+const (
+	// Consecutive failed /ready probes before a running container is restarted
+	readyFailureThreshold = 3
+	maxMonitorBackoff     = 5 * time.Minute
+)
+
+// Status describes the Envoy container as last observed by the health monitor
+type Status struct {
+	MonitorEnabled      bool       `json:"monitor_enabled"`
+	State               string     `json:"state"` // Docker state, or "missing" if the container does not exist
+	Ready               bool       `json:"ready"` // Admin /ready returned 200
+	LastCheck           *time.Time `json:"last_check,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Recoveries          int        `json:"recoveries"` // Number of times the monitor restarted or recreated Envoy
+	LastRecovery        *time.Time `json:"last_recovery,omitempty"`
+}
+
+// RecoveryFunc is called after the monitor restarts or recreates Envoy,
+// typically to re-push the current xDS snapshot
+type RecoveryFunc func(ctx context.Context) error
+
+// monitorState holds health monitor bookkeeping, guarded by its own mutex
+type monitorState struct {
+	mu          sync.RWMutex
+	status      Status
+	readyMisses int
+}
+
+// AdminAddress returns the host:port of the Envoy admin interface as reachable from the agent
+func (m *Manager) AdminAddress() string {
+	return m.adminAddr
+}
+
+// GetStatus returns the most recent health monitor observation
+func (m *Manager) GetStatus() Status {
+	m.monitor.mu.RLock()
+	defer m.monitor.mu.RUnlock()
+	return m.monitor.status
+}
+
+// StartMonitor starts a background loop that keeps the Envoy container running.
+// It is a no-op when ZEROPOINT_ENVOY_MONITOR_DISABLED is set. onRecovered is called
+// after every restart or recreation.
+func (m *Manager) StartMonitor(ctx context.Context, onRecovered RecoveryFunc) {
+	if !m.monitorEnabled {
+		m.logger.Info("envoy health monitor disabled")
+		return
+	}
+
+	m.monitor.mu.Lock()
+	m.monitor.status.MonitorEnabled = true
+	m.monitor.mu.Unlock()
+
+	m.logger.Info("starting envoy health monitor", "interval", m.monitorInterval)
+	go m.runMonitor(ctx, onRecovered)
+}
+
+// runMonitor checks Envoy every interval, backing off exponentially while recovery keeps failing
+func (m *Manager) runMonitor(ctx context.Context, onRecovered RecoveryFunc) {
+	wait := m.monitorInterval
+	for {
+		select {
+		case <-ctx.Done():
+			m.logger.Info("envoy health monitor stopped")
+			return
+		case <-time.After(wait):
+		}
+
+		if err := m.checkAndRecover(ctx, onRecovered); err != nil {
+			m.monitor.mu.Lock()
+			m.monitor.status.ConsecutiveFailures++
+			m.monitor.status.LastError = err.Error()
+			failures := m.monitor.status.ConsecutiveFailures
+			m.monitor.mu.Unlock()
+
+			wait = m.monitorInterval << min(failures, 10)
+			if wait > maxMonitorBackoff {
+				wait = maxMonitorBackoff
+			}
+			m.logger.Error("envoy health check failed", "error", err, "failures", failures, "retry_in", wait)
+			continue
+		}
+
+		m.monitor.mu.Lock()
+		m.monitor.status.ConsecutiveFailures = 0
+		m.monitor.status.LastError = ""
+		m.monitor.mu.Unlock()
+		wait = m.monitorInterval
+	}
+}
+
+// checkAndRecover inspects the container and admin endpoint, restarting or recreating
+// Envoy as needed
+func (m *Manager) checkAndRecover(ctx context.Context, onRecovered RecoveryFunc) error {
+	checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	state, err := m.ContainerState(checkCtx)
+
+	m.monitor.mu.Lock()
+	m.monitor.status.LastCheck = &now
+	m.monitor.status.State = state
+	if state == "" {
+		m.monitor.status.State = "missing"
+	}
+	m.monitor.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	switch state {
+	case "running":
+		ready := m.probeReady(checkCtx)
+
+		m.monitor.mu.Lock()
+		m.monitor.status.Ready = ready
+		if ready {
+			m.monitor.readyMisses = 0
+		} else {
+			m.monitor.readyMisses++
+		}
+		misses := m.monitor.readyMisses
+		m.monitor.mu.Unlock()
+
+		if misses < readyFailureThreshold {
+			return nil
+		}
+
+		m.logger.Warn("envoy admin not ready, restarting container", "misses", misses)
+		if _, err := m.docker.ContainerRestart(checkCtx, containerName, client.ContainerRestartOptions{}); err != nil {
+			return fmt.Errorf("failed to restart envoy: %w", err)
+		}
+	case "restarting":
+		// Docker's restart policy is already handling it
+		return nil
+	default:
+		m.logger.Warn("envoy container not running, recovering", "state", state)
+		if err := m.EnsureRunning(checkCtx); err != nil {
+			return fmt.Errorf("failed to recover envoy: %w", err)
+		}
+	}
+
+	recoveredAt := time.Now()
+	m.monitor.mu.Lock()
+	m.monitor.readyMisses = 0
+	m.monitor.status.Recoveries++
+	m.monitor.status.LastRecovery = &recoveredAt
+	m.monitor.mu.Unlock()
+
+	if onRecovered != nil {
+		if err := onRecovered(checkCtx); err != nil {
+			return fmt.Errorf("envoy recovered but resync failed: %w", err)
+		}
+	}
+
+	m.logger.Info("envoy recovered")
+	return nil
+}
+
+// probeReady queries the admin /ready endpoint
+func (m *Manager) probeReady(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s/ready", m.adminAddr), nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
@@ -321,3 +321,81 @@ func ctyToGo(val cty.Value) (interface{}, error) {
 		return nil, fmt.Errorf("unsupported cty type: %s", valType.FriendlyName())
 	}
 }
+
+// ParseModuleImages parses main.tf and returns the container images the module uses: the name
+// of every docker_image resource and the image of every docker_container whose image is not
+// taken from a docker_image. Expressions may use var.* from variables and the variable
+// defaults; images that still cannot be evaluated are skipped.
+func ParseModuleImages(modulePath string, variables map[string]string) ([]string, error) {
+	mainTfPath := filepath.Join(modulePath, "main.tf")
+
+	parser := hclparse.NewParser()
+	file, diags := parser.ParseHCLFile(mainTfPath)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("unexpected body type: %T", file.Body)
+	}
+
+	inputs, err := ParseModuleInputs(modulePath)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]cty.Value, len(inputs)+len(variables))
+	for name, input := range inputs {
+		if input.Default == nil {
+			continue
+		}
+		impliedType, err := gocty.ImpliedType(input.Default)
+		if err != nil {
+			continue // Collections decoded as interface{} have no implied type; images rarely use them
+		}
+		if val, err := gocty.ToCtyValue(input.Default, impliedType); err == nil {
+			vars[name] = val
+		}
+	}
+	for name, value := range variables {
+		vars[name] = cty.StringVal(value)
+	}
+	evalCtx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{"var": cty.ObjectVal(vars)},
+	}
+
+	seen := make(map[string]bool)
+	var images []string
+	for _, block := range body.Blocks {
+		if block.Type != "resource" || len(block.Labels) < 1 {
+			continue
+		}
+
+		var attrName string
+		switch block.Labels[0] {
+		case "docker_image":
+			attrName = "name"
+		case "docker_container":
+			attrName = "image"
+		default:
+			continue
+		}
+
+		attr, ok := block.Body.Attributes[attrName]
+		if !ok {
+			continue
+		}
+		val, diags := attr.Expr.Value(evalCtx)
+		if diags.HasErrors() || !val.IsKnown() || val.IsNull() || val.Type() != cty.String {
+			continue // e.g. docker_image.main.image_id, pulled through the docker_image resource
+		}
+
+		image := val.AsString()
+		if image != "" && !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+
+	return images, nil
+}
@@ -6,11 +6,12 @@ import (
 	"log/slog"
 	"net"
 	"os"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"zeropoint-agent/internal/config"
+
 	"github.com/grandcat/zeroconf"
 )
 
@@ -26,25 +27,18 @@ type Service struct {
 	exposures map[string]*zeroconf.Server // hostname -> server
 	mu        sync.RWMutex
 	ttl       uint32 // TTL for mDNS records in seconds
+	ifaceName string // Manual interface override
+	manualIP  string // Announce on the interface with this address
 }
 
 // NewService creates a new mDNS service announcer
-func NewService(logger *slog.Logger) *Service {
-	// Parse TTL from environment variable, default to 75 seconds
-	ttl := uint32(75)
-	if ttlEnv := os.Getenv("ZEROPOINT_MDNS_TTL"); ttlEnv != "" {
-		if parsedTTL, err := strconv.ParseUint(ttlEnv, 10, 32); err == nil {
-			ttl = uint32(parsedTTL)
-			logger.Debug("using custom mDNS TTL from environment", "ttl", ttl)
-		} else {
-			logger.Warn("invalid ZEROPOINT_MDNS_TTL value, using default", "value", ttlEnv, "default", ttl)
-		}
-	}
-
+func NewService(cfg config.MDNSConfig, logger *slog.Logger) *Service {
 	return &Service{
 		logger:    logger,
 		exposures: make(map[string]*zeroconf.Server),
-		ttl:       ttl,
+		ttl:       uint32(cfg.TTL),
+		ifaceName: cfg.Interface,
+		manualIP:  cfg.IP,
 	}
 }
 
@@ -59,14 +53,14 @@ func (s *Service) Register(ctx context.Context, port int) error {
 	var ifaces []net.Interface
 
 	// Check for manual interface override
-	if ifaceName := os.Getenv("ZEROPOINT_INTERFACE"); ifaceName != "" {
+	if ifaceName := s.ifaceName; ifaceName != "" {
 		iface, err := net.InterfaceByName(ifaceName)
 		if err != nil {
 			return fmt.Errorf("failed to get interface %s: %w", ifaceName, err)
 		}
 		ifaces = []net.Interface{*iface}
 		s.logger.Info("using manual interface", "interface", ifaceName)
-	} else if manualIP := os.Getenv("ZEROPOINT_IP"); manualIP != "" {
+	} else if manualIP := s.manualIP; manualIP != "" {
 		// Find interface with matching IP
 		targetIP := net.ParseIP(manualIP)
 		if targetIP == nil {
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+)
+
+// InstrumentDocker wraps the Docker client's transport to count failed API calls: requests
+// that could not reach the daemon and responses with a 5xx status. Client errors such as
+// 404 are part of normal operation and not counted.
+func InstrumentDocker(next http.RoundTripper) http.RoundTripper {
+	return dockerTransport{next: next}
+}
+
+type dockerTransport struct {
+	next http.RoundTripper
+}
+
+func (t dockerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode >= http.StatusInternalServerError {
+		DockerAPIError(req.Method, dockerResource(req.URL.Path))
+	}
+	return resp, err
+}
+
+// dockerResource returns the resource an API path addresses, e.g. "containers" for
+// /v1.47/containers/abc/json
+func dockerResource(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 1 && strings.HasPrefix(segments[0], "v1.") {
+		segments = segments[1:]
+	}
+	return segments[0]
+}
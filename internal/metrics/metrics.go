@@ -0,0 +1,188 @@
+// Package metrics defines the agent's Prometheus metrics. Metric names are part of the
+// agent's interface: dashboards and alerts depend on them, so they must not change.
+// Packages report through the functions here instead of holding collectors themselves.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "zeropoint"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Time from a job starting to it completing, failing or being cancelled.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 30, 60, 120, 300, 600, 1800},
+	}, []string{"command", "status"})
+
+	xdsSnapshotVersion = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "xds_snapshot_version",
+		Help:      "Version of the last xDS snapshot pushed to Envoy.",
+	})
+
+	xdsLastPush = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "xds_last_push_timestamp_seconds",
+		Help:      "Unix time of the last xDS snapshot push.",
+	})
+
+	xdsPushErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "xds_push_errors_total",
+		Help:      "xDS pushes that failed: snapshots the cache refused (set_snapshot) and updates Envoy rejected (nack).",
+	}, []string{"reason"})
+
+	dockerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "docker_api_errors_total",
+		Help:      "Docker API calls that failed to connect or returned a server error.",
+	}, []string{"method", "resource"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		jobDuration,
+		xdsSnapshotVersion,
+		xdsLastPush,
+		xdsPushErrors,
+		dockerErrors,
+		sources,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveJob records how long a finished job ran
+func ObserveJob(command, status string, duration time.Duration) {
+	jobDuration.WithLabelValues(command, status).Observe(duration.Seconds())
+}
+
+// XDSSnapshotPushed records a snapshot pushed to Envoy. Versions are the xDS server's
+// monotonic counter; other versions only update the timestamp.
+func XDSSnapshotPushed(version string) {
+	if v, err := strconv.ParseFloat(version, 64); err == nil {
+		xdsSnapshotVersion.Set(v)
+	}
+	xdsLastPush.SetToCurrentTime()
+}
+
+// XDSPushFailed counts a failed xDS push; reason is "set_snapshot" or "nack"
+func XDSPushFailed(reason string) {
+	xdsPushErrors.WithLabelValues(reason).Inc()
+}
+
+// DockerAPIError counts a failed Docker API call
+func DockerAPIError(method, resource string) {
+	dockerErrors.WithLabelValues(method, resource).Inc()
+}
+
+// JobCount is the number of jobs of one command type in one status
+type JobCount struct {
+	Command string
+	Status  string
+	Count   int
+}
+
+// QueueStats summarizes the job queue at scrape time
+type QueueStats struct {
+	Jobs            []JobCount
+	Depth           int           // Queued jobs
+	OldestQueuedAge time.Duration // Zero when nothing is queued
+}
+
+// RegisterQueue sets the function that reports the job queue on each scrape
+func RegisterQueue(stats func() QueueStats) {
+	sources.mu.Lock()
+	defer sources.mu.Unlock()
+	sources.queue = stats
+}
+
+// RegisterExposures sets the function that counts exposures by protocol on each scrape
+func RegisterExposures(count func() map[string]int) {
+	sources.mu.Lock()
+	defer sources.mu.Unlock()
+	sources.exposures = count
+}
+
+// RegisterEnvoy sets the function that reports whether the Envoy container is running on
+// each scrape
+func RegisterEnvoy(up func() bool) {
+	sources.mu.Lock()
+	defer sources.mu.Unlock()
+	sources.envoyUp = up
+}
+
+var (
+	jobsDesc = prometheus.NewDesc(namespace+"_jobs", "Jobs by command type and status.",
+		[]string{"command", "status"}, nil)
+	queueDepthDesc = prometheus.NewDesc(namespace+"_queue_depth", "Jobs waiting to run.",
+		nil, nil)
+	oldestQueuedDesc = prometheus.NewDesc(namespace+"_queue_oldest_job_age_seconds", "Age of the oldest queued job, 0 when nothing is queued.",
+		nil, nil)
+	exposuresDesc = prometheus.NewDesc(namespace+"_exposures", "Exposures by protocol.",
+		[]string{"protocol"}, nil)
+	envoyUpDesc = prometheus.NewDesc(namespace+"_envoy_up", "Whether the Envoy container is running (1) or not (0).",
+		nil, nil)
+)
+
+// sources collects the metrics computed from agent state when scraped
+var sources = &sourceCollector{}
+
+type sourceCollector struct {
+	mu        sync.Mutex
+	queue     func() QueueStats
+	exposures func() map[string]int
+	envoyUp   func() bool
+}
+
+func (c *sourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- jobsDesc
+	ch <- queueDepthDesc
+	ch <- oldestQueuedDesc
+	ch <- exposuresDesc
+	ch <- envoyUpDesc
+}
+
+func (c *sourceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	queue, exposures, envoyUp := c.queue, c.exposures, c.envoyUp
+	c.mu.Unlock()
+
+	if queue != nil {
+		stats := queue()
+		for _, jobs := range stats.Jobs {
+			ch <- prometheus.MustNewConstMetric(jobsDesc, prometheus.GaugeValue, float64(jobs.Count), jobs.Command, jobs.Status)
+		}
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(stats.Depth))
+		ch <- prometheus.MustNewConstMetric(oldestQueuedDesc, prometheus.GaugeValue, stats.OldestQueuedAge.Seconds())
+	}
+	if exposures != nil {
+		for protocol, count := range exposures() {
+			ch <- prometheus.MustNewConstMetric(exposuresDesc, prometheus.GaugeValue, float64(count), protocol)
+		}
+	}
+	if envoyUp != nil {
+		up := 0.0
+		if envoyUp() {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(envoyUpDesc, prometheus.GaugeValue, up)
+	}
+}
@@ -3,6 +3,7 @@ package modules
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/moby/moby/client"
 )
@@ -54,6 +55,28 @@ type Module struct {
 	Containers map[string]Container `json:"containers,omitempty"`
 	// @Description Optional tags for categorization
 	Tags []string `json:"tags,omitempty"`
+	// @Description Git URL the module was installed from (omitted for local modules)
+	Source string `json:"source,omitempty"`
+	// @Description Commit SHA the module was installed from (omitted for local modules)
+	Ref string `json:"ref,omitempty"`
+	// @Description When the module was installed (omitted for local modules)
+	InstalledAt *time.Time `json:"installed_at,omitempty"`
+	// @Description Resource limits the module is applied with (omitted if none are set)
+	Resources *Resources `json:"resources,omitempty"`
+	// @Description Terraform output names available for linking
+	Outputs []string `json:"outputs,omitempty"`
+	// @Description Most recent restart, stop or start of the module's containers since the agent started
+	LastAction *LifecycleAction `json:"last_action,omitempty"`
+}
+
+// LifecycleAction records a restart, stop or start of a module's containers
+type LifecycleAction struct {
+	Action      string     `json:"action"`           // restart, stop or start
+	Status      string     `json:"status"`           // running, completed or failed
+	JobID       string     `json:"job_id,omitempty"` // Set when the action ran through the job queue
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
 }
 
 // Module states
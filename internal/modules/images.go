@@ -0,0 +1,70 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"zeropoint-agent/internal/hcl"
+
+	"github.com/moby/moby/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// prefetchImages pulls every image the module's terraform uses for the target architecture
+// before apply, so a bad image reference fails the install before any resource exists
+func (i *Installer) prefetchImages(ctx context.Context, logger *slog.Logger, modulePath string, variables map[string]string, progress ProgressCallback) error {
+	images, err := hcl.ParseModuleImages(modulePath, variables)
+	if err != nil {
+		return fmt.Errorf("failed to read module images: %w", err)
+	}
+	if len(images) == 0 {
+		return nil
+	}
+
+	arch := variables["zp_arch"]
+	for _, image := range images {
+		logger.Info("pulling image", "image", image, "arch", arch)
+		progress(ProgressUpdate{Status: "pulling", Message: fmt.Sprintf("Pulling %s", image)})
+
+		if err := i.pullImage(ctx, image, arch, progress); err != nil {
+			logger.Error("image pull failed", "image", image, "error", err)
+			return fmt.Errorf("image %s: %w", image, err)
+		}
+
+		inspect, err := i.docker.ImageInspect(ctx, image)
+		if err != nil {
+			return fmt.Errorf("image %s: failed to inspect: %w", image, err)
+		}
+		if inspect.Architecture != arch {
+			return fmt.Errorf("image %s: built for %s, module needs %s", image, inspect.Architecture, arch)
+		}
+	}
+
+	progress(ProgressUpdate{Status: "pulling", Message: fmt.Sprintf("Pulled %d images", len(images))})
+	return nil
+}
+
+// pullImage pulls one image for linux/arch, passing the registry's overall status lines on
+// as progress (per-layer download updates are dropped)
+func (i *Installer) pullImage(ctx context.Context, image, arch string, progress ProgressCallback) error {
+	resp, err := i.docker.ImagePull(ctx, image, client.ImagePullOptions{
+		Platforms: []ocispec.Platform{{OS: "linux", Architecture: arch}},
+	})
+	if err != nil {
+		return err
+	}
+
+	for msg, err := range resp.JSONMessages(ctx) {
+		if err != nil {
+			return err
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if msg.ID == "" && msg.Status != "" {
+			progress(ProgressUpdate{Status: "pulling", Message: msg.Status})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"zeropoint-agent/internal/validator"
+)
+
+// InputSchemaFileName is the optional JSON schema a module ships to describe the inputs
+// links may set
+const InputSchemaFileName = "zeropoint.inputs.json"
+
+// InputSchema is the subset of JSON Schema modules use to describe their inputs: an object
+// schema whose properties are the inputs. Unlike JSON Schema, inputs missing from
+// properties are rejected unless additionalProperties is true. required is not checked,
+// as a link only sets some of a module's inputs.
+type InputSchema struct {
+	Type                 string                         `json:"type,omitempty"` // "object" if set
+	Properties           map[string]InputSchemaProperty `json:"properties"`
+	AdditionalProperties *bool                          `json:"additionalProperties,omitempty"`
+}
+
+// InputSchemaProperty describes one input
+type InputSchemaProperty struct {
+	Type        InputTypes    `json:"type,omitempty"` // Any type if empty
+	Enum        []interface{} `json:"enum,omitempty"`
+	Description string        `json:"description,omitempty"`
+}
+
+// InputTypes is a JSON Schema type, written as a single type or a list of types
+type InputTypes []string
+
+// UnmarshalJSON accepts "string" as well as ["string", "null"]
+func (t *InputTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = InputTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+// inputTypes are the JSON Schema types values can be checked against
+var inputTypes = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true, "array": true, "object": true, "null": true,
+}
+
+// LoadInputSchema reads a module's input schema. It returns nil without an error when the
+// module ships none.
+func LoadInputSchema(modulePath string) (*InputSchema, error) {
+	data, err := os.ReadFile(filepath.Join(modulePath, InputSchemaFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var schema InputSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", InputSchemaFileName, err)
+	}
+	if schema.Type != "" && schema.Type != "object" {
+		return nil, fmt.Errorf("invalid %s: type must be \"object\", got %q", InputSchemaFileName, schema.Type)
+	}
+	for name, property := range schema.Properties {
+		for _, t := range property.Type {
+			if !inputTypes[t] {
+				return nil, fmt.Errorf("invalid %s: input %s has unknown type %q", InputSchemaFileName, name, t)
+			}
+		}
+	}
+	return &schema, nil
+}
+
+// validateModule checks that a module conforms to the contract and that its input schema,
+// if it ships one, can be read, so a broken schema fails the install instead of every link
+func validateModule(modulePath, moduleID string) error {
+	if err := validator.ValidateAppModule(modulePath, moduleID); err != nil {
+		return err
+	}
+	_, err := LoadInputSchema(modulePath)
+	return err
+}
+
+// CheckInput validates the value of one input and returns why it does not match, or ""
+func (s *InputSchema) CheckInput(name string, value interface{}) string {
+	property, ok := s.Properties[name]
+	if !ok {
+		if s.AdditionalProperties != nil && *s.AdditionalProperties {
+			return ""
+		}
+		return fmt.Sprintf("unknown input; the module accepts %s", strings.Join(s.inputNames(), ", "))
+	}
+
+	if len(property.Type) > 0 {
+		actual := jsonType(value)
+		matched := false
+		for _, t := range property.Type {
+			if t == actual || (t == "number" && actual == "integer") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Sprintf("expected %s, got %s", strings.Join(property.Type, " or "), actual)
+		}
+	}
+
+	if len(property.Enum) > 0 {
+		for _, allowed := range property.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) && jsonType(allowed) == jsonType(value) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("%v is not one of %v", value, property.Enum)
+	}
+	return ""
+}
+
+// inputNames lists the inputs the schema declares, sorted
+func (s *InputSchema) inputNames() []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jsonType returns the JSON Schema type of a value decoded by encoding/json
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,180 @@
+package modules
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const lockfileName = "zeropoint.lock.json"
+
+// integrityExcluded lists files and directories that legitimately change after install:
+// terraform state and working files, and the agent's own metadata
+var integrityExcluded = map[string]bool{
+	lockfileName:                   true,
+	metadataFileName:               true,
+	".git":                         true,
+	".terraform":                   true,
+	".terraform.lock.hcl":          true,
+	".terraform.tfstate.lock.info": true,
+	"terraform.tfstate":            true,
+	"terraform.tfstate.backup":     true,
+	"terraform.tfstate.d":          true,
+}
+
+// Lockfile records the SHA-256 of every module file at install time
+type Lockfile struct {
+	CreatedAt time.Time         `json:"created_at"`
+	Files     map[string]string `json:"files"` // Slash-separated path relative to the module -> hex SHA-256
+}
+
+// VerifyResult compares a module directory with its lockfile
+type VerifyResult struct {
+	Locked   bool      `json:"locked"`   // False if the module has no lockfile (local installs, or installed before lockfiles)
+	Verified bool      `json:"verified"` // True if locked and nothing changed
+	LockedAt time.Time `json:"locked_at,omitempty"`
+	Changed  []string  `json:"changed,omitempty"`
+	Missing  []string  `json:"missing,omitempty"`
+	Extra    []string  `json:"extra,omitempty"`
+}
+
+// WriteLockfile hashes the files of a module directory and writes zeropoint.lock.json
+func WriteLockfile(modulePath string) error {
+	files, err := hashModuleFiles(modulePath)
+	if err != nil {
+		return fmt.Errorf("failed to hash module files: %w", err)
+	}
+
+	data, err := json.MarshalIndent(Lockfile{CreatedAt: time.Now(), Files: files}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(modulePath, lockfileName), data, 0644)
+}
+
+// VerifyModule re-hashes a module directory and reports files that differ from its lockfile
+func VerifyModule(modulePath string) (*VerifyResult, error) {
+	data, err := os.ReadFile(filepath.Join(modulePath, lockfileName))
+	if os.IsNotExist(err) {
+		return &VerifyResult{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lock Lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("invalid lockfile: %w", err)
+	}
+
+	current, err := hashModuleFiles(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash module files: %w", err)
+	}
+
+	result := &VerifyResult{Locked: true, LockedAt: lock.CreatedAt}
+	for name, sum := range lock.Files {
+		currentSum, ok := current[name]
+		switch {
+		case !ok:
+			result.Missing = append(result.Missing, name)
+		case currentSum != sum:
+			result.Changed = append(result.Changed, name)
+		}
+	}
+	for name := range current {
+		if _, ok := lock.Files[name]; !ok {
+			result.Extra = append(result.Extra, name)
+		}
+	}
+	sort.Strings(result.Changed)
+	sort.Strings(result.Missing)
+	sort.Strings(result.Extra)
+	result.Verified = len(result.Changed) == 0 && len(result.Missing) == 0 && len(result.Extra) == 0
+
+	return result, nil
+}
+
+type skipIntegrityKey struct{}
+
+// ContextSkippingIntegrity returns a context under which CheckIntegrity lets modified modules
+// through, for callers that were asked to force the operation
+func ContextSkippingIntegrity(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipIntegrityKey{}, true)
+}
+
+// CheckIntegrity refuses to continue with a module whose files no longer match its lockfile.
+// Modules without a lockfile pass.
+func CheckIntegrity(ctx context.Context, modulePath string) error {
+	if skip, _ := ctx.Value(skipIntegrityKey{}).(bool); skip {
+		return nil
+	}
+
+	result, err := VerifyModule(modulePath)
+	if err != nil {
+		return fmt.Errorf("failed to verify module %s: %w", filepath.Base(modulePath), err)
+	}
+	if !result.Locked || result.Verified {
+		return nil
+	}
+	return fmt.Errorf("module %s was modified since it was installed (%d changed, %d missing, %d extra files); use force to run anyway",
+		filepath.Base(modulePath), len(result.Changed), len(result.Missing), len(result.Extra))
+}
+
+// hashModuleFiles returns the SHA-256 of every regular file in a module directory, skipping
+// terraform state and working files
+func hashModuleFiles(modulePath string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(modulePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == modulePath {
+			return nil
+		}
+		if integrityExcluded[d.Name()] {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(modulePath, path)
+		if err != nil {
+			return err
+		}
+		sum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	return files, err
+}
+
+// hashFile returns the hex SHA-256 of a file
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
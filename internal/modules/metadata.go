@@ -14,6 +14,19 @@ type Metadata struct {
 	ClonedAt time.Time `json:"cloned_at"`      // When the module was installed
 	ModuleID string    `json:"module_id"`      // Unique module identifier
 	Tags     []string  `json:"tags,omitempty"` // Optional tags for categorization
+
+	CredentialRef string `json:"credential_ref,omitempty"` // Stored git credential the module is cloned with
+
+	Resources *Resources `json:"resources,omitempty"` // Resource limits re-applied on every terraform apply
+
+	UpgradeHistory []UpgradeRecord `json:"upgrade_history,omitempty"` // Previous upgrades, oldest first
+}
+
+// UpgradeRecord captures a single upgrade of a module from one commit to another
+type UpgradeRecord struct {
+	FromRef    string    `json:"from_ref"`
+	ToRef      string    `json:"to_ref"`
+	UpgradedAt time.Time `json:"upgraded_at"`
 }
 
 const metadataFileName = ".zeropoint.json"
@@ -45,6 +58,8 @@ func LoadMetadata(modulePath string) (*Metadata, error) {
 	if err := json.Unmarshal(data, &metadata); err != nil {
 		return nil, err
 	}
+	// Modules installed before sources were scrubbed may have a token in the URL
+	metadata.Source = RedactSource(metadata.Source)
 
 	return &metadata, nil
 }
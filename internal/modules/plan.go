@@ -0,0 +1,92 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"zeropoint-agent/internal/terraform"
+)
+
+// Plan runs terraform init and plan for a module without applying anything and returns
+// the human-readable plan. Git sources are cloned into a scratch directory so an
+// installed module is never touched; its terraform state is copied in so the plan shows
+// changes against what is deployed rather than a fresh install.
+func (i *Installer) Plan(ctx context.Context, req InstallRequest, progress ProgressCallback) (string, error) {
+	logger := i.logger.With("module_id", req.ModuleID)
+	logger.Info("planning installation")
+
+	if progress == nil {
+		progress = func(ProgressUpdate) {} // No-op if not provided
+	}
+
+	if err := req.Resources.Validate(); err != nil {
+		return "", fmt.Errorf("invalid resources: %w", err)
+	}
+
+	var modulePath string
+	switch {
+	case req.Source != "":
+		gitURL, ref, err := i.resolveSource(req.Source)
+		if err != nil {
+			return "", err
+		}
+
+		scratchDir, err := os.MkdirTemp(i.workingDir, "zeropoint-plan-"+req.ModuleID+"-")
+		if err != nil {
+			return "", fmt.Errorf("failed to create plan directory: %w", err)
+		}
+		defer os.RemoveAll(scratchDir)
+		modulePath = filepath.Join(scratchDir, req.ModuleID)
+
+		progress(ProgressUpdate{Status: "cloning", Message: "Cloning repository"})
+		if err := i.cloneFromGit(ctx, gitURL, ref, modulePath, req.CredentialRef); err != nil {
+			return "", fmt.Errorf("git clone failed: %w", err)
+		}
+
+		installedPath := filepath.Join(i.appsDir, req.ModuleID)
+		if _, err := os.Stat(installedPath); err == nil {
+			if err := copyStateFiles(installedPath, modulePath); err != nil {
+				return "", fmt.Errorf("failed to copy state of installed module: %w", err)
+			}
+		}
+	case req.LocalPath != "":
+		modulePath = req.LocalPath
+	default:
+		return "", fmt.Errorf("either source or local_path must be provided")
+	}
+
+	progress(ProgressUpdate{Status: "validating", Message: "Validating module"})
+	if err := validateModule(modulePath, req.ModuleID); err != nil {
+		return "", fmt.Errorf("module validation failed: %w", err)
+	}
+
+	variables, err := moduleVariables(logger, req, fmt.Sprintf("zeropoint-module-%s", req.ModuleID))
+	if err != nil {
+		return "", err
+	}
+	if err := addResourceVariables(logger, modulePath, req.Resources, variables, progress); err != nil {
+		return "", err
+	}
+
+	executor, err := terraform.NewExecutor(modulePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create terraform executor: %w", err)
+	}
+	executor = executor.WithContext(ctx)
+
+	progress(ProgressUpdate{Status: "planning", Message: "Running terraform plan"})
+	if err := executor.Init(); err != nil {
+		return "", fmt.Errorf("terraform init failed: %w", err)
+	}
+
+	plan, err := executor.PlanText(variables)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Info("plan complete")
+	progress(ProgressUpdate{Status: "complete", Message: "Plan complete, nothing was applied"})
+	return plan, nil
+}
@@ -23,6 +23,11 @@ func LoadContainers(modulePath string, moduleID string) (map[string]Container, e
 		return nil, fmt.Errorf("failed to read terraform outputs: %w", err)
 	}
 
+	return ContainersFromOutputs(outputs, moduleID)
+}
+
+// ContainersFromOutputs builds container configurations from already-read terraform outputs
+func ContainersFromOutputs(outputs map[string]*terraform.OutputMeta, moduleID string) (map[string]Container, error) {
 	containers := make(map[string]Container)
 
 	// First pass: find all container names from _ports outputs
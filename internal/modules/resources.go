@@ -0,0 +1,128 @@
+package modules
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"zeropoint-agent/internal/hcl"
+	"zeropoint-agent/internal/validator"
+)
+
+// Resources are optional limits on a module's containers. They are passed to terraform as
+// zp_cpu_limit, zp_memory_limit and zp_pids_limit for the module to wire into its
+// container definitions; 0 means unlimited.
+type Resources struct {
+	CPU    float64 `json:"cpu,omitempty"`    // CPU cores, e.g. 1.5
+	Memory int64   `json:"memory,omitempty"` // Memory in MB
+	PIDs   int64   `json:"pids,omitempty"`   // Maximum number of processes
+}
+
+// Validate checks that no limit is negative
+func (r *Resources) Validate() error {
+	if r == nil {
+		return nil
+	}
+	if r.CPU < 0 {
+		return fmt.Errorf("cpu must not be negative (got %g)", r.CPU)
+	}
+	if r.Memory < 0 {
+		return fmt.Errorf("memory must not be negative (got %d)", r.Memory)
+	}
+	if r.PIDs < 0 {
+		return fmt.Errorf("pids must not be negative (got %d)", r.PIDs)
+	}
+	return nil
+}
+
+// IsZero reports whether no limit is set
+func (r *Resources) IsZero() bool {
+	return r == nil || *r == Resources{}
+}
+
+// variables returns the resource limit variables, unset limits as "0"
+func (r *Resources) variables() map[string]string {
+	var limits Resources
+	if r != nil {
+		limits = *r
+	}
+	return map[string]string{
+		validator.CPULimitVariable:    strconv.FormatFloat(limits.CPU, 'f', -1, 64),
+		validator.MemoryLimitVariable: strconv.FormatInt(limits.Memory, 10),
+		validator.PIDsLimitVariable:   strconv.FormatInt(limits.PIDs, 10),
+	}
+}
+
+// setVariables lists the variables of the limits that are set, in a stable order
+func (r *Resources) setVariables() []string {
+	if r == nil {
+		return nil
+	}
+	var names []string
+	if r.CPU > 0 {
+		names = append(names, validator.CPULimitVariable)
+	}
+	if r.Memory > 0 {
+		names = append(names, validator.MemoryLimitVariable)
+	}
+	if r.PIDs > 0 {
+		names = append(names, validator.PIDsLimitVariable)
+	}
+	return names
+}
+
+// ResourceVariables returns the resource limit variables the module at modulePath declares.
+// Terraform rejects values for undeclared variables, so limits the module doesn't consume
+// are left out and reported as warnings instead; they are not enforced.
+func ResourceVariables(modulePath string, resources *Resources) (map[string]string, []string, error) {
+	inputs, err := hcl.ParseModuleInputs(modulePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse module inputs: %w", err)
+	}
+
+	variables := make(map[string]string)
+	for name, value := range resources.variables() {
+		if _, declared := inputs[name]; declared {
+			variables[name] = value
+		}
+	}
+
+	return variables, validator.CheckResourceLimits(inputs, resources.setVariables()), nil
+}
+
+// LoadResources returns the resource limits stored for an installed module, or nil if none
+// are set
+func LoadResources(modulePath string) (*Resources, error) {
+	metadata, err := LoadMetadata(modulePath)
+	if err != nil || metadata == nil {
+		return nil, err
+	}
+	return metadata.Resources, nil
+}
+
+// SetResources stores new resource limits for an installed module and returns the previous
+// ones. Only modules installed from git have metadata to store them in.
+func (i *Installer) SetResources(moduleID string, resources *Resources) (*Resources, error) {
+	if err := resources.Validate(); err != nil {
+		return nil, err
+	}
+	if resources.IsZero() {
+		resources = nil
+	}
+
+	modulePath := filepath.Join(i.appsDir, moduleID)
+	metadata, err := LoadMetadata(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metadata: %w", err)
+	}
+	if metadata == nil {
+		return nil, fmt.Errorf("module %s has no metadata; resource limits can only be stored for modules installed from git", moduleID)
+	}
+
+	previous := metadata.Resources
+	metadata.Resources = resources
+	if err := SaveMetadata(modulePath, metadata); err != nil {
+		return nil, fmt.Errorf("failed to save metadata: %w", err)
+	}
+	return previous, nil
+}
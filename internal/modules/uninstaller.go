@@ -31,11 +31,17 @@ func NewUninstaller(docker *client.Client, appsDir string, logger *slog.Logger)
 
 // UninstallRequest represents a module uninstallation request
 type UninstallRequest struct {
-	ModuleID string `json:"module_id"` // Module identifier to uninstall
+	ModuleID  string `json:"module_id"`            // Module identifier to uninstall
+	PurgeData bool   `json:"purge_data,omitempty"` // Also delete the module's storage directory once terraform destroy succeeded
 }
 
 // Uninstall removes a module by destroying terraform resources and deleting the module directory
 func (u *Uninstaller) Uninstall(req UninstallRequest, progress ProgressCallback) error {
+	return u.UninstallContext(context.Background(), req, progress)
+}
+
+// UninstallContext is like Uninstall, but cancelling ctx kills the running terraform process
+func (u *Uninstaller) UninstallContext(ctx context.Context, req UninstallRequest, progress ProgressCallback) error {
 	logger := u.logger.With("module_id", req.ModuleID)
 	logger.Info("starting uninstallation")
 
@@ -50,6 +56,13 @@ func (u *Uninstaller) Uninstall(req UninstallRequest, progress ProgressCallback)
 		return fmt.Errorf("module '%s' not found", req.ModuleID)
 	}
 
+	unlock, err := terraform.LockModule(ctx, modulePath)
+	if err != nil {
+		logger.Error("module is locked", "error", err)
+		return err
+	}
+	defer unlock()
+
 	// Destroy terraform resources
 	logger.Info("destroying terraform resources")
 	progress(ProgressUpdate{Status: "destroying", Message: "Destroying infrastructure"})
@@ -59,6 +72,7 @@ func (u *Uninstaller) Uninstall(req UninstallRequest, progress ProgressCallback)
 		logger.Error("failed to create terraform executor", "error", err)
 		return fmt.Errorf("failed to create terraform executor: %w", err)
 	}
+	executor = executor.WithContext(ctx)
 
 	// Need to init first
 	if err := executor.Init(); err != nil {
@@ -105,12 +119,41 @@ func (u *Uninstaller) Uninstall(req UninstallRequest, progress ProgressCallback)
 		return fmt.Errorf("failed to remove app directory: %w", err)
 	}
 
+	// The module's data survives by default so a reinstall picks it up again. Like the
+	// network, a failed purge doesn't fail the uninstall; the module itself is gone.
+	if req.PurgeData {
+		if err := u.PurgeData(req.ModuleID); err != nil {
+			logger.Warn("failed to purge module data", "error", err)
+			progress(ProgressUpdate{Status: "data_kept", Message: "Failed to delete module data", Error: err.Error()})
+		} else {
+			progress(ProgressUpdate{Status: "data_purged", Message: "Deleted module data " + absModuleStoragePath})
+		}
+	} else {
+		progress(ProgressUpdate{Status: "data_kept", Message: "Kept module data " + absModuleStoragePath + " for a reinstall"})
+	}
+
 	logger.Info("uninstallation complete")
 	progress(ProgressUpdate{Status: "complete", Message: "Uninstallation complete"})
 
 	return nil
 }
 
+// PurgeData deletes a module's storage directory. Uninstall keeps it so a reinstall picks up
+// the same data; this is for when the data should go too.
+func (u *Uninstaller) PurgeData(moduleID string) error {
+	if moduleID == "" || moduleID != filepath.Base(moduleID) || moduleID == "." || moduleID == ".." {
+		return fmt.Errorf("invalid module id %q", moduleID)
+	}
+
+	storagePath := filepath.Join(internalPaths.GetDataDir(), moduleID)
+	if err := os.RemoveAll(storagePath); err != nil {
+		return fmt.Errorf("failed to delete module data %s: %w", storagePath, err)
+	}
+
+	u.logger.Info("module data deleted", "module_id", moduleID, "path", storagePath)
+	return nil
+}
+
 // removeNetwork removes a Docker network by name
 func (u *Uninstaller) removeNetwork(networkName string) error {
 	ctx := context.Background()
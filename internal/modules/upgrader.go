@@ -0,0 +1,224 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"zeropoint-agent/internal/terraform"
+)
+
+// UpgradeRequest represents a request to move an installed module to a new commit
+type UpgradeRequest struct {
+	ModuleID string `json:"module_id"` // Installed module to upgrade
+	Source   string `json:"source"`    // Git URL with the new commit SHA (e.g., https://github.com/org/repo.git@<sha>)
+	Force    bool   `json:"force"`     // Upgrade even if the installed files no longer match the lockfile
+
+	CredentialRef     string `json:"credential_ref,omitempty"`      // Stored git credential to clone with; defaults to the one the module was installed with
+	SkipImagePrefetch bool   `json:"skip_image_prefetch,omitempty"` // Don't pull images before apply, for the upgrade and any rollback
+}
+
+// UpgradeResult describes the outcome of a successful upgrade
+type UpgradeResult struct {
+	ModuleID string `json:"module_id"`
+	OldRef   string `json:"old_ref"`
+	NewRef   string `json:"new_ref"`
+}
+
+// upgradesDirName holds staging and backup copies while an upgrade is in progress.
+// It has no main.tf so module discovery skips it.
+const upgradesDirName = ".upgrades"
+
+// terraformStateFiles are carried between module directories so terraform keeps
+// tracking the resources it already created
+var terraformStateFiles = []string{"terraform.tfstate", "terraform.tfstate.backup"}
+
+// Upgrade re-installs an existing module from a new commit SHA. The current module
+// directory and terraform state are kept as a backup; if validation or apply of the
+// new revision fails, the backup is restored and re-applied.
+func (i *Installer) Upgrade(req UpgradeRequest, progress ProgressCallback) (*UpgradeResult, error) {
+	return i.UpgradeContext(context.Background(), req, progress)
+}
+
+// UpgradeContext is like Upgrade, but cancelling ctx kills the running terraform process.
+// The rollback after a cancelled apply still runs to completion.
+func (i *Installer) UpgradeContext(ctx context.Context, req UpgradeRequest, progress ProgressCallback) (*UpgradeResult, error) {
+	logger := i.logger.With("module_id", req.ModuleID)
+	logger.Info("starting upgrade")
+
+	if progress == nil {
+		progress = func(ProgressUpdate) {} // No-op if not provided
+	}
+
+	modulePath := filepath.Join(i.appsDir, req.ModuleID)
+	if _, err := os.Stat(modulePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("module '%s' not found", req.ModuleID)
+	}
+
+	unlock, err := terraform.LockModule(ctx, modulePath)
+	if err != nil {
+		logger.Error("module is locked", "error", err)
+		return nil, err
+	}
+	defer unlock()
+
+	metadata, err := LoadMetadata(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metadata: %w", err)
+	}
+	if metadata == nil {
+		return nil, fmt.Errorf("module '%s' was not installed from git and cannot be upgraded", req.ModuleID)
+	}
+
+	// A failed upgrade re-applies the installed revision, so it must not have been tampered with
+	if !req.Force {
+		if err := CheckIntegrity(ctx, modulePath); err != nil {
+			logger.Error("integrity check failed", "error", err)
+			return nil, err
+		}
+	}
+
+	gitURL, ref, err := i.resolveSource(req.Source)
+	if err != nil {
+		logger.Error("invalid git URL", "error", err)
+		return nil, err
+	}
+
+	// Keep cloning with the module's credential unless the new source is on another host
+	credentialRef := req.CredentialRef
+	if credentialRef == "" && i.credentials.CheckRef(metadata.CredentialRef, gitURL) == nil {
+		credentialRef = metadata.CredentialRef
+	}
+
+	upgradesDir := filepath.Join(i.appsDir, upgradesDirName)
+	stagingPath := filepath.Join(upgradesDir, req.ModuleID+"-staging")
+	backupPath := filepath.Join(upgradesDir, req.ModuleID+"-backup")
+
+	if err := os.MkdirAll(upgradesDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upgrades directory: %w", err)
+	}
+	// Clear leftovers from a previous interrupted upgrade
+	os.RemoveAll(stagingPath)
+	os.RemoveAll(backupPath)
+	defer os.RemoveAll(stagingPath)
+
+	// Clone the new revision into staging
+	logger.Info("cloning new revision", "url", gitURL, "ref", ref)
+	progress(ProgressUpdate{Status: "cloning", Message: fmt.Sprintf("Cloning %s", ref)})
+	if err := i.cloneFromGit(ctx, gitURL, ref, stagingPath, credentialRef); err != nil {
+		logger.Error("git clone failed", "error", err)
+		return nil, fmt.Errorf("git clone failed: %w", err)
+	}
+	if err := os.RemoveAll(filepath.Join(stagingPath, ".git")); err != nil {
+		logger.Warn("failed to remove .git directory", "error", err)
+	}
+
+	// Validate before touching the installed module
+	progress(ProgressUpdate{Status: "validating", Message: "Validating new revision"})
+	if err := validateModule(stagingPath, req.ModuleID); err != nil {
+		logger.Error("module validation failed", "error", err)
+		return nil, fmt.Errorf("module validation failed: %w", err)
+	}
+
+	// Carry terraform state over so apply updates the existing resources in place
+	if err := copyStateFiles(modulePath, stagingPath); err != nil {
+		return nil, fmt.Errorf("failed to copy terraform state: %w", err)
+	}
+
+	oldRef := metadata.Ref
+	newMetadata := *metadata
+	newMetadata.Source = gitURL
+	newMetadata.Ref = ref
+	newMetadata.CredentialRef = credentialRef
+	newMetadata.UpgradeHistory = append(append([]UpgradeRecord{}, metadata.UpgradeHistory...), UpgradeRecord{
+		FromRef:    oldRef,
+		ToRef:      ref,
+		UpgradedAt: time.Now(),
+	})
+	if err := SaveMetadata(stagingPath, &newMetadata); err != nil {
+		return nil, fmt.Errorf("failed to save metadata: %w", err)
+	}
+	if err := WriteLockfile(stagingPath); err != nil {
+		return nil, fmt.Errorf("failed to write lockfile: %w", err)
+	}
+
+	// Swap: installed module becomes the backup, staging becomes the module
+	progress(ProgressUpdate{Status: "swapping", Message: "Swapping module revision"})
+	if err := os.Rename(modulePath, backupPath); err != nil {
+		return nil, fmt.Errorf("failed to back up module directory: %w", err)
+	}
+	if err := os.Rename(stagingPath, modulePath); err != nil {
+		if restoreErr := os.Rename(backupPath, modulePath); restoreErr != nil {
+			logger.Error("failed to restore module directory", "error", restoreErr)
+		}
+		return nil, fmt.Errorf("failed to install new revision: %w", err)
+	}
+
+	installReq := InstallRequest{
+		ModuleID:          req.ModuleID,
+		Tags:              metadata.Tags,
+		Resources:         metadata.Resources,
+		SkipImagePrefetch: req.SkipImagePrefetch,
+	}
+	if _, err := i.applyModule(ctx, logger, modulePath, installReq, progress); err != nil {
+		logger.Error("upgrade apply failed, rolling back", "error", err)
+		progress(ProgressUpdate{Status: "rolling_back", Message: "Upgrade failed, restoring previous revision", Error: err.Error()})
+
+		if rollbackErr := i.rollbackUpgrade(context.WithoutCancel(ctx), modulePath, backupPath, installReq, progress); rollbackErr != nil {
+			logger.Error("rollback failed", "error", rollbackErr)
+			return nil, fmt.Errorf("upgrade failed: %w (rollback also failed: %v)", err, rollbackErr)
+		}
+		return nil, fmt.Errorf("upgrade failed, rolled back to %s: %w", oldRef, err)
+	}
+
+	if err := os.RemoveAll(backupPath); err != nil {
+		logger.Warn("failed to remove upgrade backup", "path", backupPath, "error", err)
+	}
+
+	logger.Info("upgrade complete", "old_ref", oldRef, "new_ref", ref)
+	progress(ProgressUpdate{Status: "complete", Message: "Upgrade complete"})
+
+	return &UpgradeResult{
+		ModuleID: req.ModuleID,
+		OldRef:   oldRef,
+		NewRef:   ref,
+	}, nil
+}
+
+// rollbackUpgrade restores the backed up module directory and re-applies it. The state
+// produced by the failed apply is copied back first so terraform reconciles whatever
+// the new revision managed to change.
+func (i *Installer) rollbackUpgrade(ctx context.Context, modulePath, backupPath string, req InstallRequest, progress ProgressCallback) error {
+	logger := i.logger.With("module_id", req.ModuleID)
+
+	if err := copyStateFiles(modulePath, backupPath); err != nil {
+		logger.Warn("failed to carry state back to previous revision", "error", err)
+	}
+	if err := os.RemoveAll(modulePath); err != nil {
+		return fmt.Errorf("failed to remove failed revision: %w", err)
+	}
+	if err := os.Rename(backupPath, modulePath); err != nil {
+		return fmt.Errorf("failed to restore previous revision: %w", err)
+	}
+
+	if _, err := i.applyModule(ctx, logger, modulePath, req, progress); err != nil {
+		return fmt.Errorf("failed to re-apply previous revision: %w", err)
+	}
+	return nil
+}
+
+// copyStateFiles copies terraform state files from one module directory to another
+func copyStateFiles(srcDir, dstDir string) error {
+	for _, name := range terraformStateFiles {
+		src := filepath.Join(srcDir, name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := copyFile(src, filepath.Join(dstDir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
 
 	"github.com/moby/moby/client"
 )
@@ -69,6 +71,88 @@ func (m *Manager) ConnectContainerToNetwork(ctx context.Context, containerName,
 	return m.ConnectContainer(ctx, networkID, containerName)
 }
 
+// DisconnectContainerFromNetwork removes a container from a network (idempotent: a missing
+// network or a container that is not connected is not an error)
+func (m *Manager) DisconnectContainerFromNetwork(ctx context.Context, containerName, networkName string) error {
+	_, err := m.dockerClient.NetworkDisconnect(ctx, networkName, client.NetworkDisconnectOptions{
+		Container: containerName,
+	})
+	if err != nil && !isNotConnectedError(err) {
+		return fmt.Errorf("failed to disconnect container %s from network %s: %w", containerName, networkName, err)
+	}
+	return nil
+}
+
+// ListNetworks returns the names of networks whose name starts with prefix
+func (m *Manager) ListNetworks(ctx context.Context, prefix string) ([]string, error) {
+	networkList, err := m.dockerClient.NetworkList(ctx, client.NetworkListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	var names []string
+	for _, network := range networkList.Items {
+		if strings.HasPrefix(network.Name, prefix) {
+			names = append(names, network.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// AttachedContainers returns the names of containers connected to a network
+func (m *Manager) AttachedContainers(ctx context.Context, networkName string) ([]string, error) {
+	inspect, err := m.dockerClient.NetworkInspect(ctx, networkName, client.NetworkInspectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect network %s: %w", networkName, err)
+	}
+
+	names := make([]string, 0, len(inspect.Network.Containers))
+	for id, endpoint := range inspect.Network.Containers {
+		if endpoint.Name != "" {
+			names = append(names, endpoint.Name)
+		} else {
+			names = append(names, id)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// RemoveNetwork removes a network that no container is attached to. Returns false without an
+// error if the network does not exist.
+func (m *Manager) RemoveNetwork(ctx context.Context, networkName string) (bool, error) {
+	inspect, err := m.dockerClient.NetworkInspect(ctx, networkName, client.NetworkInspectOptions{})
+	if err != nil {
+		if isNotConnectedError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to inspect network %s: %w", networkName, err)
+	}
+
+	if attached := len(inspect.Network.Containers); attached > 0 {
+		return false, fmt.Errorf("network %s still has %d containers attached", networkName, attached)
+	}
+
+	if _, err := m.dockerClient.NetworkRemove(ctx, inspect.Network.ID, client.NetworkRemoveOptions{}); err != nil {
+		return false, fmt.Errorf("failed to remove network %s: %w", networkName, err)
+	}
+
+	m.logger.Info("Removed network", "network", networkName)
+	return true, nil
+}
+
+// isNotConnectedError checks if error indicates the container or network is already gone
+func isNotConnectedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	errStr := err.Error()
+	return containsString(errStr, "is not connected") ||
+		containsString(errStr, "not found") ||
+		containsString(errStr, "No such")
+}
+
 // isAlreadyConnectedError checks if error indicates container is already connected
 func isAlreadyConnectedError(err error) bool {
 	if err == nil {
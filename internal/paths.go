@@ -1,17 +1,25 @@
 package internal
 
 import (
-	"os"
 	"path/filepath"
 )
 
-// GetStorageRoot returns the storage root directory from environment or default
+// Directories set from the agent configuration at startup; see Configure
+var (
+	storageRoot = "."
+	certsDir    = "/etc/zeropoint/certs"
+)
+
+// Configure sets the storage root and certificate directory. It is called once at startup,
+// before any component resolves a path.
+func Configure(root, certs string) {
+	storageRoot = root
+	certsDir = certs
+}
+
+// GetStorageRoot returns the directory all agent state is kept under
 func GetStorageRoot() string {
-	root := os.Getenv("MODULE_STORAGE_ROOT")
-	if root == "" {
-		root = "."
-	}
-	return filepath.Join(root, "data")
+	return filepath.Join(storageRoot, "data")
 }
 
 // GetModulesDir returns the modules directory path
@@ -23,3 +31,8 @@ func GetModulesDir() string {
 func GetDataDir() string {
 	return filepath.Join(GetStorageRoot(), "modules", "storage")
 }
+
+// GetCertsDir returns the directory holding named TLS certificates for exposures
+func GetCertsDir() string {
+	return certsDir
+}
@@ -0,0 +1,268 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"zeropoint-agent/internal/modules"
+)
+
+// batchRequiredArgs lists the command types a batch may enqueue and the args each needs.
+// Bundle meta-jobs are left out: they track bundle records only the bundle endpoints create.
+// module_exec is left out so arbitrary commands only go through their own endpoint.
+var batchRequiredArgs = map[CommandType][]string{
+	CmdInstallModule:   {"module_id"},
+	CmdUninstallModule: {"module_id"},
+	CmdUpgradeModule:   {"module_id", "source"},
+	CmdUpdateResources: {"module_id"},
+	CmdRestartModule:   {"module_id"},
+	CmdStopModule:      {"module_id"},
+	CmdStartModule:     {"module_id"},
+	CmdCreateExposure:  {"exposure_id", "module_id", "protocol", "container_port"},
+	CmdDeleteExposure:  {"exposure_id"},
+	CmdCreateLink:      {"link_id", "modules"},
+	CmdUpdateLink:      {"link_id"},
+	CmdDeleteLink:      {"link_id"},
+	CmdSyncCatalog:     nil,
+}
+
+// BatchJobSpec is one job of a batch enqueue
+type BatchJobSpec struct {
+	Alias                  string                 `json:"alias" example:"db"` // Name other specs in the batch use to depend on this job
+	Type                   CommandType            `json:"type" example:"install_module"`
+	Args                   map[string]interface{} `json:"args"`                 // Same args as the job's enqueue_* endpoint
+	DependsOn              []string               `json:"depends_on,omitempty"` // Aliases in this batch or IDs of existing jobs
+	DependsOnTags          []string               `json:"depends_on_tags,omitempty"`
+	RunOnDependencyFailure bool                   `json:"run_on_dependency_failure,omitempty"`
+	CallbackURL            string                 `json:"callback_url,omitempty"` // POSTed the final job once it completes, fails or is cancelled
+}
+
+// EnqueueBatchRequest is the request for enqueueing several jobs at once
+type EnqueueBatchRequest struct {
+	Jobs []BatchJobSpec `json:"jobs"`
+}
+
+// EnqueueBatchResponse maps each alias of a batch to the ID of the job created for it
+type EnqueueBatchResponse struct {
+	Jobs  map[string]string `json:"jobs"`
+	Order []string          `json:"order"` // Aliases in the order their jobs were enqueued
+}
+
+// EnqueueBatch handles POST /api/jobs/batch
+// @ID enqueueBatch
+// @Summary Enqueue several jobs in one request
+// @Description Enqueues a graph of jobs. Each spec has an alias that other specs in the batch can list in depends_on, alongside IDs of existing jobs. The whole batch is validated first (command types, required args, unknown dependencies and cycles), so nothing is created for an invalid batch. If enqueueing a job fails, the jobs already created for the batch are deleted again.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param body body EnqueueBatchRequest true "Jobs to enqueue"
+// @Success 201 {object} EnqueueBatchResponse "Jobs enqueued"
+// @Failure 400 {string} string "Invalid batch"
+// @Failure 403 {string} string "Source not allowed"
+// @Failure 500 {string} string "A job could not be enqueued; none were kept"
+// @Router /jobs/batch [post]
+func (h *Handlers) EnqueueBatch(w http.ResponseWriter, r *http.Request) {
+	var req EnqueueBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Jobs) == 0 {
+		http.Error(w, "jobs is required", http.StatusBadRequest)
+		return
+	}
+
+	if status, err := h.validateBatch(req.Jobs); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	order, err := batchOrder(req.Jobs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if status, err := h.scrubBatchSources(req.Jobs); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	resp := EnqueueBatchResponse{Jobs: make(map[string]string, len(req.Jobs))}
+	var created []string
+	for _, i := range order {
+		spec := req.Jobs[i]
+
+		dependsOn := make([]string, 0, len(spec.DependsOn))
+		for _, dep := range spec.DependsOn {
+			if jobID, ok := resp.Jobs[dep]; ok {
+				dep = jobID
+			}
+			dependsOn = append(dependsOn, dep)
+		}
+
+		jobID, _, err := h.manager.EnqueueWithOptions(Command{Type: spec.Type, Args: spec.Args}, EnqueueOptions{
+			DependsOn:              dependsOn,
+			DependsOnTags:          spec.DependsOnTags,
+			RunOnDependencyFailure: spec.RunOnDependencyFailure,
+			CallbackURL:            spec.CallbackURL,
+		})
+		if err != nil {
+			h.discardJobs(created)
+			h.logger.Error("failed to enqueue batch job", "alias", spec.Alias, "error", err)
+			http.Error(w, fmt.Sprintf("failed to enqueue %s: %v", spec.Alias, err), http.StatusInternalServerError)
+			return
+		}
+		created = append(created, jobID)
+		resp.Jobs[spec.Alias] = jobID
+		resp.Order = append(resp.Order, spec.Alias)
+	}
+
+	h.logger.Info("enqueued job batch", "jobs", len(created))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// validateBatch checks every spec before anything is enqueued or stored. Returns the HTTP
+// status to fail with.
+func (h *Handlers) validateBatch(specs []BatchJobSpec) (int, error) {
+	aliases := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		if spec.Alias == "" {
+			return http.StatusBadRequest, fmt.Errorf("every job needs an alias")
+		}
+		if aliases[spec.Alias] {
+			return http.StatusBadRequest, fmt.Errorf("duplicate alias %s", spec.Alias)
+		}
+		aliases[spec.Alias] = true
+	}
+
+	for i := range specs {
+		spec := &specs[i]
+
+		required, ok := batchRequiredArgs[spec.Type]
+		if !ok {
+			return http.StatusBadRequest, fmt.Errorf("%s: unsupported job type %q", spec.Alias, spec.Type)
+		}
+		if spec.Args == nil {
+			spec.Args = make(map[string]interface{})
+		}
+		for _, arg := range required {
+			if value, ok := spec.Args[arg]; !ok || value == nil || value == "" || value == float64(0) {
+				return http.StatusBadRequest, fmt.Errorf("%s: %s requires %s", spec.Alias, spec.Type, strings.Join(required, ", "))
+			}
+		}
+
+		if spec.Type == CmdInstallModule || spec.Type == CmdUpgradeModule {
+			source, _ := spec.Args["source"].(string)
+			credentialRef, _ := spec.Args["credential_ref"].(string)
+			if localPath, _ := spec.Args["local_path"].(string); spec.Type == CmdInstallModule && source == "" && localPath == "" {
+				return http.StatusBadRequest, fmt.Errorf("%s: either source or local_path is required", spec.Alias)
+			}
+			if status, err := h.checkCredentialSource(source, credentialRef); err != nil {
+				return status, fmt.Errorf("%s: %w", spec.Alias, err)
+			}
+		}
+
+		if raw, ok := spec.Args["resources"]; ok && raw != nil {
+			var resources modules.Resources
+			if err := decodeArg(raw, &resources); err != nil {
+				return http.StatusBadRequest, fmt.Errorf("%s: invalid resources: %w", spec.Alias, err)
+			}
+			if err := resources.Validate(); err != nil {
+				return http.StatusBadRequest, fmt.Errorf("%s: invalid resources: %w", spec.Alias, err)
+			}
+		}
+
+		if spec.CallbackURL != "" {
+			if err := h.manager.callbacks.validateURL(spec.CallbackURL); err != nil {
+				return http.StatusBadRequest, fmt.Errorf("%s: %w", spec.Alias, err)
+			}
+		}
+
+		for _, dep := range spec.DependsOn {
+			if aliases[dep] {
+				if dep == spec.Alias {
+					return http.StatusBadRequest, fmt.Errorf("%s depends on itself", spec.Alias)
+				}
+				continue
+			}
+			if _, err := h.manager.Get(dep); err != nil {
+				return http.StatusBadRequest, fmt.Errorf("%s: dependency %s is neither an alias in the batch nor an existing job", spec.Alias, dep)
+			}
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// scrubBatchSources moves credentials out of the git sources of a validated batch the way
+// the single-job endpoints do
+func (h *Handlers) scrubBatchSources(specs []BatchJobSpec) (int, error) {
+	for i := range specs {
+		spec := &specs[i]
+		if spec.Type != CmdInstallModule && spec.Type != CmdUpgradeModule {
+			continue
+		}
+		source, _ := spec.Args["source"].(string)
+		if source == "" {
+			continue
+		}
+		scrubbed, status, err := h.scrubSource(source)
+		if err != nil {
+			return status, fmt.Errorf("%s: %w", spec.Alias, err)
+		}
+		spec.Args["source"] = scrubbed
+	}
+	return http.StatusOK, nil
+}
+
+// batchOrder sorts the specs so every job comes after the batch jobs it depends on,
+// otherwise keeping request order, and fails if the aliases form a cycle
+func batchOrder(specs []BatchJobSpec) ([]int, error) {
+	index := make(map[string]int, len(specs))
+	for i, spec := range specs {
+		index[spec.Alias] = i
+	}
+
+	pending := make([]int, len(specs)) // Unordered batch dependencies of each spec
+	dependents := make([][]int, len(specs))
+	for i, spec := range specs {
+		for _, dep := range spec.DependsOn {
+			if j, ok := index[dep]; ok {
+				pending[i]++
+				dependents[j] = append(dependents[j], i)
+			}
+		}
+	}
+
+	order := make([]int, 0, len(specs))
+	placed := make([]bool, len(specs))
+	for len(order) < len(specs) {
+		next := -1
+		for i := range specs {
+			if !placed[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var cycle []string
+			for i, spec := range specs {
+				if !placed[i] {
+					cycle = append(cycle, spec.Alias)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle among %s", strings.Join(cycle, ", "))
+		}
+
+		placed[next] = true
+		order = append(order, next)
+		for _, dependent := range dependents[next] {
+			pending[dependent]--
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,176 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// enqueueBundleRollback enqueues jobs that undo the components a failed bundle installation
+// created, in reverse order: exposures, then links, then modules, last installed first.
+// Every job runs even if an earlier one failed, so one stuck component doesn't keep the
+// rest in place. A bundle_rollback meta-job after them records the outcome.
+func (e *JobExecutor) enqueueBundleRollback(manager *Manager, bundleID string, created BundleDelta) (string, error) {
+	var retained []string
+	if e.bundleStore != nil {
+		_ = e.bundleStore.SetBundleStatus(bundleID, "rolling_back")
+
+		// Never remove a component another bundle already relied on
+		if installed, err := e.bundleStore.GetBundleComponents(bundleID); err == nil {
+			var shared BundleDelta
+			created, shared = installed.splitShared(created)
+			retained = installed.retainedMessages(shared)
+		}
+	}
+
+	var jobIDs []string
+	enqueue := func(cmd Command) error {
+		jobID, _, err := manager.EnqueueWithOptions(cmd, EnqueueOptions{
+			DependsOn:              append([]string{}, jobIDs...),
+			RunOnDependencyFailure: true,
+		})
+		if err != nil {
+			return err
+		}
+		jobIDs = append(jobIDs, jobID)
+		return nil
+	}
+
+	for _, exposureID := range created.Exposures {
+		if err := enqueue(Command{
+			Type: CmdDeleteExposure,
+			Args: map[string]interface{}{
+				"exposure_id": exposureID,
+				"bundle_id":   bundleID,
+			},
+		}); err != nil {
+			return "", fmt.Errorf("failed to enqueue exposure deletion: %w", err)
+		}
+	}
+
+	for _, linkID := range created.Links {
+		if err := enqueue(Command{
+			Type: CmdDeleteLink,
+			Args: map[string]interface{}{
+				"link_id":   linkID,
+				"bundle_id": bundleID,
+			},
+		}); err != nil {
+			return "", fmt.Errorf("failed to enqueue link deletion: %w", err)
+		}
+	}
+
+	for i := len(created.Modules) - 1; i >= 0; i-- {
+		if err := enqueue(Command{
+			Type: CmdUninstallModule,
+			Args: map[string]interface{}{
+				"module_id": created.Modules[i],
+				"bundle_id": bundleID,
+			},
+		}); err != nil {
+			return "", fmt.Errorf("failed to enqueue module uninstall: %w", err)
+		}
+	}
+
+	if err := enqueue(Command{
+		Type: CmdBundleRollback,
+		Args: map[string]interface{}{
+			"bundle_id": bundleID,
+			"retained":  retained,
+		},
+	}); err != nil {
+		return "", fmt.Errorf("failed to enqueue bundle rollback: %w", err)
+	}
+
+	return jobIDs[len(jobIDs)-1], nil
+}
+
+// executeBundleRollback runs a bundle_rollback command
+// The bundle_rollback is a meta-job created by a failed bundle_install with rollback_on_failure
+// set. It runs after all of the compensating jobs finished, records which components were removed,
+// and leaves the bundle "rolled_back", or "failed" if anything could not be removed.
+func (e *JobExecutor) executeBundleRollback(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
+	bundleID, ok := cmd.Args["bundle_id"].(string)
+	if !ok || bundleID == "" {
+		return nil, fmt.Errorf("bundle_id is required")
+	}
+
+	job, err := manager.Get(jobID)
+	if err != nil {
+		e.logger.Error("failed to get job", "job_id", jobID, "error", err)
+		return nil, err
+	}
+
+	var failures []string
+	for _, depJobID := range job.DependsOn {
+		depJob, err := manager.Get(depJobID)
+		if err != nil {
+			e.logger.Warn("failed to get dependency job", "dep_job_id", depJobID, "error", err)
+			continue
+		}
+
+		status := "deleted"
+		if depJob.Status != StatusCompleted {
+			status = "failed"
+		}
+
+		var kind, componentID string
+		switch depJob.Command.Type {
+		case CmdUninstallModule:
+			kind = "module"
+			componentID, _ = depJob.Command.Args["module_id"].(string)
+			if e.bundleStore != nil {
+				_ = e.bundleStore.UpdateModuleComponentStatus(bundleID, componentID, status, depJob.Error)
+			}
+		case CmdDeleteLink:
+			kind = "link"
+			componentID, _ = depJob.Command.Args["link_id"].(string)
+			if e.bundleStore != nil {
+				_ = e.bundleStore.UpdateLinkComponentStatus(bundleID, componentID, status, depJob.Error)
+			}
+		case CmdDeleteExposure:
+			kind = "exposure"
+			componentID, _ = depJob.Command.Args["exposure_id"].(string)
+			if e.bundleStore != nil {
+				_ = e.bundleStore.UpdateExposureComponentStatus(bundleID, componentID, status, depJob.Error)
+			}
+		default:
+			continue
+		}
+
+		if status == "failed" {
+			failures = append(failures, fmt.Sprintf("%s %s: %s", kind, componentID, depJob.Error))
+		}
+	}
+
+	if len(failures) > 0 {
+		if e.bundleStore != nil {
+			_ = e.bundleStore.CompleteBundleInstallation(bundleID, "failed")
+		}
+		return nil, fmt.Errorf("rollback of bundle %s incomplete: %s", bundleID, strings.Join(failures, "; "))
+	}
+
+	if e.bundleStore != nil {
+		_ = e.bundleStore.CompleteBundleInstallation(bundleID, "rolled_back")
+	}
+
+	event := Event{
+		Timestamp: time.Now().UTC(),
+		Type:      "info",
+		Message:   fmt.Sprintf("Bundle rolled back: %s", bundleID),
+	}
+	if err := manager.AppendEvent(jobID, event); err != nil {
+		e.logger.Error("failed to append event", "job_id", jobID, "error", err)
+	}
+
+	result := map[string]interface{}{
+		"bundle_id": bundleID,
+		"status":    "rolled_back",
+	}
+	if retained := e.reportRetained(manager, jobID, cmd); len(retained) > 0 {
+		result["retained"] = retained
+	}
+
+	return result, nil
+}
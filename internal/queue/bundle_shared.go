@@ -0,0 +1,70 @@
+package queue
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// splitShared separates the components in delta that other bundles still hold from those
+// this bundle can remove
+func (b *InstalledBundle) splitShared(delta BundleDelta) (owned, retained BundleDelta) {
+	split := func(ids []string, shared map[string][]string) ([]string, []string) {
+		var own, keep []string
+		for _, id := range ids {
+			if len(shared[id]) > 0 {
+				keep = append(keep, id)
+			} else {
+				own = append(own, id)
+			}
+		}
+		return own, keep
+	}
+
+	owned.Modules, retained.Modules = split(delta.Modules, b.SharedModules)
+	owned.Links, retained.Links = split(delta.Links, b.SharedLinks)
+	owned.Exposures, retained.Exposures = split(delta.Exposures, b.SharedExposures)
+	return owned, retained
+}
+
+// retainedMessages describes each retained component and the bundles still using it
+func (b *InstalledBundle) retainedMessages(retained BundleDelta) []string {
+	var messages []string
+	describe := func(kind string, ids []string, shared map[string][]string) {
+		for _, id := range ids {
+			messages = append(messages, fmt.Sprintf("%s %s retained, still used by bundle %s", kind, id, strings.Join(shared[id], ", ")))
+		}
+	}
+	describe("exposure", retained.Exposures, b.SharedExposures)
+	describe("link", retained.Links, b.SharedLinks)
+	describe("module", retained.Modules, b.SharedModules)
+	return messages
+}
+
+// reportRetained adds a warning event to a bundle meta-job for each component listed in its
+// "retained" argument, and returns them for the job result
+func (e *JobExecutor) reportRetained(manager *Manager, jobID string, cmd Command) []string {
+	var retained []string
+	switch values := cmd.Args["retained"].(type) {
+	case []string:
+		retained = values
+	case []interface{}:
+		for _, value := range values {
+			if message, ok := value.(string); ok {
+				retained = append(retained, message)
+			}
+		}
+	}
+
+	for _, message := range retained {
+		event := Event{
+			Timestamp: time.Now().UTC(),
+			Type:      "warning",
+			Message:   message,
+		}
+		if err := manager.AppendEvent(jobID, event); err != nil {
+			e.logger.Error("failed to append event", "job_id", jobID, "error", err)
+		}
+	}
+	return retained
+}
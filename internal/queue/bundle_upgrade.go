@@ -0,0 +1,426 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	internalPaths "zeropoint-agent/internal"
+	"zeropoint-agent/internal/catalog"
+	"zeropoint-agent/internal/modules"
+)
+
+// EnqueueBundleUpgradeRequest is the request for creating a bundle upgrade meta-job
+type EnqueueBundleUpgradeRequest struct {
+	BundleID       string   `json:"bundle_id"`
+	Tags           []string `json:"tags,omitempty"`            // Applied to the meta-job and every component job
+	IdempotencyKey string   `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string   `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
+
+	SkipImagePrefetch bool `json:"skip_image_prefetch,omitempty"` // Don't pull images before installing or upgrading the bundle's modules
+}
+
+// BundleDelta lists bundle components by kind
+type BundleDelta struct {
+	Modules   []string `json:"modules,omitempty"`
+	Links     []string `json:"links,omitempty"`
+	Exposures []string `json:"exposures,omitempty"`
+}
+
+// BundleUpgradePlan describes how an installed bundle differs from its catalog definition
+type BundleUpgradePlan struct {
+	Added     BundleDelta `json:"added"`
+	Changed   BundleDelta `json:"changed"` // Modules with a new source SHA, links with new bindings, exposures with a new target
+	Removed   BundleDelta `json:"removed"`
+	Retained  BundleDelta `json:"retained"` // Dropped from the bundle but still used by another bundle, so left in place
+	Unchanged BundleDelta `json:"unchanged"`
+}
+
+// EnqueueBundleUpgrade handles POST /api/jobs/enqueue_upgrade_bundle
+// @ID enqueueBundleUpgrade
+// @Summary Enqueue a bundle upgrade meta-job
+// @Description Compares an installed bundle with its current catalog definition and enqueues only the difference: new modules are installed, modules whose source SHA changed are upgraded, and links and exposures are created, updated or deleted. A bundle_upgrade meta-job runs once all of them finished; it updates the bundle record and reports the plan, or marks the bundle failed if a component job failed or was cancelled. Unchanged components are not touched. Links and exposures of bundles installed before definitions were recorded are only added or removed, never updated.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
+// @Param body body EnqueueBundleUpgradeRequest true "Bundle upgrade request"
+// @Success 201 {object} JobResponse "Bundle upgrade job created successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
+// @Failure 400 {string} string "Bad request or invalid bundle definition"
+// @Failure 404 {string} string "Bundle not found"
+// @Router /jobs/enqueue_upgrade_bundle [post]
+func (h *Handlers) EnqueueBundleUpgrade(w http.ResponseWriter, r *http.Request) {
+	var req EnqueueBundleUpgradeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.BundleID == "" {
+		http.Error(w, "bundle_id is required", http.StatusBadRequest)
+		return
+	}
+
+	// A retried request must not enqueue a second set of component jobs
+	key := idempotencyKey(r, req.IdempotencyKey)
+	if existingID, ok := h.manager.JobForIdempotencyKey(CmdBundleUpgrade, key); ok {
+		h.writeExistingJob(w, existingID)
+		return
+	}
+
+	if h.bundleStore == nil {
+		http.Error(w, "bundle store unavailable", http.StatusInternalServerError)
+		return
+	}
+	record, err := h.bundleStore.GetBundleComponents(req.BundleID)
+	if err != nil {
+		http.Error(w, "bundle not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	installed := BundleDelta{Modules: record.Modules, Links: record.Links, Exposures: record.Exposures}
+	previous := record.Definition
+
+	bundle, err := h.catalogStore.GetBundle(record.Name)
+	if err != nil {
+		http.Error(w, "failed to fetch bundle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	problems, err := h.catalogStore.ValidateBundle(bundle)
+	if err != nil {
+		http.Error(w, "failed to validate bundle: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(problems) > 0 {
+		http.Error(w, "invalid bundle "+record.Name+": "+strings.Join(problems, "; "), http.StatusBadRequest)
+		return
+	}
+
+	sources := make(map[string]string)
+	for _, moduleName := range bundle.Modules {
+		module, err := h.catalogStore.GetModule(moduleName)
+		if err != nil {
+			http.Error(w, "failed to fetch module: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		sources[moduleName] = module.Source
+	}
+
+	plan := planBundleUpgrade(installed, previous, bundle, sources)
+	plan.Removed, plan.Retained = record.splitShared(plan.Removed)
+	componentJobIDs, err := h.enqueueBundleUpgradeJobs(req.BundleID, req.Tags, req.SkipImagePrefetch, plan, previous, bundle, sources)
+	if err != nil {
+		h.discardJobs(componentJobIDs)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The meta-job runs once every component job finished, whether or not it succeeded, so the
+	// record is marked failed when the upgrade is not complete
+	jobID, existing, err := h.manager.EnqueueWithOptions(Command{
+		Type: CmdBundleUpgrade,
+		Args: map[string]interface{}{
+			"bundle_id":  req.BundleID,
+			"definition": *bundle,
+			"plan":       plan,
+		},
+	}, EnqueueOptions{DependsOn: componentJobIDs, IdempotencyKey: key, RunOnDependencyFailure: true, CallbackURL: req.CallbackURL, Tags: req.Tags})
+	if err != nil {
+		h.discardJobs(componentJobIDs)
+		h.logger.Debug("failed to enqueue bundle upgrade job", "bundle_id", req.BundleID, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if existing {
+		// A concurrent request with the same key won the race; its meta-job tracks its own
+		// component jobs, so the ones enqueued here would never be tracked
+		h.discardJobs(componentJobIDs)
+		h.writeExistingJob(w, jobID)
+		return
+	}
+
+	job, err := h.manager.Get(jobID)
+	if err != nil {
+		h.logger.Error("failed to fetch enqueued bundle upgrade job", "job_id", jobID, "error", err)
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(enqueueStatus(existing))
+	json.NewEncoder(w).Encode(job)
+}
+
+// enqueueBundleUpgradeJobs enqueues the component jobs of an upgrade plan. Each job depends
+// on every job before it: removed exposures and links go first, then modules are installed or
+// upgraded, links are created or rebound and removed modules are uninstalled. Changed
+// exposures are only deleted at the end, right before exposures are created, so a failure
+// earlier in the chain leaves them serving the previous target. The IDs enqueued so far are
+// returned even on error.
+func (h *Handlers) enqueueBundleUpgradeJobs(bundleID string, tags []string, skipImagePrefetch bool, plan BundleUpgradePlan, previous, bundle *catalog.CatalogBundle, sources map[string]string) ([]string, error) {
+	var jobIDs []string
+	enqueue := func(cmdType CommandType, args map[string]interface{}) error {
+		args["bundle_id"] = bundleID
+		jobID, err := h.enqueueComponent(Command{Type: cmdType, Args: args}, append([]string{}, jobIDs...), tags)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue %s: %w", cmdType, err)
+		}
+		jobIDs = append(jobIDs, jobID)
+		return nil
+	}
+
+	for _, exposureID := range plan.Removed.Exposures {
+		if err := enqueue(CmdDeleteExposure, map[string]interface{}{"exposure_id": exposureID}); err != nil {
+			return jobIDs, err
+		}
+	}
+	for _, linkID := range plan.Removed.Links {
+		if err := enqueue(CmdDeleteLink, map[string]interface{}{"link_id": linkID}); err != nil {
+			return jobIDs, err
+		}
+	}
+
+	for _, moduleID := range plan.Added.Modules {
+		if err := enqueue(CmdInstallModule, map[string]interface{}{"module_id": moduleID, "source": sources[moduleID], "skip_image_prefetch": skipImagePrefetch}); err != nil {
+			return jobIDs, err
+		}
+	}
+	for _, moduleID := range plan.Changed.Modules {
+		cmdType := CmdUpgradeModule
+		if !moduleInstalled(moduleID) {
+			cmdType = CmdInstallModule // Recorded in the bundle but gone from disk
+		}
+		if err := enqueue(cmdType, map[string]interface{}{"module_id": moduleID, "source": sources[moduleID], "skip_image_prefetch": skipImagePrefetch}); err != nil {
+			return jobIDs, err
+		}
+	}
+
+	for _, linkID := range plan.Added.Links {
+		if err := enqueue(CmdCreateLink, map[string]interface{}{"link_id": linkID, "modules": linkBindings(bundle.Links[linkID])}); err != nil {
+			return jobIDs, err
+		}
+	}
+	for _, linkID := range plan.Changed.Links {
+		changes, remove := linkChanges(previous.Links[linkID], bundle.Links[linkID])
+		if err := enqueue(CmdUpdateLink, map[string]interface{}{"link_id": linkID, "modules": changes, "remove": remove}); err != nil {
+			return jobIDs, err
+		}
+	}
+
+	for _, moduleID := range plan.Removed.Modules {
+		if err := enqueue(CmdUninstallModule, map[string]interface{}{"module_id": moduleID}); err != nil {
+			return jobIDs, err
+		}
+	}
+
+	// Uninstalling a module already deleted the exposures that pointed at it
+	uninstalled := toSet(plan.Removed.Modules)
+	for _, exposureID := range plan.Changed.Exposures {
+		if uninstalled[previous.Exposures[exposureID].Module] {
+			continue
+		}
+		if err := enqueue(CmdDeleteExposure, map[string]interface{}{"exposure_id": exposureID}); err != nil {
+			return jobIDs, err
+		}
+	}
+
+	if exposureIDs := append(append([]string{}, plan.Added.Exposures...), plan.Changed.Exposures...); len(exposureIDs) > 0 {
+		if err := enqueue(CmdCreateExposures, map[string]interface{}{"exposures": bundleExposures(bundle.Exposures, toSet(exposureIDs))}); err != nil {
+			return jobIDs, err
+		}
+	}
+
+	return jobIDs, nil
+}
+
+// planBundleUpgrade diffs the installed components of a bundle against its catalog
+// definition. Modules compare by installed source SHA; links and exposures compare against
+// the definition recorded at install, and without one only additions and removals are found.
+func planBundleUpgrade(installed BundleDelta, previous, bundle *catalog.CatalogBundle, sources map[string]string) BundleUpgradePlan {
+	var plan BundleUpgradePlan
+
+	installedModules := toSet(installed.Modules)
+	for _, moduleID := range bundle.Modules {
+		switch {
+		case !installedModules[moduleID]:
+			plan.Added.Modules = append(plan.Added.Modules, moduleID)
+		case moduleSourceChanged(moduleID, sources[moduleID]):
+			plan.Changed.Modules = append(plan.Changed.Modules, moduleID)
+		default:
+			plan.Unchanged.Modules = append(plan.Unchanged.Modules, moduleID)
+		}
+	}
+	plan.Removed.Modules = missingFrom(installed.Modules, toSet(bundle.Modules))
+
+	installedLinks := toSet(installed.Links)
+	for linkID, entries := range bundle.Links {
+		switch {
+		case !installedLinks[linkID]:
+			plan.Added.Links = append(plan.Added.Links, linkID)
+		case previous != nil && !reflect.DeepEqual(linkBindings(previous.Links[linkID]), linkBindings(entries)):
+			plan.Changed.Links = append(plan.Changed.Links, linkID)
+		default:
+			plan.Unchanged.Links = append(plan.Unchanged.Links, linkID)
+		}
+	}
+	plan.Removed.Links = missingFrom(installed.Links, keySet(bundle.Links))
+
+	installedExposures := toSet(installed.Exposures)
+	for exposureID, exposure := range bundle.Exposures {
+		old, recorded := catalog.BundleExposure{}, false
+		if previous != nil {
+			old, recorded = previous.Exposures[exposureID]
+		}
+		switch {
+		case !installedExposures[exposureID]:
+			plan.Added.Exposures = append(plan.Added.Exposures, exposureID)
+		case recorded && (old.Module != exposure.Module || old.Protocol != exposure.Protocol || old.ModulePort != exposure.ModulePort):
+			plan.Changed.Exposures = append(plan.Changed.Exposures, exposureID)
+		default:
+			plan.Unchanged.Exposures = append(plan.Unchanged.Exposures, exposureID)
+		}
+	}
+	plan.Removed.Exposures = missingFrom(installed.Exposures, keySet(bundle.Exposures))
+
+	for _, delta := range []*BundleDelta{&plan.Added, &plan.Changed, &plan.Removed, &plan.Unchanged} {
+		sort.Strings(delta.Modules)
+		sort.Strings(delta.Links)
+		sort.Strings(delta.Exposures)
+	}
+	return plan
+}
+
+// moduleSourceChanged reports whether an installed module was cloned from a different source
+// or commit than the catalog now lists. Modules not installed from git never change; modules
+// missing from disk always do.
+func moduleSourceChanged(moduleID, source string) bool {
+	if !moduleInstalled(moduleID) {
+		return true
+	}
+	metadata, err := modules.LoadMetadata(filepath.Join(internalPaths.GetModulesDir(), moduleID))
+	if err != nil || metadata == nil || metadata.Ref == "" {
+		return false
+	}
+	return metadata.Source+"@"+metadata.Ref != source
+}
+
+// moduleInstalled reports whether a module directory with a main.tf exists
+func moduleInstalled(moduleID string) bool {
+	_, err := os.Stat(filepath.Join(internalPaths.GetModulesDir(), moduleID, "main.tf"))
+	return err == nil
+}
+
+// linkBindings converts bundle link entries to the module bindings create_link expects
+func linkBindings(entries []catalog.BundleLink) map[string]map[string]interface{} {
+	bindings := make(map[string]map[string]interface{})
+	for _, entry := range entries {
+		bindMap := make(map[string]interface{})
+		for k, v := range entry.Bind {
+			bindMap[k] = v
+		}
+		bindings[entry.Module] = bindMap
+	}
+	return bindings
+}
+
+// linkChanges returns the update_link changes that turn the old link entries into the new
+// ones: new and rebound modules with bindings that went away set to nil, and modules to detach
+func linkChanges(previous, next []catalog.BundleLink) (map[string]map[string]interface{}, []string) {
+	oldBindings := linkBindings(previous)
+	newBindings := linkBindings(next)
+
+	changes := make(map[string]map[string]interface{})
+	for moduleName, bindings := range newBindings {
+		oldModule, existed := oldBindings[moduleName]
+		if existed && reflect.DeepEqual(oldModule, bindings) {
+			continue
+		}
+		change := make(map[string]interface{})
+		for input, value := range bindings {
+			change[input] = value
+		}
+		for input := range oldModule {
+			if _, ok := bindings[input]; !ok {
+				change[input] = nil
+			}
+		}
+		changes[moduleName] = change
+	}
+
+	var remove []string
+	for moduleName := range oldBindings {
+		if _, ok := newBindings[moduleName]; !ok {
+			remove = append(remove, moduleName)
+		}
+	}
+	sort.Strings(remove)
+	return changes, remove
+}
+
+// bundleExposures returns the create_exposures entries for the included bundle exposures,
+// sorted by ID. Bundle exposures are served under their ID as hostname.
+func bundleExposures(exposures map[string]catalog.BundleExposure, include map[string]bool) []BatchExposure {
+	var entries []BatchExposure
+	for exposureID, exposure := range exposures {
+		if !include[exposureID] {
+			continue
+		}
+		entries = append(entries, BatchExposure{
+			ExposureID:    exposureID,
+			ModuleID:      exposure.Module,
+			Protocol:      exposure.Protocol,
+			Hostname:      exposureID,
+			ContainerPort: uint32(exposure.ModulePort),
+
+			AllowUndeclaredPort: exposure.AllowUndeclaredPort,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ExposureID < entries[j].ExposureID
+	})
+	return entries
+}
+
+// decodeArg converts a job argument into a typed value. Arguments keep their Go type while
+// the job is in memory but come back as generic JSON after a restart.
+func decodeArg(arg interface{}, out interface{}) error {
+	data, err := json.Marshal(arg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// toSet returns the members of a string slice
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// keySet returns the keys of a map
+func keySet[V any](m map[string]V) map[string]bool {
+	set := make(map[string]bool, len(m))
+	for k := range m {
+		set[k] = true
+	}
+	return set
+}
+
+// missingFrom returns the values not in set
+func missingFrom(values []string, set map[string]bool) []string {
+	var missing []string
+	for _, v := range values {
+		if !set[v] {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
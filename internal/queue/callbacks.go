@@ -0,0 +1,217 @@
+package queue
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// CallbackSignatureHeader carries "sha256=<hex>", the HMAC-SHA256 of the request body
+	// keyed with the callback secret. It is left out when no secret is configured.
+	CallbackSignatureHeader = "X-Zeropoint-Signature"
+	// CallbackJobHeader carries the ID of the job a callback reports on
+	CallbackJobHeader = "X-Zeropoint-Job"
+
+	callbackAttempts       = 5
+	callbackInitialBackoff = 2 * time.Second
+	callbackTimeout        = 10 * time.Second
+)
+
+// Shared address space (RFC 6598) used by carrier-grade NAT; not covered by IsPrivate
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// callbackNotifier POSTs finished jobs to the callback URLs they were enqueued with.
+// Deliveries run in their own goroutines so a slow or unreachable receiver never holds up
+// the queue.
+type callbackNotifier struct {
+	secret  []byte
+	client  *http.Client
+	backoff time.Duration
+	logger  *slog.Logger
+
+	// Internal networks callbacks may reach despite the default block
+	allowedMu sync.RWMutex
+	allowed   []netip.Prefix
+}
+
+func newCallbackNotifier(logger *slog.Logger) *callbackNotifier {
+	n := &callbackNotifier{
+		backoff: callbackInitialBackoff,
+		logger:  logger,
+	}
+
+	// Addresses are checked once DNS has resolved them, right before connecting, so a
+	// hostname cannot point a callback at an internal address after validation. No
+	// proxy is used, since it would connect on the callback's behalf.
+	dialer := &net.Dialer{Timeout: callbackTimeout, Control: n.checkDialAddress}
+	n.client = &http.Client{
+		Timeout:   callbackTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}
+	return n
+}
+
+// SetCallbackAllowedNetworks lets callbacks reach the given private, loopback or
+// link-local networks, as CIDRs. Call it before the worker starts.
+func (m *Manager) SetCallbackAllowedNetworks(networks []string) error {
+	var allowed []netip.Prefix
+	for _, network := range networks {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return fmt.Errorf("invalid callback network %q: %w", network, err)
+		}
+		allowed = append(allowed, prefix.Masked())
+	}
+
+	m.callbacks.allowedMu.Lock()
+	defer m.callbacks.allowedMu.Unlock()
+	m.callbacks.allowed = allowed
+	return nil
+}
+
+// checkDialAddress is the dialer's control hook: it refuses connections to internal
+// addresses that are not explicitly allowed
+func (n *callbackNotifier) checkDialAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("callback address %q: %w", address, err)
+	}
+	return n.checkAddr(addrPort.Addr())
+}
+
+// checkAddr rejects loopback, private, link-local (including cloud metadata endpoints)
+// and other non-public addresses unless an allowed network contains them
+func (n *callbackNotifier) checkAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	internal := addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() ||
+		addr.IsUnspecified() || sharedAddressSpace.Contains(addr)
+	if !internal {
+		return nil
+	}
+
+	n.allowedMu.RLock()
+	defer n.allowedMu.RUnlock()
+	for _, prefix := range n.allowed {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	return fmt.Errorf("callback address %s is internal and not in callback_allowed_networks", addr)
+}
+
+// SetCallbackSecret sets the shared secret callback deliveries are signed with. Call it
+// before the worker starts.
+func (m *Manager) SetCallbackSecret(secret string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.callbacks.secret = []byte(secret)
+}
+
+// validateURL accepts absolute http and https URLs. Hosts given as internal IP addresses
+// are rejected up front; hostnames are checked when a delivery connects.
+func (n *callbackNotifier) validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid callback_url %q: must be an absolute http or https URL", raw)
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil {
+		if err := n.checkAddr(addr); err != nil {
+			return fmt.Errorf("invalid callback_url %q: %w", raw, err)
+		}
+	}
+	return nil
+}
+
+// notifyCallback delivers a job that just reached a terminal status to its callback URL,
+// if it has one. The job is read again once the caller releases the lock, so the payload
+// includes the final events.
+func (m *Manager) notifyCallback(job *Job) {
+	if job.CallbackURL == "" {
+		return
+	}
+
+	go func(jobID, callbackURL string) {
+		resp, err := m.Get(jobID)
+		if err != nil {
+			m.logger.Warn("failed to read job for callback", "job_id", jobID, "error", err)
+			return
+		}
+		payload, err := json.Marshal(resp)
+		if err != nil {
+			m.logger.Error("failed to encode job for callback", "job_id", jobID, "error", err)
+			return
+		}
+		m.callbacks.deliver(jobID, callbackURL, payload)
+	}(job.ID, job.CallbackURL)
+}
+
+// deliver POSTs the payload, retrying with exponential backoff on network errors, 5xx,
+// 408 and 429 responses. Other responses are final.
+func (n *callbackNotifier) deliver(jobID, callbackURL string, payload []byte) {
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(jobID, callbackURL, payload)
+		if err == nil {
+			n.logger.Info("job callback delivered", "job_id", jobID, "attempt", attempt)
+			return
+		}
+		if !retry || attempt == callbackAttempts {
+			n.logger.Error("job callback delivery failed", "job_id", jobID, "attempts", attempt, "error", err)
+			return
+		}
+
+		n.logger.Warn("job callback delivery failed, retrying", "job_id", jobID, "attempt", attempt, "retry_in", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt and reports whether a failure is worth retrying
+func (n *callbackNotifier) post(jobID, callbackURL string, payload []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, callbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(CallbackJobHeader, jobID)
+	if len(n.secret) > 0 {
+		req.Header.Set(CallbackSignatureHeader, signCallback(n.secret, payload))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("receiver responded %s", resp.Status)
+}
+
+// signCallback computes the signature header value for a payload
+func signCallback(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
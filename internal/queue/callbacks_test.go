@@ -0,0 +1,44 @@
+package queue
+
+import (
+	"log/slog"
+	"net/netip"
+	"testing"
+)
+
+func TestCallbackAddressCheck(t *testing.T) {
+	m := &Manager{callbacks: newCallbackNotifier(slog.Default())}
+	if err := m.SetCallbackAllowedNetworks([]string{"10.1.0.0/16"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		addr    string
+		allowed bool
+	}{
+		{"93.184.216.34", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"169.254.169.254", false},
+		{"192.168.1.10", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"fe80::1", false},
+		{"10.1.2.3", true},
+		{"10.2.0.1", false},
+	}
+	for _, tc := range cases {
+		err := m.callbacks.checkAddr(netip.MustParseAddr(tc.addr))
+		if (err == nil) != tc.allowed {
+			t.Errorf("%s: error %v, want allowed %v", tc.addr, err, tc.allowed)
+		}
+	}
+
+	if err := m.callbacks.validateURL("http://169.254.169.254/latest/meta-data"); err == nil {
+		t.Error("validateURL accepted the metadata endpoint")
+	}
+	if err := m.callbacks.validateURL("https://hooks.example.com/zeropoint"); err != nil {
+		t.Errorf("validateURL rejected a public hostname: %v", err)
+	}
+}
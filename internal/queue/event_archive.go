@@ -0,0 +1,238 @@
+package queue
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const eventsArchiveName = "events.archive.jsonl.gz"
+
+// truncatedEventType marks where compacted log lines were taken out of a job's events. A job
+// has at most one; it counts every line archived so far and is dropped when the archive is
+// merged back in.
+const truncatedEventType = "truncated"
+
+// eventsArchiveFile returns the path to a job's compacted log events
+func (m *Manager) eventsArchiveFile(jobID string) string {
+	return filepath.Join(m.jobDir(jobID), eventsArchiveName)
+}
+
+// trackEventCount counts an appended event and compacts the job's events once they exceed
+// the cap (caller must hold the lock)
+func (m *Manager) trackEventCount(jobID string) {
+	if m.maxEvents == 0 {
+		return
+	}
+
+	count, ok := m.eventCounts[jobID]
+	if ok {
+		count++
+	} else {
+		count = m.countEvents(jobID)
+	}
+	m.eventCounts[jobID] = count
+
+	if count > m.maxEvents {
+		if err := m.compactEvents(jobID); err != nil {
+			m.logger.Warn("failed to compact job events", "job_id", jobID, "error", err)
+		}
+	}
+}
+
+// countEvents counts the lines of a job's events file
+func (m *Manager) countEvents(jobID string) int {
+	file, err := os.Open(m.eventsFile(jobID))
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		count++
+	}
+	return count
+}
+
+// compactEvents moves the oldest log events into the compressed archive until half the cap
+// is left, leaving a single "N lines truncated" event where they were. Other events are never
+// archived, so a job's steps, warnings and errors stay in events.jsonl (caller must hold the
+// lock).
+func (m *Manager) compactEvents(jobID string) error {
+	events, err := m.getEvents(jobID)
+	if err != nil {
+		return err
+	}
+
+	excess := len(events) - m.maxEvents/2
+	var archived, kept []Event
+	marker := -1 // Index of the truncation marker in kept
+	for _, event := range events {
+		switch {
+		case event.Type == truncatedEventType:
+			marker = len(kept)
+			kept = append(kept, event)
+		case excess > 0 && event.Type == "log":
+			if marker < 0 {
+				marker = len(kept)
+				kept = append(kept, Event{Timestamp: event.Timestamp, Type: truncatedEventType})
+			}
+			archived = append(archived, event)
+			excess--
+		default:
+			kept = append(kept, event)
+		}
+	}
+	if len(archived) == 0 {
+		m.eventCounts[jobID] = len(events)
+		return nil
+	}
+
+	job, err := m.getJob(jobID)
+	if err != nil {
+		return err
+	}
+	job.ArchivedEvents += len(archived)
+	kept[marker].Message = fmt.Sprintf("%d lines truncated", job.ArchivedEvents)
+	kept[marker].Data = map[string]interface{}{"lines": job.ArchivedEvents}
+
+	// Each compaction appends a gzip member; readers decode them as one stream
+	archive, err := os.OpenFile(m.eventsArchiveFile(jobID), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open events archive: %w", err)
+	}
+	gz := gzip.NewWriter(archive)
+	enc := json.NewEncoder(gz)
+	for _, event := range archived {
+		if err := enc.Encode(event); err != nil {
+			archive.Close()
+			return fmt.Errorf("failed to archive event: %w", err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		archive.Close()
+		return fmt.Errorf("failed to archive events: %w", err)
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to archive events: %w", err)
+	}
+
+	// Atomic write: write to temp file, then rename
+	tmpPath := m.eventsFile(jobID) + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite events: %w", err)
+	}
+	enc = json.NewEncoder(tmp)
+	for _, event := range kept {
+		if err := enc.Encode(event); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to rewrite events: %w", err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to rewrite events: %w", err)
+	}
+	if err := os.Rename(tmpPath, m.eventsFile(jobID)); err != nil {
+		return fmt.Errorf("failed to rewrite events: %w", err)
+	}
+	m.eventCounts[jobID] = len(kept)
+
+	if err := m.writeJobMetadata(job); err != nil {
+		m.logger.Warn("failed to record archived events", "job_id", jobID, "error", err)
+	}
+
+	m.logger.Debug("compacted job events", "job_id", jobID, "archived", len(archived), "kept", len(kept))
+	return nil
+}
+
+// getArchivedEvents reads a job's compacted log events (caller must handle locking)
+func (m *Manager) getArchivedEvents(jobID string) ([]Event, error) {
+	file, err := os.Open(m.eventsArchiveFile(jobID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read events archive: %w", err)
+	}
+	defer file.Close()
+
+	return decodeArchive(file)
+}
+
+// decodeArchive decodes the events of one or more gzip members of an events archive
+func decodeArchive(r io.Reader) ([]Event, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events archive: %w", err)
+	}
+	defer gz.Close()
+
+	var events []Event
+	dec := json.NewDecoder(gz)
+	for {
+		var event Event
+		err := dec.Decode(&event)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode archived event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// getAllEvents returns a job's archived and current events merged in timestamp order
+// (caller must handle locking)
+func (m *Manager) getAllEvents(jobID string) ([]Event, error) {
+	archived, err := m.getArchivedEvents(jobID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := m.getEvents(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return mergeEvents(archived, current), nil
+}
+
+// mergeEvents merges a job's archived and current events in timestamp order. The truncation
+// marker is left out, since the lines it stands for are back.
+func mergeEvents(archived, current []Event) []Event {
+	if len(archived) == 0 {
+		return current
+	}
+
+	restored := make([]Event, 0, len(current))
+	for _, event := range current {
+		if event.Type != truncatedEventType {
+			restored = append(restored, event)
+		}
+	}
+	current = restored
+
+	// Both are in append order, so a merge restores the original order
+	merged := make([]Event, 0, len(archived)+len(current))
+	i, j := 0, 0
+	for i < len(archived) && j < len(current) {
+		if !current[j].Timestamp.Before(archived[i].Timestamp) {
+			merged = append(merged, archived[i])
+			i++
+		} else {
+			merged = append(merged, current[j])
+			j++
+		}
+	}
+	merged = append(merged, archived[i:]...)
+	merged = append(merged, current[j:]...)
+	return merged
+}
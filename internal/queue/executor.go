@@ -3,28 +3,156 @@ package queue
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"zeropoint-agent/internal/catalog"
 	"zeropoint-agent/internal/modules"
+	"zeropoint-agent/internal/terraform"
 )
 
+// ExposureOptions carries optional create_exposure settings
+type ExposureOptions struct {
+	HostPort       uint32
+	Backends       []string
+	Aliases        []string
+	PathPrefix     string
+	PrefixRewrite  string
+	TLSCertFile    string
+	TLSKeyFile     string
+	TLSCertName    string
+	RedirectHTTPS  bool
+	WebSocket      bool
+	GRPC           bool
+	RequestTimeout string
+	NumRetries     uint32
+	RetryOn        []string
+	AuthUsers      []string
+	// Session affinity; at most one of cookie and header
+	AffinityCookie    string
+	AffinityCookieTTL string
+	AffinityHeader    string
+
+	AllowUndeclaredPort bool // Skip checking the container port against the module's declared ports
+}
+
+// BatchExposure is one exposure of a create_exposures command
+type BatchExposure struct {
+	ExposureID    string `json:"exposure_id"`
+	ModuleID      string `json:"module_id"`
+	Protocol      string `json:"protocol"`
+	Hostname      string `json:"hostname,omitempty"`
+	ContainerPort uint32 `json:"container_port"`
+
+	AllowUndeclaredPort bool `json:"allow_undeclared_port,omitempty"` // Skip checking the container port against the module's declared ports
+}
+
 // ExposureHandler interface for creating/deleting exposures
 type ExposureHandler interface {
-	CreateExposure(ctx context.Context, exposureID, moduleID, protocol, hostname string, containerPort uint32, tags []string) error
+	CreateExposure(ctx context.Context, exposureID, moduleID, protocol, hostname string, containerPort uint32, tags []string, opts ExposureOptions) error
+	CreateExposures(ctx context.Context, exposures []BatchExposure) error
 	DeleteExposure(ctx context.Context, exposureID string) error
+	DeleteExposuresByModuleID(ctx context.Context, moduleID string) (int, error)
 }
 
 // LinkHandler interface for creating/deleting links
 type LinkHandler interface {
 	CreateLink(ctx context.Context, linkID string, modules map[string]map[string]interface{}, tags []string) error
 	DeleteLink(ctx context.Context, id string) error
+	UpdateLink(ctx context.Context, linkID string, modules map[string]map[string]interface{}, remove []string) error
+	LinksReferencingModule(moduleID string) []string
+	ReapplyModule(ctx context.Context, moduleID string) error
+	DetachModule(ctx context.Context, moduleID string) (LinkDetachResult, error)
+}
+
+// LinkDetachResult describes what removing an uninstalled module from its links changed
+type LinkDetachResult struct {
+	UpdatedLinks    []string `json:"updated_links,omitempty"`
+	DeletedLinks    []string `json:"deleted_links,omitempty"`    // Links that had no modules left
+	RemovedNetworks []string `json:"removed_networks,omitempty"` // Shared networks the module had joined
+}
+
+// ModuleInventory is notified when a job changes which modules are installed
+type ModuleInventory interface {
+	InvalidateModule(moduleID string)
+}
+
+// Module lifecycle actions, run on a module's containers without touching terraform
+const (
+	ModuleActionRestart = "restart"
+	ModuleActionStop    = "stop"
+	ModuleActionStart   = "start"
+)
+
+// moduleActions maps lifecycle commands to the action they run
+var moduleActions = map[CommandType]string{
+	CmdRestartModule: ModuleActionRestart,
+	CmdStopModule:    ModuleActionStop,
+	CmdStartModule:   ModuleActionStart,
+}
+
+// ModuleLifecycle stops, starts and restarts the containers of installed modules
+type ModuleLifecycle interface {
+	RunModuleAction(ctx context.Context, moduleID, action string, timeout time.Duration, jobID string) (*ModuleActionResult, error)
+}
+
+// ModuleActionResult reports what a lifecycle action did to each of a module's containers.
+// It is returned alongside the error when some containers failed.
+type ModuleActionResult struct {
+	ModuleID   string                  `json:"module_id"`
+	Action     string                  `json:"action"` // restart, stop or start
+	Containers []ContainerActionResult `json:"containers"`
+}
+
+// ContainerActionResult is the outcome of a lifecycle action for one container
+type ContainerActionResult struct {
+	Name  string `json:"name"`
+	State string `json:"state"` // Container state once the action finished, e.g. running or exited
+	Error string `json:"error,omitempty"`
+}
+
+// ModuleExec runs one-off commands inside the containers of installed modules
+type ModuleExec interface {
+	ExecInModule(ctx context.Context, moduleID, container string, argv, env []string, stdout, stderr io.Writer) (exitCode int, err error)
+}
+
+// ModuleExecResult is the outcome of a module_exec command. It is returned alongside the
+// error when the command exits non-zero.
+type ModuleExecResult struct {
+	ModuleID  string   `json:"module_id"`
+	Container string   `json:"container"` // Container name within the module, e.g. main
+	Argv      []string `json:"argv"`
+	ExitCode  int      `json:"exit_code"`
+	Duration  string   `json:"duration"`
+}
+
+// defaultModuleExecTimeout bounds a module_exec command that sets no timeout
+const defaultModuleExecTimeout = 10 * time.Minute
+
+// InstalledBundle is an installed bundle as seen by the queue: its catalog name, the
+// definition it was installed from and the IDs of its components
+type InstalledBundle struct {
+	Name       string
+	Definition *catalog.CatalogBundle // nil for bundles installed before definitions were recorded
+	Modules    []string
+	Links      []string
+	Exposures  []string
+
+	// Other installed bundles that also hold a component, by component ID. Shared components
+	// are left in place when this bundle is uninstalled or rolled back.
+	SharedModules   map[string][]string
+	SharedLinks     map[string][]string
+	SharedExposures map[string][]string
 }
 
 // BundleStoreHandler interface for persisting bundle installations
 type BundleStoreHandler interface {
 	CreateBundle(bundleID, bundleName, jobID string) interface{}
+	SetDefinition(bundleID string, definition catalog.CatalogBundle) error
 	AddModuleComponent(bundleID, moduleID string, status, errMsg string) error
 	AddLinkComponent(bundleID, linkID string, status, errMsg string) error
 	AddExposureComponent(bundleID, exposureID string, status, errMsg string) error
@@ -32,8 +160,11 @@ type BundleStoreHandler interface {
 	UpdateLinkComponentStatus(bundleID, linkID, status, errMsg string) error
 	UpdateExposureComponentStatus(bundleID, exposureID, status, errMsg string) error
 	GetBundle(bundleID string) (interface{}, error)
-	CompleteBundleInstallation(bundleID string, success bool) error
+	GetBundleComponents(bundleID string) (*InstalledBundle, error)
+	SetBundleStatus(bundleID, status string) error
+	CompleteBundleInstallation(bundleID, status string) error
 	DeleteBundle(bundleID string) error
+	UpgradeBundle(bundleID string, definition catalog.CatalogBundle, touched BundleDelta) error
 }
 
 // JobExecutor executes queued commands by calling handlers and installers directly
@@ -44,11 +175,15 @@ type JobExecutor struct {
 	linkHandler     LinkHandler
 	catalogStore    *catalog.Store
 	bundleStore     BundleStoreHandler
+	moduleInventory ModuleInventory
+	moduleLifecycle ModuleLifecycle
+	moduleExec      ModuleExec // nil when module_exec is disabled
 	logger          *slog.Logger
 }
 
-// NewJobExecutor creates a new job executor with direct access to handlers
-func NewJobExecutor(installer *modules.Installer, uninstaller *modules.Uninstaller, exposureHandler ExposureHandler, linkHandler LinkHandler, catalogStore *catalog.Store, bundleStore BundleStoreHandler, logger *slog.Logger) *JobExecutor {
+// NewJobExecutor creates a new job executor with direct access to handlers. A nil
+// moduleExec fails module_exec jobs.
+func NewJobExecutor(installer *modules.Installer, uninstaller *modules.Uninstaller, exposureHandler ExposureHandler, linkHandler LinkHandler, catalogStore *catalog.Store, bundleStore BundleStoreHandler, moduleInventory ModuleInventory, moduleLifecycle ModuleLifecycle, moduleExec ModuleExec, logger *slog.Logger) *JobExecutor {
 	return &JobExecutor{
 		installer:       installer,
 		uninstaller:     uninstaller,
@@ -56,29 +191,64 @@ func NewJobExecutor(installer *modules.Installer, uninstaller *modules.Uninstall
 		linkHandler:     linkHandler,
 		catalogStore:    catalogStore,
 		bundleStore:     bundleStore,
+		moduleInventory: moduleInventory,
+		moduleLifecycle: moduleLifecycle,
+		moduleExec:      moduleExec,
 		logger:          logger,
 	}
 }
 
-// ExecuteWithJob runs a command and captures progress events in the job
+// ExecuteWithJob runs a command and captures progress events in the job. Output of
+// any terraform command the job runs is recorded as "log" events.
 func (e *JobExecutor) ExecuteWithJob(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
+	output := terraform.NewLineWriter(func(line string) {
+		if err := manager.AppendEvent(jobID, Event{
+			Timestamp: time.Now().UTC(),
+			Type:      "log",
+			Message:   line,
+		}); err != nil {
+			e.logger.Error("failed to append log event", "job_id", jobID, "error", err)
+		}
+	})
+	defer output.Flush()
+	ctx = terraform.ContextWithOutput(ctx, output)
+	ctx = terraform.ContextWithOwner(ctx, jobID)
+
 	switch cmd.Type {
 	case CmdInstallModule:
 		return e.executeInstallModule(ctx, jobID, manager, cmd)
 	case CmdUninstallModule:
 		return e.executeUninstallModule(ctx, jobID, manager, cmd)
+	case CmdUpgradeModule:
+		return e.executeUpgradeModule(ctx, jobID, manager, cmd)
+	case CmdUpdateResources:
+		return e.executeUpdateResources(ctx, jobID, manager, cmd)
+	case CmdRestartModule, CmdStopModule, CmdStartModule:
+		return e.executeModuleAction(ctx, jobID, manager, cmd)
+	case CmdModuleExec:
+		return e.executeModuleExec(ctx, jobID, manager, cmd)
 	case CmdCreateExposure:
 		return e.executeCreateExposure(ctx, jobID, manager, cmd)
+	case CmdCreateExposures:
+		return e.executeCreateExposures(ctx, jobID, manager, cmd)
 	case CmdDeleteExposure:
 		return e.executeDeleteExposure(ctx, jobID, manager, cmd)
 	case CmdCreateLink:
 		return e.executeCreateLink(ctx, jobID, manager, cmd)
 	case CmdDeleteLink:
 		return e.executeDeleteLink(ctx, jobID, manager, cmd)
+	case CmdUpdateLink:
+		return e.executeUpdateLink(ctx, jobID, manager, cmd)
 	case CmdBundleInstall:
 		return e.executeBundleInstall(ctx, jobID, manager, cmd)
 	case CmdBundleUninstall:
 		return e.executeBundleUninstall(ctx, jobID, manager, cmd)
+	case CmdBundleRollback:
+		return e.executeBundleRollback(ctx, jobID, manager, cmd)
+	case CmdBundleUpgrade:
+		return e.executeBundleUpgrade(ctx, jobID, manager, cmd)
+	case CmdSyncCatalog:
+		return e.executeSyncCatalog(ctx, jobID, manager, cmd)
 	default:
 		return nil, fmt.Errorf("unknown command type: %s", cmd.Type)
 	}
@@ -126,22 +296,49 @@ func (e *JobExecutor) executeInstallModule(ctx context.Context, jobID string, ma
 			event.Type = "error"
 			event.Data.(map[string]string)["error"] = update.Error
 		}
+		if update.Percent > 0 {
+			event.Data.(map[string]string)["percent"] = strconv.Itoa(update.Percent)
+		}
 
 		if err := manager.AppendEvent(jobID, event); err != nil {
 			e.logger.Error("failed to append progress event", "job_id", jobID, "error", err)
 		}
 	}
 
+	skipImagePrefetch, _ := cmd.Args["skip_image_prefetch"].(bool)
+	credentialRef, _ := cmd.Args["credential_ref"].(string)
+
+	var resources *modules.Resources
+	if err := decodeArg(cmd.Args["resources"], &resources); err != nil {
+		return nil, fmt.Errorf("invalid resources: %w", err)
+	}
+
 	// Build install request
 	req := modules.InstallRequest{
-		ModuleID:  moduleID,
-		Source:    source,
-		LocalPath: localPath,
-		Tags:      tags,
+		ModuleID:          moduleID,
+		Source:            source,
+		LocalPath:         localPath,
+		Tags:              tags,
+		CredentialRef:     credentialRef,
+		Resources:         resources,
+		SkipImagePrefetch: skipImagePrefetch,
+	}
+
+	if planOnly, _ := cmd.Args["plan_only"].(bool); planOnly {
+		plan, err := e.installer.Plan(ctx, req, progressCallback)
+		if err != nil {
+			return nil, fmt.Errorf("plan failed: %w", err)
+		}
+		return map[string]interface{}{
+			"module_id": moduleID,
+			"status":    "planned",
+			"plan":      plan,
+		}, nil
 	}
 
 	// Call installer directly with progress callback
-	if err := e.installer.Install(req, progressCallback); err != nil {
+	defer e.invalidateModule(moduleID)
+	if err := e.installer.InstallContext(ctx, req, progressCallback); err != nil {
 		return nil, fmt.Errorf("installation failed: %w", err)
 	}
 
@@ -160,7 +357,15 @@ func (e *JobExecutor) executeUninstallModule(ctx context.Context, jobID string,
 		return nil, fmt.Errorf("module_id is required")
 	}
 
+	// Other modules would keep running against outputs that no longer exist
+	if force, _ := cmd.Args["force"].(bool); !force {
+		if linkIDs := e.linkHandler.LinksReferencingModule(moduleID); len(linkIDs) > 0 {
+			return nil, fmt.Errorf("module %s is referenced by links %s; set force to uninstall anyway", moduleID, strings.Join(linkIDs, ", "))
+		}
+	}
+
 	// Create progress callback that appends events to the job
+	dataPurged := false
 	progressCallback := func(update modules.ProgressUpdate) {
 		event := Event{
 			Timestamp: time.Now().UTC(),
@@ -175,26 +380,283 @@ func (e *JobExecutor) executeUninstallModule(ctx context.Context, jobID string,
 			event.Data.(map[string]string)["error"] = update.Error
 		}
 
+		if update.Status == "data_purged" {
+			dataPurged = true
+		}
+
 		if err := manager.AppendEvent(jobID, event); err != nil {
 			e.logger.Error("failed to append progress event", "job_id", jobID, "error", err)
 		}
 	}
 
 	// Build uninstall request
+	purgeData, _ := cmd.Args["purge_data"].(bool)
 	req := modules.UninstallRequest{
-		ModuleID: moduleID,
+		ModuleID:  moduleID,
+		PurgeData: purgeData,
 	}
 
 	// Call uninstaller directly with progress callback
-	if err := e.uninstaller.Uninstall(req, progressCallback); err != nil {
+	defer e.invalidateModule(moduleID)
+	if err := e.uninstaller.UninstallContext(ctx, req, progressCallback); err != nil {
 		return nil, fmt.Errorf("uninstallation failed: %w", err)
 	}
 
+	// The module is gone; from here on every cleanup step runs even if an earlier one failed
+	var cleanupErrors []string
+	cleanupStep := func(step, message string, err error) {
+		event := Event{
+			Timestamp: time.Now().UTC(),
+			Type:      "info",
+			Message:   message,
+			Data:      map[string]string{"step": step},
+		}
+		if err != nil {
+			e.logger.Warn("uninstall cleanup step failed", "module_id", moduleID, "step", step, "error", err)
+			cleanupErrors = append(cleanupErrors, fmt.Sprintf("%s: %v", step, err))
+			event.Type = "warning"
+			event.Data.(map[string]string)["error"] = err.Error()
+		}
+		if err := manager.AppendEvent(jobID, event); err != nil {
+			e.logger.Error("failed to append cleanup event", "job_id", jobID, "error", err)
+		}
+	}
+
+	// A module may have several exposures (e.g. an HTTP UI and a TCP port); none of them
+	// can route anywhere once its containers are gone
+	removed, err := e.exposureHandler.DeleteExposuresByModuleID(ctx, moduleID)
+	cleanupStep("exposures", fmt.Sprintf("Removed %d exposures", removed), err)
+
+	detached, err := e.linkHandler.DetachModule(ctx, moduleID)
+	cleanupStep("links", fmt.Sprintf("Removed module from %d links, deleted %d links and %d shared networks",
+		len(detached.UpdatedLinks)+len(detached.DeletedLinks), len(detached.DeletedLinks), len(detached.RemovedNetworks)), err)
+
 	result := map[string]interface{}{
+		"module_id":         moduleID,
+		"status":            "uninstalled",
+		"exposures_removed": removed,
+		"links":             detached,
+		"data_purged":       dataPurged,
+	}
+	if len(cleanupErrors) > 0 {
+		result["cleanup_errors"] = cleanupErrors
+	}
+
+	return result, nil
+}
+
+// executeUpgradeModule runs an upgrade_module command with direct installer call
+func (e *JobExecutor) executeUpgradeModule(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
+	moduleID, ok := cmd.Args["module_id"].(string)
+	if !ok || moduleID == "" {
+		return nil, fmt.Errorf("module_id is required")
+	}
+
+	source, ok := cmd.Args["source"].(string)
+	if !ok || source == "" {
+		return nil, fmt.Errorf("source is required")
+	}
+
+	// Create progress callback that appends events to the job
+	progressCallback := func(update modules.ProgressUpdate) {
+		event := Event{
+			Timestamp: time.Now().UTC(),
+			Type:      "progress",
+			Message:   update.Message,
+			Data: map[string]string{
+				"status": update.Status,
+			},
+		}
+		if update.Error != "" {
+			event.Type = "error"
+			event.Data.(map[string]string)["error"] = update.Error
+		}
+
+		if err := manager.AppendEvent(jobID, event); err != nil {
+			e.logger.Error("failed to append progress event", "job_id", jobID, "error", err)
+		}
+	}
+
+	force, _ := cmd.Args["force"].(bool)
+	credentialRef, _ := cmd.Args["credential_ref"].(string)
+	skipImagePrefetch, _ := cmd.Args["skip_image_prefetch"].(bool)
+	req := modules.UpgradeRequest{
+		ModuleID:          moduleID,
+		Source:            source,
+		Force:             force,
+		CredentialRef:     credentialRef,
+		SkipImagePrefetch: skipImagePrefetch,
+	}
+
+	defer e.invalidateModule(moduleID)
+	upgrade, err := e.installer.UpgradeContext(ctx, req, progressCallback)
+	if err != nil {
+		return nil, fmt.Errorf("upgrade failed: %w", err)
+	}
+
+	result := map[string]interface{}{
+		"module_id": moduleID,
+		"old_ref":   upgrade.OldRef,
+		"new_ref":   upgrade.NewRef,
+		"status":    "upgraded",
+	}
+
+	return result, nil
+}
+
+// executeUpdateResources runs an update_module_resources command: the new limits are
+// stored with the module and it is re-applied with its link configuration. If the apply
+// fails, the previous limits are stored again.
+func (e *JobExecutor) executeUpdateResources(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
+	moduleID, ok := cmd.Args["module_id"].(string)
+	if !ok || moduleID == "" {
+		return nil, fmt.Errorf("module_id is required")
+	}
+
+	var resources *modules.Resources
+	if err := decodeArg(cmd.Args["resources"], &resources); err != nil {
+		return nil, fmt.Errorf("invalid resources: %w", err)
+	}
+
+	previous, err := e.installer.SetResources(moduleID, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	defer e.invalidateModule(moduleID)
+	if err := e.linkHandler.ReapplyModule(ctx, moduleID); err != nil {
+		if _, restoreErr := e.installer.SetResources(moduleID, previous); restoreErr != nil {
+			e.logger.Error("failed to restore previous resource limits", "module_id", moduleID, "error", restoreErr)
+		}
+		return nil, fmt.Errorf("re-apply failed: %w", err)
+	}
+
+	return map[string]interface{}{
 		"module_id": moduleID,
-		"status":    "uninstalled",
+		"resources": resources,
+		"status":    "applied",
+	}, nil
+}
+
+// executeModuleAction runs a restart_module, stop_module or start_module command and
+// records each container's outcome as a job event
+func (e *JobExecutor) executeModuleAction(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
+	moduleID, ok := cmd.Args["module_id"].(string)
+	if !ok || moduleID == "" {
+		return nil, fmt.Errorf("module_id is required")
+	}
+	action := moduleActions[cmd.Type]
+
+	var timeout time.Duration
+	if seconds, ok := cmd.Args["timeout"].(float64); ok {
+		timeout = time.Duration(seconds) * time.Second
+	} else if seconds, ok := cmd.Args["timeout"].(int); ok {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	result, err := e.moduleLifecycle.RunModuleAction(ctx, moduleID, action, timeout, jobID)
+	if result != nil {
+		for _, c := range result.Containers {
+			event := Event{
+				Timestamp: time.Now().UTC(),
+				Type:      "info",
+				Message:   fmt.Sprintf("Container %s: %s", c.Name, c.State),
+				Data:      c,
+			}
+			if c.Error != "" {
+				event.Type = "error"
+				event.Message = fmt.Sprintf("Container %s: %s", c.Name, c.Error)
+			}
+			if err := manager.AppendEvent(jobID, event); err != nil {
+				e.logger.Error("failed to append event", "job_id", jobID, "error", err)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// executeModuleExec runs a module_exec command, recording each line the command writes as
+// a "log" event with the stream it came from. A non-zero exit code fails the job, with the
+// result still recording it.
+func (e *JobExecutor) executeModuleExec(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
+	if e.moduleExec == nil {
+		return nil, fmt.Errorf("module exec is disabled on this agent")
+	}
+
+	moduleID, ok := cmd.Args["module_id"].(string)
+	if !ok || moduleID == "" {
+		return nil, fmt.Errorf("module_id is required")
+	}
+	container, _ := cmd.Args["container"].(string)
+	if container == "" {
+		container = "main"
+	}
+
+	var argv []string
+	if err := decodeArg(cmd.Args["argv"], &argv); err != nil {
+		return nil, fmt.Errorf("invalid argv: %w", err)
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("argv is required")
+	}
+	var envMap map[string]string
+	if err := decodeArg(cmd.Args["env"], &envMap); err != nil {
+		return nil, fmt.Errorf("invalid env: %w", err)
+	}
+	env := make([]string, 0, len(envMap))
+	for key, value := range envMap {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+
+	timeout := defaultModuleExecTimeout
+	if seconds, ok := cmd.Args["timeout"].(float64); ok && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	} else if seconds, ok := cmd.Args["timeout"].(int); ok && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	streamWriter := func(stream string) *terraform.LineWriter {
+		return terraform.NewLineWriter(func(line string) {
+			if err := manager.AppendEvent(jobID, Event{
+				Timestamp: time.Now().UTC(),
+				Type:      "log",
+				Message:   line,
+				Data:      map[string]string{"stream": stream},
+			}); err != nil {
+				e.logger.Error("failed to append log event", "job_id", jobID, "error", err)
+			}
+		})
+	}
+	stdout, stderr := streamWriter("stdout"), streamWriter("stderr")
+
+	e.logger.Info("running command in module container", "job_id", jobID, "module_id", moduleID, "container", container, "argv", argv)
+	started := time.Now()
+	exitCode, err := e.moduleExec.ExecInModule(ctx, moduleID, container, argv, env, stdout, stderr)
+	stdout.Flush()
+	stderr.Flush()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("command did not finish within %s: %w", timeout, err)
+		}
+		return nil, err
 	}
 
+	result := &ModuleExecResult{
+		ModuleID:  moduleID,
+		Container: container,
+		Argv:      argv,
+		ExitCode:  exitCode,
+		Duration:  time.Since(started).Round(time.Millisecond).String(),
+	}
+	if exitCode != 0 {
+		return result, fmt.Errorf("command exited with code %d", exitCode)
+	}
 	return result, nil
 }
 
@@ -227,6 +689,73 @@ func (e *JobExecutor) executeCreateExposure(ctx context.Context, jobID string, m
 
 	hostname, _ := cmd.Args["hostname"].(string)
 
+	var opts ExposureOptions
+	switch v := cmd.Args["host_port"].(type) {
+	case float64:
+		opts.HostPort = uint32(v)
+	case uint32:
+		opts.HostPort = v
+	}
+	switch v := cmd.Args["aliases"].(type) {
+	case []interface{}:
+		for _, alias := range v {
+			if aliasStr, ok := alias.(string); ok {
+				opts.Aliases = append(opts.Aliases, aliasStr)
+			}
+		}
+	case []string:
+		opts.Aliases = v
+	}
+	switch v := cmd.Args["backends"].(type) {
+	case []interface{}:
+		for _, backend := range v {
+			if backendStr, ok := backend.(string); ok {
+				opts.Backends = append(opts.Backends, backendStr)
+			}
+		}
+	case []string:
+		opts.Backends = v
+	}
+	opts.PathPrefix, _ = cmd.Args["path_prefix"].(string)
+	opts.PrefixRewrite, _ = cmd.Args["prefix_rewrite"].(string)
+	opts.TLSCertFile, _ = cmd.Args["tls_cert_file"].(string)
+	opts.TLSKeyFile, _ = cmd.Args["tls_key_file"].(string)
+	opts.TLSCertName, _ = cmd.Args["tls_cert_name"].(string)
+	opts.RedirectHTTPS, _ = cmd.Args["redirect_https"].(bool)
+	opts.WebSocket, _ = cmd.Args["websocket"].(bool)
+	opts.GRPC, _ = cmd.Args["grpc"].(bool)
+	opts.RequestTimeout, _ = cmd.Args["request_timeout"].(string)
+	switch v := cmd.Args["num_retries"].(type) {
+	case float64:
+		opts.NumRetries = uint32(v)
+	case uint32:
+		opts.NumRetries = v
+	}
+	switch v := cmd.Args["retry_on"].(type) {
+	case []interface{}:
+		for _, token := range v {
+			if tokenStr, ok := token.(string); ok {
+				opts.RetryOn = append(opts.RetryOn, tokenStr)
+			}
+		}
+	case []string:
+		opts.RetryOn = v
+	}
+	switch v := cmd.Args["auth_users"].(type) {
+	case []interface{}:
+		for _, entry := range v {
+			if entryStr, ok := entry.(string); ok {
+				opts.AuthUsers = append(opts.AuthUsers, entryStr)
+			}
+		}
+	case []string:
+		opts.AuthUsers = v
+	}
+	opts.AffinityCookie, _ = cmd.Args["affinity_cookie"].(string)
+	opts.AffinityCookieTTL, _ = cmd.Args["affinity_cookie_ttl"].(string)
+	opts.AffinityHeader, _ = cmd.Args["affinity_header"].(string)
+	opts.AllowUndeclaredPort, _ = cmd.Args["allow_undeclared_port"].(bool)
+
 	var tags []string
 	if tagsInterface, ok := cmd.Args["tags"]; ok {
 		if tagsSlice, ok := tagsInterface.([]interface{}); ok {
@@ -243,7 +772,7 @@ func (e *JobExecutor) executeCreateExposure(ctx context.Context, jobID string, m
 	e.logger.Info("creating exposure", "exposure_id", exposureID, "module_id", moduleID)
 
 	// Call exposure handler method directly to create exposure
-	if err := e.exposureHandler.CreateExposure(ctx, exposureID, moduleID, protocol, hostname, uint32(containerPort), tags); err != nil {
+	if err := e.exposureHandler.CreateExposure(ctx, exposureID, moduleID, protocol, hostname, uint32(containerPort), tags, opts); err != nil {
 		e.logger.Error("failed to create exposure", "exposure_id", exposureID, "error", err)
 		return nil, fmt.Errorf("failed to create exposure: %w", err)
 	}
@@ -258,6 +787,53 @@ func (e *JobExecutor) executeCreateExposure(ctx context.Context, jobID string, m
 	return result, nil
 }
 
+// executeCreateExposures runs a create_exposures command. The exposures are created as
+// one change with a single routing update; if any of them fails, none is created.
+func (e *JobExecutor) executeCreateExposures(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
+	var exposures []BatchExposure
+	if err := decodeArg(cmd.Args["exposures"], &exposures); err != nil {
+		return nil, fmt.Errorf("invalid exposures: %w", err)
+	}
+	if len(exposures) == 0 {
+		return nil, fmt.Errorf("exposures is required")
+	}
+
+	exposureIDs := make([]string, 0, len(exposures))
+	for _, exposure := range exposures {
+		if exposure.ExposureID == "" || exposure.ModuleID == "" || exposure.Protocol == "" {
+			return nil, fmt.Errorf("every exposure requires exposure_id, module_id and protocol")
+		}
+		exposureIDs = append(exposureIDs, exposure.ExposureID)
+	}
+
+	e.logger.Info("creating exposures", "exposure_ids", exposureIDs)
+
+	if err := e.exposureHandler.CreateExposures(ctx, exposures); err != nil {
+		e.logger.Error("failed to create exposures", "exposure_ids", exposureIDs, "error", err)
+		return nil, fmt.Errorf("failed to create exposures: %w", err)
+	}
+
+	result := map[string]interface{}{
+		"exposure_ids": exposureIDs,
+		"status":       "created",
+	}
+
+	return result, nil
+}
+
+// BatchExposureIDs returns the exposure IDs of a create_exposures command
+func BatchExposureIDs(cmd Command) []string {
+	var exposures []BatchExposure
+	if err := decodeArg(cmd.Args["exposures"], &exposures); err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(exposures))
+	for _, exposure := range exposures {
+		ids = append(ids, exposure.ExposureID)
+	}
+	return ids
+}
+
 // executeDeleteExposure runs a delete_exposure command
 func (e *JobExecutor) executeDeleteExposure(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
 	exposureID, ok := cmd.Args["exposure_id"].(string)
@@ -288,6 +864,10 @@ func (e *JobExecutor) executeCreateLink(ctx context.Context, jobID string, manag
 		return nil, fmt.Errorf("link_id is required")
 	}
 
+	if force, _ := cmd.Args["force"].(bool); force {
+		ctx = modules.ContextSkippingIntegrity(ctx)
+	}
+
 	modules, ok := cmd.Args["modules"].(map[string]interface{})
 	if !ok {
 		return nil, fmt.Errorf("modules is required")
@@ -334,6 +914,60 @@ func (e *JobExecutor) executeCreateLink(ctx context.Context, jobID string, manag
 	return result, nil
 }
 
+// executeUpdateLink runs an update_link command
+func (e *JobExecutor) executeUpdateLink(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
+	linkID, ok := cmd.Args["link_id"].(string)
+	if !ok || linkID == "" {
+		return nil, fmt.Errorf("link_id is required")
+	}
+
+	modulesConfig := make(map[string]map[string]interface{})
+	switch modules := cmd.Args["modules"].(type) {
+	case map[string]interface{}:
+		for moduleName, config := range modules {
+			if moduleConfig, ok := config.(map[string]interface{}); ok {
+				modulesConfig[moduleName] = moduleConfig
+			} else {
+				return nil, fmt.Errorf("module %s config must be a map", moduleName)
+			}
+		}
+	case map[string]map[string]interface{}:
+		modulesConfig = modules
+	}
+
+	var remove []string
+	switch v := cmd.Args["remove"].(type) {
+	case []interface{}:
+		for _, name := range v {
+			if nameStr, ok := name.(string); ok {
+				remove = append(remove, nameStr)
+			}
+		}
+	case []string:
+		remove = v
+	}
+
+	e.logger.Info("updating link", "link_id", linkID, "remove", remove)
+
+	if force, _ := cmd.Args["force"].(bool); force {
+		ctx = modules.ContextSkippingIntegrity(ctx)
+	}
+
+	if err := e.linkHandler.UpdateLink(ctx, linkID, modulesConfig, remove); err != nil {
+		e.logger.Error("failed to update link", "link_id", linkID, "error", err)
+		return nil, fmt.Errorf("failed to update link: %w", err)
+	}
+
+	result := map[string]interface{}{
+		"link_id": linkID,
+		"modules": modulesConfig,
+		"removed": remove,
+		"status":  "updated",
+	}
+
+	return result, nil
+}
+
 // executeDeleteLink runs a delete_link command
 func (e *JobExecutor) executeDeleteLink(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
 	linkID, ok := cmd.Args["link_id"].(string)
@@ -361,7 +995,9 @@ func (e *JobExecutor) executeDeleteLink(ctx context.Context, jobID string, manag
 // The bundle_install is a meta-job that orchestrates installation of all bundle components.
 // All component jobs (modules, links, exposures) are created by the handler (EnqueueBundleInstall)
 // when the meta-job is first enqueued, and the meta-job's DependsOn field is set to all of them.
-// When this executor runs, all component jobs are guaranteed to be complete, so we update their statuses.
+// The meta-job runs once every component job has finished, whether or not it succeeded. It records
+// each component's outcome and fails, listing the failed components, unless all of them completed.
+// With rollback_on_failure set, a failed installation also enqueues jobs that undo what was created.
 func (e *JobExecutor) executeBundleInstall(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
 	bundleName, ok := cmd.Args["bundle_name"].(string)
 	if !ok || bundleName == "" {
@@ -373,6 +1009,8 @@ func (e *JobExecutor) executeBundleInstall(ctx context.Context, jobID string, ma
 		return nil, fmt.Errorf("bundle_id is required")
 	}
 
+	rollbackOnFailure, _ := cmd.Args["rollback_on_failure"].(bool)
+
 	// Get the current job to find all dependency jobs
 	job, err := manager.Get(jobID)
 	if err != nil {
@@ -380,60 +1018,136 @@ func (e *JobExecutor) executeBundleInstall(ctx context.Context, jobID string, ma
 		return nil, err
 	}
 
-	// Update component statuses based on their job results
-	if e.bundleStore != nil {
-		// Check each dependency job to see if it succeeded or failed
-		for _, depJobID := range job.DependsOn {
-			depJob, err := manager.Get(depJobID)
-			if err != nil {
-				e.logger.Warn("failed to get dependency job", "dep_job_id", depJobID, "error", err)
-				continue
-			}
+	// Check each dependency job to see if it succeeded or failed
+	var created BundleDelta // components this installation created, for rollback
+	var failures []string
+	skipped := 0
+	for _, depJobID := range job.DependsOn {
+		depJob, err := manager.Get(depJobID)
+		if err != nil {
+			e.logger.Warn("failed to get dependency job", "dep_job_id", depJobID, "error", err)
+			continue
+		}
 
-			if depJob.Command.Type == CmdInstallModule {
-				moduleID, _ := depJob.Command.Args["module_id"].(string)
-				if depJob.Status == StatusCompleted {
-					_ = e.bundleStore.UpdateModuleComponentStatus(bundleID, moduleID, "completed", "")
-				} else if depJob.Status == StatusFailed {
-					_ = e.bundleStore.UpdateModuleComponentStatus(bundleID, moduleID, "failed", depJob.Error)
-				}
-			} else if depJob.Command.Type == CmdCreateLink {
-				linkID, _ := depJob.Command.Args["link_id"].(string)
-				if depJob.Status == StatusCompleted {
-					_ = e.bundleStore.UpdateLinkComponentStatus(bundleID, linkID, "completed", "")
-				} else if depJob.Status == StatusFailed {
-					_ = e.bundleStore.UpdateLinkComponentStatus(bundleID, linkID, "failed", depJob.Error)
-				}
-			} else if depJob.Command.Type == CmdCreateExposure {
-				exposureID, _ := depJob.Command.Args["exposure_id"].(string)
-				if depJob.Status == StatusCompleted {
-					_ = e.bundleStore.UpdateExposureComponentStatus(bundleID, exposureID, "completed", "")
-				} else if depJob.Status == StatusFailed {
-					_ = e.bundleStore.UpdateExposureComponentStatus(bundleID, exposureID, "failed", depJob.Error)
+		status := "completed"
+		switch depJob.Status {
+		case StatusFailed:
+			status = "failed"
+		case StatusCancelled:
+			status = "cancelled"
+		}
+
+		var kind, componentID string
+		switch depJob.Command.Type {
+		case CmdInstallModule:
+			kind = "module"
+			componentID, _ = depJob.Command.Args["module_id"].(string)
+			if status == "completed" {
+				created.Modules = append(created.Modules, componentID)
+			}
+			if e.bundleStore != nil {
+				_ = e.bundleStore.UpdateModuleComponentStatus(bundleID, componentID, status, depJob.Error)
+			}
+		case CmdCreateLink:
+			kind = "link"
+			componentID, _ = depJob.Command.Args["link_id"].(string)
+			if status == "completed" {
+				created.Links = append(created.Links, componentID)
+			}
+			if e.bundleStore != nil {
+				_ = e.bundleStore.UpdateLinkComponentStatus(bundleID, componentID, status, depJob.Error)
+			}
+		case CmdCreateExposure:
+			kind = "exposure"
+			componentID, _ = depJob.Command.Args["exposure_id"].(string)
+			if status == "completed" {
+				created.Exposures = append(created.Exposures, componentID)
+			}
+			if e.bundleStore != nil {
+				_ = e.bundleStore.UpdateExposureComponentStatus(bundleID, componentID, status, depJob.Error)
+			}
+		case CmdCreateExposures:
+			// All exposures of the bundle share one job and one outcome
+			kind = "exposures"
+			exposureIDs := BatchExposureIDs(depJob.Command)
+			componentID = strings.Join(exposureIDs, ", ")
+			if status == "completed" {
+				created.Exposures = append(created.Exposures, exposureIDs...)
+			}
+			if e.bundleStore != nil {
+				for _, exposureID := range exposureIDs {
+					_ = e.bundleStore.UpdateExposureComponentStatus(bundleID, exposureID, status, depJob.Error)
 				}
 			}
+		default:
+			// Jobs the bundle was chained after through depends_on
+			continue
+		}
+
+		switch status {
+		case "failed":
+			failures = append(failures, fmt.Sprintf("%s %s: %s", kind, componentID, depJob.Error))
+		case "cancelled":
+			skipped++
+		}
+	}
+
+	if len(failures) == 0 && skipped == 0 {
+		if e.bundleStore != nil {
+			_ = e.bundleStore.CompleteBundleInstallation(bundleID, "completed")
+		}
+
+		event := Event{
+			Timestamp: time.Now().UTC(),
+			Type:      "info",
+			Message:   fmt.Sprintf("Bundle installation completed: %s", bundleName),
+		}
+		if err := manager.AppendEvent(jobID, event); err != nil {
+			e.logger.Error("failed to append event", "job_id", jobID, "error", err)
+		}
+
+		result := map[string]interface{}{
+			"bundle_name": bundleName,
+			"bundle_id":   bundleID,
+			"status":      "completed",
 		}
 
-		// Mark bundle installation complete
-		_ = e.bundleStore.CompleteBundleInstallation(bundleID, true)
+		return result, nil
+	}
+
+	// Components whose jobs were cancelled were skipped because something they depend on failed,
+	// or were cancelled by the user
+	installErr := fmt.Errorf("bundle %s failed: %d components cancelled", bundleName, skipped)
+	if len(failures) > 0 {
+		installErr = fmt.Errorf("bundle %s failed: %s (%d more skipped)", bundleName, strings.Join(failures, "; "), skipped)
+	}
+
+	if !rollbackOnFailure {
+		if e.bundleStore != nil {
+			_ = e.bundleStore.CompleteBundleInstallation(bundleID, "failed")
+		}
+		return nil, installErr
+	}
+
+	rollbackJobID, err := e.enqueueBundleRollback(manager, bundleID, created)
+	if err != nil {
+		if e.bundleStore != nil {
+			_ = e.bundleStore.CompleteBundleInstallation(bundleID, "failed")
+		}
+		return nil, fmt.Errorf("%w; rollback could not be started: %v", installErr, err)
 	}
 
 	event := Event{
 		Timestamp: time.Now().UTC(),
-		Type:      "info",
-		Message:   fmt.Sprintf("Bundle installation completed: %s", bundleName),
+		Type:      "warning",
+		Message:   fmt.Sprintf("Rolling back bundle %s in job %s", bundleName, rollbackJobID),
+		Data:      map[string]interface{}{"rollback_job_id": rollbackJobID},
 	}
 	if err := manager.AppendEvent(jobID, event); err != nil {
 		e.logger.Error("failed to append event", "job_id", jobID, "error", err)
 	}
 
-	result := map[string]interface{}{
-		"bundle_name": bundleName,
-		"bundle_id":   bundleID,
-		"status":      "completed",
-	}
-
-	return result, nil
+	return nil, fmt.Errorf("%w; rolling back in job %s", installErr, rollbackJobID)
 }
 
 // executeBundleUninstall runs a bundle_uninstall command
@@ -507,9 +1221,136 @@ func (e *JobExecutor) executeBundleUninstall(ctx context.Context, jobID string,
 		"bundle_id": bundleID,
 		"status":    "completed",
 	}
+	if retained := e.reportRetained(manager, jobID, cmd); len(retained) > 0 {
+		result["retained"] = retained
+	}
+
+	return result, nil
+}
+
+// executeBundleUpgrade runs a bundle_upgrade command
+// The bundle_upgrade is a meta-job created by EnqueueBundleUpgrade after the component jobs that
+// apply the upgrade plan. It runs once all of them finished: when they all completed it records
+// the new definition and components in the bundle record, otherwise it marks the bundle failed.
+func (e *JobExecutor) executeBundleUpgrade(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
+	bundleID, ok := cmd.Args["bundle_id"].(string)
+	if !ok || bundleID == "" {
+		return nil, fmt.Errorf("bundle_id is required")
+	}
+
+	job, err := manager.Get(jobID)
+	if err != nil {
+		e.logger.Error("failed to get job", "job_id", jobID, "error", err)
+		return nil, err
+	}
+	var failures []string
+	skipped := 0
+	for _, depJobID := range job.DependsOn {
+		depJob, err := manager.Get(depJobID)
+		if err != nil {
+			e.logger.Warn("failed to get dependency job", "dep_job_id", depJobID, "error", err)
+			continue
+		}
+		switch depJob.Status {
+		case StatusFailed:
+			failures = append(failures, fmt.Sprintf("%s: %s", depJob.Command.Type, depJob.Error))
+		case StatusCancelled:
+			skipped++
+		}
+	}
+	if len(failures) > 0 || skipped > 0 {
+		if e.bundleStore != nil {
+			_ = e.bundleStore.CompleteBundleInstallation(bundleID, "failed")
+		}
+		if len(failures) == 0 {
+			return nil, fmt.Errorf("bundle upgrade %s failed: %d component jobs cancelled", bundleID, skipped)
+		}
+		return nil, fmt.Errorf("bundle upgrade %s failed: %s (%d more skipped)", bundleID, strings.Join(failures, "; "), skipped)
+	}
+
+	var definition catalog.CatalogBundle
+	if err := decodeArg(cmd.Args["definition"], &definition); err != nil {
+		return nil, fmt.Errorf("invalid bundle definition: %w", err)
+	}
+	var plan BundleUpgradePlan
+	if err := decodeArg(cmd.Args["plan"], &plan); err != nil {
+		return nil, fmt.Errorf("invalid upgrade plan: %w", err)
+	}
+
+	if e.bundleStore != nil {
+		touched := BundleDelta{
+			Modules:   append(append([]string{}, plan.Added.Modules...), plan.Changed.Modules...),
+			Links:     append(append([]string{}, plan.Added.Links...), plan.Changed.Links...),
+			Exposures: append(append([]string{}, plan.Added.Exposures...), plan.Changed.Exposures...),
+		}
+		if err := e.bundleStore.UpgradeBundle(bundleID, definition, touched); err != nil {
+			return nil, fmt.Errorf("failed to update bundle record: %w", err)
+		}
+	}
+
+	event := Event{
+		Timestamp: time.Now().UTC(),
+		Type:      "info",
+		Message:   fmt.Sprintf("Bundle upgrade completed: %s", bundleID),
+	}
+	if err := manager.AppendEvent(jobID, event); err != nil {
+		e.logger.Error("failed to append event", "job_id", jobID, "error", err)
+	}
+
+	result := map[string]interface{}{
+		"bundle_id": bundleID,
+		"status":    "upgraded",
+		"added":     plan.Added,
+		"changed":   plan.Changed,
+		"removed":   plan.Removed,
+		"unchanged": plan.Unchanged,
+	}
 
 	return result, nil
 }
 
+// invalidateModule clears cached inventory details for a module after it changed
+func (e *JobExecutor) invalidateModule(moduleID string) {
+	if e.moduleInventory != nil {
+		e.moduleInventory.InvalidateModule(moduleID)
+	}
+}
+
 // Ensure JobExecutor implements Executor interface
 var _ Executor = (*JobExecutor)(nil)
+
+// executeSyncCatalog runs a sync_catalog command
+func (e *JobExecutor) executeSyncCatalog(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error) {
+	result, err := e.catalogStore.Sync(ctx)
+	if err != nil {
+		if result != nil {
+			for _, problem := range result.Problems {
+				if appendErr := manager.AppendEvent(jobID, Event{
+					Timestamp: time.Now().UTC(),
+					Type:      "error",
+					Message:   problem,
+				}); appendErr != nil {
+					e.logger.Error("failed to append event", "job_id", jobID, "error", appendErr)
+				}
+			}
+		}
+		return nil, fmt.Errorf("catalog sync failed: %w", err)
+	}
+
+	message := "Catalog index not modified"
+	if !result.NotModified {
+		message = fmt.Sprintf("Catalog synced: %d modules and %d bundles added, %d and %d updated, %d and %d removed",
+			len(result.ModulesAdded), len(result.BundlesAdded),
+			len(result.ModulesUpdated), len(result.BundlesUpdated),
+			len(result.ModulesRemoved), len(result.BundlesRemoved))
+	}
+	if err := manager.AppendEvent(jobID, Event{
+		Timestamp: time.Now().UTC(),
+		Type:      "info",
+		Message:   message,
+	}); err != nil {
+		e.logger.Error("failed to append event", "job_id", jobID, "error", err)
+	}
+
+	return result, nil
+}
@@ -2,81 +2,262 @@ package queue
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
-	"reflect"
+	"net/url"
+	"strconv"
 	"strings"
 
+	"zeropoint-agent/internal/audit"
 	"zeropoint-agent/internal/catalog"
+	"zeropoint-agent/internal/modules"
+	"zeropoint-agent/internal/validator"
 
 	"github.com/gorilla/mux"
 )
 
+func init() {
+	// Password hashes of exposure users and environments passed to module commands stay
+	// out of the audit log, as do credentials embedded in module sources and callback URLs
+	audit.RegisterRedactedFields("auth_users", "env")
+	audit.RegisterScrubbedFields(modules.RedactSource, "source", "callback_url")
+}
+
 // Handlers handles HTTP requests for the job queue API
 type Handlers struct {
 	manager      *Manager
 	catalogStore *catalog.Store
-	bundleStore  interface{} // BundleStoreHandler interface - avoid circular imports
+	bundleStore  BundleStoreHandler
+	credentials  *modules.CredentialStore
 	logger       *slog.Logger
+
+	moduleExecDisabled bool // Reject module_exec jobs
+	listEvents         int  // Most recent events included per job by ListJobs
 }
 
+// defaultListEvents is how many events per job ListJobs includes unless SetListEvents
+// changes it
+const defaultListEvents = 20
+
 // NewHandlers creates a new queue handlers instance
-func NewHandlers(manager *Manager, catalogStore *catalog.Store, bundleStore interface{}, logger *slog.Logger) *Handlers {
+func NewHandlers(manager *Manager, catalogStore *catalog.Store, bundleStore BundleStoreHandler, credentials *modules.CredentialStore, logger *slog.Logger) *Handlers {
 	return &Handlers{
 		manager:      manager,
 		catalogStore: catalogStore,
 		bundleStore:  bundleStore,
+		credentials:  credentials,
 		logger:       logger,
+		listEvents:   defaultListEvents,
+	}
+}
+
+// SetListEvents sets how many of each job's most recent events ListJobs includes; with 0
+// every job's events are empty. GET /jobs/{id} always returns all of them.
+func (h *Handlers) SetListEvents(n int) {
+	h.listEvents = n
+}
+
+// DisableModuleExec rejects module_exec jobs, for deployments that must not run arbitrary
+// commands in module containers
+func (h *Handlers) DisableModuleExec() {
+	h.moduleExecDisabled = true
+}
+
+// prepareCredentialSource checks a git source and moves credentials embedded in it into
+// the credential store, so they are never written to the job file. Returns the source to
+// store in the job and the HTTP status to fail with.
+func (h *Handlers) prepareCredentialSource(source, credentialRef string) (string, int, error) {
+	if status, err := h.checkCredentialSource(source, credentialRef); err != nil {
+		return "", status, err
 	}
+	return h.scrubSource(source)
+}
+
+// checkCredentialSource checks a git source against the allowlist and the credential_ref a
+// request may name, without storing anything. A credential_ref replaces a token in the
+// source, so the two can't be combined, and it has to exist for the source's host.
+func (h *Handlers) checkCredentialSource(source, credentialRef string) (int, error) {
+	if credentialRef != "" {
+		if source == "" {
+			return http.StatusBadRequest, fmt.Errorf("credential_ref requires a git source")
+		}
+		if u, err := url.Parse(source); err == nil && u.User != nil {
+			return http.StatusBadRequest, fmt.Errorf("source must not embed credentials when credential_ref is set")
+		}
+		if err := h.credentials.CheckRef(credentialRef, source); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	if source != "" {
+		if err := modules.CheckSourceAllowed(source); err != nil {
+			return http.StatusForbidden, err
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// scrubSource moves credentials embedded in a checked git source into the credential store
+// and returns the source without them
+func (h *Handlers) scrubSource(source string) (string, int, error) {
+	if source == "" {
+		return "", http.StatusOK, nil
+	}
+	source, err := h.credentials.ScrubSource(source)
+	if err != nil {
+		h.logger.Error("failed to store source credentials", "error", err)
+		return "", http.StatusInternalServerError, err
+	}
+	return source, http.StatusOK, nil
 }
 
 // EnqueueInstallRequest is the request for enqueueing an install job
 type EnqueueInstallRequest struct {
-	ModuleID  string   `json:"module_id"`
-	Source    string   `json:"source,omitempty"`
-	LocalPath string   `json:"local_path,omitempty"`
-	Tags      []string `json:"tags,omitempty"`
-	DependsOn []string `json:"depends_on,omitempty"`
+	ModuleID          string   `json:"module_id"`
+	Source            string   `json:"source,omitempty"`
+	LocalPath         string   `json:"local_path,omitempty"`
+	PlanOnly          bool     `json:"plan_only,omitempty"`           // Run terraform init and plan only; the plan is returned in the job result
+	SkipImagePrefetch bool     `json:"skip_image_prefetch,omitempty"` // Don't pull images before apply (air-gapped hosts with pre-loaded images)
+	Tags              []string `json:"tags,omitempty"`
+
+	// Stored git credential (by host) to clone a private source with, instead of a token
+	// embedded in the source URL
+	CredentialRef string `json:"credential_ref,omitempty" example:"github.com"`
+
+	Resources *modules.Resources `json:"resources,omitempty"` // Optional CPU, memory and process limits, stored with the module
+
+	DependsOn      []string `json:"depends_on,omitempty"`
+	DependsOnTags  []string `json:"depends_on_tags,omitempty"` // Also depend on queued/running jobs with these tags (resolved at enqueue time)
+	IdempotencyKey string   `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string   `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
 }
 
 // EnqueueUninstallRequest is the request for enqueueing an uninstall job
 type EnqueueUninstallRequest struct {
-	ModuleID  string   `json:"module_id"`
-	Tags      []string `json:"tags,omitempty" example:"local-ai-chat"`
-	DependsOn []string `json:"depends_on,omitempty" example:"job-1,job-2"`
+	ModuleID       string   `json:"module_id"`
+	Force          bool     `json:"force,omitempty"`      // Uninstall even if links still reference the module
+	PurgeData      bool     `json:"purge_data,omitempty"` // Also delete the module's storage directory
+	Tags           []string `json:"tags,omitempty" example:"local-ai-chat"`
+	DependsOn      []string `json:"depends_on,omitempty" example:"job-1,job-2"`
+	DependsOnTags  []string `json:"depends_on_tags,omitempty"` // Also depend on queued/running jobs with these tags (resolved at enqueue time)
+	IdempotencyKey string   `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string   `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
+}
+
+// EnqueueUpgradeRequest is the request for enqueueing a module upgrade job
+type EnqueueUpgradeRequest struct {
+	ModuleID       string   `json:"module_id"`
+	Source         string   `json:"source"`                   // Git URL with the new commit SHA after '@'
+	Force          bool     `json:"force,omitempty"`          // Upgrade even if the installed files fail integrity verification
+	CredentialRef  string   `json:"credential_ref,omitempty"` // Stored git credential to clone with; defaults to the one the module was installed with
+	Tags           []string `json:"tags,omitempty"`
+	DependsOn      []string `json:"depends_on,omitempty"`
+	DependsOnTags  []string `json:"depends_on_tags,omitempty"` // Also depend on queued/running jobs with these tags (resolved at enqueue time)
+	IdempotencyKey string   `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string   `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
+
+	SkipImagePrefetch bool `json:"skip_image_prefetch,omitempty"` // Don't pull images before apply (air-gapped hosts with pre-loaded images)
 }
 
 // EnqueueCreateExposureRequest is the request for enqueueing a create exposure job
 type EnqueueCreateExposureRequest struct {
-	ExposureID    string   `json:"exposure_id"`
-	ModuleID      string   `json:"module_id"`
-	Protocol      string   `json:"protocol"`
-	Hostname      string   `json:"hostname,omitempty"`
-	ContainerPort uint32   `json:"container_port"`
-	Tags          []string `json:"tags,omitempty"`
-	DependsOn     []string `json:"depends_on,omitempty"`
+	ExposureID     string                   `json:"exposure_id"`
+	ModuleID       string                   `json:"module_id"`
+	Protocol       string                   `json:"protocol"`
+	Hostname       string                   `json:"hostname,omitempty"`
+	Aliases        []string                 `json:"aliases,omitempty"`        // Additional hostnames served by the same routes (http only)
+	PathPrefix     string                   `json:"path_prefix,omitempty"`    // Route only this path on the hostname (http only)
+	PrefixRewrite  string                   `json:"prefix_rewrite,omitempty"` // Replace path_prefix before forwarding, e.g. "/"
+	ContainerPort  uint32                   `json:"container_port"`
+	HostPort       uint32                   `json:"host_port,omitempty"`       // Requested host port (tcp/udp only); allocated if omitted
+	Backends       []string                 `json:"backends,omitempty"`        // Replica containers of the module ({module}-*) by name or "label:<key>=<value>" selector; defaults to the module's main container
+	TLS            *EnqueueExposureTLS      `json:"tls,omitempty"`             // Terminate TLS on port 443 (http only)
+	Options        *EnqueueHTTPOptions      `json:"options,omitempty"`         // Upstream protocol options (http only)
+	RequestTimeout string                   `json:"request_timeout,omitempty"` // Go duration, e.g. "30s"; no timeout if omitted (http only)
+	NumRetries     uint32                   `json:"num_retries,omitempty"`     // Retries per request; requires retry_on (http only)
+	RetryOn        []string                 `json:"retry_on,omitempty"`        // Envoy retry conditions, e.g. "5xx", "reset" (http only)
+	AuthUsers      []string                 `json:"auth_users,omitempty"`      // Require basic auth; htpasswd "user:$2y$..." bcrypt entries (http only)
+	Affinity       *EnqueueExposureAffinity `json:"affinity,omitempty"`        // Pin clients to one upstream (http only)
+
+	// Expose a container_port the module does not declare in its {container}_ports outputs
+	AllowUndeclaredPort bool `json:"allow_undeclared_port,omitempty"`
+
+	Tags           []string `json:"tags,omitempty"`
+	DependsOn      []string `json:"depends_on,omitempty"`
+	DependsOnTags  []string `json:"depends_on_tags,omitempty"` // Also depend on queued/running jobs with these tags (resolved at enqueue time)
+	IdempotencyKey string   `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string   `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
+}
+
+// EnqueueExposureTLS configures TLS termination for an HTTP exposure. Either cert_name
+// or both cert_file and key_file must be set.
+type EnqueueExposureTLS struct {
+	CertFile      string `json:"cert_file,omitempty"`
+	KeyFile       string `json:"key_file,omitempty"`
+	CertName      string `json:"cert_name,omitempty"` // Stored certificate under the agent's certs directory
+	RedirectHTTPS bool   `json:"redirect_https,omitempty"`
+}
+
+// EnqueueHTTPOptions describes what an HTTP exposure's upstream speaks
+type EnqueueHTTPOptions struct {
+	WebSocket bool `json:"websocket,omitempty"`
+	GRPC      bool `json:"grpc,omitempty"` // Proxy to the upstream over HTTP/2
+}
+
+// EnqueueExposureAffinity pins clients of an HTTP exposure to one upstream by cookie or
+// header. Set exactly one of cookie and header. It only has an effect once the exposure
+// maps to more than one backend.
+type EnqueueExposureAffinity struct {
+	Cookie    string `json:"cookie,omitempty"`
+	CookieTTL string `json:"cookie_ttl,omitempty"` // Go duration, e.g. "1h"; session cookie if omitted
+	Header    string `json:"header,omitempty"`
 }
 
 // EnqueueDeleteExposureRequest is the request for enqueueing a delete exposure job
 type EnqueueDeleteExposureRequest struct {
-	ExposureID string   `json:"exposure_id"`
-	Tags       []string `json:"tags,omitempty" example:"local-ai-chat"`
-	DependsOn  []string `json:"depends_on,omitempty" example:"job-1,job-2"`
+	ExposureID     string   `json:"exposure_id"`
+	Tags           []string `json:"tags,omitempty" example:"local-ai-chat"`
+	DependsOn      []string `json:"depends_on,omitempty" example:"job-1,job-2"`
+	DependsOnTags  []string `json:"depends_on_tags,omitempty"` // Also depend on queued/running jobs with these tags (resolved at enqueue time)
+	IdempotencyKey string   `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string   `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
 }
 
 // EnqueueCreateLinkRequest is the request for enqueueing a create link job
 type EnqueueCreateLinkRequest struct {
-	LinkID    string                            `json:"link_id"`
-	Modules   map[string]map[string]interface{} `json:"modules,omitempty"`
-	Tags      []string                          `json:"tags,omitempty"`
-	DependsOn []string                          `json:"depends_on,omitempty"`
+	LinkID         string                            `json:"link_id"`
+	Modules        map[string]map[string]interface{} `json:"modules,omitempty"`
+	Force          bool                              `json:"force,omitempty"` // Apply even if module files fail integrity verification
+	Tags           []string                          `json:"tags,omitempty"`
+	DependsOn      []string                          `json:"depends_on,omitempty"`
+	DependsOnTags  []string                          `json:"depends_on_tags,omitempty"` // Also depend on queued/running jobs with these tags (resolved at enqueue time)
+	IdempotencyKey string                            `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string                            `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
+}
+
+// EnqueueUpdateLinkRequest is the request for enqueueing a partial link update job
+type EnqueueUpdateLinkRequest struct {
+	LinkID         string                            `json:"link_id"`
+	Modules        map[string]map[string]interface{} `json:"modules,omitempty"` // Add modules, or merge bindings into existing ones; a null binding removes it
+	Remove         []string                          `json:"remove,omitempty"`  // Modules to detach from the link
+	Force          bool                              `json:"force,omitempty"`   // Apply even if module files fail integrity verification
+	Tags           []string                          `json:"tags,omitempty"`
+	DependsOn      []string                          `json:"depends_on,omitempty"`
+	DependsOnTags  []string                          `json:"depends_on_tags,omitempty"` // Also depend on queued/running jobs with these tags (resolved at enqueue time)
+	IdempotencyKey string                            `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string                            `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
 }
 
 // EnqueueDeleteLinkRequest is the request for enqueueing a delete link job
 type EnqueueDeleteLinkRequest struct {
-	LinkID    string   `json:"link_id"`
-	Tags      []string `json:"tags,omitempty" example:"local-ai-chat"`
-	DependsOn []string `json:"depends_on,omitempty" example:"job-1,job-2"`
+	LinkID         string   `json:"link_id"`
+	Tags           []string `json:"tags,omitempty" example:"local-ai-chat"`
+	DependsOn      []string `json:"depends_on,omitempty" example:"job-1,job-2"`
+	DependsOnTags  []string `json:"depends_on_tags,omitempty"` // Also depend on queued/running jobs with these tags (resolved at enqueue time)
+	IdempotencyKey string   `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string   `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
 }
 
 // EnqueueBundleInstallRequest is the request for creating a bundle installation meta-job.
@@ -84,24 +265,38 @@ type EnqueueDeleteLinkRequest struct {
 // fetch the bundle definition and enqueue all component jobs. The DependsOn field allows
 // chaining multiple bundle installations (e.g., for specialized sequential installs).
 type EnqueueBundleInstallRequest struct {
-	BundleName string   `json:"bundle_name"`
-	DependsOn  []string `json:"depends_on,omitempty"` // For chaining multiple bundle installations
+	BundleName     string   `json:"bundle_name"`
+	Tags           []string `json:"tags,omitempty"`            // Applied to the meta-job and every component job
+	DependsOn      []string `json:"depends_on,omitempty"`      // For chaining multiple bundle installations
+	IdempotencyKey string   `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string   `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
+
+	// Remove the components that were created if any component fails, leaving the bundle
+	// "rolled_back" instead of "failed"
+	RollbackOnFailure bool `json:"rollback_on_failure,omitempty"`
+
+	SkipImagePrefetch bool `json:"skip_image_prefetch,omitempty"` // Don't pull images before applying the bundle's modules
 }
 
 // EnqueueBundleUninstallRequest is the request for creating a bundle uninstallation meta-job.
 type EnqueueBundleUninstallRequest struct {
-	BundleID string `json:"bundle_id"`
+	BundleID       string   `json:"bundle_id"`
+	Tags           []string `json:"tags,omitempty"`            // Applied to the meta-job and every component job
+	IdempotencyKey string   `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string   `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
 }
 
 // EnqueueInstall handles POST /api/jobs/enqueue_install
 // @ID enqueueInstall
 // @Summary Enqueue a module installation job
-// @Description Enqueue a module installation job with optional dependencies on other jobs
+// @Description Enqueue a module installation job with optional dependencies on other jobs. With plan_only, the job runs terraform init and plan, returns the plan in its result and applies nothing.
 // @Tags jobs
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
 // @Param body body EnqueueInstallRequest true "Installation request"
 // @Success 201 {object} JobResponse "Job enqueued successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
 // @Failure 400 {string} string "Bad request"
 // @Router /jobs/enqueue_install_module [post]
 func (h *Handlers) EnqueueInstall(w http.ResponseWriter, r *http.Request) {
@@ -121,17 +316,38 @@ func (h *Handlers) EnqueueInstall(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := req.Resources.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("invalid resources: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	source, status, err := h.prepareCredentialSource(req.Source, req.CredentialRef)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	req.Source = source
+
 	cmd := Command{
 		Type: CmdInstallModule,
 		Args: map[string]interface{}{
-			"module_id":  req.ModuleID,
-			"source":     req.Source,
-			"local_path": req.LocalPath,
-			"tags":       req.Tags,
+			"module_id":           req.ModuleID,
+			"source":              req.Source,
+			"local_path":          req.LocalPath,
+			"plan_only":           req.PlanOnly,
+			"skip_image_prefetch": req.SkipImagePrefetch,
+			"tags":                req.Tags,
+			"credential_ref":      req.CredentialRef,
+			"resources":           req.Resources,
 		},
 	}
 
-	jobID, err := h.manager.Enqueue(cmd, req.DependsOn)
+	jobID, existing, err := h.manager.EnqueueWithOptions(cmd, EnqueueOptions{
+		DependsOn:      req.DependsOn,
+		DependsOnTags:  req.DependsOnTags,
+		IdempotencyKey: idempotencyKey(r, req.IdempotencyKey),
+		CallbackURL:    req.CallbackURL,
+	})
 	if err != nil {
 		h.logger.Error("failed to enqueue install job", "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -146,7 +362,7 @@ func (h *Handlers) EnqueueInstall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(enqueueStatus(existing))
 	json.NewEncoder(w).Encode(job)
 }
 
@@ -157,8 +373,10 @@ func (h *Handlers) EnqueueInstall(w http.ResponseWriter, r *http.Request) {
 // @Tags jobs
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
 // @Param body body EnqueueUninstallRequest true "Uninstallation request"
 // @Success 201 {object} JobResponse "Job enqueued successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
 // @Failure 400 {string} string "Bad request"
 // @Router /jobs/enqueue_uninstall_module [post]
 func (h *Handlers) EnqueueUninstall(w http.ResponseWriter, r *http.Request) {
@@ -175,15 +393,250 @@ func (h *Handlers) EnqueueUninstall(w http.ResponseWriter, r *http.Request) {
 
 	cmd := Command{
 		Type: CmdUninstallModule,
+		Args: map[string]interface{}{
+			"module_id":  req.ModuleID,
+			"force":      req.Force,
+			"purge_data": req.PurgeData,
+			"tags":       req.Tags,
+		},
+	}
+
+	jobID, existing, err := h.manager.EnqueueWithOptions(cmd, EnqueueOptions{
+		DependsOn:      req.DependsOn,
+		DependsOnTags:  req.DependsOnTags,
+		IdempotencyKey: idempotencyKey(r, req.IdempotencyKey),
+		CallbackURL:    req.CallbackURL,
+	})
+	if err != nil {
+		h.logger.Error("failed to enqueue uninstall job", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.manager.Get(jobID)
+	if err != nil {
+		h.logger.Error("failed to fetch enqueued job", "job_id", jobID, "error", err)
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(enqueueStatus(existing))
+	json.NewEncoder(w).Encode(job)
+}
+
+// EnqueueUpgrade handles POST /api/jobs/enqueue_upgrade_module
+// @ID enqueueUpgrade
+// @Summary Enqueue a module upgrade job
+// @Description Enqueue a job that re-installs an existing module from a new commit SHA, rolling back to the previous revision if the upgrade fails
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
+// @Param body body EnqueueUpgradeRequest true "Upgrade request"
+// @Success 201 {object} JobResponse "Job enqueued successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
+// @Failure 400 {string} string "Bad request"
+// @Router /jobs/enqueue_upgrade_module [post]
+func (h *Handlers) EnqueueUpgrade(w http.ResponseWriter, r *http.Request) {
+	var req EnqueueUpgradeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ModuleID == "" {
+		http.Error(w, "module_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Source == "" {
+		http.Error(w, "source is required", http.StatusBadRequest)
+		return
+	}
+
+	source, status, err := h.prepareCredentialSource(req.Source, req.CredentialRef)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	req.Source = source
+
+	cmd := Command{
+		Type: CmdUpgradeModule,
+		Args: map[string]interface{}{
+			"module_id":           req.ModuleID,
+			"source":              req.Source,
+			"force":               req.Force,
+			"tags":                req.Tags,
+			"credential_ref":      req.CredentialRef,
+			"skip_image_prefetch": req.SkipImagePrefetch,
+		},
+	}
+
+	jobID, existing, err := h.manager.EnqueueWithOptions(cmd, EnqueueOptions{
+		DependsOn:      req.DependsOn,
+		DependsOnTags:  req.DependsOnTags,
+		IdempotencyKey: idempotencyKey(r, req.IdempotencyKey),
+		CallbackURL:    req.CallbackURL,
+	})
+	if err != nil {
+		h.logger.Error("failed to enqueue upgrade job", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.manager.Get(jobID)
+	if err != nil {
+		h.logger.Error("failed to fetch enqueued job", "job_id", jobID, "error", err)
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(enqueueStatus(existing))
+	json.NewEncoder(w).Encode(job)
+}
+
+// UpdateModuleResourcesRequest is the body of PATCH /modules/{name}/resources. The limits
+// replace the stored ones; omitted or 0 limits are lifted.
+type UpdateModuleResourcesRequest struct {
+	modules.Resources
+	Tags           []string `json:"tags,omitempty"`
+	DependsOn      []string `json:"depends_on,omitempty"`
+	IdempotencyKey string   `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string   `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
+}
+
+// UpdateModuleResources handles PATCH /api/modules/{name}/resources
+// @ID updateModuleResources
+// @Summary Change a module's resource limits
+// @Description Enqueue a job that stores new CPU, memory and process limits for an installed module and re-applies it with them, keeping its link configuration. The limits are passed as zp_cpu_limit, zp_memory_limit and zp_pids_limit to modules that declare those variables. If the apply fails, the previous limits are kept.
+// @Tags modules
+// @Accept json
+// @Produce json
+// @Param name path string true "Module name"
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
+// @Param body body UpdateModuleResourcesRequest true "New resource limits"
+// @Success 201 {object} JobResponse "Job enqueued successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
+// @Failure 400 {string} string "Bad request"
+// @Router /modules/{name}/resources [patch]
+func (h *Handlers) UpdateModuleResources(w http.ResponseWriter, r *http.Request) {
+	moduleID := mux.Vars(r)["name"]
+
+	var req UpdateModuleResourcesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Resources.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("invalid resources: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	cmd := Command{
+		Type: CmdUpdateResources,
+		Args: map[string]interface{}{
+			"module_id": moduleID,
+			"resources": req.Resources,
+			"tags":      req.Tags,
+		},
+	}
+
+	jobID, existing, err := h.manager.EnqueueWithOptions(cmd, EnqueueOptions{
+		DependsOn:      req.DependsOn,
+		IdempotencyKey: idempotencyKey(r, req.IdempotencyKey),
+		CallbackURL:    req.CallbackURL,
+	})
+	if err != nil {
+		h.logger.Error("failed to enqueue resource update job", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.manager.Get(jobID)
+	if err != nil {
+		h.logger.Error("failed to fetch enqueued job", "job_id", jobID, "error", err)
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(enqueueStatus(existing))
+	json.NewEncoder(w).Encode(job)
+}
+
+// EnqueueModuleActionRequest is the request for enqueueing a module restart, stop or start
+type EnqueueModuleActionRequest struct {
+	ModuleID       string   `json:"module_id"`
+	Action         string   `json:"action" example:"restart"` // restart, stop or start
+	Timeout        int      `json:"timeout,omitempty"`        // Seconds to wait for containers to stop or reach running (default 60)
+	Tags           []string `json:"tags,omitempty"`
+	DependsOn      []string `json:"depends_on,omitempty"`
+	DependsOnTags  []string `json:"depends_on_tags,omitempty"` // Also depend on queued/running jobs with these tags (resolved at enqueue time)
+	IdempotencyKey string   `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string   `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
+}
+
+// EnqueueModuleAction handles POST /api/jobs/enqueue_module_action
+// @ID enqueueModuleAction
+// @Summary Enqueue a module restart, stop or start job
+// @Description Enqueue a restart_module, stop_module or start_module job, so container bounces can be chained with other jobs through depends_on. The job records each container's outcome as an event.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
+// @Param body body EnqueueModuleActionRequest true "Module action request"
+// @Success 201 {object} JobResponse "Job enqueued successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
+// @Failure 400 {string} string "Bad request"
+// @Router /jobs/enqueue_module_action [post]
+func (h *Handlers) EnqueueModuleAction(w http.ResponseWriter, r *http.Request) {
+	var req EnqueueModuleActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ModuleID == "" {
+		http.Error(w, "module_id is required", http.StatusBadRequest)
+		return
+	}
+	if req.Timeout < 0 {
+		http.Error(w, "timeout must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	var cmdType CommandType
+	for t, action := range moduleActions {
+		if action == req.Action {
+			cmdType = t
+		}
+	}
+	if cmdType == "" {
+		http.Error(w, "action must be restart, stop or start", http.StatusBadRequest)
+		return
+	}
+
+	cmd := Command{
+		Type: cmdType,
 		Args: map[string]interface{}{
 			"module_id": req.ModuleID,
+			"timeout":   req.Timeout,
 			"tags":      req.Tags,
 		},
 	}
 
-	jobID, err := h.manager.Enqueue(cmd, req.DependsOn)
+	jobID, existing, err := h.manager.EnqueueWithOptions(cmd, EnqueueOptions{
+		DependsOn:      req.DependsOn,
+		DependsOnTags:  req.DependsOnTags,
+		IdempotencyKey: idempotencyKey(r, req.IdempotencyKey),
+		CallbackURL:    req.CallbackURL,
+	})
 	if err != nil {
-		h.logger.Error("failed to enqueue uninstall job", "error", err)
+		h.logger.Error("failed to enqueue module action job", "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -196,7 +649,106 @@ func (h *Handlers) EnqueueUninstall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(enqueueStatus(existing))
+	json.NewEncoder(w).Encode(job)
+}
+
+// EnqueueModuleExecRequest is the request for enqueueing a command to run inside a module
+// container
+type EnqueueModuleExecRequest struct {
+	ModuleID       string            `json:"module_id"`
+	Container      string            `json:"container,omitempty" example:"main"` // Container within the module, {module}-{container}; defaults to main
+	Argv           []string          `json:"argv" example:"psql,-c,VACUUM"`      // Run as is, without a shell
+	Env            map[string]string `json:"env,omitempty"`                      // Added to the container's environment for this command; values are redacted in job responses
+	Timeout        int               `json:"timeout,omitempty"`                  // Seconds the command may run (default 600)
+	Tags           []string          `json:"tags,omitempty"`
+	DependsOn      []string          `json:"depends_on,omitempty"`
+	DependsOnTags  []string          `json:"depends_on_tags,omitempty"` // Also depend on queued/running jobs with these tags (resolved at enqueue time)
+	IdempotencyKey string            `json:"idempotency_key,omitempty"` // Alternative to the Idempotency-Key header
+	CallbackURL    string            `json:"callback_url,omitempty"`    // POSTed the final job once it completes, fails or is cancelled
+}
+
+// EnqueueModuleExec handles POST /api/jobs/enqueue_module_exec
+// @ID enqueueModuleExec
+// @Summary Enqueue a command to run inside a module container
+// @Description Enqueue a module_exec job that runs argv in {module}-{container} through the Docker exec API, for administrative tasks such as migrations. Each line the command writes is recorded as a "log" event with its stream, the exit code is stored in the result, and a non-zero exit code fails the job. The command must be running in a started container; on timeout the job fails, but Docker offers no way to stop the command itself. Requires the admin scope, and is rejected when module_exec_disabled is set.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
+// @Param body body EnqueueModuleExecRequest true "Module exec request"
+// @Success 201 {object} JobResponse "Job enqueued successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
+// @Failure 400 {string} string "Bad request"
+// @Failure 403 {string} string "Module exec is disabled on this agent"
+// @Router /jobs/enqueue_module_exec [post]
+func (h *Handlers) EnqueueModuleExec(w http.ResponseWriter, r *http.Request) {
+	if h.moduleExecDisabled {
+		http.Error(w, "module exec is disabled on this agent", http.StatusForbidden)
+		return
+	}
+
+	var req EnqueueModuleExecRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ModuleID == "" {
+		http.Error(w, "module_id is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Argv) == 0 {
+		http.Error(w, "argv is required", http.StatusBadRequest)
+		return
+	}
+	if req.Container == "" {
+		req.Container = "main"
+	}
+	if req.Timeout < 0 {
+		http.Error(w, "timeout must not be negative", http.StatusBadRequest)
+		return
+	}
+	for key := range req.Env {
+		if key == "" || strings.Contains(key, "=") {
+			http.Error(w, fmt.Sprintf("invalid env name %q", key), http.StatusBadRequest)
+			return
+		}
+	}
+
+	cmd := Command{
+		Type: CmdModuleExec,
+		Args: map[string]interface{}{
+			"module_id": req.ModuleID,
+			"container": req.Container,
+			"argv":      req.Argv,
+			"env":       req.Env,
+			"timeout":   req.Timeout,
+			"tags":      req.Tags,
+		},
+	}
+
+	jobID, existing, err := h.manager.EnqueueWithOptions(cmd, EnqueueOptions{
+		DependsOn:      req.DependsOn,
+		DependsOnTags:  req.DependsOnTags,
+		IdempotencyKey: idempotencyKey(r, req.IdempotencyKey),
+		CallbackURL:    req.CallbackURL,
+	})
+	if err != nil {
+		h.logger.Error("failed to enqueue module exec job", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.manager.Get(jobID)
+	if err != nil {
+		h.logger.Error("failed to fetch enqueued job", "job_id", jobID, "error", err)
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(enqueueStatus(existing))
 	json.NewEncoder(w).Encode(job)
 }
 
@@ -207,8 +759,10 @@ func (h *Handlers) EnqueueUninstall(w http.ResponseWriter, r *http.Request) {
 // @Tags jobs
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
 // @Param body body EnqueueCreateExposureRequest true "Create exposure request"
 // @Success 201 {object} JobResponse "Job enqueued successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
 // @Failure 400 {string} string "Bad request"
 // @Router /jobs/enqueue_create_exposure [post]
 func (h *Handlers) EnqueueCreateExposure(w http.ResponseWriter, r *http.Request) {
@@ -223,19 +777,58 @@ func (h *Handlers) EnqueueCreateExposure(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Malformed names fail here rather than in the job; the exposure store checks them
+	// again, along with conflicts, when the job runs
+	if err := checkExposureNames(req.Hostname, req.Aliases); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	cmd := Command{
 		Type: CmdCreateExposure,
 		Args: map[string]interface{}{
-			"exposure_id":    req.ExposureID,
-			"module_id":      req.ModuleID,
-			"protocol":       req.Protocol,
-			"hostname":       req.Hostname,
-			"container_port": req.ContainerPort,
-			"tags":           req.Tags,
+			"exposure_id":     req.ExposureID,
+			"module_id":       req.ModuleID,
+			"protocol":        req.Protocol,
+			"hostname":        req.Hostname,
+			"aliases":         req.Aliases,
+			"path_prefix":     req.PathPrefix,
+			"prefix_rewrite":  req.PrefixRewrite,
+			"container_port":  req.ContainerPort,
+			"host_port":       req.HostPort,
+			"backends":        req.Backends,
+			"request_timeout": req.RequestTimeout,
+			"num_retries":     req.NumRetries,
+			"retry_on":        req.RetryOn,
+			"auth_users":      req.AuthUsers,
+			"tags":            req.Tags,
 		},
 	}
+	if req.AllowUndeclaredPort {
+		cmd.Args["allow_undeclared_port"] = true
+	}
+	if req.TLS != nil {
+		cmd.Args["tls_cert_file"] = req.TLS.CertFile
+		cmd.Args["tls_key_file"] = req.TLS.KeyFile
+		cmd.Args["tls_cert_name"] = req.TLS.CertName
+		cmd.Args["redirect_https"] = req.TLS.RedirectHTTPS
+	}
+	if req.Options != nil {
+		cmd.Args["websocket"] = req.Options.WebSocket
+		cmd.Args["grpc"] = req.Options.GRPC
+	}
+	if req.Affinity != nil {
+		cmd.Args["affinity_cookie"] = req.Affinity.Cookie
+		cmd.Args["affinity_cookie_ttl"] = req.Affinity.CookieTTL
+		cmd.Args["affinity_header"] = req.Affinity.Header
+	}
 
-	jobID, err := h.manager.Enqueue(cmd, req.DependsOn)
+	jobID, existing, err := h.manager.EnqueueWithOptions(cmd, EnqueueOptions{
+		DependsOn:      req.DependsOn,
+		DependsOnTags:  req.DependsOnTags,
+		IdempotencyKey: idempotencyKey(r, req.IdempotencyKey),
+		CallbackURL:    req.CallbackURL,
+	})
 	if err != nil {
 		h.logger.Error("failed to enqueue create exposure job", "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -250,10 +843,25 @@ func (h *Handlers) EnqueueCreateExposure(w http.ResponseWriter, r *http.Request)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(enqueueStatus(existing))
 	json.NewEncoder(w).Encode(job)
 }
 
+// checkExposureNames rejects a hostname or alias that is not an RFC 1123 name. Empty
+// names are left for the exposure store to report.
+func checkExposureNames(hostname string, aliases []string) error {
+	for _, name := range append([]string{hostname}, aliases...) {
+		name = validator.NormalizeHostname(name)
+		if name == "" {
+			continue
+		}
+		if err := validator.ValidateHostname(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // EnqueueDeleteExposure handles POST /api/jobs/enqueue_delete_exposure
 // @ID enqueueDeleteExposure
 // @Summary Enqueue an exposure deletion job
@@ -261,8 +869,10 @@ func (h *Handlers) EnqueueCreateExposure(w http.ResponseWriter, r *http.Request)
 // @Tags jobs
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
 // @Param body body EnqueueDeleteExposureRequest true "Delete exposure request"
 // @Success 201 {object} JobResponse "Job enqueued successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
 // @Failure 400 {string} string "Bad request"
 // @Router /jobs/enqueue_delete_exposure [post]
 func (h *Handlers) EnqueueDeleteExposure(w http.ResponseWriter, r *http.Request) {
@@ -285,7 +895,12 @@ func (h *Handlers) EnqueueDeleteExposure(w http.ResponseWriter, r *http.Request)
 		},
 	}
 
-	jobID, err := h.manager.Enqueue(cmd, req.DependsOn)
+	jobID, existing, err := h.manager.EnqueueWithOptions(cmd, EnqueueOptions{
+		DependsOn:      req.DependsOn,
+		DependsOnTags:  req.DependsOnTags,
+		IdempotencyKey: idempotencyKey(r, req.IdempotencyKey),
+		CallbackURL:    req.CallbackURL,
+	})
 	if err != nil {
 		h.logger.Error("failed to enqueue delete exposure job", "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -300,7 +915,7 @@ func (h *Handlers) EnqueueDeleteExposure(w http.ResponseWriter, r *http.Request)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(enqueueStatus(existing))
 	json.NewEncoder(w).Encode(job)
 }
 
@@ -311,8 +926,10 @@ func (h *Handlers) EnqueueDeleteExposure(w http.ResponseWriter, r *http.Request)
 // @Tags jobs
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
 // @Param body body EnqueueCreateLinkRequest true "Create link request"
 // @Success 201 {object} JobResponse "Job enqueued successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
 // @Failure 400 {string} string "Bad request"
 // @Router /jobs/enqueue_create_link [post]
 func (h *Handlers) EnqueueCreateLink(w http.ResponseWriter, r *http.Request) {
@@ -338,11 +955,17 @@ func (h *Handlers) EnqueueCreateLink(w http.ResponseWriter, r *http.Request) {
 		Args: map[string]interface{}{
 			"link_id": req.LinkID,
 			"modules": req.Modules,
+			"force":   req.Force,
 			"tags":    req.Tags,
 		},
 	}
 
-	jobID, err := h.manager.Enqueue(cmd, req.DependsOn)
+	jobID, existing, err := h.manager.EnqueueWithOptions(cmd, EnqueueOptions{
+		DependsOn:      req.DependsOn,
+		DependsOnTags:  req.DependsOnTags,
+		IdempotencyKey: idempotencyKey(r, req.IdempotencyKey),
+		CallbackURL:    req.CallbackURL,
+	})
 	if err != nil {
 		h.logger.Error("failed to enqueue create link job", "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -357,7 +980,72 @@ func (h *Handlers) EnqueueCreateLink(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(enqueueStatus(existing))
+	json.NewEncoder(w).Encode(job)
+}
+
+// EnqueueUpdateLink handles POST /api/jobs/enqueue_update_link
+// @ID enqueueUpdateLink
+// @Summary Enqueue a partial link update job
+// @Description Enqueue a job that adds modules to a link, updates their bindings or detaches modules, with optional dependencies on other jobs
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
+// @Param body body EnqueueUpdateLinkRequest true "Update link request"
+// @Success 201 {object} JobResponse "Job enqueued successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
+// @Failure 400 {string} string "Bad request"
+// @Router /jobs/enqueue_update_link [post]
+func (h *Handlers) EnqueueUpdateLink(w http.ResponseWriter, r *http.Request) {
+	var req EnqueueUpdateLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.LinkID == "" {
+		http.Error(w, "link_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Modules) == 0 && len(req.Remove) == 0 {
+		http.Error(w, "modules or remove is required", http.StatusBadRequest)
+		return
+	}
+
+	cmd := Command{
+		Type: CmdUpdateLink,
+		Args: map[string]interface{}{
+			"link_id": req.LinkID,
+			"modules": req.Modules,
+			"remove":  req.Remove,
+			"force":   req.Force,
+			"tags":    req.Tags,
+		},
+	}
+
+	jobID, existing, err := h.manager.EnqueueWithOptions(cmd, EnqueueOptions{
+		DependsOn:      req.DependsOn,
+		DependsOnTags:  req.DependsOnTags,
+		IdempotencyKey: idempotencyKey(r, req.IdempotencyKey),
+		CallbackURL:    req.CallbackURL,
+	})
+	if err != nil {
+		h.logger.Error("failed to enqueue update link job", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.manager.Get(jobID)
+	if err != nil {
+		h.logger.Error("failed to fetch enqueued job", "job_id", jobID, "error", err)
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(enqueueStatus(existing))
 	json.NewEncoder(w).Encode(job)
 }
 
@@ -368,8 +1056,10 @@ func (h *Handlers) EnqueueCreateLink(w http.ResponseWriter, r *http.Request) {
 // @Tags jobs
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
 // @Param body body EnqueueDeleteLinkRequest true "Delete link request"
 // @Success 201 {object} JobResponse "Job enqueued successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
 // @Failure 400 {string} string "Bad request"
 // @Router /jobs/enqueue_delete_link [post]
 func (h *Handlers) EnqueueDeleteLink(w http.ResponseWriter, r *http.Request) {
@@ -392,7 +1082,12 @@ func (h *Handlers) EnqueueDeleteLink(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	jobID, err := h.manager.Enqueue(cmd, req.DependsOn)
+	jobID, existing, err := h.manager.EnqueueWithOptions(cmd, EnqueueOptions{
+		DependsOn:      req.DependsOn,
+		DependsOnTags:  req.DependsOnTags,
+		IdempotencyKey: idempotencyKey(r, req.IdempotencyKey),
+		CallbackURL:    req.CallbackURL,
+	})
 	if err != nil {
 		h.logger.Error("failed to enqueue delete link job", "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -407,17 +1102,18 @@ func (h *Handlers) EnqueueDeleteLink(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(enqueueStatus(existing))
 	json.NewEncoder(w).Encode(job)
 }
 
 // GetJob handles GET /jobs/{id}
 // @ID getJob
 // @Summary Get job details
-// @Description Get job details including status and all events
+// @Description Get job details including status and events. Older log events of verbose jobs are compacted into an archive (see archived_events) and only returned with full=true.
 // @Tags jobs
 // @Produce json
 // @Param id path string true "Job ID"
+// @Param full query bool false "Include archived log events"
 // @Success 200 {object} JobResponse "Job details"
 // @Failure 404 {string} string "Job not found"
 // @Router /jobs/{id} [get]
@@ -429,7 +1125,12 @@ func (h *Handlers) GetJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	job, err := h.manager.Get(jobID)
+	get := h.manager.Get
+	if r.URL.Query().Get("full") == "true" {
+		get = h.manager.GetFull
+	}
+
+	job, err := get(jobID)
 	if err != nil {
 		h.logger.Debug("job not found", "job_id", jobID)
 		http.Error(w, "job not found", http.StatusNotFound)
@@ -443,15 +1144,44 @@ func (h *Handlers) GetJob(w http.ResponseWriter, r *http.Request) {
 // ListJobs handles GET /jobs (returns jobs in topological order, optionally filtered by status)
 // @ID listJobs
 // @Summary List all jobs
-// @Description List all jobs sorted in topological order by dependencies, optionally filtered by status
+// @Description List all jobs sorted in topological order by dependencies, optionally filtered by status and tags and paginated. Each job includes only its most recent events (retention.list_job_events, default 20); GET /jobs/{id} returns all of them and GET /jobs/{id}/logs the full output.
 // @Tags jobs
 // @Produce json
 // @Param status query string false "Status filter: all, active, completed, failed, cancelled (default: all)"
+// @Param tags query string false "Comma-separated tags; only jobs carrying all of them are returned"
+// @Param limit query int false "Maximum number of jobs to return (default: all)"
+// @Param offset query int false "Number of jobs to skip (default: 0)"
+// @Param fields query string false "Set to 'summary' to omit job events"
 // @Success 200 {object} ListJobsResponse "List of jobs"
+// @Failure 400 {string} string "Invalid limit, offset or fields"
 // @Failure 500 {string} string "Internal server error"
 // @Router /jobs [get]
 func (h *Handlers) ListJobs(w http.ResponseWriter, r *http.Request) {
-	jobs, err := h.manager.ListAllTopoSorted()
+	query := r.URL.Query()
+
+	limit, err := parseNonNegativeInt(query.Get("limit"))
+	if err != nil {
+		http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+	offset, err := parseNonNegativeInt(query.Get("offset"))
+	if err != nil {
+		http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+	fields := query.Get("fields")
+	if fields != "" && fields != "summary" {
+		http.Error(w, "fields must be 'summary'", http.StatusBadRequest)
+		return
+	}
+
+	withEvents := fields != "summary" && h.listEvents > 0
+	var jobs []JobResponse
+	if tags := parseTagsQuery(query.Get("tags")); len(tags) > 0 {
+		jobs, err = h.manager.ListWithTags(tags, withEvents)
+	} else {
+		jobs, err = h.manager.ListAllTopoSorted(withEvents)
+	}
 	if err != nil {
 		h.logger.Error("failed to list jobs", "error", err)
 		http.Error(w, "failed to list jobs", http.StatusInternalServerError)
@@ -470,17 +1200,64 @@ func (h *Handlers) ListJobs(w http.ResponseWriter, r *http.Request) {
 		jobs = filteredJobs
 	}
 
+	total := len(jobs)
+
+	// Paginate the filtered, topo-sorted slice
+	if offset > len(jobs) {
+		offset = len(jobs)
+	}
+	jobs = jobs[offset:]
+	if limit > 0 && limit < len(jobs) {
+		jobs = jobs[:limit]
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(ListJobsResponse{Jobs: jobs})
+
+	if fields == "summary" {
+		summaries := make([]jobSummary, len(jobs))
+		for i, job := range jobs {
+			summaries[i] = jobSummary{JobResponse: job}
+		}
+		json.NewEncoder(w).Encode(listJobSummariesResponse{Jobs: summaries, Total: total})
+		return
+	}
+
+	for i := range jobs {
+		if len(jobs[i].Events) > h.listEvents {
+			jobs[i].Events = jobs[i].Events[len(jobs[i].Events)-h.listEvents:]
+		}
+	}
+	json.NewEncoder(w).Encode(ListJobsResponse{Jobs: jobs, Total: total})
+}
+
+// parseNonNegativeInt parses an optional query parameter, treating empty as 0
+func parseNonNegativeInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return n, nil
+}
+
+// BulkJobsResponse counts what a bulk cancel or delete did
+type BulkJobsResponse struct {
+	Deleted   int `json:"deleted"`
+	Cancelled int `json:"cancelled"`
+	Running   int `json:"running"` // Matching jobs left alone because they are running
 }
 
 // DeleteJobs handles DELETE /jobs (deletes jobs based on status filter)
 // @ID deleteJobs
 // @Summary Delete jobs by status filter
-// @Description Delete jobs filtered by status. Only allows deletion of completed, failed, or cancelled jobs. Cannot delete active or running jobs for safety.
+// @Description Delete jobs filtered by status. Only allows deletion of completed, failed, or cancelled jobs. Cannot delete active or running jobs for safety. With tags, only jobs carrying all of them are affected, and those still queued are cancelled (with their dependents) instead of deleted; running jobs are counted but left alone.
 // @Tags jobs
 // @Param status query string false "Status filter: completed, failed, cancelled (default: completed,failed,cancelled). 'all', 'active', 'queued', and 'running' are not allowed"
-// @Success 200 {object} map[string]interface{} "Number of jobs deleted"
+// @Param tags query string false "Comma-separated tags, e.g. a bundle ID; only jobs carrying all of them are affected and queued ones are cancelled"
+// @Param tag query string false "Single tag; older form of tags"
+// @Success 200 {object} BulkJobsResponse "Number of jobs deleted, and cancelled with tags"
 // @Failure 400 {string} string "Bad request - invalid or unsafe status filter"
 // @Failure 500 {string} string "Internal server error"
 // @Router /jobs [delete]
@@ -511,28 +1288,97 @@ func (h *Handlers) DeleteJobs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	jobs, err := h.manager.ListAllTopoSorted()
+	tags := tagsQuery(r)
+	var jobs []JobResponse
+	var err error
+	if len(tags) > 0 {
+		jobs, err = h.manager.ListWithTags(tags, false)
+	} else {
+		jobs, err = h.manager.ListAllTopoSorted(false)
+	}
 	if err != nil {
 		h.logger.Error("failed to list jobs for deletion", "error", err)
 		http.Error(w, "failed to list jobs", http.StatusInternalServerError)
 		return
 	}
 
-	deletedCount := 0
+	var resp BulkJobsResponse
+	if len(tags) > 0 {
+		resp.Cancelled, resp.Running = h.cancelJobs(jobs)
+	}
+
 	for _, job := range jobs {
 		if matchesStatusFilterResponse(job, statusFilter) {
 			if err := h.manager.Delete(job.ID); err != nil {
 				h.logger.Warn("failed to delete job", "job_id", job.ID, "error", err)
 			} else {
-				deletedCount++
+				resp.Deleted++
 			}
 		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"deleted": deletedCount,
-	})
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CancelJobs handles POST /api/jobs/cancel
+// @ID cancelJobs
+// @Summary Cancel all queued jobs with the given tags
+// @Description Cancels every queued job carrying all of the tags, e.g. all jobs of a bundle install, along with the jobs depending on them. Running jobs are counted but not stopped; finished jobs are left as they are.
+// @Tags jobs
+// @Param tags query string false "Comma-separated tags to match, e.g. a bundle ID; tags or tag is required"
+// @Param tag query string false "Single tag; older form of tags"
+// @Success 200 {object} BulkJobsResponse "Number of jobs cancelled"
+// @Failure 400 {string} string "tags is required"
+// @Failure 500 {string} string "Internal server error"
+// @Router /jobs/cancel [post]
+func (h *Handlers) CancelJobs(w http.ResponseWriter, r *http.Request) {
+	tags := tagsQuery(r)
+	if len(tags) == 0 {
+		http.Error(w, "tags is required", http.StatusBadRequest)
+		return
+	}
+
+	jobs, err := h.manager.ListWithTags(tags, false)
+	if err != nil {
+		h.logger.Error("failed to list jobs for cancellation", "error", err)
+		http.Error(w, "failed to list jobs", http.StatusInternalServerError)
+		return
+	}
+
+	var resp BulkJobsResponse
+	resp.Cancelled, resp.Running = h.cancelJobs(jobs)
+	h.logger.Info("cancelled jobs by tags", "tags", tags, "cancelled", resp.Cancelled, "running", resp.Running)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// cancelJobs cancels the queued jobs among jobs and counts the running ones. A job that
+// was already cancelled along with an earlier one in the list still counts as cancelled.
+func (h *Handlers) cancelJobs(jobs []JobResponse) (cancelled, running int) {
+	for _, job := range jobs {
+		switch job.Status {
+		case StatusRunning:
+			running++
+		case StatusQueued:
+			if err := h.manager.Cancel(job.ID); err != nil {
+				if current, getErr := h.manager.Get(job.ID); getErr != nil || current.Status != StatusCancelled {
+					h.logger.Warn("failed to cancel job", "job_id", job.ID, "error", err)
+					continue
+				}
+			}
+			cancelled++
+		}
+	}
+	return cancelled, running
+}
+
+// tagsQuery reads the tags query parameter of the bulk job endpoints, folding in the
+// single-tag form they accepted before
+func tagsQuery(r *http.Request) []string {
+	query := r.URL.Query()
+	return mergeTags(parseTagsQuery(query.Get("tags")), parseTagsQuery(query.Get("tag")))
 }
 
 // matchesStatusFilterResponse checks if a job response matches one of the provided status filters
@@ -558,6 +1404,10 @@ func matchesStatusFilterResponse(job JobResponse, statusFilter string) bool {
 			if job.Status == StatusCancelled {
 				return true
 			}
+		case "interrupted":
+			if job.Status == StatusInterrupted {
+				return true
+			}
 		}
 	}
 	return false
@@ -565,11 +1415,12 @@ func matchesStatusFilterResponse(job JobResponse, statusFilter string) bool {
 
 // CancelJob handles DELETE /jobs/{id}
 // @ID cancelJob
-// @Summary Cancel a queued job
-// @Description Cancel a queued job (only queued jobs can be cancelled). Cascades cancellation to dependent jobs.
+// @Summary Cancel a job
+// @Description Cancel a queued job. Cascades cancellation to dependent jobs. With force, a running job is stopped by killing its processes; it is marked failed with "cancelled by user (forced)" once its executor returns.
 // @Tags jobs
 // @Produce json
 // @Param id path string true "Job ID"
+// @Param force query bool false "Also stop a running job"
 // @Success 200 {object} JobResponse "Job cancelled"
 // @Failure 400 {string} string "Cannot cancel job (already running or completed)"
 // @Failure 404 {string} string "Job not found"
@@ -578,13 +1429,15 @@ func matchesStatusFilterResponse(job JobResponse, statusFilter string) bool {
 // EnqueueBundleInstall handles POST /api/jobs/enqueue_install_bundle
 // @ID enqueueBundleInstall
 // @Summary Enqueue a bundle installation meta-job
-// @Description Create a meta-job for bundle installation that orchestrates installation of all bundle components
+// @Description Create a meta-job for bundle installation that orchestrates installation of all bundle components. The meta-job runs once every component job has finished and fails, listing the failed components, unless all of them completed. With rollback_on_failure, a failed installation enqueues jobs that remove the components it created.
 // @Tags jobs
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
 // @Param body body EnqueueBundleInstallRequest true "Bundle installation request"
 // @Success 201 {object} JobResponse "Bundle job created successfully"
-// @Failure 400 {string} string "Bad request"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
+// @Failure 400 {string} string "Bad request or invalid bundle definition"
 // @Router /jobs/enqueue_install_bundle [post]
 func (h *Handlers) EnqueueBundleInstall(w http.ResponseWriter, r *http.Request) {
 	var req EnqueueBundleInstallRequest
@@ -598,6 +1451,13 @@ func (h *Handlers) EnqueueBundleInstall(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// A retried request must not enqueue a second set of component jobs
+	key := idempotencyKey(r, req.IdempotencyKey)
+	if existingID, ok := h.manager.JobForIdempotencyKey(CmdBundleInstall, key); ok {
+		h.writeExistingJob(w, existingID)
+		return
+	}
+
 	// Fetch bundle from catalog
 	bundle, err := h.catalogStore.GetBundle(req.BundleName)
 	if err != nil {
@@ -609,6 +1469,18 @@ func (h *Handlers) EnqueueBundleInstall(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Catch every problem before any component job is created, so a bad definition
+	// doesn't leave half a bundle queued
+	problems, err := h.catalogStore.ValidateBundle(bundle)
+	if err != nil {
+		http.Error(w, "failed to validate bundle: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(problems) > 0 {
+		http.Error(w, "invalid bundle "+req.BundleName+": "+strings.Join(problems, "; "), http.StatusBadRequest)
+		return
+	}
+
 	var componentJobIDs []string
 
 	// Enqueue install_module jobs for each module in the bundle
@@ -618,23 +1490,27 @@ func (h *Handlers) EnqueueBundleInstall(w http.ResponseWriter, r *http.Request)
 			// Fetch module from catalog to get source
 			module, err := h.catalogStore.GetModule(moduleName)
 			if err != nil {
+				h.discardJobs(componentJobIDs)
 				http.Error(w, "failed to fetch module: "+err.Error(), http.StatusBadRequest)
 				return
 			}
 			if module == nil {
+				h.discardJobs(componentJobIDs)
 				http.Error(w, "module not found in catalog: "+moduleName, http.StatusNotFound)
 				return
 			}
 
-			moduleJobID, err := h.manager.Enqueue(Command{
+			moduleJobID, err := h.enqueueComponent(Command{
 				Type: CmdInstallModule,
 				Args: map[string]interface{}{
-					"module_id": moduleName,
-					"source":    module.Source,
-					"bundle_id": req.BundleName, // Track which bundle this module is for
+					"module_id":           moduleName,
+					"source":              module.Source,
+					"bundle_id":           req.BundleName, // Track which bundle this module is for
+					"skip_image_prefetch": req.SkipImagePrefetch,
 				},
-			}, moduleDeps)
+			}, moduleDeps, req.Tags)
 			if err != nil {
+				h.discardJobs(componentJobIDs)
 				http.Error(w, "failed to enqueue module: "+err.Error(), http.StatusBadRequest)
 				return
 			}
@@ -657,15 +1533,16 @@ func (h *Handlers) EnqueueBundleInstall(w http.ResponseWriter, r *http.Request)
 				modules[link.Module] = bindMap
 			}
 
-			linkJobID, err := h.manager.Enqueue(Command{
+			linkJobID, err := h.enqueueComponent(Command{
 				Type: CmdCreateLink,
 				Args: map[string]interface{}{
 					"link_id":   linkID,
 					"modules":   modules,
 					"bundle_id": req.BundleName, // Track which bundle this link is for
 				},
-			}, componentJobIDs)
+			}, componentJobIDs, req.Tags)
 			if err != nil {
+				h.discardJobs(componentJobIDs)
 				http.Error(w, "failed to enqueue link: "+err.Error(), http.StatusBadRequest)
 				return
 			}
@@ -673,72 +1550,66 @@ func (h *Handlers) EnqueueBundleInstall(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	// Enqueue create_exposure jobs for each exposure in the bundle
-	if bundle.Exposures != nil && len(bundle.Exposures) > 0 {
-		for exposureID, exposureConfig := range bundle.Exposures {
-			exposureJobID, err := h.manager.Enqueue(Command{
-				Type: CmdCreateExposure,
-				Args: map[string]interface{}{
-					"exposure_id":    exposureID,
-					"module_id":      exposureConfig.Module,
-					"container_port": uint32(exposureConfig.ModulePort),
-					"protocol":       exposureConfig.Protocol,
-					"hostname":       exposureID,
-					"bundle_id":      req.BundleName, // Track which bundle this exposure is for
-				},
-			}, componentJobIDs)
-			if err != nil {
-				http.Error(w, "failed to enqueue exposure: "+err.Error(), http.StatusBadRequest)
-				return
-			}
-			componentJobIDs = append(componentJobIDs, exposureJobID)
+	// Enqueue one create_exposures job for all exposures in the bundle, so routing for
+	// the bundle is switched on in a single update rather than one hostname at a time
+	if len(bundle.Exposures) > 0 {
+		exposuresJobID, err := h.enqueueComponent(Command{
+			Type: CmdCreateExposures,
+			Args: map[string]interface{}{
+				"exposures": bundleExposures(bundle.Exposures, keySet(bundle.Exposures)),
+				"bundle_id": req.BundleName, // Track which bundle these exposures are for
+			},
+		}, componentJobIDs, req.Tags)
+		if err != nil {
+			h.discardJobs(componentJobIDs)
+			http.Error(w, "failed to enqueue exposures: "+err.Error(), http.StatusBadRequest)
+			return
 		}
+		componentJobIDs = append(componentJobIDs, exposuresJobID)
 	}
 
 	// Create the bundle_install meta-job that depends on all component jobs
-	jobID, err := h.manager.Enqueue(Command{
+	jobID, existing, err := h.manager.EnqueueWithOptions(Command{
 		Type: CmdBundleInstall,
 		Args: map[string]interface{}{
-			"bundle_id":   req.BundleName,
-			"bundle_name": req.BundleName,
+			"bundle_id":           req.BundleName,
+			"bundle_name":         req.BundleName,
+			"rollback_on_failure": req.RollbackOnFailure,
 		},
-	}, componentJobIDs)
+	}, EnqueueOptions{DependsOn: componentJobIDs, IdempotencyKey: key, RunOnDependencyFailure: true, CallbackURL: req.CallbackURL, Tags: req.Tags})
 
 	if err != nil {
+		h.discardJobs(componentJobIDs)
 		h.logger.Debug("failed to enqueue bundle install job", "bundle_name", req.BundleName, "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if existing {
+		// A concurrent request with the same key won the race; its meta-job tracks its own
+		// component jobs, so the ones enqueued here would never be tracked
+		h.discardJobs(componentJobIDs)
+		h.writeExistingJob(w, jobID)
+		return
+	}
 
 	// Create persistent bundle record with all component details
-	if h.bundleStore != nil {
-		// Type assert to get the actual BundleStore methods
-		if bs, ok := h.bundleStore.(interface {
-			CreateBundle(bundleID, bundleName, jobID string) interface{}
-			AddModuleComponent(bundleID, moduleID string, status, errMsg string) error
-			AddLinkComponent(bundleID, linkID string, status, errMsg string) error
-			AddExposureComponent(bundleID, exposureID string, status, errMsg string) error
-		}); ok {
-			bs.CreateBundle(req.BundleName, bundle.Name, jobID)
-
-			// Add all modules as components
-			for _, moduleName := range bundle.Modules {
-				_ = bs.AddModuleComponent(req.BundleName, moduleName, "queued", "")
-			}
+	if bs := h.bundleStore; bs != nil {
+		bs.CreateBundle(req.BundleName, bundle.Name, jobID)
+		_ = bs.SetDefinition(req.BundleName, *bundle)
 
-			// Add all links as components
-			if bundle.Links != nil {
-				for linkID := range bundle.Links {
-					_ = bs.AddLinkComponent(req.BundleName, linkID, "queued", "")
-				}
-			}
+		// Add all modules as components
+		for _, moduleName := range bundle.Modules {
+			_ = bs.AddModuleComponent(req.BundleName, moduleName, "queued", "")
+		}
 
-			// Add all exposures as components
-			if bundle.Exposures != nil {
-				for exposureID := range bundle.Exposures {
-					_ = bs.AddExposureComponent(req.BundleName, exposureID, "queued", "")
-				}
-			}
+		// Add all links as components
+		for linkID := range bundle.Links {
+			_ = bs.AddLinkComponent(req.BundleName, linkID, "queued", "")
+		}
+
+		// Add all exposures as components
+		for exposureID := range bundle.Exposures {
+			_ = bs.AddExposureComponent(req.BundleName, exposureID, "queued", "")
 		}
 	}
 
@@ -750,19 +1621,39 @@ func (h *Handlers) EnqueueBundleInstall(w http.ResponseWriter, r *http.Request)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(enqueueStatus(existing))
 	json.NewEncoder(w).Encode(job)
 }
 
+// enqueueComponent enqueues one component job of a bundle operation. The tags only label
+// the job; unlike a "tags" arg they are not stored on the modules it installs.
+func (h *Handlers) enqueueComponent(cmd Command, dependsOn []string, tags []string) (string, error) {
+	jobID, _, err := h.manager.EnqueueWithOptions(cmd, EnqueueOptions{DependsOn: dependsOn, Tags: tags})
+	return jobID, err
+}
+
+// discardJobs deletes component jobs enqueued for a bundle whose meta-job could not be
+// created; nothing would ever track them. Jobs are removed newest first so dependents go
+// before the jobs they depend on. A job the worker already started is left to finish.
+func (h *Handlers) discardJobs(jobIDs []string) {
+	for i := len(jobIDs) - 1; i >= 0; i-- {
+		if err := h.manager.Delete(jobIDs[i]); err != nil {
+			h.logger.Warn("failed to discard orphaned component job", "job_id", jobIDs[i], "error", err)
+		}
+	}
+}
+
 // EnqueueBundleUninstall handles POST /api/jobs/enqueue_uninstall_bundle
 // @ID enqueueBundleUninstall
 // @Summary Enqueue a bundle uninstallation meta-job
-// @Description Create a meta-job for bundle uninstallation that orchestrates removal of all bundle components
+// @Description Create a meta-job for bundle uninstallation that orchestrates removal of all bundle components. Components another installed bundle still uses are retained and reported as warnings on the meta-job.
 // @Tags jobs
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
 // @Param body body EnqueueBundleUninstallRequest true "Bundle uninstallation request"
 // @Success 201 {object} JobResponse "Bundle uninstall job created successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
 // @Failure 400 {string} string "Bad request"
 // @Router /jobs/enqueue_uninstall_bundle [post]
 func (h *Handlers) EnqueueBundleUninstall(w http.ResponseWriter, r *http.Request) {
@@ -777,118 +1668,101 @@ func (h *Handlers) EnqueueBundleUninstall(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Get bundle from bundleStore to find all components
-	bundleIface := h.bundleStore.(interface {
-		GetBundle(bundleID string) (interface{}, error)
-	})
-
-	bundleData, err := bundleIface.GetBundle(req.BundleID)
-	if err != nil {
-		http.Error(w, "bundle not found: "+err.Error(), http.StatusNotFound)
+	// A retried request must not enqueue a second set of component jobs
+	key := idempotencyKey(r, req.IdempotencyKey)
+	if existingID, ok := h.manager.JobForIdempotencyKey(CmdBundleUninstall, key); ok {
+		h.writeExistingJob(w, existingID)
 		return
 	}
 
-	// Use reflection to extract components from bundle data
-	// The bundleData is a *BundleRecord from the API package
-	bundleVal := reflect.ValueOf(bundleData)
-	if bundleVal.Kind() == reflect.Ptr {
-		bundleVal = bundleVal.Elem()
-	}
-
-	if bundleVal.Kind() != reflect.Struct {
-		http.Error(w, "invalid bundle data", http.StatusInternalServerError)
+	if h.bundleStore == nil {
+		http.Error(w, "bundle store unavailable", http.StatusInternalServerError)
 		return
 	}
-
-	componentsField := bundleVal.FieldByName("Components")
-	if !componentsField.IsValid() {
-		http.Error(w, "unable to get bundle components", http.StatusInternalServerError)
+	installed, err := h.bundleStore.GetBundleComponents(req.BundleID)
+	if err != nil {
+		http.Error(w, "bundle not found: "+err.Error(), http.StatusNotFound)
 		return
 	}
 
-	var componentJobIDs []string
+	// Components another bundle still holds stay in place until their last bundle goes away
+	owned, retained := installed.splitShared(BundleDelta{Modules: installed.Modules, Links: installed.Links, Exposures: installed.Exposures})
 
-	// Components is a struct with Exposures, Links, Modules slices
-	exposuresField := componentsField.FieldByName("Exposures")
-	linksField := componentsField.FieldByName("Links")
-	modulesField := componentsField.FieldByName("Modules")
+	var componentJobIDs []string
 
 	// Enqueue delete_exposure jobs first (no dependencies)
-	if exposuresField.IsValid() && exposuresField.Kind() == reflect.Slice {
-		for i := 0; i < exposuresField.Len(); i++ {
-			exp := exposuresField.Index(i)
-			expID := exp.FieldByName("ID").String()
-
-			exposureJobID, err := h.manager.Enqueue(Command{
-				Type: CmdDeleteExposure,
-				Args: map[string]interface{}{
-					"exposure_id": expID,
-					"bundle_id":   req.BundleID,
-				},
-			}, []string{}) // No dependencies
-			if err != nil {
-				http.Error(w, "failed to enqueue exposure deletion: "+err.Error(), http.StatusBadRequest)
-				return
-			}
-			componentJobIDs = append(componentJobIDs, exposureJobID)
+	for _, expID := range owned.Exposures {
+		exposureJobID, err := h.enqueueComponent(Command{
+			Type: CmdDeleteExposure,
+			Args: map[string]interface{}{
+				"exposure_id": expID,
+				"bundle_id":   req.BundleID,
+			},
+		}, []string{}, req.Tags) // No dependencies
+		if err != nil {
+			h.discardJobs(componentJobIDs)
+			http.Error(w, "failed to enqueue exposure deletion: "+err.Error(), http.StatusBadRequest)
+			return
 		}
+		componentJobIDs = append(componentJobIDs, exposureJobID)
 	}
 
 	// Enqueue delete_link jobs (depend on all exposures being deleted)
-	if linksField.IsValid() && linksField.Kind() == reflect.Slice {
-		for i := 0; i < linksField.Len(); i++ {
-			link := linksField.Index(i)
-			linkID := link.FieldByName("ID").String()
-
-			linkJobID, err := h.manager.Enqueue(Command{
-				Type: CmdDeleteLink,
-				Args: map[string]interface{}{
-					"link_id":   linkID,
-					"bundle_id": req.BundleID,
-				},
-			}, componentJobIDs)
-			if err != nil {
-				http.Error(w, "failed to enqueue link deletion: "+err.Error(), http.StatusBadRequest)
-				return
-			}
-			componentJobIDs = append(componentJobIDs, linkJobID)
+	for _, linkID := range owned.Links {
+		linkJobID, err := h.enqueueComponent(Command{
+			Type: CmdDeleteLink,
+			Args: map[string]interface{}{
+				"link_id":   linkID,
+				"bundle_id": req.BundleID,
+			},
+		}, componentJobIDs, req.Tags)
+		if err != nil {
+			h.discardJobs(componentJobIDs)
+			http.Error(w, "failed to enqueue link deletion: "+err.Error(), http.StatusBadRequest)
+			return
 		}
+		componentJobIDs = append(componentJobIDs, linkJobID)
 	}
 
 	// Enqueue uninstall_module jobs (depend on all links being deleted)
-	if modulesField.IsValid() && modulesField.Kind() == reflect.Slice {
-		for i := 0; i < modulesField.Len(); i++ {
-			mod := modulesField.Index(i)
-			modID := mod.FieldByName("ID").String()
-
-			moduleJobID, err := h.manager.Enqueue(Command{
-				Type: CmdUninstallModule,
-				Args: map[string]interface{}{
-					"module_id": modID,
-					"bundle_id": req.BundleID,
-				},
-			}, componentJobIDs)
-			if err != nil {
-				http.Error(w, "failed to enqueue module uninstall: "+err.Error(), http.StatusBadRequest)
-				return
-			}
-			componentJobIDs = append(componentJobIDs, moduleJobID)
+	for _, modID := range owned.Modules {
+		moduleJobID, err := h.enqueueComponent(Command{
+			Type: CmdUninstallModule,
+			Args: map[string]interface{}{
+				"module_id": modID,
+				"bundle_id": req.BundleID,
+			},
+		}, componentJobIDs, req.Tags)
+		if err != nil {
+			h.discardJobs(componentJobIDs)
+			http.Error(w, "failed to enqueue module uninstall: "+err.Error(), http.StatusBadRequest)
+			return
 		}
+		componentJobIDs = append(componentJobIDs, moduleJobID)
 	}
 
 	// Create the bundle_uninstall meta-job that depends on all component jobs
-	jobID, err := h.manager.Enqueue(Command{
+	jobID, existing, err := h.manager.EnqueueWithOptions(Command{
 		Type: CmdBundleUninstall,
 		Args: map[string]interface{}{
 			"bundle_id": req.BundleID,
+			"retained":  installed.retainedMessages(retained),
 		},
-	}, componentJobIDs)
+	}, EnqueueOptions{DependsOn: componentJobIDs, IdempotencyKey: key, CallbackURL: req.CallbackURL, Tags: req.Tags})
 
 	if err != nil {
+		h.discardJobs(componentJobIDs)
 		h.logger.Debug("failed to enqueue bundle uninstall job", "bundle_id", req.BundleID, "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if existing {
+		// A concurrent request with the same key won the race; its meta-job tracks its own
+		// component jobs, so the ones enqueued here would never be tracked
+		h.discardJobs(componentJobIDs)
+		h.writeExistingJob(w, jobID)
+		return
+	}
 
 	job, err := h.manager.Get(jobID)
 	if err != nil {
@@ -898,7 +1772,41 @@ func (h *Handlers) EnqueueBundleUninstall(w http.ResponseWriter, r *http.Request
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(enqueueStatus(existing))
+	json.NewEncoder(w).Encode(job)
+}
+
+// EnqueueSyncCatalog handles POST /api/catalogs/sync
+// @ID enqueueSyncCatalog
+// @Summary Enqueue a catalog sync job
+// @Description Enqueue a job that fetches the remote catalog index (ZEROPOINT_CATALOG_INDEX_URL), validates it and applies added, updated and removed modules and bundles atomically. The job result summarizes the changes.
+// @Tags catalog
+// @Produce json
+// @Param Idempotency-Key header string false "Deduplicates retried requests; the same key returns the existing job"
+// @Success 201 {object} JobResponse "Job enqueued successfully"
+// @Success 200 {object} JobResponse "Existing job returned for a repeated idempotency key"
+// @Failure 400 {string} string "Bad request"
+// @Router /catalogs/sync [post]
+func (h *Handlers) EnqueueSyncCatalog(w http.ResponseWriter, r *http.Request) {
+	jobID, existing, err := h.manager.EnqueueWithOptions(Command{
+		Type: CmdSyncCatalog,
+		Args: map[string]interface{}{},
+	}, EnqueueOptions{IdempotencyKey: idempotencyKey(r, "")})
+	if err != nil {
+		h.logger.Error("failed to enqueue catalog sync job", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.manager.Get(jobID)
+	if err != nil {
+		h.logger.Error("failed to fetch enqueued job", "job_id", jobID, "error", err)
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(enqueueStatus(existing))
 	json.NewEncoder(w).Encode(job)
 }
 
@@ -910,7 +1818,12 @@ func (h *Handlers) CancelJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.manager.Cancel(jobID); err != nil {
+	cancel := h.manager.Cancel
+	if r.URL.Query().Get("force") == "true" {
+		cancel = h.manager.ForceCancel
+	}
+
+	if err := cancel(jobID); err != nil {
 		h.logger.Debug("failed to cancel job", "job_id", jobID, "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -926,3 +1839,89 @@ func (h *Handlers) CancelJob(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(job)
 }
+
+// RerunJobRequest is the optional body of POST /jobs/{id}/rerun
+type RerunJobRequest struct {
+	DependsOn *[]string `json:"depends_on,omitempty"` // Replaces the original dependencies; [] for none
+	KeepTags  bool      `json:"keep_tags,omitempty"`  // Carry the original job's tags over to the new job
+}
+
+// RerunJob handles POST /api/jobs/{id}/rerun
+// @ID rerunJob
+// @Summary Rerun a failed or cancelled job
+// @Description Enqueues a new job with the same command and arguments as a failed or cancelled job. The new job depends on the original job's dependencies unless depends_on is given, and records the original job in rerun_of.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param body body RerunJobRequest false "Rerun options"
+// @Success 201 {object} JobResponse "Job enqueued"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Job not found"
+// @Failure 409 {string} string "Job is not failed or cancelled, or its dependencies no longer exist"
+// @Router /jobs/{id}/rerun [post]
+func (h *Handlers) RerunJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+
+	var req RerunJobRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := h.manager.Get(jobID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var dependsOn []string
+	if req.DependsOn != nil {
+		dependsOn = append([]string{}, *req.DependsOn...)
+	}
+
+	newJobID, err := h.manager.Rerun(jobID, dependsOn, req.KeepTags)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrRerunConflict) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	job, err := h.manager.Get(newJobID)
+	if err != nil {
+		h.logger.Error("failed to fetch rerun job", "job_id", newJobID, "error", err)
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(job)
+}
+
+// enqueueStatus returns 201 for a newly created job and 200 when an idempotency key
+// matched an existing job
+func enqueueStatus(existing bool) int {
+	if existing {
+		return http.StatusOK
+	}
+	return http.StatusCreated
+}
+
+// writeExistingJob responds with a job that was previously enqueued under the same idempotency key
+func (h *Handlers) writeExistingJob(w http.ResponseWriter, jobID string) {
+	job, err := h.manager.Get(jobID)
+	if err != nil {
+		h.logger.Error("failed to fetch existing job", "job_id", jobID, "error", err)
+		http.Error(w, "failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
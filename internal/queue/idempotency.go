@@ -0,0 +1,61 @@
+package queue
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header clients use to deduplicate enqueue retries
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyKeyTTL is how long a key stays claimed by a job. After that the key
+// can be reused even if the original job succeeded.
+const idempotencyKeyTTL = 24 * time.Hour
+
+// idempotencyScope namespaces a key by command type so the same key sent to
+// two different enqueue endpoints never resolves to the wrong job
+func idempotencyScope(cmdType CommandType, key string) string {
+	return string(cmdType) + ":" + key
+}
+
+// lookupIdempotencyKey returns the job that currently holds a key (caller must hold the lock).
+// Keys held by failed or cancelled jobs, missing jobs, or jobs older than the TTL are released.
+func (m *Manager) lookupIdempotencyKey(cmdType CommandType, key string) (string, bool) {
+	scoped := idempotencyScope(cmdType, key)
+
+	jobID, ok := m.idempotencyIndex[scoped]
+	if !ok {
+		return "", false
+	}
+
+	job, err := m.getJob(jobID)
+	if err != nil ||
+		job.Status == StatusFailed ||
+		job.Status == StatusCancelled ||
+		time.Since(job.CreatedAt) > idempotencyKeyTTL {
+		delete(m.idempotencyIndex, scoped)
+		return "", false
+	}
+
+	return jobID, true
+}
+
+// idempotencyKey returns the key from the Idempotency-Key header, falling back to the body field
+func idempotencyKey(r *http.Request, bodyKey string) string {
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		return key
+	}
+	return bodyKey
+}
+
+// JobForIdempotencyKey returns the job currently holding a key for a command type, if any
+func (m *Manager) JobForIdempotencyKey(cmdType CommandType, key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lookupIdempotencyKey(cmdType, key)
+}
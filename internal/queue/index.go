@@ -0,0 +1,120 @@
+package queue
+
+import (
+	"os"
+	"sort"
+	"time"
+)
+
+// jobMeta is a job as kept in memory by the job index: enough to pick, sort, count and
+// list jobs without reading their job.json files
+type jobMeta struct {
+	ID                     string
+	Status                 JobStatus
+	CommandType            CommandType
+	DependsOn              []string
+	CreatedAt              time.Time
+	CompletedAt            *time.Time
+	RunOnDependencyFailure bool
+
+	job Job // Copy of the job as last written, served by the job lists
+}
+
+// loadJobIndex reads every persisted job once at startup and builds the job, dependents,
+// idempotency and tag indexes from them. Disk stays the source of truth; after this the
+// indexes are kept in sync by writeJobMetadata and Delete.
+func (m *Manager) loadJobIndex() {
+	entries, err := os.ReadDir(m.jobsDir)
+	if err != nil {
+		m.logger.Warn("failed to read jobs directory for job index", "error", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		job, err := m.getJob(entry.Name())
+		if err != nil {
+			m.logger.Warn("skipping unreadable job", "job_id", entry.Name(), "error", err)
+			continue
+		}
+
+		m.indexJob(job)
+		if job.IdempotencyKey != "" {
+			m.idempotencyIndex[idempotencyScope(job.Command.Type, job.IdempotencyKey)] = job.ID
+		}
+		m.indexTags(job)
+	}
+}
+
+// indexJob records the current state of a job and its dependency edges (caller must hold
+// the lock)
+func (m *Manager) indexJob(job *Job) {
+	if previous, ok := m.jobIndex[job.ID]; ok {
+		m.unlinkDependencies(previous)
+	}
+
+	meta := &jobMeta{
+		ID:                     job.ID,
+		Status:                 job.Status,
+		CommandType:            job.Command.Type,
+		DependsOn:              append([]string(nil), job.DependsOn...),
+		CreatedAt:              job.CreatedAt,
+		CompletedAt:            job.CompletedAt,
+		RunOnDependencyFailure: job.RunOnDependencyFailure,
+		job:                    *job,
+	}
+	m.jobIndex[job.ID] = meta
+
+	for _, dep := range meta.DependsOn {
+		if m.dependents[dep] == nil {
+			m.dependents[dep] = make(map[string]bool)
+		}
+		m.dependents[dep][job.ID] = true
+	}
+}
+
+// unindexJob drops a deleted job from the job index (caller must hold the lock)
+func (m *Manager) unindexJob(jobID string) {
+	if meta, ok := m.jobIndex[jobID]; ok {
+		m.unlinkDependencies(meta)
+		delete(m.jobIndex, jobID)
+	}
+	delete(m.dependents, jobID)
+}
+
+// unlinkDependencies removes a job from the dependents of the jobs it depends on
+func (m *Manager) unlinkDependencies(meta *jobMeta) {
+	for _, dep := range meta.DependsOn {
+		delete(m.dependents[dep], meta.ID)
+		if len(m.dependents[dep]) == 0 {
+			delete(m.dependents, dep)
+		}
+	}
+}
+
+// indexedJobIDs returns the IDs of indexed jobs for which keep returns true, or of all
+// jobs if keep is nil, sorted (caller must hold the lock)
+func (m *Manager) indexedJobIDs(keep func(*jobMeta) bool) []string {
+	var ids []string
+	for id, meta := range m.jobIndex {
+		if keep == nil || keep(meta) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// dependentIDs returns the IDs of the jobs that depend on jobID, sorted (caller must hold
+// the lock)
+func (m *Manager) dependentIDs(jobID string) []string {
+	ids := make([]string, 0, len(m.dependents[jobID]))
+	for id := range m.dependents[jobID] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,228 @@
+package queue
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+)
+
+// Watch returns a channel that receives a signal whenever a job's metadata or events
+// change, and a function that stops watching. Signals are coalesced, so a slow reader
+// sees at least one signal after the latest change rather than one per change.
+func (m *Manager) Watch(jobID string) (<-chan struct{}, func()) {
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	m.nextWatcherID++
+	id := m.nextWatcherID
+	if m.watchers[jobID] == nil {
+		m.watchers[jobID] = make(map[int]chan struct{})
+	}
+	m.watchers[jobID][id] = ch
+
+	return ch, func() {
+		m.watchMu.Lock()
+		defer m.watchMu.Unlock()
+		delete(m.watchers[jobID], id)
+		if len(m.watchers[jobID]) == 0 {
+			delete(m.watchers, jobID)
+		}
+	}
+}
+
+// notify signals everyone watching a job
+func (m *Manager) notify(jobID string) {
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+
+	for _, ch := range m.watchers[jobID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// A signal is already pending
+		}
+	}
+}
+
+// jobOutputReader reads a job's output for one logs request. Archived events are included
+// so positions in the output stay stable while the job's events are compacted. Compaction
+// only appends gzip members to the archive, so each member is decoded once per request
+// rather than on every poll.
+type jobOutputReader struct {
+	manager     *Manager
+	jobID       string
+	archived    []Event
+	archiveSize int64 // Bytes of the archive decoded into archived
+}
+
+// read returns the job's status and events in one consistent read
+func (r *jobOutputReader) read() (JobStatus, []Event, error) {
+	m := r.manager
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, err := m.getJob(r.jobID)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := r.readArchive(); err != nil {
+		return "", nil, err
+	}
+	current, err := m.getEvents(r.jobID)
+	if err != nil {
+		return "", nil, err
+	}
+	return job.Status, mergeEvents(r.archived, current), nil
+}
+
+// readArchive decodes the gzip members appended to the job's archive since the last read
+// (caller must hold the lock)
+func (r *jobOutputReader) readArchive() error {
+	file, err := os.Open(r.manager.eventsArchiveFile(r.jobID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read events archive: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read events archive: %w", err)
+	}
+	if info.Size() == r.archiveSize {
+		return nil
+	}
+	if info.Size() < r.archiveSize {
+		// Not written by compaction; start over
+		r.archived, r.archiveSize = nil, 0
+	}
+
+	if _, err := file.Seek(r.archiveSize, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read events archive: %w", err)
+	}
+	events, err := decodeArchive(io.LimitReader(file, info.Size()-r.archiveSize))
+	if err != nil {
+		return err
+	}
+	r.archived = append(r.archived, events...)
+	r.archiveSize = info.Size()
+	return nil
+}
+
+// isTerminal reports whether a job will not change status again
+func isTerminal(status JobStatus) bool {
+	return status == StatusCompleted || status == StatusFailed || status == StatusCancelled
+}
+
+// isLogEvent reports whether an event is part of a job's output
+func isLogEvent(event Event) bool {
+	return event.Type == "log" || event.Type == "error"
+}
+
+// formatLogEvent renders an event as a line of plain text
+func formatLogEvent(event Event) string {
+	if event.Type != "error" {
+		return event.Message
+	}
+	if data, ok := event.Data.(map[string]interface{}); ok {
+		if detail, ok := data["error"].(string); ok && detail != "" && detail != event.Message {
+			return fmt.Sprintf("error: %s: %s", event.Message, detail)
+		}
+	}
+	return "error: " + event.Message
+}
+
+// JobLogs handles GET /jobs/{id}/logs
+// @ID getJobLogs
+// @Summary Get job output
+// @Description Returns the job's log and error events as newline-delimited plain text. With follow, the response stays open and streams new lines until the job completes, fails or is cancelled.
+// @Tags jobs
+// @Produce text/plain
+// @Param id path string true "Job ID"
+// @Param follow query bool false "Stream new output until the job is finished"
+// @Success 200 {string} string "Job output"
+// @Failure 404 {string} string "Job not found"
+// @Router /jobs/{id}/logs [get]
+func (h *Handlers) JobLogs(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+	follow := r.URL.Query().Get("follow") == "true"
+
+	// Watch before the first read so a change between the read and the wait isn't missed
+	changed, stop := h.manager.Watch(jobID)
+	defer stop()
+
+	output := &jobOutputReader{manager: h.manager, jobID: jobID}
+	status, events, err := output.read()
+	if err != nil {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	flusher, _ := w.(http.Flusher)
+
+	written := 0
+	for {
+		for _, event := range events[written:] {
+			if isLogEvent(event) {
+				fmt.Fprintln(w, formatLogEvent(event))
+			}
+		}
+		written = len(events)
+
+		if !follow || isTerminal(status) {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		}
+
+		status, events, err = output.read()
+		if err != nil {
+			return // Job was deleted while following
+		}
+	}
+}
+
+// DownloadJobLog handles GET /jobs/{id}/log
+// @ID downloadJobLog
+// @Summary Download job output
+// @Description Returns the job's complete output, archived lines included, as a plain text file attachment
+// @Tags jobs
+// @Produce text/plain
+// @Param id path string true "Job ID"
+// @Success 200 {file} file "Job output"
+// @Failure 404 {string} string "Job not found"
+// @Router /jobs/{id}/log [get]
+func (h *Handlers) DownloadJobLog(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+
+	output := &jobOutputReader{manager: h.manager, jobID: jobID}
+	_, events, err := output.read()
+	if err != nil {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "job-"+jobID+".log"))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	for _, event := range events {
+		if isLogEvent(event) {
+			fmt.Fprintln(w, formatLogEvent(event))
+		}
+	}
+}
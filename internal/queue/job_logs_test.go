@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestJobOutputReaderFollowsCompaction(t *testing.T) {
+	m, err := NewManager(t.TempDir(), 6, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobID := mustEnqueue(t, m)
+
+	logLines := func(from, to int) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			if err := m.AppendEvent(jobID, Event{Timestamp: time.Now().UTC(), Type: "log", Message: fmt.Sprintf("line %d", i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	output := &jobOutputReader{manager: m, jobID: jobID}
+	check := func(want int) {
+		t.Helper()
+		_, events, err := output.read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		all, err := m.GetFull(jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != want || len(all.Events) != want {
+			t.Fatalf("read %d events and GetFull %d, want %d", len(events), len(all.Events), want)
+		}
+		for i := range events {
+			if events[i].Message != all.Events[i].Message {
+				t.Fatalf("event %d is %q, GetFull has %q", i, events[i].Message, all.Events[i].Message)
+			}
+		}
+	}
+
+	logLines(0, 10)
+	check(11)
+	archived := output.archiveSize
+	if archived == 0 {
+		t.Fatal("nothing was compacted into the archive")
+	}
+
+	// Reading again without a compaction keeps the decoded archive
+	check(11)
+	if output.archiveSize != archived {
+		t.Errorf("archive size changed from %d to %d without a compaction", archived, output.archiveSize)
+	}
+
+	// A later compaction appends to the archive; only the new part is decoded
+	logLines(10, 20)
+	check(21)
+	if output.archiveSize <= archived {
+		t.Errorf("archive size %d did not grow past %d", output.archiveSize, archived)
+	}
+}
@@ -1,37 +1,93 @@
 package queue
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"zeropoint-agent/internal/metrics"
+
 	"github.com/google/uuid"
 )
 
+// ErrRerunConflict is returned by Rerun for a job that can't be rerun as stored
+var ErrRerunConflict = errors.New("cannot rerun job")
+
 // Manager handles job enqueueing, tracking, and execution
 type Manager struct {
 	jobsDir string
 	mu      sync.RWMutex
 	logger  *slog.Logger
+
+	// idempotencyIndex maps scoped idempotency keys to the job that claimed them
+	idempotencyIndex map[string]string
+
+	// tagIndex maps each tag to the IDs of the jobs carrying it (see tags.go)
+	tagIndex map[string]map[string]bool
+
+	// jobIndex holds the metadata of every job and dependents the IDs of the jobs
+	// depending on each job, so listing and cascading don't rescan the jobs directory
+	// (see index.go)
+	jobIndex   map[string]*jobMeta
+	dependents map[string]map[string]bool
+
+	// Per-job change notifications for followers of a job's output
+	watchMu       sync.Mutex
+	watchers      map[string]map[int]chan struct{}
+	nextWatcherID int
+
+	// Cancel funcs of executing jobs, registered by the worker, so a forced cancel can
+	// stop them; forced records jobs whose execution was cancelled that way
+	runningMu sync.Mutex
+	running   map[string]context.CancelFunc
+	forced    map[string]bool
+
+	// Events kept per job before older log events are compacted into the archive, and
+	// the number of lines in each job's events file
+	maxEvents   int
+	eventCounts map[string]int
+
+	// Hides secrets in event messages and job errors before they are written
+	redact func(string) string
+
+	// Delivers finished jobs to their callback URLs
+	callbacks *callbackNotifier
 }
 
-// NewManager creates a new job manager
-func NewManager(jobsDir string, logger *slog.Logger) (*Manager, error) {
+// NewManager creates a new job manager. Jobs with more than maxEvents events have older
+// log events compacted into an archive; 0 disables compaction.
+func NewManager(jobsDir string, maxEvents int, logger *slog.Logger) (*Manager, error) {
 	// Ensure jobs directory exists
 	if err := os.MkdirAll(jobsDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create jobs directory: %w", err)
 	}
 
-	return &Manager{
-		jobsDir: jobsDir,
-		logger:  logger,
-	}, nil
+	m := &Manager{
+		jobsDir:          jobsDir,
+		logger:           logger,
+		idempotencyIndex: make(map[string]string),
+		tagIndex:         make(map[string]map[string]bool),
+		jobIndex:         make(map[string]*jobMeta),
+		dependents:       make(map[string]map[string]bool),
+		watchers:         make(map[string]map[int]chan struct{}),
+		running:          make(map[string]context.CancelFunc),
+		forced:           make(map[string]bool),
+		maxEvents:        maxEvents,
+		eventCounts:      make(map[string]int),
+		callbacks:        newCallbackNotifier(logger),
+	}
+	m.loadJobIndex()
+
+	return m, nil
 }
 
 // jobDir returns the directory for a specific job
@@ -54,6 +110,155 @@ func (m *Manager) Enqueue(cmd Command, dependsOn []string) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	return m.enqueue(cmd, dependsOn, EnqueueOptions{})
+}
+
+// EnqueueOptions controls how EnqueueWithOptions creates a job
+type EnqueueOptions struct {
+	DependsOn      []string // Explicit job IDs this job depends on
+	DependsOnTags  []string // Tags resolved to the IDs of queued/running jobs carrying them
+	IdempotencyKey string   // Deduplicates retried requests (see EnqueueWithOptions)
+
+	// Run the job once its dependencies finish even if some of them failed, e.g. for
+	// meta-jobs that report on or clean up after their components
+	RunOnDependencyFailure bool
+
+	RerunOf string // ID of the job this one reruns
+
+	CallbackURL string // POSTed the final job once it reaches a terminal status (see callbacks.go)
+
+	Tags []string // Added to any tags in the command's args
+}
+
+// EnqueueWithOptions creates a job like Enqueue with support for tag-based dependencies
+// and idempotency keys.
+//
+// DependsOnTags is resolved once, at enqueue time: the job depends on every queued or
+// running job that carries any of the tags right now. Jobs enqueued later with the same
+// tags are not added retroactively.
+//
+// If a non-failed job already claimed IdempotencyKey for the same command type, its ID
+// is returned with existing set to true and no new job is created.
+func (m *Manager) EnqueueWithOptions(cmd Command, opts EnqueueOptions) (jobID string, existing bool, err error) {
+	if opts.CallbackURL != "" {
+		if err := m.callbacks.validateURL(opts.CallbackURL); err != nil {
+			return "", false, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if opts.IdempotencyKey != "" {
+		if jobID, ok := m.lookupIdempotencyKey(cmd.Type, opts.IdempotencyKey); ok {
+			m.logger.Info("returning existing job for idempotency key", "job_id", jobID, "command", cmd.Type)
+			return jobID, true, nil
+		}
+	}
+
+	dependsOn := opts.DependsOn
+	if len(opts.DependsOnTags) > 0 {
+		tagged, err := m.pendingJobsWithTags(opts.DependsOnTags)
+		if err != nil {
+			return "", false, err
+		}
+		dependsOn = mergeJobIDs(dependsOn, tagged)
+	}
+
+	jobID, err = m.enqueue(cmd, dependsOn, opts)
+	return jobID, false, err
+}
+
+// Rerun enqueues a copy of a failed or cancelled job's command as a new job. The new job
+// depends on dependsOn if it is non-nil, otherwise on the original job's dependencies, which
+// must all still exist. Tags are dropped from the copy unless keepTags is set.
+func (m *Manager) Rerun(jobID string, dependsOn []string, keepTags bool) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.getJob(jobID)
+	if err != nil {
+		return "", err
+	}
+	if job.Status != StatusFailed && job.Status != StatusCancelled {
+		return "", fmt.Errorf("%w: job %s is %s, only failed or cancelled jobs can be rerun", ErrRerunConflict, jobID, job.Status)
+	}
+
+	if dependsOn == nil {
+		var missing []string
+		for _, depID := range job.DependsOn {
+			if _, err := m.getJob(depID); err != nil {
+				missing = append(missing, depID)
+			}
+		}
+		if len(missing) > 0 {
+			return "", fmt.Errorf("%w: dependencies no longer exist: %s; pass depends_on to rerun without them", ErrRerunConflict, strings.Join(missing, ", "))
+		}
+		dependsOn = append([]string{}, job.DependsOn...)
+	}
+
+	args := make(map[string]interface{}, len(job.Command.Args))
+	for key, value := range job.Command.Args {
+		args[key] = value
+	}
+	if !keepTags {
+		delete(args, "tags")
+	}
+
+	opts := EnqueueOptions{
+		RunOnDependencyFailure: job.RunOnDependencyFailure,
+		RerunOf:                jobID,
+		CallbackURL:            job.CallbackURL,
+	}
+	if keepTags {
+		opts.Tags = job.Tags
+	}
+	newJobID, err := m.enqueue(Command{Type: job.Command.Type, Args: args}, dependsOn, opts)
+	if err != nil {
+		return "", err
+	}
+
+	m.logger.Info("job rerun", "job_id", jobID, "new_job_id", newJobID)
+	return newJobID, nil
+}
+
+// pendingJobsWithTags returns IDs of queued or running jobs carrying any of the tags
+// (caller must hold the lock)
+func (m *Manager) pendingJobsWithTags(tags []string) ([]string, error) {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		for id := range m.tagIndex[tag] {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			if meta, ok := m.jobIndex[id]; ok && (meta.Status == StatusQueued || meta.Status == StatusRunning) {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// mergeJobIDs appends extra IDs to ids, skipping duplicates
+func mergeJobIDs(ids, extra []string) []string {
+	seen := make(map[string]bool, len(ids)+len(extra))
+	merged := make([]string, 0, len(ids)+len(extra))
+	for _, id := range append(append([]string{}, ids...), extra...) {
+		if !seen[id] {
+			seen[id] = true
+			merged = append(merged, id)
+		}
+	}
+	return merged
+}
+
+// enqueue creates the job on disk (caller must hold the lock)
+func (m *Manager) enqueue(cmd Command, dependsOn []string, opts EnqueueOptions) (string, error) {
 	jobID := uuid.New().String()
 
 	// Validate dependencies exist and are not cycles
@@ -76,8 +281,11 @@ func (m *Manager) Enqueue(cmd Command, dependsOn []string) (string, error) {
 					tags = append(tags, tagStr)
 				}
 			}
+		} else if tagsList, ok := tagsInterface.([]string); ok {
+			tags = tagsList
 		}
 	}
+	tags = mergeTags(tags, opts.Tags)
 
 	// Create job metadata
 	job := &Job{
@@ -87,6 +295,11 @@ func (m *Manager) Enqueue(cmd Command, dependsOn []string) (string, error) {
 		DependsOn: dependsOn,
 		Tags:      tags,
 		CreatedAt: time.Now().UTC(),
+
+		IdempotencyKey:         opts.IdempotencyKey,
+		RunOnDependencyFailure: opts.RunOnDependencyFailure,
+		RerunOf:                opts.RerunOf,
+		CallbackURL:            opts.CallbackURL,
 	}
 
 	// Write job metadata
@@ -94,6 +307,11 @@ func (m *Manager) Enqueue(cmd Command, dependsOn []string) (string, error) {
 		return "", err
 	}
 
+	if opts.IdempotencyKey != "" {
+		m.idempotencyIndex[idempotencyScope(cmd.Type, opts.IdempotencyKey)] = jobID
+	}
+	m.indexTags(job)
+
 	// Append initial event
 	if err := m.appendEvent(jobID, Event{
 		Timestamp: time.Now().UTC(),
@@ -154,8 +372,17 @@ func (m *Manager) checkCycle(jobID string, deps []string) error {
 	return nil
 }
 
-// Get retrieves a job by ID
+// Get retrieves a job by ID. Log events compacted into the archive are left out.
 func (m *Manager) Get(jobID string) (*JobResponse, error) {
+	return m.get(jobID, false)
+}
+
+// GetFull retrieves a job by ID with all of its events, including archived log events
+func (m *Manager) GetFull(jobID string) (*JobResponse, error) {
+	return m.get(jobID, true)
+}
+
+func (m *Manager) get(jobID string, full bool) (*JobResponse, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -164,24 +391,49 @@ func (m *Manager) Get(jobID string) (*JobResponse, error) {
 		return nil, err
 	}
 
-	events, err := m.getEvents(jobID)
+	readEvents := m.getEvents
+	if full {
+		readEvents = m.getAllEvents
+	}
+	events, err := readEvents(jobID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &JobResponse{
-		ID:          job.ID,
-		Status:      job.Status,
-		Command:     job.Command,
-		DependsOn:   job.DependsOn,
-		Tags:        job.Tags,
-		CreatedAt:   job.CreatedAt,
-		StartedAt:   job.StartedAt,
-		CompletedAt: job.CompletedAt,
-		Result:      job.Result,
-		Error:       job.Error,
-		Events:      events,
-	}, nil
+	resp := newJobResponse(job, events)
+	return &resp, nil
+}
+
+// newJobResponse builds the API view of a job; tags and events are always present, even
+// when empty, and secrets in the command args are redacted
+func newJobResponse(job *Job, events []Event) JobResponse {
+	tags := job.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	if events == nil {
+		events = []Event{}
+	}
+
+	return JobResponse{
+		ID:             job.ID,
+		Status:         job.Status,
+		Command:        redactCommand(job.Command),
+		DependsOn:      job.DependsOn,
+		Tags:           tags,
+		CreatedAt:      job.CreatedAt,
+		StartedAt:      job.StartedAt,
+		CompletedAt:    job.CompletedAt,
+		Result:         job.Result,
+		Error:          job.Error,
+		IdempotencyKey: job.IdempotencyKey,
+		Events:         events,
+
+		RunOnDependencyFailure: job.RunOnDependencyFailure,
+		RerunOf:                job.RerunOf,
+		CallbackURL:            job.CallbackURL,
+		ArchivedEvents:         job.ArchivedEvents,
+	}
 }
 
 // getJob is an internal method that reads job metadata without locking (caller must lock)
@@ -229,48 +481,15 @@ func (m *Manager) getEvents(jobID string) ([]Event, error) {
 	return events, nil
 }
 
-// ListAll returns all jobs
-func (m *Manager) ListAll() ([]JobResponse, error) {
+// ListAll returns all jobs, newest first. Jobs come from the job index; events are only
+// read when withEvents is set and are empty otherwise.
+func (m *Manager) ListAll(withEvents bool) ([]JobResponse, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	entries, err := os.ReadDir(m.jobsDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read jobs directory: %w", err)
-	}
-
 	var jobs []JobResponse
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		jobID := entry.Name()
-		job, err := m.getJob(jobID)
-		if err != nil {
-			m.logger.Error("failed to read job", "job_id", jobID, "error", err)
-			continue
-		}
-
-		events, err := m.getEvents(jobID)
-		if err != nil {
-			m.logger.Error("failed to read job events", "job_id", jobID, "error", err)
-			events = []Event{}
-		}
-
-		jobs = append(jobs, JobResponse{
-			ID:          job.ID,
-			Status:      job.Status,
-			Command:     job.Command,
-			DependsOn:   job.DependsOn,
-			Tags:        job.Tags,
-			CreatedAt:   job.CreatedAt,
-			StartedAt:   job.StartedAt,
-			CompletedAt: job.CompletedAt,
-			Result:      job.Result,
-			Error:       job.Error,
-			Events:      events,
-		})
+	for _, jobID := range m.indexedJobIDs(nil) {
+		jobs = append(jobs, m.indexedJobResponse(jobID, withEvents))
 	}
 
 	// Sort by created time, newest first
@@ -281,63 +500,45 @@ func (m *Manager) ListAll() ([]JobResponse, error) {
 	return jobs, nil
 }
 
-// ListAllTopoSorted returns all jobs sorted in topological order
-func (m *Manager) ListAllTopoSorted() ([]JobResponse, error) {
+// ListAllTopoSorted returns all jobs sorted in topological order. Jobs come from the job
+// index; events are only read when withEvents is set and are empty otherwise.
+func (m *Manager) ListAllTopoSorted(withEvents bool) ([]JobResponse, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	entries, err := os.ReadDir(m.jobsDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read jobs directory: %w", err)
-	}
-
-	var jobs []*Job
-	jobMap := make(map[string]*Job)
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		jobID := entry.Name()
-		job, err := m.getJob(jobID)
-		if err != nil {
-			m.logger.Error("failed to read job", "job_id", jobID, "error", err)
-			continue
-		}
+	return m.topoSortedResponses(m.indexedJobIDs(nil), withEvents), nil
+}
 
+// topoSortedResponses returns the indexed jobs with the given IDs in topological order
+// (caller must hold the lock)
+func (m *Manager) topoSortedResponses(jobIDs []string, withEvents bool) []JobResponse {
+	jobs := make([]*Job, 0, len(jobIDs))
+	jobMap := make(map[string]*Job, len(jobIDs))
+	for _, jobID := range jobIDs {
+		job := &m.jobIndex[jobID].job
 		jobs = append(jobs, job)
 		jobMap[jobID] = job
 	}
 
-	// Topological sort
-	sorted := m.topoSort(jobs, jobMap)
+	responses := make([]JobResponse, 0, len(jobs))
+	for _, job := range m.topoSort(jobs, jobMap) {
+		responses = append(responses, m.indexedJobResponse(job.ID, withEvents))
+	}
+	return responses
+}
 
-	// Convert to JobResponse with events
-	var responses []JobResponse
-	for _, job := range sorted {
-		events, err := m.getEvents(job.ID)
-		if err != nil {
-			m.logger.Error("failed to read job events", "job_id", job.ID, "error", err)
+// indexedJobResponse builds the API view of an indexed job, reading its events only when
+// withEvents is set (caller must hold the lock)
+func (m *Manager) indexedJobResponse(jobID string, withEvents bool) JobResponse {
+	events := []Event{}
+	if withEvents {
+		var err error
+		if events, err = m.getEvents(jobID); err != nil {
+			m.logger.Error("failed to read job events", "job_id", jobID, "error", err)
 			events = []Event{}
 		}
-
-		responses = append(responses, JobResponse{
-			ID:          job.ID,
-			Status:      job.Status,
-			Command:     job.Command,
-			DependsOn:   job.DependsOn,
-			Tags:        job.Tags,
-			CreatedAt:   job.CreatedAt,
-			StartedAt:   job.StartedAt,
-			CompletedAt: job.CompletedAt,
-			Result:      job.Result,
-			Error:       job.Error,
-			Events:      events,
-		})
 	}
-
-	return responses, nil
+	return newJobResponse(&m.jobIndex[jobID].job, events)
 }
 
 // Cancel cancels a queued job and all its dependents
@@ -372,6 +573,8 @@ func (m *Manager) Cancel(jobID string) error {
 		return err
 	}
 
+	m.notifyCallback(job)
+
 	// Cascade cancellation to all dependent jobs
 	m.cascadeCancelDependents(jobID)
 
@@ -380,57 +583,136 @@ func (m *Manager) Cancel(jobID string) error {
 	return nil
 }
 
-// cascadeCancelDependents recursively cancels all jobs that depend on jobID
-func (m *Manager) cascadeCancelDependents(jobID string) {
-	entries, err := os.ReadDir(m.jobsDir)
+// ForceCancel stops a running job by cancelling its execution context, which kills the
+// terraform or other processes it started. The worker then marks it failed. Queued jobs
+// are cancelled as with Cancel.
+func (m *Manager) ForceCancel(jobID string) error {
+	m.mu.RLock()
+	job, err := m.getJob(jobID)
+	m.mu.RUnlock()
 	if err != nil {
-		m.logger.Error("failed to read jobs directory for cascade", "error", err)
-		return
+		return fmt.Errorf("job not found: %w", err)
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
+	switch job.Status {
+	case StatusQueued:
+		return m.Cancel(jobID)
+	case StatusRunning:
+	default:
+		return fmt.Errorf("job already finished with status %s", job.Status)
+	}
 
-		depJobID := entry.Name()
-		depJob, err := m.getJob(depJobID)
-		if err != nil {
-			continue
-		}
+	m.runningMu.Lock()
+	cancel, ok := m.running[jobID]
+	if ok {
+		m.forced[jobID] = true
+	}
+	m.runningMu.Unlock()
+	if !ok {
+		return fmt.Errorf("job %s is not executing in this process", jobID)
+	}
 
-		// Check if this job depends on the cancelled job
-		for _, dep := range depJob.DependsOn {
-			if dep == jobID && depJob.Status == StatusQueued {
-				// Cancel this job
-				depJob.Status = StatusCancelled
-				depJob.Error = fmt.Sprintf("dependency cancelled: %s", jobID)
-				now := time.Now().UTC()
-				depJob.CompletedAt = &now
-
-				if err := m.writeJobMetadata(depJob); err != nil {
-					m.logger.Error("failed to write job metadata during cascade", "job_id", depJobID, "error", err)
-					continue
-				}
+	cancel()
 
-				if err := m.appendEvent(depJobID, Event{
-					Timestamp: time.Now().UTC(),
-					Type:      "info",
-					Message:   fmt.Sprintf("Job cancelled due to dependency cancellation: %s", jobID),
-				}); err != nil {
-					m.logger.Error("failed to append event during cascade", "job_id", depJobID, "error", err)
-				}
+	if err := m.AppendEvent(jobID, Event{
+		Timestamp: time.Now().UTC(),
+		Type:      "warning",
+		Message:   "Forced cancellation requested, stopping execution",
+	}); err != nil {
+		m.logger.Error("failed to append event", "job_id", jobID, "error", err)
+	}
 
-				m.logger.Info("job cascade cancelled", "job_id", depJobID, "due_to", jobID)
+	m.logger.Warn("job force cancelled", "job_id", jobID)
+	return nil
+}
+
+// setRunning registers the cancel func of a job the worker started executing
+func (m *Manager) setRunning(jobID string, cancel context.CancelFunc) {
+	m.runningMu.Lock()
+	defer m.runningMu.Unlock()
+	m.running[jobID] = cancel
+}
+
+// clearRunning unregisters an executing job and reports whether it was force cancelled
+func (m *Manager) clearRunning(jobID string) bool {
+	m.runningMu.Lock()
+	defer m.runningMu.Unlock()
+	forced := m.forced[jobID]
+	delete(m.running, jobID)
+	delete(m.forced, jobID)
+	return forced
+}
+
+// CancelDependents cancels all queued jobs that depend on jobID, e.g. after it failed
+func (m *Manager) CancelDependents(jobID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.cascadeCancelDependents(jobID)
+}
+
+// cascadeCancelDependents cancels all queued jobs that depend on jobID, directly or
+// through other cancelled jobs. It walks the dependents index breadth-first and visits
+// each job once, so a job reached along several paths (a diamond) is cancelled once,
+// naming the first cancelled dependency it was reached from (caller must hold the lock).
+func (m *Manager) cascadeCancelDependents(jobID string) {
+	visited := map[string]bool{jobID: true}
+	queue := []string{jobID}
+
+	for len(queue) > 0 {
+		cause := queue[0]
+		queue = queue[1:]
+
+		for _, depJobID := range m.dependentIDs(cause) {
+			if visited[depJobID] {
+				continue
+			}
 
-				// Recursively cancel its dependents
-				m.cascadeCancelDependents(depJobID)
-				break
+			// Jobs that run regardless of how their dependencies ended are left for the worker
+			meta, ok := m.jobIndex[depJobID]
+			if !ok || meta.Status != StatusQueued || meta.RunOnDependencyFailure {
+				continue
+			}
+			visited[depJobID] = true
+
+			if m.cancelDependent(depJobID, cause) {
+				queue = append(queue, depJobID)
 			}
 		}
 	}
 }
 
+// cancelDependent marks a queued job cancelled because its dependency cause was, and
+// reports whether it was (caller must hold the lock)
+func (m *Manager) cancelDependent(jobID, cause string) bool {
+	job, err := m.getJob(jobID)
+	if err != nil {
+		return false
+	}
+
+	job.Status = StatusCancelled
+	job.Error = fmt.Sprintf("dependency cancelled: %s", cause)
+	now := time.Now().UTC()
+	job.CompletedAt = &now
+
+	if err := m.writeJobMetadata(job); err != nil {
+		m.logger.Error("failed to write job metadata during cascade", "job_id", jobID, "error", err)
+		return false
+	}
+
+	if err := m.appendEvent(jobID, Event{
+		Timestamp: now,
+		Type:      "info",
+		Message:   fmt.Sprintf("Job cancelled due to dependency cancellation: %s", cause),
+	}); err != nil {
+		m.logger.Error("failed to append event during cascade", "job_id", jobID, "error", err)
+	}
+
+	m.logger.Info("job cascade cancelled", "job_id", jobID, "due_to", cause)
+	m.notifyCallback(job)
+	return true
+}
+
 // Delete deletes a job (only if not running)
 func (m *Manager) Delete(jobID string) error {
 	m.mu.Lock()
@@ -451,30 +733,44 @@ func (m *Manager) Delete(jobID string) error {
 		return fmt.Errorf("failed to delete job directory: %w", err)
 	}
 
+	if job.IdempotencyKey != "" {
+		delete(m.idempotencyIndex, idempotencyScope(job.Command.Type, job.IdempotencyKey))
+	}
+	m.unindexTags(job)
+	m.unindexJob(jobID)
+	delete(m.eventCounts, jobID)
+
 	m.logger.Info("job deleted", "job_id", jobID)
 
 	return nil
 }
 
+// CountFailedSince returns the number of jobs that failed at or after the given time
+func (m *Manager) CountFailedSince(since time.Time) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, meta := range m.jobIndex {
+		if meta.Status == StatusFailed && meta.CompletedAt != nil && !meta.CompletedAt.Before(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
 // GetQueued returns all queued jobs in topological order
 func (m *Manager) GetQueued() ([]*Job, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	entries, err := os.ReadDir(m.jobsDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read jobs directory: %w", err)
-	}
+	queued := m.indexedJobIDs(func(meta *jobMeta) bool { return meta.Status == StatusQueued })
 
 	var jobs []*Job
 	jobMap := make(map[string]*Job)
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		jobID := entry.Name()
+	for _, jobID := range queued {
 		job, err := m.getJob(jobID)
 		if err != nil {
 			m.logger.Error("failed to read job", "job_id", jobID, "error", err)
@@ -553,8 +849,52 @@ func (m *Manager) UpdateStatus(jobID string, status JobStatus, startedAt, comple
 	job.CompletedAt = completedAt
 	job.Result = result
 	job.Error = errMsg
+	if m.redact != nil {
+		job.Error = m.redact(errMsg)
+	}
 
-	return m.writeJobMetadata(job)
+	if err := m.writeJobMetadata(job); err != nil {
+		return err
+	}
+
+	if isTerminal(status) {
+		if startedAt != nil && completedAt != nil {
+			metrics.ObserveJob(string(job.Command.Type), string(status), completedAt.Sub(*startedAt))
+		}
+		m.notifyCallback(job)
+	}
+	return nil
+}
+
+// Stats counts jobs by command type and status and measures the queue for the metrics
+func (m *Manager) Stats() metrics.QueueStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var stats metrics.QueueStats
+	type key struct {
+		command CommandType
+		status  JobStatus
+	}
+	counts := make(map[key]int)
+	var oldest time.Time
+	for _, meta := range m.jobIndex {
+		counts[key{meta.CommandType, meta.Status}]++
+		if meta.Status == StatusQueued {
+			stats.Depth++
+			if oldest.IsZero() || meta.CreatedAt.Before(oldest) {
+				oldest = meta.CreatedAt
+			}
+		}
+	}
+
+	for k, count := range counts {
+		stats.Jobs = append(stats.Jobs, metrics.JobCount{Command: string(k.command), Status: string(k.status), Count: count})
+	}
+	if !oldest.IsZero() {
+		stats.OldestQueuedAge = time.Since(oldest)
+	}
+	return stats
 }
 
 // UpdateDependencies updates a job's DependsOn field
@@ -571,6 +911,33 @@ func (m *Manager) UpdateDependencies(jobID string, dependsOn []string) error {
 	return m.writeJobMetadata(job)
 }
 
+// SetRedactor sets the function that hides secrets, such as git tokens, in event messages
+// and job errors before they are written. Call it before the worker starts.
+func (m *Manager) SetRedactor(redact func(string) string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.redact = redact
+}
+
+// redactEvent hides secrets in an event's message and string data (caller must hold the lock)
+func (m *Manager) redactEvent(event Event) Event {
+	if m.redact == nil {
+		return event
+	}
+	event.Message = m.redact(event.Message)
+	switch data := event.Data.(type) {
+	case map[string]string:
+		redacted := make(map[string]string, len(data))
+		for k, v := range data {
+			redacted[k] = m.redact(v)
+		}
+		event.Data = redacted
+	case string:
+		event.Data = m.redact(data)
+	}
+	return event
+}
+
 // AppendEvent appends an event to a job's event log
 func (m *Manager) AppendEvent(jobID string, event Event) error {
 	m.mu.Lock()
@@ -595,16 +962,22 @@ func (m *Manager) appendEvent(jobID string, event Event) error {
 	defer file.Close()
 
 	// Write event as JSON line
-	data, err := json.Marshal(event)
+	data, err := json.Marshal(m.redactEvent(event))
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	_, err = file.Write(append(data, '\n'))
-	return err
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	m.trackEventCount(jobID)
+
+	m.notify(jobID)
+	return nil
 }
 
-// writeJobMetadata writes job metadata to disk (caller must handle locking)
+// writeJobMetadata writes job metadata to disk and updates the job index (caller must
+// handle locking)
 func (m *Manager) writeJobMetadata(job *Job) error {
 	jobPath := m.jobFile(job.ID)
 
@@ -627,6 +1000,8 @@ func (m *Manager) writeJobMetadata(job *Job) error {
 	if err := os.Rename(tmpPath, jobPath); err != nil {
 		return fmt.Errorf("failed to rename job file: %w", err)
 	}
+	m.indexJob(job)
 
+	m.notify(job.ID)
 	return nil
 }
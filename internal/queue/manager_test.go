@@ -0,0 +1,128 @@
+package queue
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	m, err := NewManager(t.TempDir(), 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func mustEnqueue(t *testing.T, m *Manager, dependsOn ...string) string {
+	t.Helper()
+	jobID, err := m.Enqueue(Command{Type: CmdInstallModule, Args: map[string]interface{}{}}, dependsOn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jobID
+}
+
+func TestCancelDiamondDependents(t *testing.T) {
+	m := newTestManager(t)
+
+	// A <- B, A <- C, B and C <- D
+	a := mustEnqueue(t, m)
+	b := mustEnqueue(t, m, a)
+	c := mustEnqueue(t, m, a)
+	d := mustEnqueue(t, m, b, c)
+
+	if err := m.Cancel(a); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{b, c, d} {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != StatusCancelled {
+			t.Errorf("job %s is %s, want cancelled", id, job.Status)
+		}
+
+		cancellations := 0
+		for _, event := range job.Events {
+			if strings.HasPrefix(event.Message, "Job cancelled due to dependency cancellation") {
+				cancellations++
+			}
+		}
+		if cancellations != 1 {
+			t.Errorf("job %s has %d cancellation events, want 1", id, cancellations)
+		}
+	}
+
+	job, err := m.Get(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Error != "dependency cancelled: "+b && job.Error != "dependency cancelled: "+c {
+		t.Errorf("job D error = %q, want it to name B or C", job.Error)
+	}
+}
+
+func TestCancelDependentsSkipsRunOnDependencyFailure(t *testing.T) {
+	m := newTestManager(t)
+
+	a := mustEnqueue(t, m)
+	meta, _, err := m.EnqueueWithOptions(Command{Type: CmdInstallModule, Args: map[string]interface{}{}}, EnqueueOptions{
+		DependsOn:              []string{a},
+		RunOnDependencyFailure: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Cancel(a); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := m.Get(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != StatusQueued {
+		t.Errorf("meta-job is %s, want it left queued for the worker", job.Status)
+	}
+}
+
+func TestListServedFromIndex(t *testing.T) {
+	m := newTestManager(t)
+	a := mustEnqueue(t, m)
+	b := mustEnqueue(t, m, a)
+
+	// Lists come from the job index, so they don't depend on the job files
+	if err := os.WriteFile(m.jobFile(a), []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := m.ListAllTopoSorted(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != a || jobs[1].ID != b {
+		t.Fatalf("listed %v, want a before b", jobs)
+	}
+	for _, job := range jobs {
+		if job.Events == nil || len(job.Events) != 0 {
+			t.Errorf("job %s has events %v without asking for them", job.ID, job.Events)
+		}
+	}
+
+	jobs, err = m.ListAll(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range jobs {
+		if len(job.Events) != 1 {
+			t.Errorf("job %s has %d events, want the enqueue event", job.ID, len(job.Events))
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+
+	"zeropoint-agent/internal/metrics"
+)
+
+// recoveryPolicy is what startup recovery does with a job that was cut off midway
+type recoveryPolicy string
+
+const (
+	recoverRequeue recoveryPolicy = "requeue" // Run again from the start
+	recoverFail    recoveryPolicy = "fail"    // Fail with the reason; a person decides whether to rerun
+)
+
+// commandRecovery lists the recovery policy of every command type. A command is retry-safe
+// when running it again from the start converges to the same result: terraform applies
+// and destroys are declarative, exposure and link changes are keyed by ID, and a catalog
+// sync just pulls again. Module upgrades swap revisions with a backup, the bundle
+// meta-jobs record outcomes, and commands run in module containers may not be idempotent,
+// so repeating them from an unknown point is not safe. Commands missing from the table fail.
+var commandRecovery = map[CommandType]recoveryPolicy{
+	CmdInstallModule:   recoverRequeue,
+	CmdUninstallModule: recoverRequeue,
+	CmdUpgradeModule:   recoverFail,
+	CmdUpdateResources: recoverRequeue,
+	CmdRestartModule:   recoverRequeue,
+	CmdStopModule:      recoverRequeue,
+	CmdStartModule:     recoverRequeue,
+	CmdModuleExec:      recoverFail,
+	CmdCreateExposure:  recoverRequeue,
+	CmdCreateExposures: recoverRequeue,
+	CmdDeleteExposure:  recoverRequeue,
+	CmdCreateLink:      recoverRequeue,
+	CmdDeleteLink:      recoverRequeue,
+	CmdUpdateLink:      recoverRequeue,
+	CmdBundleInstall:   recoverFail,
+	CmdBundleUninstall: recoverFail,
+	CmdBundleRollback:  recoverFail,
+	CmdBundleUpgrade:   recoverFail,
+	CmdSyncCatalog:     recoverRequeue,
+}
+
+// recoveryPolicyFor returns the recovery policy of a command type
+func recoveryPolicyFor(cmdType CommandType) recoveryPolicy {
+	if policy, ok := commandRecovery[cmdType]; ok {
+		return policy
+	}
+	return recoverFail
+}
+
+// RecoverInterrupted handles jobs left behind by the previous run: jobs marked interrupted
+// by a shutdown that outlasted the grace period, and jobs still marked running because the
+// agent crashed or lost power. Each job gets an event saying why it stopped and is then
+// requeued or failed according to commandRecovery; failing cancels its dependents. Call it
+// once at startup, before the worker starts.
+func (m *Manager) RecoverInterrupted() (requeued, failed int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stopped := m.indexedJobIDs(func(meta *jobMeta) bool {
+		return meta.Status == StatusInterrupted || meta.Status == StatusRunning
+	})
+
+	for _, jobID := range stopped {
+		job, err := m.getJob(jobID)
+		if err != nil {
+			continue
+		}
+		if job.Status != StatusInterrupted && job.Status != StatusRunning {
+			continue
+		}
+
+		reason := "interrupted by agent shutdown"
+		message := "Agent shut down before the job finished"
+		if job.Status == StatusRunning {
+			reason = "interrupted by agent restart"
+			message = "Agent restarted while job was running"
+		}
+		if err := m.appendEvent(job.ID, Event{
+			Timestamp: time.Now().UTC(),
+			Type:      "warning",
+			Message:   message,
+		}); err != nil {
+			m.logger.Error("failed to append event", "job_id", job.ID, "error", err)
+		}
+
+		if recoveryPolicyFor(job.Command.Type) == recoverRequeue {
+			job.Status = StatusQueued
+			job.StartedAt = nil
+			job.CompletedAt = nil
+			job.Result = nil
+			job.Error = ""
+			if err := m.writeJobMetadata(job); err != nil {
+				m.logger.Error("failed to requeue interrupted job", "job_id", job.ID, "error", err)
+				continue
+			}
+			if err := m.appendEvent(job.ID, Event{
+				Timestamp: time.Now().UTC(),
+				Type:      "info",
+				Message:   "Job requeued: the command is safe to run again",
+			}); err != nil {
+				m.logger.Error("failed to append event", "job_id", job.ID, "error", err)
+			}
+			m.logger.Info("requeued interrupted job", "job_id", job.ID, "command", job.Command.Type)
+			requeued++
+			continue
+		}
+
+		now := time.Now().UTC()
+		job.Status = StatusFailed
+		job.CompletedAt = &now
+		job.Error = fmt.Sprintf("%s; %s is not safe to repeat automatically, rerun it once the affected components have been checked", reason, job.Command.Type)
+		if err := m.writeJobMetadata(job); err != nil {
+			m.logger.Error("failed to fail interrupted job", "job_id", job.ID, "error", err)
+			continue
+		}
+		if err := m.appendEvent(job.ID, Event{
+			Timestamp: now,
+			Type:      "error",
+			Message:   fmt.Sprintf("Job failed: %s", job.Error),
+		}); err != nil {
+			m.logger.Error("failed to append event", "job_id", job.ID, "error", err)
+		}
+		if job.StartedAt != nil {
+			metrics.ObserveJob(string(job.Command.Type), string(StatusFailed), now.Sub(*job.StartedAt))
+		}
+		m.logger.Warn("failed interrupted job", "job_id", job.ID, "command", job.Command.Type)
+		m.notifyCallback(job)
+		m.cascadeCancelDependents(job.ID)
+		failed++
+	}
+
+	return requeued, failed
+}
@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecoveryPolicyPerCommand(t *testing.T) {
+	want := map[CommandType]recoveryPolicy{
+		CmdInstallModule:   recoverRequeue,
+		CmdUninstallModule: recoverRequeue,
+		CmdUpgradeModule:   recoverFail,
+		CmdUpdateResources: recoverRequeue,
+		CmdRestartModule:   recoverRequeue,
+		CmdStopModule:      recoverRequeue,
+		CmdStartModule:     recoverRequeue,
+		CmdModuleExec:      recoverFail,
+		CmdCreateExposure:  recoverRequeue,
+		CmdCreateExposures: recoverRequeue,
+		CmdDeleteExposure:  recoverRequeue,
+		CmdCreateLink:      recoverRequeue,
+		CmdDeleteLink:      recoverRequeue,
+		CmdUpdateLink:      recoverRequeue,
+		CmdBundleInstall:   recoverFail,
+		CmdBundleUninstall: recoverFail,
+		CmdBundleRollback:  recoverFail,
+		CmdBundleUpgrade:   recoverFail,
+		CmdSyncCatalog:     recoverRequeue,
+		"unknown_command":  recoverFail,
+	}
+	for cmdType, policy := range want {
+		if got := recoveryPolicyFor(cmdType); got != policy {
+			t.Errorf("%s: policy %s, want %s", cmdType, got, policy)
+		}
+	}
+	for cmdType := range commandRecovery {
+		if _, ok := want[cmdType]; !ok {
+			t.Errorf("%s has a recovery policy but no expectation in this test", cmdType)
+		}
+	}
+}
+
+// markRunning moves a queued job to running the way the worker does
+func markRunning(t *testing.T, m *Manager, jobID string) {
+	t.Helper()
+	now := time.Now().UTC()
+	if err := m.UpdateStatus(jobID, StatusRunning, &now, nil, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecoverInterrupted(t *testing.T) {
+	m := newTestManager(t)
+
+	install := mustEnqueue(t, m)
+	upgrade, err := m.Enqueue(Command{Type: CmdUpgradeModule, Args: map[string]interface{}{}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	afterUpgrade := mustEnqueue(t, m, upgrade)
+	markRunning(t, m, install)
+	markRunning(t, m, upgrade)
+
+	requeued, failed := m.RecoverInterrupted()
+	if requeued != 1 || failed != 1 {
+		t.Fatalf("requeued %d and failed %d jobs, want 1 and 1", requeued, failed)
+	}
+
+	cases := []struct {
+		jobID  string
+		status JobStatus
+	}{
+		{install, StatusQueued},
+		{upgrade, StatusFailed},
+		{afterUpgrade, StatusCancelled},
+	}
+	for _, tc := range cases {
+		job, err := m.Get(tc.jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != tc.status {
+			t.Errorf("job %s (%s) is %s, want %s", tc.jobID, job.Command.Type, job.Status, tc.status)
+		}
+	}
+
+	job, err := m.Get(install)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted := false
+	for _, event := range job.Events {
+		if event.Message == "Agent restarted while job was running" {
+			restarted = true
+		}
+	}
+	if !restarted {
+		t.Error("requeued job has no restart event")
+	}
+}
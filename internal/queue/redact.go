@@ -0,0 +1,77 @@
+package queue
+
+import "strings"
+
+const redactedArg = "[REDACTED]"
+
+// Job args that hold secrets. They stay in job.json for the executor, but API responses
+// and callback payloads carry a redacted copy of each.
+var redactedArgs = map[string]func(interface{}) interface{}{
+	"auth_users": redactAuthUsers,
+	"env":        redactEnv,
+}
+
+// redactCommand returns cmd with the secrets in its args replaced, leaving cmd untouched
+func redactCommand(cmd Command) Command {
+	var args map[string]interface{}
+	for key, value := range cmd.Args {
+		redact, ok := redactedArgs[key]
+		if !ok {
+			continue
+		}
+		if args == nil {
+			args = make(map[string]interface{}, len(cmd.Args))
+			for k, v := range cmd.Args {
+				args[k] = v
+			}
+		}
+		args[key] = redact(value)
+	}
+
+	if args != nil {
+		cmd.Args = args
+	}
+	return cmd
+}
+
+// redactAuthUsers keeps the user names of htpasswd entries and drops their hashes
+func redactAuthUsers(value interface{}) interface{} {
+	var entries []string
+	switch v := value.(type) {
+	case []string:
+		entries = v
+	case []interface{}:
+		for _, entry := range v {
+			if s, ok := entry.(string); ok {
+				entries = append(entries, s)
+			}
+		}
+	default:
+		return redactedArg
+	}
+
+	redacted := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		user, _, _ := strings.Cut(entry, ":")
+		redacted = append(redacted, user+":"+redactedArg)
+	}
+	return redacted
+}
+
+// redactEnv keeps the variable names of a module_exec environment and drops their values
+func redactEnv(value interface{}) interface{} {
+	redacted := make(map[string]string)
+	switch v := value.(type) {
+	case map[string]string:
+		for name := range v {
+			redacted[name] = redactedArg
+		}
+	case map[string]interface{}:
+		for name := range v {
+			redacted[name] = redactedArg
+		}
+	default:
+		return redactedArg
+	}
+	return redacted
+}
@@ -0,0 +1,35 @@
+package queue
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedactCommand(t *testing.T) {
+	cmd := Command{
+		Type: CmdModuleExec,
+		Args: map[string]interface{}{
+			"module_id": "db",
+			"argv":      []string{"psql"},
+			"env":       map[string]interface{}{"PGPASSWORD": "hunter2"},
+		},
+	}
+
+	redacted := redactCommand(cmd)
+	if got := redacted.Args["env"]; !reflect.DeepEqual(got, map[string]string{"PGPASSWORD": redactedArg}) {
+		t.Errorf("env = %v, want the value redacted", got)
+	}
+	if redacted.Args["module_id"] != "db" {
+		t.Errorf("module_id = %v, want it kept", redacted.Args["module_id"])
+	}
+	if cmd.Args["env"].(map[string]interface{})["PGPASSWORD"] != "hunter2" {
+		t.Error("redactCommand modified the original args")
+	}
+
+	users := redactCommand(Command{Args: map[string]interface{}{
+		"auth_users": []interface{}{"alice:$2y$10$abcdefghijklmnopqrstuv"},
+	}})
+	if got := users.Args["auth_users"]; !reflect.DeepEqual(got, []string{"alice:" + redactedArg}) {
+		t.Errorf("auth_users = %v, want the hash redacted", got)
+	}
+}
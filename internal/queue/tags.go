@@ -0,0 +1,95 @@
+package queue
+
+import (
+	"sort"
+	"strings"
+)
+
+// indexTags records the tags of a job (caller must hold the lock). Tags are fixed at
+// enqueue time, so a job is only indexed once and unindexed when it is deleted.
+func (m *Manager) indexTags(job *Job) {
+	for _, tag := range job.Tags {
+		if m.tagIndex[tag] == nil {
+			m.tagIndex[tag] = make(map[string]bool)
+		}
+		m.tagIndex[tag][job.ID] = true
+	}
+}
+
+// unindexTags drops a deleted job from the tag lookup (caller must hold the lock)
+func (m *Manager) unindexTags(job *Job) {
+	for _, tag := range job.Tags {
+		delete(m.tagIndex[tag], job.ID)
+		if len(m.tagIndex[tag]) == 0 {
+			delete(m.tagIndex, tag)
+		}
+	}
+}
+
+// jobIDsWithAllTags returns the IDs of jobs carrying every one of the tags, sorted (caller
+// must hold the lock)
+func (m *Manager) jobIDsWithAllTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	var ids []string
+	for id := range m.tagIndex[tags[0]] {
+		matches := true
+		for _, tag := range tags[1:] {
+			if !m.tagIndex[tag][id] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ListWithTags returns the jobs carrying every one of the tags, in topological order like
+// ListAllTopoSorted. Jobs come from the job index; events are only read when withEvents is
+// set.
+func (m *Manager) ListWithTags(tags []string, withEvents bool) ([]JobResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var jobIDs []string
+	for _, jobID := range m.jobIDsWithAllTags(tags) {
+		if _, ok := m.jobIndex[jobID]; ok {
+			jobIDs = append(jobIDs, jobID)
+		}
+	}
+	return m.topoSortedResponses(jobIDs, withEvents), nil
+}
+
+// mergeTags appends the tags in extra that tags does not hold yet
+func mergeTags(tags, extra []string) []string {
+	for _, tag := range extra {
+		found := false
+		for _, existing := range tags {
+			if existing == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// parseTagsQuery splits a comma-separated tags query parameter, dropping empty entries
+func parseTagsQuery(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = mergeTags(tags, []string{tag})
+		}
+	}
+	return tags
+}
@@ -13,6 +13,10 @@ const (
 	StatusCompleted JobStatus = "completed"
 	StatusFailed    JobStatus = "failed"
 	StatusCancelled JobStatus = "cancelled"
+
+	// StatusInterrupted marks a job that was still running when the agent shut down. It
+	// only lasts until the next start, when recovery requeues or fails the job.
+	StatusInterrupted JobStatus = "interrupted"
 )
 
 // CommandType represents the type of command to execute
@@ -21,12 +25,23 @@ type CommandType string
 const (
 	CmdInstallModule   CommandType = "install_module"
 	CmdUninstallModule CommandType = "uninstall_module"
+	CmdUpgradeModule   CommandType = "upgrade_module"
+	CmdUpdateResources CommandType = "update_module_resources" // Store new resource limits and re-apply the module
+	CmdRestartModule   CommandType = "restart_module"          // Restart a module's containers without touching terraform
+	CmdStopModule      CommandType = "stop_module"
+	CmdStartModule     CommandType = "start_module"
+	CmdModuleExec      CommandType = "module_exec" // Run a one-off command inside a module container
 	CmdCreateExposure  CommandType = "create_exposure"
+	CmdCreateExposures CommandType = "create_exposures" // Creates several exposures with a single routing update
 	CmdDeleteExposure  CommandType = "delete_exposure"
 	CmdCreateLink      CommandType = "create_link"
 	CmdDeleteLink      CommandType = "delete_link"
+	CmdUpdateLink      CommandType = "update_link"      // Partial link update: add, rebind or detach modules
 	CmdBundleInstall   CommandType = "bundle_install"   // Meta-job that orchestrates bundle installation
 	CmdBundleUninstall CommandType = "bundle_uninstall" // Meta-job that orchestrates bundle uninstallation
+	CmdBundleRollback  CommandType = "bundle_rollback"  // Meta-job that records the outcome of undoing a failed bundle installation
+	CmdBundleUpgrade   CommandType = "bundle_upgrade"   // Meta-job that records a bundle upgrade once its component jobs succeed
+	CmdSyncCatalog     CommandType = "sync_catalog"     // Refresh the catalog from the remote index
 )
 
 // Command represents a queued command to execute
@@ -47,12 +62,24 @@ type Job struct {
 	CompletedAt *time.Time  `json:"completed_at,omitempty"`
 	Result      interface{} `json:"result,omitempty"`
 	Error       string      `json:"error,omitempty"`
+
+	IdempotencyKey string `json:"idempotency_key,omitempty"` // Client-supplied key used to deduplicate enqueues
+
+	// Run once every dependency has finished, even if some failed or were cancelled,
+	// instead of being cancelled along with them
+	RunOnDependencyFailure bool `json:"run_on_dependency_failure,omitempty"`
+
+	RerunOf string `json:"rerun_of,omitempty"` // ID of the failed or cancelled job this one reruns
+
+	CallbackURL string `json:"callback_url,omitempty"` // Receives the final job once it completes, fails or is cancelled
+
+	ArchivedEvents int `json:"archived_events,omitempty"` // Log events compacted into events.archive.jsonl.gz
 }
 
 // Event represents a single event in a job's execution
 type Event struct {
 	Timestamp time.Time   `json:"timestamp"`
-	Type      string      `json:"type"` // "info", "progress", "error", "warning"
+	Type      string      `json:"type"` // "info", "progress", "error", "warning", "log", "truncated"
 	Message   string      `json:"message"`
 	Data      interface{} `json:"data,omitempty"`
 }
@@ -63,22 +90,42 @@ type JobResponse struct {
 	Status      JobStatus   `json:"status"`
 	Command     Command     `json:"command"`
 	DependsOn   []string    `json:"depends_on"`
-	Tags        []string    `json:"tags,omitempty"` // Tags associated with this job (e.g., bundle name)
+	Tags        []string    `json:"tags"` // Always present; empty when the job has no tags
 	CreatedAt   time.Time   `json:"created_at"`
 	StartedAt   *time.Time  `json:"started_at,omitempty"`
 	CompletedAt *time.Time  `json:"completed_at,omitempty"`
 	Result      interface{} `json:"result,omitempty"`
 	Error       string      `json:"error,omitempty"`
-	Events      []Event     `json:"events"`
+	Events      []Event     `json:"events"` // Always present; only the most recent in job lists, omitted with fields=summary
+
+	IdempotencyKey         string `json:"idempotency_key,omitempty"`
+	RunOnDependencyFailure bool   `json:"run_on_dependency_failure,omitempty"`
+	RerunOf                string `json:"rerun_of,omitempty"`
+	CallbackURL            string `json:"callback_url,omitempty"`
+	ArchivedEvents         int    `json:"archived_events,omitempty"` // Log events left out unless full=true
 }
 
 // EnqueueRequest is the base for operation-specific enqueue requests
 // Specific operations (install, expose, etc) add this as an embedded field
 type EnqueueRequest struct {
-	DependsOn []string `json:"depends_on,omitempty"`
+	DependsOn     []string `json:"depends_on,omitempty"`
+	DependsOnTags []string `json:"depends_on_tags,omitempty"`
 }
 
 // ListJobsResponse is the response for listing jobs
 type ListJobsResponse struct {
-	Jobs []JobResponse `json:"jobs"`
+	Jobs  []JobResponse `json:"jobs"`
+	Total int           `json:"total"` // Jobs matching the status filter, before limit/offset
+}
+
+// jobSummary is a job listed with fields=summary: a JobResponse without the events key
+type jobSummary struct {
+	JobResponse
+	Events []Event `json:"events,omitempty"` // Never set; hides JobResponse.Events
+}
+
+// listJobSummariesResponse is ListJobsResponse for fields=summary
+type listJobSummariesResponse struct {
+	Jobs  []jobSummary `json:"jobs"`
+	Total int          `json:"total"`
 }
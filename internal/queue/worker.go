@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 )
 
@@ -12,6 +13,9 @@ type Executor interface {
 	ExecuteWithJob(ctx context.Context, jobID string, manager *Manager, cmd Command) (interface{}, error)
 }
 
+// interruptWait is how long Drain waits for an interrupted executor to return
+const interruptWait = 5 * time.Second
+
 // Worker processes queued jobs in topological order
 type Worker struct {
 	manager  *Manager
@@ -19,6 +23,14 @@ type Worker struct {
 	logger   *slog.Logger
 	stop     chan struct{}
 	done     chan struct{}
+	stopOnce sync.Once
+
+	// Tracks the job currently executing so shutdown can interrupt it
+	mu          sync.Mutex
+	currentJob  string
+	startedAt   time.Time
+	cancelJob   context.CancelFunc
+	interrupted bool
 }
 
 // NewWorker creates a new job worker
@@ -32,17 +44,69 @@ func NewWorker(manager *Manager, executor Executor, logger *slog.Logger) *Worker
 	}
 }
 
-// Start begins the worker loop in a goroutine
+// Start recovers jobs interrupted by the previous run, then begins the worker loop in a
+// goroutine. Cancelling ctx stops the loop and cancels the running job.
 func (w *Worker) Start(ctx context.Context) {
+	if requeued, failed := w.manager.RecoverInterrupted(); requeued > 0 || failed > 0 {
+		w.logger.Info("recovered interrupted jobs", "requeued", requeued, "failed", failed)
+	}
 	go w.run(ctx)
 }
 
 // Stop signals the worker to stop processing
 func (w *Worker) Stop() {
-	close(w.stop)
+	w.stopOnce.Do(func() { close(w.stop) })
 	<-w.done
 }
 
+// Drain stops dequeuing new jobs and waits up to grace for the running job to finish.
+// If the job is still running after the grace period, its execution context is cancelled
+// and it is marked interrupted, so RecoverInterrupted requeues or fails it on the next
+// start. Returns true if the worker stopped cleanly within the grace period.
+func (w *Worker) Drain(grace time.Duration) bool {
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	select {
+	case <-w.done:
+		w.logger.Info("worker drained")
+		return true
+	case <-time.After(grace):
+	}
+
+	w.mu.Lock()
+	jobID, startedAt := w.currentJob, w.startedAt
+	if jobID == "" {
+		w.mu.Unlock()
+		return true
+	}
+	w.interrupted = true
+	w.cancelJob()
+	w.mu.Unlock()
+
+	w.logger.Warn("job still running after grace period, interrupting", "job_id", jobID, "grace", grace)
+
+	if err := w.manager.UpdateStatus(jobID, StatusInterrupted, &startedAt, nil, nil, "interrupted by agent shutdown"); err != nil {
+		w.logger.Error("failed to mark job interrupted", "job_id", jobID, "error", err)
+	}
+
+	if err := w.manager.AppendEvent(jobID, Event{
+		Timestamp: time.Now().UTC(),
+		Type:      "warning",
+		Message:   fmt.Sprintf("Job interrupted by shutdown after a %s grace period", grace),
+	}); err != nil {
+		w.logger.Error("failed to append event", "job_id", jobID, "error", err)
+	}
+
+	// Give the executor a moment to see the cancellation and kill its processes
+	select {
+	case <-w.done:
+	case <-time.After(interruptWait):
+		w.logger.Warn("executor did not return after interruption", "job_id", jobID)
+	}
+
+	return false
+}
+
 // run is the main worker loop
 func (w *Worker) run(ctx context.Context) {
 	defer close(w.done)
@@ -59,6 +123,13 @@ func (w *Worker) run(ctx context.Context) {
 			w.logger.Info("worker context cancelled")
 			return
 		case <-ticker.C:
+			// Don't pick up new work once a stop has been requested
+			select {
+			case <-w.stop:
+				w.logger.Info("worker stopping")
+				return
+			default:
+			}
 			w.processNextJob(ctx)
 		}
 	}
@@ -88,7 +159,8 @@ func (w *Worker) processNextJob(ctx context.Context) {
 	w.executeJob(ctx, job)
 }
 
-// dependenciesSatisfied checks if all dependencies of a job are completed
+// dependenciesSatisfied checks if all dependencies of a job are completed. Jobs flagged
+// RunOnDependencyFailure only need their dependencies to have finished.
 func (w *Worker) dependenciesSatisfied(job *Job) bool {
 	for _, depID := range job.DependsOn {
 		depJob, err := w.manager.Get(depID)
@@ -103,7 +175,7 @@ func (w *Worker) dependenciesSatisfied(job *Job) bool {
 		}
 
 		// If dependency failed or was cancelled, this job will be auto-cancelled
-		if depJob.Status == StatusFailed || depJob.Status == StatusCancelled {
+		if (depJob.Status == StatusFailed || depJob.Status == StatusCancelled) && !job.RunOnDependencyFailure {
 			// Auto-cancel this job as well
 			w.autoCancelDueToFailedDep(job.ID, depID, depJob.Status)
 			return false
@@ -131,7 +203,7 @@ func (w *Worker) autoCancelDueToFailedDep(jobID, depID string, depStatus JobStat
 	}
 
 	// Cascade cancellation to dependents
-	w.manager.cascadeCancelDependents(jobID)
+	w.manager.CancelDependents(jobID)
 }
 
 // executeJob runs a single job
@@ -153,14 +225,43 @@ func (w *Worker) executeJob(ctx context.Context, job *Job) {
 		w.logger.Error("failed to append event", "job_id", job.ID, "error", err)
 	}
 
-	// Execute the command
-	result, execErr := w.executor.ExecuteWithJob(ctx, job.ID, w.manager, job.Command)
+	// Execute the command with a context that shutdown can cancel
+	jobCtx, cancelJob := context.WithCancel(ctx)
+	defer cancelJob()
+
+	w.mu.Lock()
+	w.currentJob = job.ID
+	w.startedAt = now
+	w.cancelJob = cancelJob
+	w.mu.Unlock()
+	w.manager.setRunning(job.ID, cancelJob)
+
+	result, execErr := w.executor.ExecuteWithJob(jobCtx, job.ID, w.manager, job.Command)
+
+	forced := w.manager.clearRunning(job.ID)
+	w.mu.Lock()
+	interrupted := w.interrupted
+	w.currentJob = ""
+	w.cancelJob = nil
+	w.mu.Unlock()
+
+	// Job was marked interrupted by Drain; recovery handles it on the next start
+	if interrupted {
+		w.logger.Info("skipping status update for interrupted job", "job_id", job.ID)
+		return
+	}
 
 	// Mark job as completed or failed
 	completedTime := time.Now().UTC()
 	var status JobStatus
 	var errMsg string
 
+	if forced {
+		// Whatever the executor returned, the job was stopped on request
+		execErr = fmt.Errorf("cancelled by user (forced)")
+		result = nil
+	}
+
 	if execErr != nil {
 		status = StatusFailed
 		errMsg = execErr.Error()
@@ -175,7 +276,7 @@ func (w *Worker) executeJob(ctx context.Context, job *Job) {
 		}
 
 		// Cascade cancellation to dependents
-		w.manager.cascadeCancelDependents(job.ID)
+		w.manager.CancelDependents(job.ID)
 	} else {
 		status = StatusCompleted
 		w.logger.Info("job execution completed", "job_id", job.ID)
@@ -0,0 +1,42 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// DiskUsage describes the filesystem holding a path
+type DiskUsage struct {
+	Device         uint64 // Device ID of the filesystem; equal IDs mean a shared filesystem
+	TotalBytes     uint64
+	UsedBytes      uint64
+	AvailableBytes uint64 // Space available to unprivileged users
+}
+
+// GetDiskUsage returns usage of the filesystem that holds path
+func GetDiskUsage(path string) (*DiskUsage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil, fmt.Errorf("cannot determine device of %s", path)
+	}
+
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return nil, fmt.Errorf("statfs %s: %w", path, err)
+	}
+
+	blockSize := uint64(fs.Bsize)
+	total := fs.Blocks * blockSize
+	free := fs.Bfree * blockSize
+	return &DiskUsage{
+		Device:         uint64(stat.Dev),
+		TotalBytes:     total,
+		UsedBytes:      total - free,
+		AvailableBytes: fs.Bavail * blockSize,
+	}, nil
+}
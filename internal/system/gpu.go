@@ -3,11 +3,53 @@ package system
 import (
 	"os"
 	"os/exec"
+	"sync"
 )
 
-// DetectGPU detects the GPU vendor on the host system
-// Returns "nvidia", "amd", "intel", or "" if no GPU detected
+// GPU vendor detection state. The override is set once at startup, before DetectGPU runs.
+var (
+	gpuOverride    string
+	gpuOverridden  bool
+	gpuDetectOnce  sync.Once
+	gpuDetectedVal string
+)
+
+// SetGPUVendorOverride makes DetectGPU report vendor without probing the host, e.g. on
+// headless servers where the probe is slow or flaps. "none" reports no GPU; an empty
+// vendor keeps detection.
+func SetGPUVendorOverride(vendor string) {
+	switch vendor {
+	case "":
+		gpuOverride, gpuOverridden = "", false
+	case "none":
+		gpuOverride, gpuOverridden = "", true
+	default:
+		gpuOverride, gpuOverridden = vendor, true
+	}
+}
+
+// DetectGPU returns the GPU vendor of the host: "nvidia", "amd", "intel", or "" if no GPU
+// was detected. The host is probed once per process and the result reused.
 func DetectGPU() string {
+	if gpuOverridden {
+		return gpuOverride
+	}
+	gpuDetectOnce.Do(func() {
+		gpuDetectedVal = probeGPU()
+	})
+	return gpuDetectedVal
+}
+
+// GPUVendorSource reports where DetectGPU's answer comes from: "override" or "detected"
+func GPUVendorSource() string {
+	if gpuOverridden {
+		return "override"
+	}
+	return "detected"
+}
+
+// probeGPU looks for vendor tools and runtimes on the host
+func probeGPU() string {
 	// Check for NVIDIA GPU
 	if hasNvidiaGPU() {
 		return "nvidia"
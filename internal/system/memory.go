@@ -0,0 +1,57 @@
+package system
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MemoryInfo describes the host's physical memory
+type MemoryInfo struct {
+	TotalBytes     uint64
+	AvailableBytes uint64 // Memory available for new workloads without swapping
+}
+
+// GetMemoryInfo reads total and available memory from /proc/meminfo
+func GetMemoryInfo() (*MemoryInfo, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info := &MemoryInfo{}
+	found := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Lines look like "MemTotal:       16316412 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		var dst *uint64
+		switch fields[0] {
+		case "MemTotal:":
+			dst = &info.TotalBytes
+		case "MemAvailable:":
+			dst = &info.AvailableBytes
+		default:
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q in /proc/meminfo", fields[0], fields[1])
+		}
+		*dst = kb * 1024
+		found++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if found < 2 {
+		return nil, fmt.Errorf("MemTotal or MemAvailable missing from /proc/meminfo")
+	}
+	return info, nil
+}
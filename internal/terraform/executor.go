@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 
 	"github.com/hashicorp/terraform-exec/tfexec"
@@ -13,6 +15,8 @@ import (
 type Executor struct {
 	tf         *tfexec.Terraform
 	workingDir string
+	ctx        context.Context // Cancelling it kills the running terraform process
+	output     io.Writer       // Receives init/plan/apply/destroy output, if set
 }
 
 // OutputMeta represents metadata about a Terraform output
@@ -38,12 +42,41 @@ func NewExecutor(modulePath string) (*Executor, error) {
 	return &Executor{
 		tf:         tf,
 		workingDir: modulePath,
+		ctx:        context.Background(),
 	}, nil
 }
 
+// WithContext returns a copy of the executor whose terraform commands run under ctx.
+// If ctx carries an output writer (see ContextWithOutput), terraform's output goes to it.
+func (e *Executor) WithContext(ctx context.Context) *Executor {
+	copied := *e
+	copied.ctx = ctx
+	if w := outputFromContext(ctx); w != nil {
+		copied.output = w
+	}
+	return &copied
+}
+
+// SetOutput makes init, plan, apply and destroy write terraform's stdout and stderr to w.
+// Output and show are never streamed; their output is data and may hold sensitive values.
+func (e *Executor) SetOutput(w io.Writer) {
+	e.output = w
+}
+
+// stream attaches the output writer for the next command, or detaches it
+func (e *Executor) stream(enabled bool) {
+	var w io.Writer
+	if enabled {
+		w = e.output
+	}
+	e.tf.SetStdout(w)
+	e.tf.SetStderr(w)
+}
+
 // Init runs terraform init
 func (e *Executor) Init() error {
-	return e.tf.Init(context.Background())
+	e.stream(true)
+	return e.tf.Init(e.ctx)
 }
 
 // Plan runs terraform plan
@@ -58,7 +91,8 @@ func (e *Executor) Plan(outFile string, variables map[string]string) error {
 	}
 
 	// Run plan and check for errors
-	hasChanges, err := e.tf.Plan(context.Background(), opts...)
+	e.stream(true)
+	hasChanges, err := e.tf.Plan(e.ctx, opts...)
 	if err != nil {
 		return fmt.Errorf("terraform plan failed: %w", err)
 	}
@@ -69,6 +103,27 @@ func (e *Executor) Plan(outFile string, variables map[string]string) error {
 	return nil
 }
 
+// PlanText runs terraform plan and returns the human-readable plan
+func (e *Executor) PlanText(variables map[string]string) (string, error) {
+	planFile, err := os.CreateTemp("", "zeropoint-plan-*.tfplan")
+	if err != nil {
+		return "", fmt.Errorf("failed to create plan file: %w", err)
+	}
+	planFile.Close()
+	defer os.Remove(planFile.Name())
+
+	if err := e.Plan(planFile.Name(), variables); err != nil {
+		return "", err
+	}
+
+	e.stream(false)
+	plan, err := e.tf.ShowPlanFileRaw(e.ctx, planFile.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to render plan: %w", err)
+	}
+	return plan, nil
+}
+
 // Apply runs terraform apply
 func (e *Executor) Apply(variables map[string]string) error {
 	opts := []tfexec.ApplyOption{}
@@ -77,7 +132,8 @@ func (e *Executor) Apply(variables map[string]string) error {
 		opts = append(opts, tfexec.Var(k+"="+v))
 	}
 
-	return e.tf.Apply(context.Background(), opts...)
+	e.stream(true)
+	return e.tf.Apply(e.ctx, opts...)
 }
 
 // Destroy runs terraform destroy
@@ -88,12 +144,14 @@ func (e *Executor) Destroy(variables map[string]string) error {
 		opts = append(opts, tfexec.Var(k+"="+v))
 	}
 
-	return e.tf.Destroy(context.Background(), opts...)
+	e.stream(true)
+	return e.tf.Destroy(e.ctx, opts...)
 }
 
 // Output reads terraform outputs
 func (e *Executor) Output() (map[string]*OutputMeta, error) {
-	outputs, err := e.tf.Output(context.Background())
+	e.stream(false)
+	outputs, err := e.tf.Output(e.ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -113,7 +171,8 @@ func (e *Executor) Output() (map[string]*OutputMeta, error) {
 // Show reads the terraform plan JSON
 func (e *Executor) Show(planFile string) ([]byte, error) {
 	// Use ShowPlanFile to get the plan as a struct, then marshal to JSON
-	plan, err := e.tf.ShowPlanFile(context.Background(), planFile)
+	e.stream(false)
+	plan, err := e.tf.ShowPlanFile(e.ctx, planFile)
 	if err != nil {
 		return nil, fmt.Errorf("ShowPlanFile failed for %s: %w", planFile, err)
 	}
@@ -0,0 +1,96 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultLockTimeout is how long an operation waits for a module that another operation holds
+const defaultLockTimeout = 5 * time.Minute
+
+type ownerKey struct{}
+
+// ContextWithOwner returns a context that records owner (usually a job ID) as the holder of
+// any module lock taken with it, so operations that time out waiting can say who is in the way
+func ContextWithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// ownerFromContext returns the owner set by ContextWithOwner, if any
+func ownerFromContext(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+	return owner
+}
+
+// moduleLock serializes operations on one module directory
+type moduleLock struct {
+	held  chan struct{} // Buffered with capacity 1; full while the lock is held
+	owner string
+}
+
+var (
+	locksMu     sync.Mutex
+	moduleLocks = make(map[string]*moduleLock)
+	lockTimeout = defaultLockTimeout
+)
+
+// SetLockTimeout sets how long LockModule waits for another operation to release a module
+func SetLockTimeout(timeout time.Duration) {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+	lockTimeout = timeout
+}
+
+// LockModule takes the exclusive lock for a module directory, waiting up to the configured
+// timeout for another operation to release it. Terraform must not run twice in the same
+// directory: with local state the second run can corrupt it. The returned function releases
+// the lock.
+func LockModule(ctx context.Context, moduleDir string) (func(), error) {
+	key, err := filepath.Abs(moduleDir)
+	if err != nil {
+		key = filepath.Clean(moduleDir)
+	}
+
+	locksMu.Lock()
+	lock, ok := moduleLocks[key]
+	if !ok {
+		lock = &moduleLock{held: make(chan struct{}, 1)}
+		moduleLocks[key] = lock
+	}
+	timeout := lockTimeout
+	locksMu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case lock.held <- struct{}{}:
+	case <-timer.C:
+		locksMu.Lock()
+		owner := lock.owner
+		locksMu.Unlock()
+		if owner == "" {
+			return nil, fmt.Errorf("module %s is busy with another operation", filepath.Base(key))
+		}
+		return nil, fmt.Errorf("module %s is busy with another operation (job %s)", filepath.Base(key), owner)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	locksMu.Lock()
+	lock.owner = ownerFromContext(ctx)
+	locksMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			locksMu.Lock()
+			lock.owner = ""
+			locksMu.Unlock()
+			<-lock.held
+		})
+	}, nil
+}
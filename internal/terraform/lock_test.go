@@ -0,0 +1,83 @@
+package terraform
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setLockTimeout changes the lock wait for one test
+func setLockTimeout(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	locksMu.Lock()
+	previous := lockTimeout
+	lockTimeout = timeout
+	locksMu.Unlock()
+	t.Cleanup(func() {
+		locksMu.Lock()
+		lockTimeout = previous
+		locksMu.Unlock()
+	})
+}
+
+func TestLockModuleContention(t *testing.T) {
+	setLockTimeout(t, 50*time.Millisecond)
+	moduleDir := filepath.Join(t.TempDir(), "wiki")
+
+	// An install holds the module while a link tries to apply in the same directory
+	unlockInstall, err := LockModule(ContextWithOwner(context.Background(), "install-job"), moduleDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LockModule(ContextWithOwner(context.Background(), "link-job"), moduleDir+"/")
+	if err == nil {
+		t.Fatal("link took the module lock while the install held it")
+	}
+	if !strings.Contains(err.Error(), "module wiki is busy with another operation (job install-job)") {
+		t.Errorf("error %q does not name the module and the job holding it", err)
+	}
+
+	// Another module is not affected
+	unlockOther, err := LockModule(context.Background(), filepath.Join(filepath.Dir(moduleDir), "db"))
+	if err != nil {
+		t.Fatalf("lock on another module: %v", err)
+	}
+	unlockOther()
+
+	// The link gets the module once the install releases it
+	acquired := make(chan error, 1)
+	go func() {
+		unlockLink, err := LockModule(ContextWithOwner(context.Background(), "link-job"), moduleDir)
+		if err == nil {
+			unlockLink()
+		}
+		acquired <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	unlockInstall()
+	unlockInstall()
+
+	if err := <-acquired; err != nil {
+		t.Errorf("link waiting for the install: %v", err)
+	}
+}
+
+func TestLockModuleCancelled(t *testing.T) {
+	setLockTimeout(t, time.Minute)
+	moduleDir := t.TempDir()
+
+	unlock, err := LockModule(context.Background(), moduleDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := LockModule(ctx, moduleDir); err != context.DeadlineExceeded {
+		t.Errorf("error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
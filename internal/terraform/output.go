@@ -0,0 +1,73 @@
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+)
+
+type outputKey struct{}
+
+// ContextWithOutput returns a context that makes executors bound to it (see
+// Executor.WithContext) write terraform's stdout and stderr to w
+func ContextWithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, w)
+}
+
+// outputFromContext returns the writer set by ContextWithOutput, if any
+func outputFromContext(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(outputKey{}).(io.Writer)
+	return w
+}
+
+// LineWriter calls a function for every complete line written to it. It is safe for
+// concurrent use, so one LineWriter can take both stdout and stderr.
+type LineWriter struct {
+	mu     sync.Mutex
+	buf    []byte
+	onLine func(string)
+}
+
+// NewLineWriter creates a LineWriter that passes each line, without its newline, to onLine
+func NewLineWriter(onLine func(string)) *LineWriter {
+	return &LineWriter{onLine: onLine}
+}
+
+// Write buffers p and emits every line it completes
+func (l *LineWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.emit(l.buf[:i])
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush emits a trailing line that was not terminated by a newline
+func (l *LineWriter) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.buf) > 0 {
+		l.emit(l.buf)
+		l.buf = nil
+	}
+}
+
+// emit passes a line on, skipping blank ones (caller must hold the lock)
+func (l *LineWriter) emit(line []byte) {
+	text := strings.TrimRight(string(line), "\r")
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	l.onLine(text)
+}
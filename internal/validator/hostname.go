@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizeHostname trims and lowercases a hostname; DNS names are case-insensitive
+func NormalizeHostname(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ValidateHostname checks that a normalized name is an RFC 1123 hostname: dot-separated
+// labels of 1-63 letters, digits and hyphens that don't start or end with a hyphen, at
+// most 253 characters in all
+func ValidateHostname(name string) error {
+	if name == "" {
+		return fmt.Errorf("hostname cannot be empty")
+	}
+	if len(name) > 253 {
+		return fmt.Errorf("hostname %q is longer than 253 characters", name)
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return fmt.Errorf("hostname %q: labels must be 1-63 characters", name)
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("hostname %q: labels cannot start or end with a hyphen", name)
+		}
+		for _, ch := range label {
+			if (ch < 'a' || ch > 'z') && (ch < '0' || ch > '9') && ch != '-' {
+				return fmt.Errorf("hostname %q: only letters, digits, hyphens and dots are allowed", name)
+			}
+		}
+	}
+	return nil
+}
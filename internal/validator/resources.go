@@ -0,0 +1,27 @@
+package validator
+
+import (
+	"fmt"
+
+	"zeropoint-agent/internal/hcl"
+)
+
+// Resource limit variables the agent passes to modules that declare them
+const (
+	CPULimitVariable    = "zp_cpu_limit"
+	MemoryLimitVariable = "zp_memory_limit"
+	PIDsLimitVariable   = "zp_pids_limit"
+)
+
+// CheckResourceLimits returns a warning for each of the given resource limit variables a
+// module's inputs don't declare. Limits are optional in the contract, so these are not
+// validation errors, but a limit the module's terraform doesn't consume is not enforced.
+func CheckResourceLimits(inputs map[string]hcl.Variable, limits []string) []string {
+	var warnings []string
+	for _, name := range limits {
+		if _, declared := inputs[name]; !declared {
+			warnings = append(warnings, fmt.Sprintf("module does not declare variable '%s'; the limit is not enforced", name))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,49 @@
+package xds
+
+import (
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// SessionAffinity pins clients to one upstream by hashing a cookie or a request header.
+// Exactly one of Cookie and Header is set. Affinity only has an effect once an exposure's
+// cluster has more than one endpoint; with a single backend every request lands there anyway.
+type SessionAffinity struct {
+	Cookie    string        // Cookie name; Envoy sets it on the first response when missing
+	CookieTTL time.Duration // Lifetime of the generated cookie; zero makes it a session cookie
+	Header    string        // Request header to hash, e.g. "x-session-id"
+}
+
+// makeHashPolicy returns the route hash policy for the affinity
+func makeHashPolicy(a *SessionAffinity) []*route.RouteAction_HashPolicy {
+	if a.Header != "" {
+		return []*route.RouteAction_HashPolicy{
+			{
+				PolicySpecifier: &route.RouteAction_HashPolicy_Header_{
+					Header: &route.RouteAction_HashPolicy_Header{HeaderName: a.Header},
+				},
+			},
+		}
+	}
+	return []*route.RouteAction_HashPolicy{
+		{
+			PolicySpecifier: &route.RouteAction_HashPolicy_Cookie_{
+				Cookie: &route.RouteAction_HashPolicy_Cookie{
+					Name: a.Cookie,
+					// Envoy only generates the cookie when a TTL is set; zero yields a session cookie
+					Ttl:  durationpb.New(a.CookieTTL),
+					Path: "/",
+				},
+			},
+		},
+	}
+}
+
+// enableSessionAffinity switches the cluster to consistent hashing so the route's hash
+// policy decides the endpoint. Round-robin clusters ignore hash policies.
+func enableSessionAffinity(c *cluster.Cluster) {
+	c.LbPolicy = cluster.Cluster_RING_HASH
+}
@@ -0,0 +1,96 @@
+package xds
+
+import (
+	"strings"
+	"time"
+
+	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	extauthz "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	basicAuthFilterName = "envoy.filters.http.ext_authz"
+	basicAuthRealm      = `Basic realm="zeropoint"`
+
+	// Envoy reaches the agent's gRPC listener through the cluster its bootstrap config
+	// defines for xDS, so the basic auth check needs no extra cluster
+	basicAuthCluster = "xds_cluster"
+
+	// bcrypt verification is deliberately slow; allow for it on a cache miss
+	basicAuthTimeout = 2 * time.Second
+
+	// Context extension carrying a route's htpasswd entries to the check service
+	basicAuthUsersKey = "basic_auth_users"
+)
+
+// makeBasicAuthFilter returns the external authorization filter that checks basic auth
+// credentials against the agent, disabled by default. Routes of exposures with users
+// enable it through their per-route config. Envoy's own basic_auth filter only verifies
+// SHA-1 hashes, so bcrypt entries are checked by the agent instead.
+func makeBasicAuthFilter() *hcm.HttpFilter {
+	return &hcm.HttpFilter{
+		Name:     basicAuthFilterName,
+		Disabled: true,
+		ConfigType: &hcm.HttpFilter_TypedConfig{
+			TypedConfig: mustMarshalAny(&extauthz.ExtAuthz{
+				TransportApiVersion: core.ApiVersion_V3,
+				Services: &extauthz.ExtAuthz_GrpcService{
+					GrpcService: &core.GrpcService{
+						TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
+							EnvoyGrpc: &core.GrpcService_EnvoyGrpc{ClusterName: basicAuthCluster},
+						},
+						Timeout: durationpb.New(basicAuthTimeout),
+					},
+				},
+			}),
+		},
+	}
+}
+
+// makeBasicAuthRouteConfig enables basic auth on a route for the given htpasswd entries,
+// which Envoy passes along with every check request for the route
+func makeBasicAuthRouteConfig(users []string) map[string]*anypb.Any {
+	return map[string]*anypb.Any{
+		basicAuthFilterName: mustMarshalAny(&extauthz.ExtAuthzPerRoute{
+			Override: &extauthz.ExtAuthzPerRoute_CheckSettings{
+				CheckSettings: &extauthz.CheckSettings{
+					ContextExtensions: map[string]string{basicAuthUsersKey: strings.Join(users, "\n")},
+				},
+			},
+		}),
+	}
+}
+
+// makeBasicAuthLocalReply adds a WWW-Authenticate challenge to the 401 Envoy sends for
+// missing or wrong credentials, so browsers prompt for them
+func makeBasicAuthLocalReply() *hcm.LocalReplyConfig {
+	return &hcm.LocalReplyConfig{
+		Mappers: []*hcm.ResponseMapper{
+			{
+				Filter: &accesslog.AccessLogFilter{
+					FilterSpecifier: &accesslog.AccessLogFilter_StatusCodeFilter{
+						StatusCodeFilter: &accesslog.StatusCodeFilter{
+							Comparison: &accesslog.ComparisonFilter{
+								Op: accesslog.ComparisonFilter_EQ,
+								Value: &core.RuntimeUInt32{
+									DefaultValue: 401,
+									RuntimeKey:   "zeropoint.basic_auth.status_code",
+								},
+							},
+						},
+					},
+				},
+				HeadersToAdd: []*core.HeaderValueOption{
+					{
+						Header:       &core.HeaderValue{Key: "WWW-Authenticate", Value: basicAuthRealm},
+						AppendAction: core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+					},
+				},
+			},
+		},
+	}
+}
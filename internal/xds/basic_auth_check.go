@@ -0,0 +1,111 @@
+package xds
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+	"sync"
+
+	authservice "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"golang.org/x/crypto/bcrypt"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+// Verified credentials remembered so bcrypt only runs once per user and password
+const basicAuthCacheSize = 1024
+
+// basicAuthChecker answers Envoy's external authorization checks for exposures behind
+// basic auth. The route's htpasswd entries arrive with each request as a context extension.
+type basicAuthChecker struct {
+	authservice.UnimplementedAuthorizationServer
+
+	mu       sync.Mutex
+	verified map[[sha256.Size]byte]bool
+}
+
+func newBasicAuthChecker() *basicAuthChecker {
+	return &basicAuthChecker{verified: make(map[[sha256.Size]byte]bool)}
+}
+
+// Check allows the request if its basic auth credentials match one of the route's entries
+func (c *basicAuthChecker) Check(ctx context.Context, req *authservice.CheckRequest) (*authservice.CheckResponse, error) {
+	attrs := req.GetAttributes()
+	users := attrs.GetContextExtensions()[basicAuthUsersKey]
+	header := attrs.GetRequest().GetHttp().GetHeaders()["authorization"]
+
+	user, password, ok := parseBasicAuth(header)
+	if ok && c.allowed(users, user, password) {
+		return &authservice.CheckResponse{
+			Status:       &rpcstatus.Status{Code: int32(codes.OK)},
+			HttpResponse: &authservice.CheckResponse_OkResponse{OkResponse: &authservice.OkHttpResponse{}},
+		}, nil
+	}
+
+	return &authservice.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(codes.Unauthenticated)},
+		HttpResponse: &authservice.CheckResponse_DeniedResponse{
+			DeniedResponse: &authservice.DeniedHttpResponse{
+				Status: &typev3.HttpStatus{Code: typev3.StatusCode_Unauthorized},
+			},
+		},
+	}, nil
+}
+
+// allowed looks up the user's htpasswd entry and verifies the password against it
+func (c *basicAuthChecker) allowed(users, user, password string) bool {
+	for _, entry := range strings.Split(users, "\n") {
+		name, hash, ok := strings.Cut(entry, ":")
+		if !ok || name != user {
+			continue
+		}
+
+		key := sha256.Sum256([]byte(entry + "\n" + password))
+		c.mu.Lock()
+		cached := c.verified[key]
+		c.mu.Unlock()
+		if cached {
+			return true
+		}
+
+		if !verifyPassword(hash, password) {
+			return false
+		}
+
+		c.mu.Lock()
+		if len(c.verified) >= basicAuthCacheSize {
+			c.verified = make(map[[sha256.Size]byte]bool)
+		}
+		c.verified[key] = true
+		c.mu.Unlock()
+		return true
+	}
+	return false
+}
+
+// verifyPassword checks a password against a bcrypt hash, or a {SHA} hash of an exposure
+// created before bcrypt was required
+func verifyPassword(hash, password string) bool {
+	if encoded, ok := strings.CutPrefix(hash, "{SHA}"); ok {
+		digest := sha1.Sum([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(encoded), []byte(base64.StdEncoding.EncodeToString(digest[:]))) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// parseBasicAuth decodes an "Authorization: Basic ..." header value
+func parseBasicAuth(header string) (string, string, bool) {
+	encoded, ok := strings.CutPrefix(header, "Basic ")
+	if !ok {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
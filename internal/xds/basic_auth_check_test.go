@@ -0,0 +1,60 @@
+package xds
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	authservice "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+)
+
+func checkRequest(users, authorization string) *authservice.CheckRequest {
+	return &authservice.CheckRequest{
+		Attributes: &authservice.AttributeContext{
+			ContextExtensions: map[string]string{basicAuthUsersKey: users},
+			Request: &authservice.AttributeContext_Request{
+				Http: &authservice.AttributeContext_HttpRequest{
+					Headers: map[string]string{"authorization": authorization},
+				},
+			},
+		},
+	}
+}
+
+func basicAuthHeader(user, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
+
+func TestBasicAuthCheckerBcrypt(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := "alice:" + string(hash) + "\nbob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g="
+
+	checker := newBasicAuthChecker()
+	cases := []struct {
+		name   string
+		header string
+		want   codes.Code
+	}{
+		{"bcrypt match", basicAuthHeader("alice", "s3cret"), codes.OK},
+		{"bcrypt match cached", basicAuthHeader("alice", "s3cret"), codes.OK},
+		{"wrong password", basicAuthHeader("alice", "wrong"), codes.Unauthenticated},
+		{"legacy SHA entry", basicAuthHeader("bob", "password"), codes.OK},
+		{"unknown user", basicAuthHeader("carol", "s3cret"), codes.Unauthenticated},
+		{"no credentials", "", codes.Unauthenticated},
+		{"bearer token", "Bearer abc", codes.Unauthenticated},
+	}
+	for _, tc := range cases {
+		resp, err := checker.Check(context.Background(), checkRequest(users, tc.header))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := codes.Code(resp.GetStatus().GetCode()); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
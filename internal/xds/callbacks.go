@@ -0,0 +1,146 @@
+package xds
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"zeropoint-agent/internal/metrics"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	xdsserver "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+)
+
+// Nack is a configuration update Envoy rejected
+type Nack struct {
+	Version     string    `json:"version"`
+	ErrorDetail string    `json:"error_detail"`
+	At          time.Time `json:"at"`
+}
+
+// ResourceStatus is Envoy's view of one resource type (listeners, routes, clusters)
+type ResourceStatus struct {
+	TypeURL      string `json:"type_url"`
+	AckedVersion string `json:"acked_version,omitempty"` // Last version Envoy accepted
+	LastNack     *Nack  `json:"last_nack,omitempty"`
+}
+
+// ConfigStatus reports whether Envoy accepted the latest snapshot
+type ConfigStatus struct {
+	SnapshotVersion string           `json:"snapshot_version,omitempty"`
+	State           string           `json:"state"` // "accepted", "rejected", "pending" or "no_snapshot"
+	Resources       []ResourceStatus `json:"resources"`
+}
+
+// ackTracker records ACKs and NACKs per resource type from the xDS stream callbacks
+type ackTracker struct {
+	mu        sync.RWMutex
+	resources map[string]*ResourceStatus
+	sent      map[string]map[string]string // type URL -> response nonce -> version, to attribute NACKs
+}
+
+func newAckTracker() *ackTracker {
+	return &ackTracker{
+		resources: make(map[string]*ResourceStatus),
+		sent:      make(map[string]map[string]string),
+	}
+}
+
+// callbacks returns the xDS server callbacks that feed the tracker
+func (t *ackTracker) callbacks(s *Server) xdsserver.Callbacks {
+	return xdsserver.CallbackFuncs{
+		StreamRequestFunc: func(_ int64, req *discovery.DiscoveryRequest) error {
+			t.onRequest(s, req)
+			s.markAccepted()
+			return nil
+		},
+		StreamResponseFunc: func(_ context.Context, _ int64, _ *discovery.DiscoveryRequest, resp *discovery.DiscoveryResponse) {
+			t.mu.Lock()
+			if t.sent[resp.GetTypeUrl()] == nil {
+				t.sent[resp.GetTypeUrl()] = make(map[string]string)
+			}
+			t.sent[resp.GetTypeUrl()][resp.GetNonce()] = resp.GetVersionInfo()
+			t.mu.Unlock()
+		},
+	}
+}
+
+// onRequest classifies a discovery request: the first request on a stream has no
+// nonce, an ACK echoes the nonce with the new version, a NACK carries error_detail
+func (t *ackTracker) onRequest(s *Server, req *discovery.DiscoveryRequest) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status, ok := t.resources[req.GetTypeUrl()]
+	if !ok {
+		status = &ResourceStatus{TypeURL: req.GetTypeUrl()}
+		t.resources[req.GetTypeUrl()] = status
+	}
+
+	nonce := req.GetResponseNonce()
+	if nonce == "" {
+		return
+	}
+	version, known := t.sent[req.GetTypeUrl()][nonce]
+	// Envoy answers the newest response it saw, so older nonces are superseded
+	delete(t.sent, req.GetTypeUrl())
+
+	if detail := req.GetErrorDetail(); detail != nil {
+		if !known {
+			version = "unknown"
+		}
+		status.LastNack = &Nack{
+			Version:     version,
+			ErrorDetail: detail.GetMessage(),
+			At:          time.Now(),
+		}
+		s.logger.Error("envoy rejected configuration", "type", req.GetTypeUrl(), "version", version, "error", detail.GetMessage())
+		metrics.XDSPushFailed("nack")
+		return
+	}
+
+	status.AckedVersion = req.GetVersionInfo()
+}
+
+// status summarizes the tracked resource types against the latest snapshot version
+func (t *ackTracker) status(snapshotVersion string) ConfigStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	result := ConfigStatus{
+		SnapshotVersion: snapshotVersion,
+		Resources:       make([]ResourceStatus, 0, len(t.resources)),
+	}
+
+	pending := false
+	rejected := false
+	typeURLs := make([]string, 0, len(t.resources))
+	for typeURL := range t.resources {
+		typeURLs = append(typeURLs, typeURL)
+	}
+	sort.Strings(typeURLs)
+
+	for _, typeURL := range typeURLs {
+		status := *t.resources[typeURL]
+		result.Resources = append(result.Resources, status)
+
+		if status.LastNack != nil && status.LastNack.Version == snapshotVersion {
+			rejected = true
+		} else if status.AckedVersion != snapshotVersion {
+			pending = true
+		}
+	}
+
+	switch {
+	case snapshotVersion == "":
+		result.State = "no_snapshot"
+	case rejected:
+		result.State = "rejected"
+	case pending || len(t.resources) == 0:
+		result.State = "pending"
+	default:
+		result.State = "accepted"
+	}
+	return result
+}
@@ -2,16 +2,24 @@ package xds
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
+	"zeropoint-agent/internal/metrics"
+
+	authservice "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	clusterservice "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
 	discoverygrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	endpointservice "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
 	listenerservice "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
 	routeservice "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	xdsserver "github.com/envoyproxy/go-control-plane/pkg/server/v3"
@@ -21,14 +29,63 @@ import (
 const (
 	// NodeID that Envoy uses in bootstrap config
 	nodeID = "zeropoint-node"
+
+	// Number of pushed snapshots kept in memory for rollback
+	snapshotHistorySize = 10
+
+	// How often WaitForAck re-checks the ACK state
+	ackPollInterval = 50 * time.Millisecond
+)
+
+var (
+	// ErrNoGoodSnapshot is returned by RollbackSnapshot when no earlier snapshot was accepted by Envoy
+	ErrNoGoodSnapshot = errors.New("no earlier snapshot was accepted by envoy")
+
+	// ErrAckTimeout is returned by WaitForAck when Envoy neither ACKed nor NACKed in time
+	ErrAckTimeout = errors.New("timed out waiting for envoy to acknowledge the snapshot")
 )
 
+// RejectedError is returned by WaitForAck when Envoy NACKed the snapshot
+type RejectedError struct {
+	Version string
+	Details []string // "<type URL>: <error detail>" per rejected resource type
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("envoy rejected snapshot %s: %s", e.Version, strings.Join(e.Details, "; "))
+}
+
 // Server manages the xDS control plane for Envoy
 type Server struct {
 	cache   cache.SnapshotCache
 	server  xdsserver.Server
 	logger  *slog.Logger
 	version atomic.Uint64
+
+	listening atomic.Bool
+
+	statusMu       sync.RWMutex
+	lastVersion    string
+	lastSnapshotAt time.Time
+
+	acks *ackTracker
+
+	historyMu sync.Mutex
+	history   []*snapshotRecord // oldest first
+}
+
+// snapshotRecord is a pushed snapshot; good is set once Envoy has ACKed every resource type
+type snapshotRecord struct {
+	version  string
+	snapshot *cache.Snapshot
+	good     bool
+}
+
+// Status describes the state of the xDS control plane
+type Status struct {
+	Listening       bool       `json:"listening"`
+	SnapshotVersion string     `json:"snapshot_version,omitempty"`
+	LastSnapshotAt  *time.Time `json:"last_snapshot_at,omitempty"`
 }
 
 // NewServer creates a new xDS control plane server
@@ -36,14 +93,16 @@ func NewServer(logger *slog.Logger) *Server {
 	// Create snapshot cache (pass nil for logger to avoid interface issues)
 	snapshotCache := cache.NewSnapshotCache(false, cache.IDHash{}, nil)
 
-	// Create xDS server
-	srv := xdsserver.NewServer(context.Background(), snapshotCache, nil)
-
-	return &Server{
+	s := &Server{
 		cache:  snapshotCache,
-		server: srv,
 		logger: logger,
+		acks:   newAckTracker(),
 	}
+
+	// Create xDS server; callbacks record whether Envoy ACKs or NACKs each push
+	s.server = xdsserver.NewServer(context.Background(), snapshotCache, s.acks.callbacks(s))
+
+	return s
 }
 
 // Start starts the xDS gRPC server
@@ -62,12 +121,17 @@ func (s *Server) Start(ctx context.Context, port int) error {
 	routeservice.RegisterRouteDiscoveryServiceServer(grpcServer, s.server)
 	listenerservice.RegisterListenerDiscoveryServiceServer(grpcServer, s.server)
 
+	// Envoy checks basic auth credentials of exposures against the agent
+	authservice.RegisterAuthorizationServer(grpcServer, newBasicAuthChecker())
+
 	s.logger.Info("xDS server starting", "port", port)
+	s.listening.Store(true)
 
 	// Start serving (blocks)
 	go func() {
 		if err := grpcServer.Serve(lis); err != nil {
 			s.logger.Error("xDS server error", "error", err)
+			s.listening.Store(false)
 		}
 	}()
 
@@ -75,6 +139,7 @@ func (s *Server) Start(ctx context.Context, port int) error {
 	go func() {
 		<-ctx.Done()
 		s.logger.Info("xDS server shutting down")
+		s.listening.Store(false)
 		grpcServer.GracefulStop()
 	}()
 
@@ -88,13 +153,174 @@ func (s *Server) UpdateSnapshot(ctx context.Context, snapshot *cache.Snapshot) e
 	}
 
 	if err := s.cache.SetSnapshot(ctx, nodeID, snapshot); err != nil {
+		metrics.XDSPushFailed("set_snapshot")
 		return fmt.Errorf("failed to set snapshot: %w", err)
 	}
 
-	s.logger.Info("snapshot updated", "version", snapshot.GetVersion(resource.ListenerType))
+	version := snapshot.GetVersion(resource.ListenerType)
+	metrics.XDSSnapshotPushed(version)
+	s.statusMu.Lock()
+	s.lastVersion = version
+	s.lastSnapshotAt = time.Now()
+	s.statusMu.Unlock()
+
+	s.historyMu.Lock()
+	s.history = append(s.history, &snapshotRecord{version: version, snapshot: snapshot})
+	if len(s.history) > snapshotHistorySize {
+		s.history = s.history[len(s.history)-snapshotHistorySize:]
+	}
+	s.historyMu.Unlock()
+
+	s.logger.Info("snapshot updated", "version", version)
 	return nil
 }
 
+// Resync re-pushes the current snapshot under a new version so a freshly (re)started
+// Envoy is guaranteed to receive the full configuration
+func (s *Server) Resync(ctx context.Context) error {
+	current, err := s.cache.GetSnapshot(nodeID)
+	if err != nil {
+		return fmt.Errorf("no snapshot to resync: %w", err)
+	}
+
+	snapshot, err := cloneSnapshot(current, s.NextVersion())
+	if err != nil {
+		return err
+	}
+
+	return s.UpdateSnapshot(ctx, snapshot)
+}
+
+// RollbackSnapshot re-pushes the newest snapshot before the current one that Envoy
+// accepted, under a new version. It returns the version that was restored and the
+// version it was pushed as.
+func (s *Server) RollbackSnapshot(ctx context.Context) (string, string, error) {
+	s.historyMu.Lock()
+	var target *snapshotRecord
+	for i := len(s.history) - 2; i >= 0; i-- {
+		if s.history[i].good {
+			target = s.history[i]
+			break
+		}
+	}
+	s.historyMu.Unlock()
+
+	if target == nil {
+		return "", "", ErrNoGoodSnapshot
+	}
+
+	snapshot, err := cloneSnapshot(target.snapshot, s.NextVersion())
+	if err != nil {
+		return "", "", err
+	}
+	newVersion := snapshot.GetVersion(resource.ListenerType)
+
+	if err := s.UpdateSnapshot(ctx, snapshot); err != nil {
+		return "", "", err
+	}
+
+	s.logger.Warn("rolled back xDS snapshot", "restored", target.version, "version", newVersion)
+	return target.version, newVersion, nil
+}
+
+// markAccepted flags the current snapshot as known good once Envoy has ACKed it
+func (s *Server) markAccepted() {
+	status := s.ConfigStatus()
+	if status.State != "accepted" {
+		return
+	}
+
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	for _, record := range s.history {
+		if record.version == status.SnapshotVersion {
+			record.good = true
+		}
+	}
+}
+
+// cloneSnapshot copies a snapshot's resources under a new version
+func cloneSnapshot(src cache.ResourceSnapshot, version string) (*cache.Snapshot, error) {
+	resources := make(map[resource.Type][]types.Resource)
+	for _, typeURL := range []resource.Type{resource.ClusterType, resource.EndpointType, resource.RouteType, resource.ListenerType} {
+		resources[typeURL] = []types.Resource{}
+		for _, res := range src.GetResources(typeURL) {
+			resources[typeURL] = append(resources[typeURL], res)
+		}
+	}
+
+	snapshot, err := cache.NewSnapshot(version, resources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// Status returns whether the server is listening and the last snapshot pushed
+func (s *Server) Status() Status {
+	s.statusMu.RLock()
+	defer s.statusMu.RUnlock()
+
+	status := Status{
+		Listening:       s.listening.Load(),
+		SnapshotVersion: s.lastVersion,
+	}
+	if !s.lastSnapshotAt.IsZero() {
+		at := s.lastSnapshotAt
+		status.LastSnapshotAt = &at
+	}
+	return status
+}
+
+// ConfigStatus reports whether Envoy accepted the most recently pushed snapshot
+func (s *Server) ConfigStatus() ConfigStatus {
+	s.statusMu.RLock()
+	version := s.lastVersion
+	s.statusMu.RUnlock()
+
+	return s.acks.status(version)
+}
+
+// WaitForAck blocks until Envoy ACKs every resource type of the given snapshot version.
+// It returns a *RejectedError if Envoy NACKed it, and ErrAckTimeout if ctx expires first.
+// A version that has been superseded by a newer push is not waited on.
+func (s *Server) WaitForAck(ctx context.Context, version string) error {
+	ticker := time.NewTicker(ackPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.statusMu.RLock()
+		current := s.lastVersion
+		s.statusMu.RUnlock()
+		if current != version {
+			return nil
+		}
+
+		status := s.acks.status(version)
+		switch status.State {
+		case "accepted":
+			return nil
+		case "rejected":
+			rejected := &RejectedError{Version: version}
+			for _, res := range status.Resources {
+				if res.LastNack != nil && res.LastNack.Version == version {
+					rejected.Details = append(rejected.Details, res.TypeURL+": "+res.LastNack.ErrorDetail)
+				}
+			}
+			return rejected
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ErrAckTimeout
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
 // NextVersion returns the next monotonic version number
 func (s *Server) NextVersion() string {
 	v := s.version.Add(1)
@@ -1,17 +1,29 @@
 package xds
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
+	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	fileaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
 	router "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	tlsinspector "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/tls_inspector/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	tcpproxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	udpproxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/udp/udp_proxy/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	upstreamhttp "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
@@ -19,9 +31,114 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
+// accessLogFormat is the default Envoy format plus the upstream cluster, so each line
+// can be attributed to an exposure (clusters are named cluster_<exposure id>)
+const accessLogFormat = `[%START_TIME%] "%REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH)% %PROTOCOL%" ` +
+	`%RESPONSE_CODE% %RESPONSE_FLAGS% %BYTES_RECEIVED% %BYTES_SENT% %DURATION%ms ` +
+	`"%REQ(:AUTHORITY)%" "%REQ(USER-AGENT)%" %UPSTREAM_CLUSTER% %UPSTREAM_HOST%` + "\n"
+
+// tcpAccessLogFormat is the text format for TCP proxy connections, which have no request line
+const tcpAccessLogFormat = `[%START_TIME%] %DOWNSTREAM_REMOTE_ADDRESS% %RESPONSE_FLAGS% ` +
+	`%BYTES_RECEIVED% %BYTES_SENT% %DURATION%ms %UPSTREAM_CLUSTER% %UPSTREAM_HOST%` + "\n"
+
+// accessLogJSONFields is the JSON access log format for HTTP requests
+var accessLogJSONFields = map[string]string{
+	"start_time":     "%START_TIME%",
+	"method":         "%REQ(:METHOD)%",
+	"path":           "%REQ(X-ENVOY-ORIGINAL-PATH?:PATH)%",
+	"protocol":       "%PROTOCOL%",
+	"response_code":  "%RESPONSE_CODE%",
+	"response_flags": "%RESPONSE_FLAGS%",
+	"bytes_received": "%BYTES_RECEIVED%",
+	"bytes_sent":     "%BYTES_SENT%",
+	"duration_ms":    "%DURATION%",
+	"authority":      "%REQ(:AUTHORITY)%",
+	"user_agent":     "%REQ(USER-AGENT)%",
+	"client":         "%DOWNSTREAM_REMOTE_ADDRESS%",
+	"cluster":        "%UPSTREAM_CLUSTER%",
+	"upstream_host":  "%UPSTREAM_HOST%",
+}
+
+// tcpAccessLogJSONFields is the JSON access log format for TCP proxy connections
+var tcpAccessLogJSONFields = map[string]string{
+	"start_time":     "%START_TIME%",
+	"response_flags": "%RESPONSE_FLAGS%",
+	"bytes_received": "%BYTES_RECEIVED%",
+	"bytes_sent":     "%BYTES_SENT%",
+	"duration_ms":    "%DURATION%",
+	"client":         "%DOWNSTREAM_REMOTE_ADDRESS%",
+	"cluster":        "%UPSTREAM_CLUSTER%",
+	"upstream_host":  "%UPSTREAM_HOST%",
+}
+
+// Where Envoy writes access logs, "" when they are off, and whether they are JSON rather
+// than text. The path is inside the Envoy container; the default sends them to
+// `docker logs zeropoint-envoy`.
+var (
+	accessLogPath = "/dev/stdout"
+	accessLogJSON = true
+)
+
+// SetAccessLog configures the access logs of snapshots built from now on. path is inside
+// the Envoy container; "off", "false", "0" or "none" turn access logs off. format is json
+// or text.
+func SetAccessLog(path, format string) {
+	switch strings.ToLower(path) {
+	case "off", "false", "0", "none":
+		accessLogPath = ""
+	default:
+		accessLogPath = path
+	}
+	accessLogJSON = !strings.EqualFold(format, "text")
+}
+
+// makeAccessLogs returns the access log configuration for a listener filter, or nil when
+// access logging is off
+func makeAccessLogs(textFormat string, jsonFields map[string]string) []*accesslog.AccessLog {
+	path := accessLogPath
+	if path == "" {
+		return nil
+	}
+
+	format := &core.SubstitutionFormatString{
+		Format: &core.SubstitutionFormatString_TextFormatSource{
+			TextFormatSource: &core.DataSource{
+				Specifier: &core.DataSource_InlineString{InlineString: textFormat},
+			},
+		},
+	}
+	if accessLogJSON {
+		fields := make(map[string]interface{}, len(jsonFields))
+		for name, operator := range jsonFields {
+			fields[name] = operator
+		}
+		jsonFormat, err := structpb.NewStruct(fields)
+		if err == nil {
+			format = &core.SubstitutionFormatString{
+				Format: &core.SubstitutionFormatString_JsonFormat{JsonFormat: jsonFormat},
+			}
+		}
+	}
+
+	return []*accesslog.AccessLog{
+		{
+			Name: wellknown.FileAccessLog,
+			ConfigType: &accesslog.AccessLog_TypedConfig{
+				TypedConfig: mustMarshalAny(&fileaccesslog.FileAccessLog{
+					Path: path,
+					AccessLogFormat: &fileaccesslog.FileAccessLog_LogFormat{
+						LogFormat: format,
+					},
+				}),
+			},
+		},
+	}
+}
+
 // BuildSnapshot creates a snapshot with listeners, routes, and clusters
 func BuildSnapshot(version string) (*cache.Snapshot, error) {
 	// Create HTTP listener on port 80
@@ -51,10 +168,100 @@ func BuildSnapshot(version string) (*cache.Snapshot, error) {
 
 // makeHTTPListener creates a listener on port 80 with HTTP connection manager
 func makeHTTPListener() (*listener.Listener, error) {
-	// Create HTTP connection manager config
+	pbst, err := makeHTTPConnectionManager("http", "http_routes")
+	if err != nil {
+		return nil, err
+	}
+
+	return &listener.Listener{
+		Name:    "http_listener",
+		Address: makeListenerAddress(80),
+		FilterChains: []*listener.FilterChain{
+			{
+				Filters: []*listener.Filter{
+					{
+						Name: wellknown.HTTPConnectionManager,
+						ConfigType: &listener.Filter_TypedConfig{
+							TypedConfig: pbst,
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// makeHTTPSListener creates a listener on port 443 that terminates TLS, with one
+// filter chain per hostname selected by SNI
+func makeHTTPSListener(exposures []*Exposure) (*listener.Listener, error) {
+	pbst, err := makeHTTPConnectionManager("https", "https_routes")
+	if err != nil {
+		return nil, err
+	}
+
+	var filterChains []*listener.FilterChain
+	for _, group := range groupByHostname(exposures) {
+		// Exposures sharing a hostname share a certificate; the first route's wins
+		exp := group[0]
+
+		tlsContext := &tlsv3.DownstreamTlsContext{
+			CommonTlsContext: &tlsv3.CommonTlsContext{
+				TlsCertificates: []*tlsv3.TlsCertificate{
+					{
+						CertificateChain: &core.DataSource{
+							Specifier: &core.DataSource_InlineBytes{InlineBytes: exp.TLS.CertPEM},
+						},
+						PrivateKey: &core.DataSource{
+							Specifier: &core.DataSource_InlineBytes{InlineBytes: exp.TLS.KeyPEM},
+						},
+					},
+				},
+			},
+		}
+
+		filterChains = append(filterChains, &listener.FilterChain{
+			Name: fmt.Sprintf("https_%s", exp.Hostname),
+			FilterChainMatch: &listener.FilterChainMatch{
+				ServerNames: groupDomains(group),
+			},
+			TransportSocket: &core.TransportSocket{
+				Name: wellknown.TransportSocketTLS,
+				ConfigType: &core.TransportSocket_TypedConfig{
+					TypedConfig: mustMarshalAny(tlsContext),
+				},
+			},
+			Filters: []*listener.Filter{
+				{
+					Name: wellknown.HTTPConnectionManager,
+					ConfigType: &listener.Filter_TypedConfig{
+						TypedConfig: pbst,
+					},
+				},
+			},
+		})
+	}
+
+	return &listener.Listener{
+		Name:    "https_listener",
+		Address: makeListenerAddress(443),
+		ListenerFilters: []*listener.ListenerFilter{
+			{
+				// Required so filter chains can match on SNI
+				Name: wellknown.TlsInspector,
+				ConfigType: &listener.ListenerFilter_TypedConfig{
+					TypedConfig: mustMarshalAny(&tlsinspector.TlsInspector{}),
+				},
+			},
+		},
+		FilterChains: filterChains,
+	}, nil
+}
+
+// makeHTTPConnectionManager builds the HTTP connection manager shared by the HTTP and HTTPS listeners
+func makeHTTPConnectionManager(statPrefix, routeConfigName string) (*anypb.Any, error) {
 	manager := &hcm.HttpConnectionManager{
 		CodecType:  hcm.HttpConnectionManager_AUTO,
-		StatPrefix: "http",
+		StatPrefix: statPrefix,
 		// Configure long timeouts for AI applications
 		RequestTimeout:        durationpb.New(0),                // Disable request timeout (infinite)
 		StreamIdleTimeout:     durationpb.New(600 * 1000000000), // 10 minutes for streaming
@@ -70,10 +277,11 @@ func makeHTTPListener() (*listener.Listener, error) {
 						Ads: &core.AggregatedConfigSource{},
 					},
 				},
-				RouteConfigName: "http_routes",
+				RouteConfigName: routeConfigName,
 			},
 		},
 		HttpFilters: []*hcm.HttpFilter{
+			makeBasicAuthFilter(),
 			{
 				Name: wellknown.Router,
 				ConfigType: &hcm.HttpFilter_TypedConfig{
@@ -81,40 +289,27 @@ func makeHTTPListener() (*listener.Listener, error) {
 				},
 			},
 		},
+		AccessLog:        makeAccessLogs(accessLogFormat, accessLogJSONFields),
+		LocalReplyConfig: makeBasicAuthLocalReply(),
 	}
 
 	// Marshal to Any
-	pbst, err := anypb.New(manager)
-	if err != nil {
-		return nil, err
-	}
+	return anypb.New(manager)
+}
 
-	return &listener.Listener{
-		Name: "http_listener",
-		Address: &core.Address{
-			Address: &core.Address_SocketAddress{
-				SocketAddress: &core.SocketAddress{
-					Protocol: core.SocketAddress_TCP,
-					Address:  "0.0.0.0",
-					PortSpecifier: &core.SocketAddress_PortValue{
-						PortValue: 80,
-					},
-				},
-			},
-		},
-		FilterChains: []*listener.FilterChain{
-			{
-				Filters: []*listener.Filter{
-					{
-						Name: wellknown.HTTPConnectionManager,
-						ConfigType: &listener.Filter_TypedConfig{
-							TypedConfig: pbst,
-						},
-					},
+// makeListenerAddress returns a TCP address on all interfaces for the given port
+func makeListenerAddress(port uint32) *core.Address {
+	return &core.Address{
+		Address: &core.Address_SocketAddress{
+			SocketAddress: &core.SocketAddress{
+				Protocol: core.SocketAddress_TCP,
+				Address:  "0.0.0.0",
+				PortSpecifier: &core.SocketAddress_PortValue{
+					PortValue: port,
 				},
 			},
 		},
-	}, nil
+	}
 }
 
 // makeEmptyRouteConfig creates a route configuration that returns 404 for all requests
@@ -149,8 +344,29 @@ func makeEmptyRouteConfig() *route.RouteConfiguration {
 	}
 }
 
-// makeCluster creates a cluster for an app service
-func makeCluster(name string, host string, port uint32) *cluster.Cluster {
+// makeCluster creates a cluster for an app service with one endpoint per backend host
+func makeCluster(name string, hosts []string, port uint32) *cluster.Cluster {
+	endpoints := make([]*endpoint.LbEndpoint, 0, len(hosts))
+	for _, host := range hosts {
+		endpoints = append(endpoints, &endpoint.LbEndpoint{
+			HostIdentifier: &endpoint.LbEndpoint_Endpoint{
+				Endpoint: &endpoint.Endpoint{
+					Address: &core.Address{
+						Address: &core.Address_SocketAddress{
+							SocketAddress: &core.SocketAddress{
+								Protocol: core.SocketAddress_TCP,
+								Address:  host,
+								PortSpecifier: &core.SocketAddress_PortValue{
+									PortValue: port,
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+
 	return &cluster.Cluster{
 		Name:                 name,
 		ConnectTimeout:       durationpb.New(5 * 1000000000), // 5 seconds in nanoseconds
@@ -160,31 +376,29 @@ func makeCluster(name string, host string, port uint32) *cluster.Cluster {
 			ClusterName: name,
 			Endpoints: []*endpoint.LocalityLbEndpoints{
 				{
-					LbEndpoints: []*endpoint.LbEndpoint{
-						{
-							HostIdentifier: &endpoint.LbEndpoint_Endpoint{
-								Endpoint: &endpoint.Endpoint{
-									Address: &core.Address{
-										Address: &core.Address_SocketAddress{
-											SocketAddress: &core.SocketAddress{
-												Protocol: core.SocketAddress_TCP,
-												Address:  host,
-												PortSpecifier: &core.SocketAddress_PortValue{
-													PortValue: port,
-												},
-											},
-										},
-									},
-								},
-							},
-						},
-					},
+					LbEndpoints: endpoints,
 				},
 			},
 		},
 	}
 }
 
+// enableUpstreamHTTP2 makes Envoy talk plaintext HTTP/2 to the cluster, as gRPC requires.
+// Downstream needs no change: the AUTO codec already negotiates HTTP/2 with clients.
+func enableUpstreamHTTP2(c *cluster.Cluster) {
+	c.TypedExtensionProtocolOptions = map[string]*anypb.Any{
+		"envoy.extensions.upstreams.http.v3.HttpProtocolOptions": mustMarshalAny(&upstreamhttp.HttpProtocolOptions{
+			UpstreamProtocolOptions: &upstreamhttp.HttpProtocolOptions_ExplicitHttpConfig_{
+				ExplicitHttpConfig: &upstreamhttp.HttpProtocolOptions_ExplicitHttpConfig{
+					ProtocolConfig: &upstreamhttp.HttpProtocolOptions_ExplicitHttpConfig_Http2ProtocolOptions{
+						Http2ProtocolOptions: &core.Http2ProtocolOptions{},
+					},
+				},
+			},
+		}),
+	}
+}
+
 // mustMarshalAny marshals a protobuf message to Any, panicking on error
 func mustMarshalAny(msg proto.Message) *anypb.Any {
 	a, err := anypb.New(msg)
@@ -206,8 +420,41 @@ type Exposure struct {
 	ModuleName    string
 	Protocol      string
 	Hostname      string
+	Aliases       []string // HTTP only; additional hostnames served by the same virtual host
 	ContainerPort uint32
 	HostPort      uint32
+	Backends      []string        // Container names of the replicas behind the exposure; ModuleName when empty
+	PathPrefix    string          // HTTP only; "" matches every path
+	PrefixRewrite string          // HTTP only; replaces PathPrefix before forwarding upstream
+	TLS           *TLSCertificate // HTTP only; serves the hostname on the HTTPS listener
+	RedirectHTTPS bool            // HTTP only; plaintext requests get a redirect instead of being proxied
+	WebSocket     bool            // HTTP only; upgrades are already enabled on every route, kept for visibility
+	GRPC          bool            // HTTP only; the upstream speaks HTTP/2 (h2c) instead of HTTP/1.1
+	// HTTP only; zero RequestTimeout disables the route timeout
+	RequestTimeout time.Duration
+	NumRetries     uint32
+	RetryOn        []string // Envoy retry conditions; no retry policy when empty
+	// HTTP only; htpasswd "user:$2y$..." bcrypt entries, requests without matching basic auth get a 401
+	BasicAuthUsers []string
+	// HTTP only; nil keeps round-robin load balancing
+	Affinity *SessionAffinity
+	// HTTP only; no backend container is running, so Envoy answers with a 503 itself
+	UpstreamDown bool
+}
+
+// backendHosts returns the hosts the exposure's cluster balances across
+func (e *Exposure) backendHosts() []string {
+	if len(e.Backends) > 0 {
+		return e.Backends
+	}
+	return []string{e.ModuleName}
+}
+
+// TLSCertificate is a validated PEM certificate chain and private key, inlined into the
+// Envoy config so the certificate files do not need to be mounted into the container
+type TLSCertificate struct {
+	CertPEM []byte
+	KeyPEM  []byte
 }
 
 // BuildSnapshotFromExposures creates a snapshot from a list of exposures
@@ -216,15 +463,19 @@ func BuildSnapshotFromExposures(version string, exposures []*Exposure) (*cache.S
 	var routes []types.Resource
 	var clusters []types.Resource
 
-	// Separate HTTP and TCP exposures
+	// Separate HTTP, TCP and UDP exposures
 	var httpExposures []*Exposure
 	var tcpExposures []*Exposure
+	var udpExposures []*Exposure
 
 	for _, exp := range exposures {
-		if exp.Protocol == "http" {
+		switch exp.Protocol {
+		case "http":
 			httpExposures = append(httpExposures, exp)
-		} else if exp.Protocol == "tcp" {
+		case "tcp":
 			tcpExposures = append(tcpExposures, exp)
+		case "udp":
+			udpExposures = append(udpExposures, exp)
 		}
 	}
 
@@ -243,9 +494,31 @@ func BuildSnapshotFromExposures(version string, exposures []*Exposure) (*cache.S
 		// Build clusters for HTTP exposures
 		for _, exp := range httpExposures {
 			clusterName := fmt.Sprintf("cluster_%s", exp.ID)
-			cluster := makeCluster(clusterName, exp.ModuleName, exp.ContainerPort)
+			cluster := makeCluster(clusterName, exp.backendHosts(), exp.ContainerPort)
+			if exp.GRPC {
+				enableUpstreamHTTP2(cluster)
+			}
+			if exp.Affinity != nil {
+				enableSessionAffinity(cluster)
+			}
 			clusters = append(clusters, cluster)
 		}
+
+		// Build HTTPS listener and routes for exposures with TLS
+		var tlsExposures []*Exposure
+		for _, exp := range httpExposures {
+			if exp.TLS != nil {
+				tlsExposures = append(tlsExposures, exp)
+			}
+		}
+		if len(tlsExposures) > 0 {
+			httpsListener, err := makeHTTPSListener(tlsExposures)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create HTTPS listener: %w", err)
+			}
+			listeners = append(listeners, httpsListener)
+			routes = append(routes, makeHTTPSRouteConfig(tlsExposures))
+		}
 	} else {
 		// No HTTP exposures, use empty route config
 		httpListener, err := makeHTTPListener()
@@ -265,7 +538,20 @@ func BuildSnapshotFromExposures(version string, exposures []*Exposure) (*cache.S
 		listeners = append(listeners, tcpListener)
 
 		clusterName := fmt.Sprintf("cluster_%s", exp.ID)
-		cluster := makeCluster(clusterName, exp.ModuleName, exp.ContainerPort)
+		cluster := makeCluster(clusterName, exp.backendHosts(), exp.ContainerPort)
+		clusters = append(clusters, cluster)
+	}
+
+	// Build UDP listeners for UDP exposures
+	for _, exp := range udpExposures {
+		udpListener, err := makeUDPListener(exp.ID, exp.HostPort)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create UDP listener for %s: %w", exp.ID, err)
+		}
+		listeners = append(listeners, udpListener)
+
+		clusterName := fmt.Sprintf("cluster_%s", exp.ID)
+		cluster := makeCluster(clusterName, exp.backendHosts(), exp.ContainerPort)
 		clusters = append(clusters, cluster)
 	}
 
@@ -285,56 +571,233 @@ func BuildSnapshotFromExposures(version string, exposures []*Exposure) (*cache.S
 	return snapshot, nil
 }
 
+// ResourceHash returns a digest of a snapshot's resources, ignoring its version, so
+// identical configurations can be detected before pushing
+func ResourceHash(snapshot cache.ResourceSnapshot) (string, error) {
+	h := sha256.New()
+	marshal := proto.MarshalOptions{Deterministic: true}
+
+	for _, typeURL := range []resource.Type{resource.ClusterType, resource.EndpointType, resource.RouteType, resource.ListenerType} {
+		resources := snapshot.GetResources(typeURL)
+		names := make([]string, 0, len(resources))
+		for name := range resources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			data, err := marshal.Marshal(resources[name])
+			if err != nil {
+				return "", fmt.Errorf("failed to marshal %s: %w", name, err)
+			}
+			fmt.Fprintf(h, "%s\x00%s\x00%d\x00", typeURL, name, len(data))
+			h.Write(data)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // makeRouteConfigFromExposures creates a route configuration from HTTP exposures
 func makeRouteConfigFromExposures(exposures []*Exposure) *route.RouteConfiguration {
 	virtualHosts := make([]*route.VirtualHost, 0, len(exposures))
 
+	for _, group := range groupByHostname(exposures) {
+		routes := make([]*route.Route, 0, len(group))
+		for _, exp := range group {
+			redirect := exp.TLS != nil && exp.RedirectHTTPS
+			routes = append(routes, makeExposureRoute(exp, redirect))
+		}
+		virtualHosts = append(virtualHosts, makeVirtualHost(group, routes...))
+	}
+
+	return &route.RouteConfiguration{
+		Name:         "http_routes",
+		VirtualHosts: virtualHosts,
+	}
+}
+
+// makeHTTPSRouteConfig creates the route configuration served behind the HTTPS listener
+func makeHTTPSRouteConfig(exposures []*Exposure) *route.RouteConfiguration {
+	virtualHosts := make([]*route.VirtualHost, 0, len(exposures))
+
+	for _, group := range groupByHostname(exposures) {
+		routes := make([]*route.Route, 0, len(group))
+		for _, exp := range group {
+			routes = append(routes, makeExposureRoute(exp, false))
+		}
+		virtualHosts = append(virtualHosts, makeVirtualHost(group, routes...))
+	}
+
+	return &route.RouteConfiguration{
+		Name:         "https_routes",
+		VirtualHosts: virtualHosts,
+	}
+}
+
+// groupByHostname groups exposures sharing a hostname into one virtual host. Groups are
+// ordered by hostname, and routes within a group longest-prefix-first so Envoy's
+// first-match routing picks the most specific exposure.
+func groupByHostname(exposures []*Exposure) [][]*Exposure {
+	byHost := make(map[string][]*Exposure)
+	var hostnames []string
 	for _, exp := range exposures {
-		clusterName := fmt.Sprintf("cluster_%s", exp.ID)
+		if _, ok := byHost[exp.Hostname]; !ok {
+			hostnames = append(hostnames, exp.Hostname)
+		}
+		byHost[exp.Hostname] = append(byHost[exp.Hostname], exp)
+	}
+	sort.Strings(hostnames)
+
+	groups := make([][]*Exposure, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		group := byHost[hostname]
+		sort.Slice(group, func(i, j int) bool {
+			if len(group[i].PathPrefix) != len(group[j].PathPrefix) {
+				return len(group[i].PathPrefix) > len(group[j].PathPrefix)
+			}
+			return group[i].ID < group[j].ID
+		})
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// makeVirtualHost creates a virtual host for a group of exposures sharing a hostname
+func makeVirtualHost(group []*Exposure, routes ...*route.Route) *route.VirtualHost {
+	return &route.VirtualHost{
+		Name:    group[0].Hostname,
+		Domains: groupDomains(group),
+		Routes:  routes,
+	}
+}
 
-		// Match both hostname and hostname.local for mDNS compatibility
-		domains := []string{exp.Hostname}
-		if !strings.HasSuffix(exp.Hostname, ".local") {
-			domains = append(domains, exp.Hostname+".local")
+// makeExposureRoute creates a route for the exposure's path prefix that forwards to the
+// exposure's cluster, or redirects to HTTPS when redirect is set
+func makeExposureRoute(exp *Exposure, redirect bool) *route.Route {
+	match := &route.RouteMatch{
+		PathSpecifier: &route.RouteMatch_Prefix{
+			Prefix: "/",
+		},
+	}
+	if exp.PathPrefix != "" {
+		// Matches /api and /api/..., but not /apiary
+		match.PathSpecifier = &route.RouteMatch_PathSeparatedPrefix{
+			PathSeparatedPrefix: exp.PathPrefix,
 		}
+	}
 
-		virtualHost := &route.VirtualHost{
-			Name:    exp.Hostname,
-			Domains: domains,
-			Routes: []*route.Route{
-				{
-					Match: &route.RouteMatch{
-						PathSpecifier: &route.RouteMatch_Prefix{
-							Prefix: "/",
-						},
+	if redirect {
+		return &route.Route{
+			Match: match,
+			Action: &route.Route_Redirect{
+				Redirect: &route.RedirectAction{
+					SchemeRewriteSpecifier: &route.RedirectAction_HttpsRedirect{
+						HttpsRedirect: true,
 					},
-					Action: &route.Route_Route{
-						Route: &route.RouteAction{
-							ClusterSpecifier: &route.RouteAction_Cluster{
-								Cluster: clusterName,
-							},
-							// Set long timeouts for AI model downloads and streaming
-							Timeout:     durationpb.New(0),                // Disable route timeout (infinite)
-							IdleTimeout: durationpb.New(300 * 1000000000), // 5 minutes idle timeout
-							// Enable WebSocket upgrade support
-							UpgradeConfigs: []*route.RouteAction_UpgradeConfig{
-								{
-									UpgradeType: "websocket",
-									Enabled:     &wrapperspb.BoolValue{Value: true},
-								},
-							},
+				},
+			},
+		}
+	}
+
+	if exp.UpstreamDown {
+		return &route.Route{
+			Match: match,
+			Action: &route.Route_DirectResponse{
+				DirectResponse: &route.DirectResponseAction{
+					Status: 503,
+					Body: &core.DataSource{
+						Specifier: &core.DataSource_InlineString{
+							InlineString: "Service unavailable: the app's containers are not running\n",
 						},
 					},
 				},
 			},
 		}
-		virtualHosts = append(virtualHosts, virtualHost)
 	}
 
-	return &route.RouteConfiguration{
-		Name:         "http_routes",
-		VirtualHosts: virtualHosts,
+	clusterName := fmt.Sprintf("cluster_%s", exp.ID)
+
+	action := &route.RouteAction{
+		ClusterSpecifier: &route.RouteAction_Cluster{
+			Cluster: clusterName,
+		},
+		// Set long timeouts for AI model downloads and streaming
+		Timeout:     durationpb.New(0),                // Disable route timeout (infinite)
+		IdleTimeout: durationpb.New(300 * 1000000000), // 5 minutes idle timeout
+		// Enable WebSocket upgrade support
+		UpgradeConfigs: []*route.RouteAction_UpgradeConfig{
+			{
+				UpgradeType: "websocket",
+				Enabled:     &wrapperspb.BoolValue{Value: true},
+			},
+		},
+	}
+
+	if exp.RequestTimeout > 0 {
+		action.Timeout = durationpb.New(exp.RequestTimeout)
+	}
+
+	if len(exp.RetryOn) > 0 {
+		action.RetryPolicy = &route.RetryPolicy{
+			RetryOn: strings.Join(exp.RetryOn, ","),
+		}
+		if exp.NumRetries > 0 {
+			action.RetryPolicy.NumRetries = &wrapperspb.UInt32Value{Value: exp.NumRetries}
+		}
+	}
+
+	if exp.Affinity != nil {
+		action.HashPolicy = makeHashPolicy(exp.Affinity)
+	}
+
+	if exp.PathPrefix != "" && exp.PrefixRewrite != "" {
+		// A plain prefix_rewrite of "/api" -> "/" would turn /api/x into //x, so rewrite
+		// the prefix and an optional following slash with a regex instead
+		action.RegexRewrite = &matcher.RegexMatchAndSubstitute{
+			Pattern: &matcher.RegexMatcher{
+				Regex: "^" + regexp.QuoteMeta(exp.PathPrefix) + "/?(.*)",
+			},
+			Substitution: strings.TrimSuffix(exp.PrefixRewrite, "/") + "/\\1",
+		}
+	}
+
+	r := &route.Route{
+		Match:  match,
+		Action: &route.Route_Route{Route: action},
+	}
+	if len(exp.BasicAuthUsers) > 0 {
+		r.TypedPerFilterConfig = makeBasicAuthRouteConfig(exp.BasicAuthUsers)
+	}
+	return r
+}
+
+// exposureDomains matches the hostname and every alias, each also as name.local for mDNS compatibility
+func exposureDomains(exp *Exposure) []string {
+	var domains []string
+	for _, name := range append([]string{exp.Hostname}, exp.Aliases...) {
+		domains = append(domains, name)
+		if !strings.HasSuffix(name, ".local") {
+			domains = append(domains, name+".local")
+		}
+	}
+	return domains
+}
+
+// groupDomains returns the union of domains for exposures sharing a virtual host.
+// Envoy rejects a route config that lists a domain twice, so duplicates are dropped.
+func groupDomains(group []*Exposure) []string {
+	var domains []string
+	seen := make(map[string]bool)
+	for _, exp := range group {
+		for _, domain := range exposureDomains(exp) {
+			if !seen[domain] {
+				seen[domain] = true
+				domains = append(domains, domain)
+			}
+		}
 	}
+	return domains
 }
 
 // makeTCPListener creates a TCP listener for a specific port
@@ -346,6 +809,7 @@ func makeTCPListener(id string, hostPort uint32, targetHost string, targetPort u
 		ClusterSpecifier: &tcpproxy.TcpProxy_Cluster{
 			Cluster: clusterName,
 		},
+		AccessLog: makeAccessLogs(tcpAccessLogFormat, tcpAccessLogJSONFields),
 	}
 
 	pbst, err := anypb.New(tcpProxy)
@@ -380,3 +844,46 @@ func makeTCPListener(id string, hostPort uint32, targetHost string, targetPort u
 		},
 	}, nil
 }
+
+// makeUDPListener creates a UDP listener for a specific port that proxies datagrams
+// to the exposure's cluster
+func makeUDPListener(id string, hostPort uint32) (*listener.Listener, error) {
+	clusterName := fmt.Sprintf("cluster_%s", id)
+
+	udpProxy := &udpproxy.UdpProxyConfig{
+		StatPrefix: fmt.Sprintf("udp_%s", id),
+		RouteSpecifier: &udpproxy.UdpProxyConfig_Cluster{
+			Cluster: clusterName,
+		},
+	}
+
+	pbst, err := anypb.New(udpProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listener.Listener{
+		Name: fmt.Sprintf("udp_listener_%s", id),
+		Address: &core.Address{
+			Address: &core.Address_SocketAddress{
+				SocketAddress: &core.SocketAddress{
+					Protocol: core.SocketAddress_UDP,
+					Address:  "0.0.0.0",
+					PortSpecifier: &core.SocketAddress_PortValue{
+						PortValue: hostPort,
+					},
+				},
+			},
+		},
+		// UDP listeners have no filter chains; the proxy runs as a listener filter
+		UdpListenerConfig: &listener.UdpListenerConfig{},
+		ListenerFilters: []*listener.ListenerFilter{
+			{
+				Name: "envoy.filters.udp_listener.udp_proxy",
+				ConfigType: &listener.ListenerFilter_TypedConfig{
+					TypedConfig: pbst,
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,73 @@
+package xds
+
+import (
+	"testing"
+	"time"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+// routeAction returns the action of the route for an exposure's hostname in the snapshot's
+// plaintext route configuration
+func routeAction(t *testing.T, exposures []*Exposure, hostname string) *route.RouteAction {
+	t.Helper()
+	snapshot, err := BuildSnapshotFromExposures("1", exposures)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, res := range snapshot.GetResources(resource.RouteType) {
+		config := res.(*route.RouteConfiguration)
+		if config.GetName() != "http_routes" {
+			continue
+		}
+		for _, vh := range config.GetVirtualHosts() {
+			if vh.GetName() == hostname {
+				return vh.GetRoutes()[0].GetRoute()
+			}
+		}
+	}
+	t.Fatalf("no route for %s", hostname)
+	return nil
+}
+
+func TestRouteRetryPolicy(t *testing.T) {
+	exposures := []*Exposure{
+		{
+			ID:             "api",
+			ModuleName:     "api",
+			Protocol:       "http",
+			Hostname:       "api.local",
+			ContainerPort:  8080,
+			RequestTimeout: 30 * time.Second,
+			NumRetries:     3,
+			RetryOn:        []string{"5xx", "reset", "connect-failure"},
+		},
+		{ID: "wiki", ModuleName: "wiki", Protocol: "http", Hostname: "wiki.local", ContainerPort: 80},
+	}
+
+	action := routeAction(t, exposures, "api.local")
+	if got := action.GetTimeout().AsDuration(); got != 30*time.Second {
+		t.Errorf("timeout = %v, want 30s", got)
+	}
+	policy := action.GetRetryPolicy()
+	if policy == nil {
+		t.Fatal("route has no retry policy")
+	}
+	if policy.GetRetryOn() != "5xx,reset,connect-failure" {
+		t.Errorf("retry_on = %q, want 5xx,reset,connect-failure", policy.GetRetryOn())
+	}
+	if policy.GetNumRetries().GetValue() != 3 {
+		t.Errorf("num_retries = %d, want 3", policy.GetNumRetries().GetValue())
+	}
+
+	// Exposures without settings keep the unlimited timeout and get no retry policy
+	action = routeAction(t, exposures, "wiki.local")
+	if got := action.GetTimeout().AsDuration(); got != 0 {
+		t.Errorf("default timeout = %v, want 0 (disabled)", got)
+	}
+	if action.GetRetryPolicy() != nil {
+		t.Errorf("default route has retry policy %v", action.GetRetryPolicy())
+	}
+}
@@ -33,7 +33,7 @@ export default function App() {
   useEffect(() => {
     const checkBoot = async () => {
       try {
-        const data = await bootApi.getBootStatus();
+        const data = await bootApi.listBootServices();
         // Check if boot-complete marker exists
         let isComplete = false;
         if (data && Array.isArray(data)) {
@@ -31,8 +31,8 @@ export default function BootStatus() {
 
     const checkAndPoll = async () => {
       try {
-        const data = await bootApi.getBootStatus();
-        // data is now BootServiceMarkers[] - array of {service, markers}
+        const data = await bootApi.listBootServices();
+        // data is BootServiceMarkers[] - array of {service, markers}
         setMarkersList(data || []);
         setError(null);
         // detect final marker (boot-complete step)
@@ -79,7 +79,7 @@ export default function BootStatus() {
   // fetchStatus/polling handled in effect above; helper to manually refresh if needed
   const refreshStatusOnce = async () => {
     try {
-      const data = await bootApi.getBootStatus();
+      const data = await bootApi.listBootServices();
       setMarkersList(data);
       setError(null);
     } catch (err) {